@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -174,6 +177,7 @@ func createGitHubRelease(ctx context.Context, projectRoot, newVersion string) er
 		{"windows", "amd64"},
 	}
 
+	var checksums string
 	for _, arch := range architectures {
 		filename := fmt.Sprintf("gitspace_%s_%s", arch.goos, arch.goarch)
 		if arch.goos == "windows" {
@@ -191,6 +195,16 @@ func createGitHubRelease(ctx context.Context, projectRoot, newVersion string) er
 		}
 		defer file.Close()
 
+		h := sha256.New()
+		if _, err := io.Copy(h, file); err != nil {
+			fmt.Printf("Warning: failed to checksum binary %s: %v\n", filename, err)
+			continue
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			fmt.Printf("Warning: failed to rewind binary %s: %v\n", filename, err)
+			continue
+		}
+
 		_, _, err = client.Repositories.UploadReleaseAsset(ctx, "ssotops", "gitspace", *release.ID, &github.UploadOptions{
 			Name: filename,
 		}, file)
@@ -199,9 +213,27 @@ func createGitHubRelease(ctx context.Context, projectRoot, newVersion string) er
 			// Continue with the next architecture instead of returning an error
 			continue
 		}
+		checksums += fmt.Sprintf("%s  %s\n", hex.EncodeToString(h.Sum(nil)), filename)
 		fmt.Printf("Successfully uploaded: %s\n", filename)
 	}
 
+	// gitspace upgrade refuses binaries that aren't listed here
+	checksumsPath := filepath.Join(projectRoot, "checksums.txt")
+	if err := os.WriteFile(checksumsPath, []byte(checksums), 0644); err != nil {
+		return fmt.Errorf("failed to write checksums: %v", err)
+	}
+	checksumsFile, err := os.Open(checksumsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open checksums: %v", err)
+	}
+	defer checksumsFile.Close()
+	_, _, err = client.Repositories.UploadReleaseAsset(ctx, "ssotops", "gitspace", *release.ID, &github.UploadOptions{
+		Name: "checksums.txt",
+	}, checksumsFile)
+	if err != nil {
+		return fmt.Errorf("failed to upload checksums: %v", err)
+	}
+
 	fmt.Printf("Release %s created: %s\n", newVersion, *release.HTMLURL)
 	return nil
 }
@@ -0,0 +1,693 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/plugin"
+)
+
+// Exit codes for non-interactive runs
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+
+	// exitUpdateAvailable is --check-update finding a newer release
+	exitUpdateAvailable = 10
+)
+
+const cliUsage = `Usage: gitspace [command] [flags]
+
+Run without a command, or with only --config <path> or --profile <name>, to
+start the interactive menu.
+
+Commands:
+  clone                          Clone or update repositories matching the config
+  sync                           Fetch updates for already-cloned repositories pushed to
+                                 since their last sync (--force-all to fetch all,
+                                 --since 24h or --since 2024-05-01 to pick the cutoff)
+  watch                          Sync on an interval until interrupted (--interval 10m)
+  symlinks create|delete         Create or delete symlinks (--scope local|global|all)
+  plugins list                   List installed plugins
+  plugins run <name> <command>   Run a plugin command (--param key=value, repeatable;
+                                 --skip-checksum to run a rebuilt plugin during development)
+  init [path]                    Create a commented config with a few questions
+                                 (default: gs.toml)
+  config validate [path]         Check a config without cloning anything (default:
+                                 the --config path, then the active config)
+  config migrate <file>          Convert a legacy gitspace.hcl or JSON config to TOML
+                                 on stdout (--out gs.toml to write a new file)
+  index list                     List the repositories in index.toml (--scm, --owner,
+                                 --type, --label, --synced-within 24h, --not-synced-for 168h)
+  find <query>                   Find cached repositories by name across every scm and
+                                 owner (--match startsWith|endsWith|includes|isExactly|
+                                 regex|hasTopic, default: includes)
+  prune                          Remove clones the config no longer matches, with their
+                                 links; clones with uncommitted changes or unpushed
+                                 commits are kept (--force to remove them too; --yes to
+                                 remove without asking)
+  status                         Show each clone's branch, commits ahead of and behind
+                                 origin, uncommitted changes and last sync, without
+                                 fetching
+  doctor                         Check tokens, SSH key, config and directories
+  upgrade                        Show the release notes and install the latest release
+                                 (--version v1.2.3 for a specific one; --yes to install
+                                 without asking)
+  version                        Print version information
+  --version                      Print only the version, without checking for updates
+  --check-update                 Report whether a newer release exists without
+                                 upgrading (exit code 10 when one does)
+  help                           Show this help
+
+Flags:
+  --config <path>                Config file to use (default: the --profile's config,
+                                 then $GITSPACE_CONFIG, then the active config)
+  --profile <name>               Use a saved profile for this run, without switching
+                                 to it
+  --log-level <level>            debug, info, warn, error or fatal (default: info,
+                                 or $GITSPACE_LOG_LEVEL)
+  -q, --quiet                    Print only errors and final summaries (log level
+                                 error unless --log-level is given)
+  -v, --verbose                  Print every detail (log level debug unless
+                                 --log-level is given)
+  --output text|json|yaml        Result format for clone, sync and status (default: text)
+  --offline                      Make no network requests (or $GITSPACE_OFFLINE=1):
+                                 cached repository lists are used however old, and
+                                 clone, sync, upgrades and the catalog are disabled
+                                 except for local repositories
+`
+
+// runCLI runs a single non-interactive command and returns the process exit code
+func runCLI(logger *logger.RateLimitedLogger, args []string) int {
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "clone", "sync":
+		return runRepositoriesCommand(logger, command, args)
+	case "watch":
+		return runWatchCommand(logger, args)
+	case "symlinks":
+		return runSymlinksCommand(logger, args)
+	case "plugins", "plugin":
+		return runPluginsCommand(logger, args)
+	case "config":
+		return runConfigCommand(logger, args)
+	case "init":
+		return runInitCommand(logger, args)
+	case "index":
+		return runIndexCommand(args)
+	case "find":
+		return runFindCommand(logger, args)
+	case "prune":
+		return runPruneCommand(logger, args)
+	case "status":
+		return runStatusCommand(logger, args)
+	case "doctor":
+		return runDoctorCommand(logger, args)
+	case "upgrade":
+		return runUpgradeCommand(logger, args)
+	case "version":
+		printVersionInfo(logger)
+		return exitOK
+	case "--version":
+		version, _ := getCurrentVersion()
+		fmt.Println(version)
+		return exitOK
+	case "--check-update":
+		check, err := checkForUpdate("ssotops/gitspace")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to check for updates: %v\n", err)
+			return exitError
+		}
+		fmt.Println(check)
+		if check.Available {
+			return exitUpdateAvailable
+		}
+		return exitOK
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, cliUsage)
+		return exitUsage
+	}
+}
+
+// configPathEnv names the config to use when --config isn't given
+const configPathEnv = "GITSPACE_CONFIG"
+
+// newFlagSet returns a flag set for a command with the shared --config flag,
+// which defaults to the --profile's config, then GITSPACE_CONFIG
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defaultPath := profileConfigPath
+	if defaultPath == "" {
+		defaultPath = os.Getenv(configPathEnv)
+	}
+	configPath := fs.String("config", defaultPath, "config file to use")
+	return fs, configPath
+}
+
+// menuConfigPath returns the config the interactive menu should load without
+// prompting, and where it came from: --config when it is the only argument,
+// then --profile, then GITSPACE_CONFIG. menuOnly reports whether args were just --config, so
+// they start the menu rather than select a command.
+func menuConfigPath(args []string) (path, source string, menuOnly bool) {
+	switch {
+	case len(args) == 2 && (args[0] == "--config" || args[0] == "-config"):
+		return args[1], "--config", true
+	case len(args) == 1 && (strings.HasPrefix(args[0], "--config=") || strings.HasPrefix(args[0], "-config=")):
+		_, path, _ = strings.Cut(args[0], "=")
+		return path, "--config", true
+	}
+	if profileConfigPath != "" {
+		return profileConfigPath, "--profile", len(args) == 0
+	}
+	if path = os.Getenv(configPathEnv); path != "" {
+		return path, configPathEnv, len(args) == 0
+	}
+	return "", "", len(args) == 0
+}
+
+// parseFlags parses args and reports usage errors on stderr
+func parseFlags(fs *flag.FlagSet, args []string) bool {
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n\n%s", fs.Name(), err, cliUsage)
+		return false
+	}
+	return true
+}
+
+// loadCLIConfig loads the config at path, or the active config when path is empty
+func loadCLIConfig(logger *logger.RateLimitedLogger, path string) (*Config, error) {
+	if path == "" {
+		currentPath, err := getCurrentConfigPath(logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the active config: %w", err)
+		}
+		if currentPath == "" {
+			return nil, fmt.Errorf("no active config; pass --config <path>")
+		}
+		path = currentPath
+	}
+
+	config, err := loadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config %s: %w", path, err)
+	}
+	pruneLogFiles(logger, config)
+	return config, nil
+}
+
+func runRepositoriesCommand(logger *logger.RateLimitedLogger, command string, args []string) int {
+	fs, configPath := newFlagSet(command)
+	output := fs.String("output", outputText, "text, json or yaml")
+	var opts syncOptions
+	var since string
+	if command == "sync" {
+		fs.BoolVar(&opts.ForceAll, "force-all", false, "fetch every repository, even those with no pushes since the last sync")
+		fs.StringVar(&since, "since", "", "only fetch repositories pushed to since this duration ago or date")
+	}
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+	if since != "" {
+		var err error
+		if opts.Since, err = parseSince(since, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: --since: %v\n", command, err)
+			return exitUsage
+		}
+	}
+	switch *output {
+	case outputText:
+	case outputJSON, outputYAML:
+		// Keep stdout parseable
+		progressOutput = os.Stderr
+	default:
+		fmt.Fprintf(os.Stderr, "%s: --output must be text, json or yaml (got %q)\n", command, *output)
+		return exitUsage
+	}
+
+	config, err := loadCLIConfig(logger, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	var results map[string]*RepoResult
+	if command == "clone" {
+		results, err = cloneRepositories(logger, config, nil)
+	} else {
+		results, err = syncRepositories(logger, config, opts)
+	}
+	finishSession(logger, config, false)
+
+	if len(results) > 0 || *output != outputText {
+		if writeErr := writeResults(os.Stdout, *output, results); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "failed to write results: %v\n", writeErr)
+			return exitError
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", command, err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runWatchCommand(logger *logger.RateLimitedLogger, args []string) int {
+	fs, configPath := newFlagSet("watch")
+	intervalFlag := fs.String("interval", "", "time between syncs, e.g. 10m")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	config, err := loadCLIConfig(logger, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	interval := getWatchInterval(config)
+	if *intervalFlag != "" {
+		interval, err = parseWatchInterval(*intervalFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "watch: --interval: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watchRepositories(ctx, logger, config, interval)
+	finishSession(logger, config, false)
+	return exitOK
+}
+
+func runSymlinksCommand(logger *logger.RateLimitedLogger, args []string) int {
+	if len(args) == 0 || (args[0] != "create" && args[0] != "delete") {
+		fmt.Fprintf(os.Stderr, "symlinks: expected create or delete\n\n%s", cliUsage)
+		return exitUsage
+	}
+	action := args[0]
+
+	fs, configPath := newFlagSet("symlinks " + action)
+	scope := fs.String("scope", "all", "local, global or all")
+	if !parseFlags(fs, args[1:]) {
+		return exitUsage
+	}
+
+	var local, global bool
+	switch strings.ToLower(*scope) {
+	case "local":
+		local = true
+	case "global":
+		global = true
+	case "all":
+		local, global = true, true
+	default:
+		fmt.Fprintf(os.Stderr, "symlinks: --scope must be local, global or all (got %q)\n", *scope)
+		return exitUsage
+	}
+
+	config, err := loadCLIConfig(logger, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if action == "create" {
+		if local {
+			createLocalSymlinks(logger, config)
+		}
+		if global {
+			createGlobalSymlinks(logger, config)
+		}
+	} else {
+		if local {
+			deleteLocalSymlinks(logger, config)
+		}
+		if global {
+			deleteGlobalSymlinks(logger, config)
+		}
+	}
+	return exitOK
+}
+
+func runPluginsCommand(logger *logger.RateLimitedLogger, args []string) int {
+	if len(args) > 0 && args[0] == "run" {
+		return runPluginCommand(logger, args[1:])
+	}
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintf(os.Stderr, "plugins: expected list or run\n\n%s", cliUsage)
+		return exitUsage
+	}
+
+	plugins, err := plugin.ListInstalledPlugins(logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to list installed plugins: %v\n", err)
+		return exitError
+	}
+
+	for _, name := range plugins {
+		// The data directory holds plugin state, not a plugin
+		if name != "data" {
+			fmt.Println(name)
+		}
+	}
+	return exitOK
+}
+
+// paramFlag collects repeated --param key=value flags
+type paramFlag map[string]string
+
+func (p paramFlag) String() string {
+	return fmt.Sprint(map[string]string(p))
+}
+
+func (p paramFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	p[key] = val
+	return nil
+}
+
+// runPluginCommand loads a plugin, runs one of its menu commands and prints
+// the result. Parameters are validated against the plugin's menu by
+// Manager.ExecuteCommand.
+func runPluginCommand(logger *logger.RateLimitedLogger, args []string) int {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintf(os.Stderr, "plugins run: expected a plugin name and command\n\n%s", cliUsage)
+		return exitUsage
+	}
+	name, command := args[0], args[1]
+
+	params := paramFlag{}
+	fs, configPath := newFlagSet("plugins run")
+	fs.Var(params, "param", "key=value parameter, may be repeated")
+	skipChecksum := fs.Bool("skip-checksum", false, "don't verify the plugin binary's install checksum")
+	if !parseFlags(fs, args[2:]) {
+		return exitUsage
+	}
+
+	// Plugins run without a config; one only tunes the plugin settings
+	config, err := loadCLIConfig(logger, *configPath)
+	if err != nil && *configPath != "" {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	manager := plugin.NewManager(logger)
+	configurePluginManager(manager, config)
+	if *skipChecksum {
+		manager.SetSkipChecksum(true)
+	}
+	if err := manager.DiscoverPlugins(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to discover plugins: %v\n", err)
+		return exitError
+	}
+	if _, ok := manager.GetFilteredPlugins()[name]; !ok {
+		fmt.Fprintf(os.Stderr, "plugin %q is not installed\n", name)
+		return exitError
+	}
+	if err := manager.LoadPlugin(name); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load plugin %s: %v\n", name, err)
+		return exitError
+	}
+	defer manager.UnloadPlugin(name)
+
+	result, err := manager.ExecuteCommand(name, command, params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s failed: %v\n", name, command, err)
+		return exitError
+	}
+	fmt.Println(result)
+	return exitOK
+}
+
+func runConfigCommand(logger *logger.RateLimitedLogger, args []string) int {
+	if len(args) > 0 && args[0] == "migrate" {
+		return runConfigMigrateCommand(args[1:])
+	}
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintf(os.Stderr, "config: expected validate or migrate\n\n%s", cliUsage)
+		return exitUsage
+	}
+
+	fs, configPath := newFlagSet("config validate")
+	if !parseFlags(fs, args[1:]) {
+		return exitUsage
+	}
+	path := *configPath
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	// Resolve the active config directly; loadCLIConfig also prunes logs
+	if path == "" {
+		currentPath, err := getCurrentConfigPath(logger)
+		if err != nil || currentPath == "" {
+			fmt.Fprintln(os.Stderr, "config validate: no active config; pass a path")
+			return exitUsage
+		}
+		path = currentPath
+	}
+
+	if !printConfigCheck(path, checkConfigFile(path)) {
+		return exitError
+	}
+	return exitOK
+}
+
+// runConfigMigrateCommand converts a legacy gitspace.hcl or JSON config to
+// TOML on stdout, or into a new file with --out
+func runConfigMigrateCommand(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "config migrate: expected a legacy config file\n\n%s", cliUsage)
+		return exitUsage
+	}
+	oldPath := args[0]
+
+	fs := flag.NewFlagSet("config migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	out := fs.String("out", "", "file to write the TOML config to")
+	if !parseFlags(fs, args[1:]) {
+		return exitUsage
+	}
+
+	data, err := os.ReadFile(oldPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config migrate: %v\n", err)
+		return exitError
+	}
+	migrated, problems, err := migrateLegacyConfig(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config migrate: %s: %v\n", oldPath, err)
+		return exitError
+	}
+
+	if *out == "" {
+		os.Stdout.Write(migrated)
+	} else if err := writeMigratedConfig(*out, migrated); err != nil {
+		fmt.Fprintf(os.Stderr, "config migrate: %v\n", err)
+		return exitError
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", *out)
+	}
+
+	// Converted fine, but the legacy file lacked something the TOML needs
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", problem)
+	}
+	return exitOK
+}
+
+func runInitCommand(logger *logger.RateLimitedLogger, args []string) int {
+	path := defaultInitPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	err := runInitWizard(logger, path)
+	if errors.Is(err, errInitCancelled) {
+		fmt.Fprintf(os.Stderr, "init: left %s unchanged\n", path)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return exitError
+	}
+	fmt.Printf("Created %s. Edit its groups, then run: gitspace clone --config %s\n", path, path)
+	return exitOK
+}
+
+func runIndexCommand(args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintf(os.Stderr, "index: expected list\n\n%s", cliUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("index list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var filter indexFilter
+	fs.StringVar(&filter.SCM, "scm", "", "only this scm")
+	fs.StringVar(&filter.Owner, "owner", "", "only this owner")
+	fs.StringVar(&filter.Type, "type", "", "only this type")
+	fs.StringVar(&filter.Label, "label", "", "only repositories with this label")
+	fs.DurationVar(&filter.SyncedWithin, "synced-within", 0, "only repositories synced this recently")
+	fs.DurationVar(&filter.NotSyncedFor, "not-synced-for", 0, "only repositories not synced for this long")
+	if !parseFlags(fs, args[1:]) {
+		return exitUsage
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting cache directory: %v\n", err)
+		return exitError
+	}
+	repos, err := readIndex(cacheDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	printIndexTable(filterIndex(repos, filter, time.Now()))
+	return exitOK
+}
+
+func runFindCommand(logger *logger.RateLimitedLogger, args []string) int {
+	fs := flag.NewFlagSet("find", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	match := fs.String("match", "includes", "how the query matches repository names")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+	positional := fs.Args()
+	// Allow flags after the query too
+	if len(positional) > 1 {
+		if !parseFlags(fs, positional[1:]) {
+			return exitUsage
+		}
+		positional = append([]string{positional[0]}, fs.Args()...)
+	}
+	if len(positional) != 1 || positional[0] == "" {
+		fmt.Fprintf(os.Stderr, "find: expected a single query\n\n%s", cliUsage)
+		return exitUsage
+	}
+	query := positional[0]
+	if err := checkFindQuery(*match, query); err != nil {
+		fmt.Fprintf(os.Stderr, "find: %v\n", err)
+		return exitUsage
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting cache directory: %v\n", err)
+		return exitError
+	}
+	found, err := findRepositories(logger, cacheDir, *match, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "find failed: %v\n", err)
+		return exitError
+	}
+	printFoundRepositories(found)
+	return exitOK
+}
+
+func runStatusCommand(logger *logger.RateLimitedLogger, args []string) int {
+	fs, configPath := newFlagSet("status")
+	output := fs.String("output", outputText, "text, json or yaml")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+	switch *output {
+	case outputText, outputJSON, outputYAML:
+	default:
+		fmt.Fprintf(os.Stderr, "status: --output must be text, json or yaml (got %q)\n", *output)
+		return exitUsage
+	}
+
+	config, err := loadCLIConfig(logger, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	statuses, err := workspaceStatus(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "status failed: %v\n", err)
+		return exitError
+	}
+	if err := writeStatus(os.Stdout, *output, statuses); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write status: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runPruneCommand(logger *logger.RateLimitedLogger, args []string) int {
+	fs, configPath := newFlagSet("prune")
+	force := fs.Bool("force", false, "also remove clones with local work")
+	yes := fs.Bool("yes", false, "remove without asking")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	config, err := loadCLIConfig(logger, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	confirm := confirmPrune
+	if *yes {
+		confirm = func(string) (bool, error) { return true, nil }
+	}
+	if err := pruneRepositories(logger, config, *force, confirm); err != nil {
+		fmt.Fprintf(os.Stderr, "prune failed: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runUpgradeCommand(logger *logger.RateLimitedLogger, args []string) int {
+	fs := flag.NewFlagSet("upgrade", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	version := fs.String("version", "", "release to install (default: the latest)")
+	yes := fs.Bool("yes", false, "install without asking")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	confirm := confirmUpgrade
+	if *yes {
+		confirm = func(string) (bool, error) { return true, nil }
+	}
+	if err := upgradeGitspace(logger, *version, confirm); err != nil {
+		fmt.Fprintf(os.Stderr, "upgrade failed, keeping the current binary: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runDoctorCommand(logger *logger.RateLimitedLogger, args []string) int {
+	fs, configPath := newFlagSet("doctor")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	config, err := loadCLIConfig(logger, *configPath)
+	if !printDoctorReport(runDoctorChecks(config, err)) {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestConfig writes a config file to path, creating its directory
+func writeTestConfig(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func validTestConfig(t *testing.T) string {
+	return fmt.Sprintf(`
+[global]
+path = %q
+scm = "github"
+owner = "acme"
+
+[groups.all]
+match = "regex"
+values = [".*"]
+`, t.TempDir())
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	logger := newTestLogger(t)
+	valid := filepath.Join(t.TempDir(), "valid.toml")
+	writeTestConfig(t, valid, validTestConfig(t))
+	invalid := filepath.Join(t.TempDir(), "invalid.toml")
+	writeTestConfig(t, invalid, "[global]\nscm = \"svn\"\n")
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI(logger, []string{"config", "validate", valid}) })
+	if code != exitOK || !strings.Contains(out, valid+": valid") {
+		t.Errorf("valid config: exit %d, printed %q", code, out)
+	}
+
+	out = captureStdout(t, func() { code = runCLI(logger, []string{"config", "validate", "--config", invalid}) })
+	if code != exitError || !strings.Contains(out, "3 problem(s)") || !strings.Contains(out, "unsupported SCM type: svn") {
+		t.Errorf("invalid config: exit %d, printed %q", code, out)
+	}
+
+	if code := runCLI(logger, []string{"config"}); code != exitUsage {
+		t.Errorf("config without a subcommand exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestConfigValidateDefaultsToActiveConfig(t *testing.T) {
+	logger := newTestLogger(t)
+	if code := runCLI(logger, []string{"config", "validate"}); code != exitUsage {
+		t.Errorf("without an active config exited %d, want %d", code, exitUsage)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := filepath.Join(home, managedConfigDir, activeConfigFile)
+	writeTestConfig(t, active, validTestConfig(t))
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI(logger, []string{"config", "validate"}) })
+	if code != exitOK || !strings.Contains(out, active+": valid") {
+		t.Errorf("active config: exit %d, printed %q", code, out)
+	}
+}
+
+func TestMenuConfigPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		env          string
+		wantPath     string
+		wantSource   string
+		wantMenuOnly bool
+	}{
+		{"no arguments", nil, "", "", "", true},
+		{"config flag", []string{"--config", "a.toml"}, "", "a.toml", "--config", true},
+		{"single dash config flag", []string{"-config", "a.toml"}, "", "a.toml", "--config", true},
+		{"config flag with equals", []string{"--config=a.toml"}, "", "a.toml", "--config", true},
+		{"flag overrides environment", []string{"--config", "a.toml"}, "b.toml", "a.toml", "--config", true},
+		{"environment", nil, "b.toml", "b.toml", configPathEnv, true},
+		{"command with environment", []string{"clone"}, "b.toml", "b.toml", configPathEnv, false},
+		{"command with config flag", []string{"clone", "--config", "a.toml"}, "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configPathEnv, tt.env)
+			path, source, menuOnly := menuConfigPath(tt.args)
+			if path != tt.wantPath || source != tt.wantSource || menuOnly != tt.wantMenuOnly {
+				t.Errorf("menuConfigPath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.args, path, source, menuOnly, tt.wantPath, tt.wantSource, tt.wantMenuOnly)
+			}
+		})
+	}
+}
+
+func TestCommandsDefaultToConfigEnv(t *testing.T) {
+	logger := newTestLogger(t)
+	valid := filepath.Join(t.TempDir(), "valid.toml")
+	writeTestConfig(t, valid, validTestConfig(t))
+	invalid := filepath.Join(t.TempDir(), "invalid.toml")
+	writeTestConfig(t, invalid, "[global]\nscm = \"svn\"\n")
+	t.Setenv(configPathEnv, valid)
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI(logger, []string{"config", "validate"}) })
+	if code != exitOK || !strings.Contains(out, valid+": valid") {
+		t.Errorf("%s: exit %d, printed %q", configPathEnv, code, out)
+	}
+
+	out = captureStdout(t, func() { code = runCLI(logger, []string{"config", "validate", "--config", invalid}) })
+	if code != exitError || !strings.Contains(out, invalid) {
+		t.Errorf("--config didn't override %s: exit %d, printed %q", configPathEnv, code, out)
+	}
+}
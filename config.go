@@ -1,43 +1,117 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/log"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pelletier/go-toml"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
 )
 
 type Config struct {
 	Global struct {
-		Path                   string `toml:"path"`
-		SCM                    string `toml:"scm"`
-		Owner                  string `toml:"owner"`
-		BaseURL                string `toml:"base_url"`
-		EmptyRepoInitialBranch string `toml:"empty_repo_initial_branch"`
+		Path                   string   `toml:"path"`
+		SCM                    string   `toml:"scm"`
+		Owner                  string   `toml:"owner"`
+		OwnerType              string   `toml:"owner_type"`
+		BaseURL                string   `toml:"base_url"`
+		EmptyRepoInitialBranch string   `toml:"empty_repo_initial_branch"`
+		Concurrency            int      `toml:"concurrency"`
+		DefaultBranch          string   `toml:"default_branch"`
+		CloneDepth             int      `toml:"clone_depth"`
+		RepoListTTL            string   `toml:"repo_list_ttl"`
+		Visibility             string   `toml:"visibility"`
+		IncludeArchived        bool     `toml:"include_archived"`
+		Labels                 []string `toml:"labels"`
+		LinkMode               string   `toml:"link_mode"`
+		RateLimitWait          string   `toml:"rate_limit_wait"`
+		NetworkTimeout         string   `toml:"network_timeout"`
+		MaxRetries             *int     `toml:"max_retries"` // nil means the default, 0 disables retries
+		WatchInterval          string   `toml:"watch_interval"`
+		PostCloneHook          string   `toml:"post_clone_hook" expand:"false"`
+		SyncStrategy           string   `toml:"sync_strategy"`
+		DirtyPolicy            string   `toml:"dirty_policy"`
+		InitSubmodules         bool     `toml:"init_submodules"`
+		SubmodulesRecursive    bool     `toml:"submodules_recursive"` // Also update submodules of submodules
+		LFS                    bool     `toml:"lfs"`
+		PathTemplate           string   `toml:"path_template" expand:"false"` // Where each repository is linked under path
+		LocalSymlinkLayout     string   `toml:"local_symlink_layout"`
 	} `toml:"global"`
 	Auth struct {
-		Type    string `toml:"type"`
-		KeyPath string `toml:"key_path"`
+		Type      string `toml:"type"`
+		KeyPath   string `toml:"key_path"`
+		Token     string `toml:"token"`
+		TokenPath string `toml:"token_path"`
 	} `toml:"auth"`
+	Plugins struct {
+		MaxRestarts       *int              `toml:"max_restarts"` // nil means plugin.DefaultMaxRestarts
+		RequestTimeout    string            `toml:"request_timeout"`
+		AllowIncompatible bool              `toml:"allow_incompatible"`
+		SkipChecksum      bool              `toml:"skip_checksum"`
+		MaxMessageSize    int               `toml:"max_message_size"` // Bytes; 0 means plugin.DefaultMaxMessageSize
+		MaxMemoryMB       int               `toml:"max_memory_mb"`
+		MaxRuntimeSeconds int               `toml:"max_runtime_seconds"`
+		LogLevels         map[string]string `toml:"log_levels"` // Plugin name to log level
+	} `toml:"plugins"`
+	Logging struct {
+		MaxBackups  int  `toml:"max_backups"`  // Log files kept per logger besides the current one; 0 keeps all
+		MaxAgeDays  int  `toml:"max_age_days"` // 0 keeps log files regardless of age
+		MaxSizeMB   int  `toml:"max_size_mb"`  // Total size of a logger's files; 0 means no cap
+		SessionFile bool `toml:"session_file"` // Write ~/.ssot/gitspace/last_session.json at exit
+	} `toml:"logging"`
 	Groups map[string]Group `toml:"groups"`
+	Clone  []CloneTarget    `toml:"clone"`
+}
+
+// CloneTarget is a single scm/owner to clone from. Fields left empty fall back
+// to the matching global value.
+type CloneTarget struct {
+	SCM       string `toml:"scm"`
+	Owner     string `toml:"owner"`
+	BaseURL   string `toml:"base_url"`
+	OwnerType string `toml:"owner_type"`
 }
 
 type Group struct {
 	Match  string   `toml:"match"`
 	Values []string `toml:"values"`
 	Type   string   `toml:"type,omitempty"`
+	Branch string   `toml:"branch,omitempty"`
+	Commit string   `toml:"commit,omitempty"` // Pin repositories to this commit, detached
+	// Overrides global.post_clone_hook for the group's repositories
+	PostCloneHook string      `toml:"post_clone_hook,omitempty" expand:"false"`
+	Labels        []string    `toml:"labels,omitempty"`
+	Priority      int         `toml:"priority,omitempty"` // Higher wins when a repo matches several groups
+	Exclude       []MatchRule `toml:"exclude,omitempty"`
+	// Repositories cloned and synced before the group's repositories
+	DependsOn []string `toml:"depends_on,omitempty"`
+	LFS       *bool    `toml:"lfs,omitempty"` // Overrides global.lfs; nil leaves it
+}
+
+// MatchRule is a standalone match verb and values, used for group exclusions
+type MatchRule struct {
+	Match  string   `toml:"match"`
+	Values []string `toml:"values"`
 }
 
 const (
-	managedConfigDir = "/.ssot/gitspace/configs/active" // Where we store our active config
-	configBackupDir  = "/.ssot/gitspace/configs/backup" // Where we store backups
-	activeConfigFile = "current.toml"                   // The name of our active config file
+	managedConfigDir  = "/.ssot/gitspace/configs/active"         // Where we store our active config
+	configBackupDir   = "/.ssot/gitspace/configs/backup"         // Where we store backups
+	activeConfigFile  = "current.toml"                           // The name of our active config file
+	profilesDir       = "/.ssot/gitspace/configs/profiles"       // Named configs, one <name>.toml each
+	activeProfileFile = "/.ssot/gitspace/configs/active_profile" // Name of the active profile, if any
 
 	// Legacy paths (needed for transition/compatibility)
 	configSymlinkDir = "/.ssot/gitspace/.symlinks"    // Legacy symlink directory
@@ -56,6 +130,96 @@ func getSSHKeyPath(configPath string) (string, error) {
 	return configPath, nil
 }
 
+// resolveToken returns the API token to use, in order of precedence:
+// auth.token (which may name an environment variable with a "$" prefix, like
+// key_path), the contents of auth.token_path, then the envVar environment
+// variable. An empty result with a nil error means no token is configured.
+func resolveToken(config *Config, envVar string) (string, error) {
+	if config.Auth.Token != "" {
+		token, err := getSSHKeyPath(config.Auth.Token)
+		if err != nil {
+			return "", fmt.Errorf("auth.token: %w", err)
+		}
+		return token, nil
+	}
+
+	if config.Auth.TokenPath != "" {
+		tokenPath, err := homedir.Expand(config.Auth.TokenPath)
+		if err != nil {
+			return "", fmt.Errorf("auth.token_path: %w", err)
+		}
+		data, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return "", fmt.Errorf("auth.token_path: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return os.Getenv(envVar), nil
+}
+
+// normalizeSCM maps the scm value from a config file onto a lib.SCMType.
+// Both the short form ("github") and the hostname form ("github.com") are
+// accepted; any other hostname is treated as a self-hosted Gitea instance.
+// Self-hosted GitLab is scm "gitlab" with base_url set to the instance.
+func normalizeSCM(scm string) (lib.SCMType, error) {
+	value := canonicalSCM(scm)
+
+	switch value {
+	case "":
+		return "", fmt.Errorf("scm is empty")
+	case "github":
+		return lib.SCMTypeGitHub, nil
+	case "gitea":
+		return lib.SCMTypeGitea, nil
+	case "gitlab":
+		return lib.SCMTypeGitLab, nil
+	case "bitbucket":
+		return lib.SCMTypeBitbucket, nil
+	case "local":
+		return lib.SCMTypeLocal, nil
+	}
+
+	if strings.ContainsAny(value, ".:") {
+		return lib.SCMTypeGitea, nil
+	}
+
+	return "", fmt.Errorf("unsupported SCM type: %s", scm)
+}
+
+// scmAliases maps the hostname forms of the hosted SCMs onto their short
+// names, so "github" and "github.com" share one cache tree
+var scmAliases = map[string]string{
+	"github.com":     "github",
+	"www.github.com": "github",
+	"gitlab.com":     "gitlab",
+	"bitbucket.org":  "bitbucket",
+}
+
+// canonicalSCM is the scm value as used in cache paths, result keys and the
+// index: lowercase, without a scheme or surrounding whitespace, and the short
+// name for the hostname of a hosted SCM
+func canonicalSCM(scm string) string {
+	value := strings.ToLower(strings.TrimSpace(scm))
+	value = strings.TrimPrefix(value, "https://")
+	value = strings.TrimPrefix(value, "http://")
+	value = strings.TrimSuffix(value, "/")
+	if alias, ok := scmAliases[value]; ok {
+		return alias
+	}
+	return value
+}
+
+// validateBaseURL checks a base_url for the given scm. Local repositories
+// take a directory rather than an http(s) URL.
+func validateBaseURL(scm, baseURL string) error {
+	if scmType, err := normalizeSCM(scm); err == nil && scmType == lib.SCMTypeLocal {
+		_, err := lib.LocalRoot(baseURL)
+		return err
+	}
+	return lib.ValidateBaseURL(baseURL)
+}
+
 func getCacheDir() (string, error) {
 	homeDir, err := homedir.Dir()
 	if err != nil {
@@ -130,9 +294,9 @@ func getConfigFromUser(logger *logger.RateLimitedLogger) (*Config, error) {
 
 func loadConfig(path string) (*Config, error) {
 	config := &Config{}
-	tree, err := toml.LoadFile(path)
+	tree, err := loadConfigTree(path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load TOML file: %w", err)
+		return nil, err
 	}
 
 	err = tree.Unmarshal(config)
@@ -140,21 +304,367 @@ func loadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal TOML: %w", err)
 	}
 
+	// Report unset variables along with every validation problem
+	errs := expandConfigEnv(config)
+	errs = append(errs, splitErrors(validateConfig(config))...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return config, nil
+}
+
+// checkConfigFile loads and validates the config at path without changing
+// anything on disk or the network, and returns every problem found
+func checkConfigFile(path string) []error {
+	_, err := loadConfig(path)
+	return splitErrors(err)
+}
+
+// splitErrors returns the errors joined into err by errors.Join, err itself
+// if it wasn't joined, or nil
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// printConfigCheck prints the result of checkConfigFile and reports whether
+// the config is valid
+func printConfigCheck(path string, problems []error) bool {
+	if len(problems) == 0 {
+		fmt.Printf("%s: valid\n", path)
+		return true
+	}
+	fmt.Printf("%s: %d problem(s)\n", path, len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  - %v\n", problem)
+	}
+	return false
+}
+
+// commitPattern matches a full or abbreviated commit hash
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// knownMatchVerbs are the values accepted for a group's or exclude rule's match
+var knownMatchVerbs = []string{"startsWith", "endsWith", "includes", "isExactly", "regex", "hasTopic"}
+
+// validateConfig checks the config and fills in defaults. Every problem found
+// is reported at once, joined with errors.Join, rather than just the first.
+func validateConfig(config *Config) error {
+	var errs []error
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
 	// Validate required fields
 	if config.Global.Path == "" {
-		return nil, fmt.Errorf("global.path is required")
+		addErr("global.path is required")
+	}
+	if len(config.Clone) == 0 {
+		if config.Global.SCM == "" {
+			addErr("global.scm is required")
+		}
+		if config.Global.Owner == "" {
+			addErr("global.owner is required")
+		}
+	}
+	if config.Global.SCM != "" {
+		if _, err := normalizeSCM(config.Global.SCM); err != nil {
+			addErr("global.scm: %w", err)
+		}
+		config.Global.SCM = canonicalSCM(config.Global.SCM)
 	}
-	if config.Global.SCM == "" {
-		return nil, fmt.Errorf("global.scm is required")
+	if config.Global.BaseURL != "" {
+		if err := validateBaseURL(config.Global.SCM, config.Global.BaseURL); err != nil {
+			addErr("global.base_url: %w", err)
+		}
+	}
+	if err := validateOwnerType(config.Global.OwnerType); err != nil {
+		addErr("global.owner_type: %w", err)
 	}
-	if config.Global.Owner == "" {
-		return nil, fmt.Errorf("global.owner is required")
+	if config.Global.OwnerType == "" {
+		config.Global.OwnerType = string(lib.OwnerTypeAuto)
+	}
+	for i := range config.Clone {
+		target := &config.Clone[i]
+		if target.SCM == "" {
+			target.SCM = config.Global.SCM
+		}
+		target.SCM = canonicalSCM(target.SCM)
+		if target.Owner == "" {
+			addErr("clone[%d].owner is required", i)
+		}
+		if target.SCM == "" {
+			addErr("clone[%d].scm is required", i)
+		} else if _, err := normalizeSCM(target.SCM); err != nil {
+			addErr("clone[%d].scm: %w", i, err)
+		}
+		if err := validateOwnerType(target.OwnerType); err != nil {
+			addErr("clone[%d].owner_type: %w", i, err)
+		}
+		if target.BaseURL != "" {
+			if err := validateBaseURL(target.SCM, target.BaseURL); err != nil {
+				addErr("clone[%d].base_url: %w", i, err)
+			}
+		}
+	}
+	switch lib.Visibility(config.Global.Visibility) {
+	case "":
+		config.Global.Visibility = string(lib.VisibilityAll)
+	case lib.VisibilityAll, lib.VisibilityPublic, lib.VisibilityPrivate:
+	default:
+		addErr("global.visibility must be one of all, public, private (got %q)", config.Global.Visibility)
 	}
 	if config.Global.EmptyRepoInitialBranch == "" {
 		config.Global.EmptyRepoInitialBranch = "master"
 	}
+	if config.Global.CloneDepth < 0 {
+		addErr("global.clone_depth must not be negative")
+	}
+	if config.Global.MaxRetries != nil && *config.Global.MaxRetries < 0 {
+		addErr("global.max_retries must not be negative")
+	}
+	if config.Global.WatchInterval != "" {
+		if _, err := parseWatchInterval(config.Global.WatchInterval); err != nil {
+			addErr("global.watch_interval: %w", err)
+		}
+	}
+	if config.Global.RepoListTTL != "" {
+		if _, err := time.ParseDuration(config.Global.RepoListTTL); err != nil {
+			addErr("global.repo_list_ttl: %w", err)
+		}
+	}
+	if config.Global.NetworkTimeout != "" {
+		if timeout, err := time.ParseDuration(config.Global.NetworkTimeout); err != nil {
+			addErr("global.network_timeout: %w", err)
+		} else if timeout <= 0 {
+			addErr("global.network_timeout must be positive")
+		}
+	}
+	if config.Global.RateLimitWait != "" {
+		if _, err := time.ParseDuration(config.Global.RateLimitWait); err != nil {
+			addErr("global.rate_limit_wait: %w", err)
+		}
+	}
+	if config.Global.Concurrency <= 0 {
+		config.Global.Concurrency = runtime.NumCPU()
+	}
 
-	return config, nil
+	switch config.Global.LinkMode {
+	case "":
+		config.Global.LinkMode = linkModeSymlink
+	case linkModeSymlink, linkModeCopy, linkModeHardlink:
+	default:
+		addErr("global.link_mode must be one of symlink, copy, hardlink (got %q)", config.Global.LinkMode)
+	}
+
+	switch config.Global.SyncStrategy {
+	case "":
+		config.Global.SyncStrategy = syncStrategyFetch
+	case syncStrategyFetch, syncStrategyFFOnly, syncStrategyPull:
+	default:
+		addErr("global.sync_strategy must be one of fetch, ff-only, pull (got %q)", config.Global.SyncStrategy)
+	}
+
+	switch config.Global.DirtyPolicy {
+	case "":
+		config.Global.DirtyPolicy = dirtyPolicySkip
+	case dirtyPolicySkip, dirtyPolicyStash, dirtyPolicyForce:
+	default:
+		addErr("global.dirty_policy must be one of skip, stash, force (got %q)", config.Global.DirtyPolicy)
+	}
+
+	if config.Plugins.MaxRestarts != nil && *config.Plugins.MaxRestarts < 0 {
+		addErr("plugins.max_restarts must not be negative")
+	}
+	if config.Plugins.MaxMessageSize < 0 {
+		addErr("plugins.max_message_size must not be negative")
+	}
+	if config.Plugins.MaxMemoryMB < 0 {
+		addErr("plugins.max_memory_mb must not be negative")
+	}
+	if config.Plugins.MaxRuntimeSeconds < 0 {
+		addErr("plugins.max_runtime_seconds must not be negative")
+	}
+	if config.Logging.MaxBackups < 0 {
+		addErr("logging.max_backups must not be negative")
+	}
+	if config.Logging.MaxAgeDays < 0 {
+		addErr("logging.max_age_days must not be negative")
+	}
+	if config.Logging.MaxSizeMB < 0 {
+		addErr("logging.max_size_mb must not be negative")
+	}
+	for name, level := range config.Plugins.LogLevels {
+		if _, err := log.ParseLevel(level); err != nil {
+			addErr("plugins.log_levels.%s: %w", name, err)
+		}
+	}
+	if config.Plugins.RequestTimeout != "" {
+		if _, err := time.ParseDuration(config.Plugins.RequestTimeout); err != nil {
+			addErr("plugins.request_timeout: %w", err)
+		}
+	}
+
+	switch config.Auth.Type {
+	case "", "ssh", "https", "token":
+	default:
+		addErr("auth.type must be one of ssh, https, token (got %q)", config.Auth.Type)
+	}
+
+	labels, err := normalizeLabels(config.Global.Labels)
+	if err != nil {
+		addErr("global.labels: %w", err)
+	} else {
+		config.Global.Labels = labels
+	}
+
+	if _, err := renderHook(config.Global.PostCloneHook, hookData{}); err != nil {
+		addErr("global.post_clone_hook: %w", err)
+	}
+	switch config.Global.LocalSymlinkLayout {
+	case "":
+		config.Global.LocalSymlinkLayout = layoutFlat
+	case layoutFlat, layoutByOwner:
+	default:
+		addErr("global.local_symlink_layout must be one of flat, by-owner (got %q)", config.Global.LocalSymlinkLayout)
+	}
+	if config.Global.PathTemplate == "" {
+		config.Global.PathTemplate = defaultPathTemplate
+	}
+	if _, err := renderPathTemplate(config.Global.PathTemplate, pathData{Name: "repo", Type: "default", Owner: "owner", SCM: "github"}); err != nil {
+		addErr("global.path_template: %w", err)
+	}
+
+	// Walk groups in name order so errors come out the same way every time
+	groupNames := make([]string, 0, len(config.Groups))
+	for name := range config.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+
+	for _, name := range groupNames {
+		group := config.Groups[name]
+		errs = append(errs, validateGroup(name, &group)...)
+		config.Groups[name] = group
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateGroup checks a single group and trims its labels, returning every
+// problem found
+func validateGroup(name string, group *Group) []error {
+	var errs []error
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	labels, err := normalizeLabels(group.Labels)
+	if err != nil {
+		addErr("groups.%s.labels: %w", name, err)
+	} else {
+		group.Labels = labels
+	}
+	if group.Commit != "" && !commitPattern.MatchString(group.Commit) {
+		addErr("groups.%s.commit must be a commit hash of 4 to 40 hex characters (got %q)", name, group.Commit)
+	}
+	if _, err := renderHook(group.PostCloneHook, hookData{}); err != nil {
+		addErr("groups.%s.post_clone_hook: %w", name, err)
+	}
+	for i, dep := range group.DependsOn {
+		if strings.TrimSpace(dep) == "" {
+			addErr("groups.%s.depends_on[%d] is empty", name, i)
+		}
+	}
+
+	// A group matches when its primary rule matches and none of its exclude
+	// rules do; exclusions are evaluated after the primary match. Compile regex
+	// patterns for both up front so typos surface here rather than during filtering.
+	rules := append([]MatchRule{{Match: group.Match, Values: group.Values}}, group.Exclude...)
+	for i, rule := range rules {
+		field := fmt.Sprintf("groups.%s", name)
+		if i > 0 {
+			field = fmt.Sprintf("groups.%s.exclude[%d]", name, i-1)
+		}
+		if !slices.Contains(knownMatchVerbs, rule.Match) {
+			addErr("%s: unknown match %q, must be one of %s", field, rule.Match, strings.Join(knownMatchVerbs, ", "))
+		}
+		if len(rule.Values) == 0 {
+			addErr("%s: values must not be empty", field)
+		}
+		if rule.Match != "regex" {
+			continue
+		}
+		for _, value := range rule.Values {
+			if _, err := compileMatchPattern(value); err != nil {
+				addErr("%s: invalid regex %q: %w", field, value, err)
+			}
+		}
+	}
+	return errs
+}
+
+// normalizeLabels trims surrounding whitespace from each label and rejects
+// labels that are left empty
+func normalizeLabels(labels []string) ([]string, error) {
+	for i, label := range labels {
+		labels[i] = strings.TrimSpace(label)
+		if labels[i] == "" {
+			return nil, fmt.Errorf("label %d is empty", i)
+		}
+	}
+	return labels, nil
+}
+
+func validateOwnerType(ownerType string) error {
+	switch lib.OwnerType(ownerType) {
+	case "", lib.OwnerTypeAuto, lib.OwnerTypeUser, lib.OwnerTypeOrg:
+		return nil
+	default:
+		return fmt.Errorf("must be one of auto, user, org (got %q)", ownerType)
+	}
+}
+
+// CloneTargets returns every scm/owner this config clones from. Configs
+// without [[clone]] blocks have a single target built from the global section.
+func (c *Config) CloneTargets() []CloneTarget {
+	if len(c.Clone) > 0 {
+		return c.Clone
+	}
+	if c.Global.SCM == "" || c.Global.Owner == "" {
+		return nil
+	}
+	return []CloneTarget{{
+		SCM:       c.Global.SCM,
+		Owner:     c.Global.Owner,
+		BaseURL:   c.Global.BaseURL,
+		OwnerType: c.Global.OwnerType,
+	}}
+}
+
+// forTarget returns a copy of the config with the global scm, owner, base_url
+// and owner_type replaced by the target's, so per-owner code can keep reading
+// them from config.Global. base_url and owner_type fall back to the global
+// values only when the target shares the global scm.
+func (c *Config) forTarget(target CloneTarget) *Config {
+	scoped := *c
+	sameSCM := target.SCM == c.Global.SCM
+	scoped.Global.SCM = target.SCM
+	scoped.Global.Owner = target.Owner
+	if target.BaseURL != "" || !sameSCM {
+		scoped.Global.BaseURL = target.BaseURL
+	}
+	if target.OwnerType != "" {
+		scoped.Global.OwnerType = target.OwnerType
+	} else if !sameSCM {
+		scoped.Global.OwnerType = string(lib.OwnerTypeAuto)
+	}
+	return &scoped
 }
 
 // getLastUsedConfig retrieves the path of the last successfully used config file
@@ -247,13 +757,24 @@ func backupConfig(logger *logger.RateLimitedLogger, originalPath string) error {
 	return saveLastUsedConfig(logger, originalPath)
 }
 
-// getCurrentConfigPath attempts to get the current config file path
+// getCurrentConfigPath attempts to get the current config file path: the
+// active profile's, then current.toml
 func getCurrentConfigPath(logger *logger.RateLimitedLogger) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
 
+	profile, err := getActiveProfile()
+	if err != nil {
+		logger.Warn("Ignoring the active profile", "error", err)
+	} else if profile != "" {
+		if path, err := profilePath(profile); err == nil && isGitspaceConfig(path) {
+			return path, nil
+		}
+		logger.Debug("Active profile is missing or invalid", "profile", profile)
+	}
+
 	activePath := filepath.Join(homeDir, managedConfigDir, activeConfigFile)
 
 	// Check if the active config exists and is valid
@@ -267,21 +788,81 @@ func getCurrentConfigPath(logger *logger.RateLimitedLogger) (string, error) {
 	return "", nil
 }
 
-// deleteCurrentConfig removes the current config symlink and backup
-func deleteCurrentConfig(logger *logger.RateLimitedLogger) error {
+// configDeleteOptions chooses what deleteCurrentConfig removes besides the
+// active config
+type configDeleteOptions struct {
+	LegacySymlink bool          // The .symlinks/current_config.toml symlink
+	PurgeBackups  bool          // Backups older than BackupMaxAge
+	BackupMaxAge  time.Duration // Zero purges every backup
+}
+
+// legacyConfigSymlink returns the symlink backupConfig leaves in .symlinks
+func legacyConfigSymlink() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, configSymlinkDir, "current_config.toml"), nil
+}
+
+// deleteCurrentConfig removes the active config and, as opts asks, the legacy
+// symlink and old backups. It returns the paths it removed.
+func deleteCurrentConfig(logger *logger.RateLimitedLogger, opts configDeleteOptions) ([]string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	var removed []string
+	remove := func(path string) error {
+		if err := os.Remove(path); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		removed = append(removed, path)
+		return nil
 	}
 
 	// Remove active config
 	activePath := filepath.Join(homeDir, managedConfigDir, activeConfigFile)
-	if err := os.Remove(activePath); err != nil && !os.IsNotExist(err) {
-		logger.Warn("Failed to remove active config", "error", err)
+	if err := remove(activePath); err != nil {
+		return removed, fmt.Errorf("failed to remove active config: %w", err)
+	}
+	// The profile itself is kept; it just stops being the active config
+	if err := clearActiveProfile(); err != nil {
+		logger.Warn("Failed to deactivate profile", "error", err)
 	}
 
-	logger.Info("Current config deleted successfully")
-	return nil
+	if opts.LegacySymlink {
+		symlinkPath, err := legacyConfigSymlink()
+		if err != nil {
+			return removed, err
+		}
+		if err := remove(symlinkPath); err != nil {
+			return removed, fmt.Errorf("failed to remove legacy symlink: %w", err)
+		}
+	}
+
+	if opts.PurgeBackups {
+		backups, err := listConfigBackups()
+		if err != nil {
+			return removed, err
+		}
+		cutoff := time.Now().Add(-opts.BackupMaxAge)
+		for _, backup := range backups {
+			if backup.SavedAt.After(cutoff) {
+				continue
+			}
+			if err := remove(backup.Path); err != nil {
+				return removed, fmt.Errorf("failed to remove backup: %w", err)
+			}
+		}
+	}
+
+	logger.Info("Current config deleted successfully", "removed", len(removed))
+	return removed, nil
 }
 
 // Add a new function to distinguish gitspace configs from other .toml files
@@ -301,9 +882,8 @@ func isGitspaceConfig(path string) bool {
 		return false
 	}
 
-	// A valid gitspace config must have these fields
-	return config.Global.SCM != "" &&
-		config.Global.Owner != "" &&
+	// A valid gitspace config must have a path and at least one scm/owner
+	return len(config.CloneTargets()) > 0 &&
 		config.Global.Path != ""
 }
 
@@ -334,11 +914,13 @@ func installConfig(logger *logger.RateLimitedLogger, sourcePath string) error {
 	if err := toml.Unmarshal(sourceData, config); err != nil {
 		return fmt.Errorf("invalid config file: %w", err)
 	}
+	sourceData, err = absoluteIncludes(sourcePath, sourceData)
+	if err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
 
-	// Create backup with timestamp
-	backupName := fmt.Sprintf("config_%s.toml", time.Now().Format("20060102_150405"))
-	backupPath := filepath.Join(backupDir, backupName)
-	if err := os.WriteFile(backupPath, sourceData, 0644); err != nil {
+	backupPath, err := writeConfigBackup(sourceData)
+	if err != nil {
 		logger.Warn("Failed to create backup", "error", err)
 	}
 
@@ -347,6 +929,9 @@ func installConfig(logger *logger.RateLimitedLogger, sourcePath string) error {
 	if err := os.WriteFile(activePath, sourceData, 0644); err != nil {
 		return fmt.Errorf("failed to write active config: %w", err)
 	}
+	if err := clearActiveProfile(); err != nil {
+		logger.Warn("Failed to deactivate profile", "error", err)
+	}
 
 	logger.Info("Config installed successfully",
 		"source", sourcePath,
@@ -355,3 +940,23 @@ func installConfig(logger *logger.RateLimitedLogger, sourcePath string) error {
 
 	return nil
 }
+
+// writeConfigBackup saves a config under configs/backup with a timestamped
+// name and returns its path
+func writeConfigBackup(data []byte) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	backupDir := filepath.Join(homeDir, configBackupDir)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	backupName := fmt.Sprintf("config_%s.toml", time.Now().Format("20060102_150405"))
+	backupPath := filepath.Join(backupDir, backupName)
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return backupPath, nil
+}
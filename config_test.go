@@ -0,0 +1,320 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// setValidGlobal fills in the global settings validateConfig requires, so a
+// test only sets what it is about
+func setValidGlobal(t *testing.T, config *Config) {
+	config.Global.Path = t.TempDir()
+	config.Global.SCM = "github"
+	config.Global.Owner = "acme"
+}
+
+func TestValidateConfigRejectsPluginLogLevels(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Plugins.LogLevels = map[string]string{"linter": "debug", "indexer": "100%"}
+
+	err := validateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), `plugins.log_levels.indexer: invalid level: "100%"`) {
+		t.Errorf("got error %v, want the indexer level rejected verbatim", err)
+	}
+	if strings.Contains(err.Error(), "linter") {
+		t.Errorf("valid level rejected: %v", err)
+	}
+}
+
+func TestNormalizeSCM(t *testing.T) {
+	tests := []struct {
+		scm  string
+		want lib.SCMType
+	}{
+		{"github", lib.SCMTypeGitHub},
+		{"https://github.com/", lib.SCMTypeGitHub},
+		{"gitlab", lib.SCMTypeGitLab},
+		{"GitLab.com", lib.SCMTypeGitLab},
+		{"bitbucket.org", lib.SCMTypeBitbucket},
+		{"local", lib.SCMTypeLocal},
+		// A bare hostname means self-hosted Gitea; self-hosted GitLab is
+		// "gitlab" with base_url
+		{"gitlab.example.com", lib.SCMTypeGitea},
+		{"git.example.com:3000", lib.SCMTypeGitea},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scm, func(t *testing.T) {
+			got, err := normalizeSCM(tt.scm)
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeSCM(%q) = %q, %v, want %q", tt.scm, got, err, tt.want)
+			}
+		})
+	}
+	for _, scm := range []string{"", "svn"} {
+		if _, err := normalizeSCM(scm); err == nil {
+			t.Errorf("normalizeSCM(%q) succeeded", scm)
+		}
+	}
+}
+
+func TestCanonicalSCM(t *testing.T) {
+	// Every spelling of a hosted SCM shares one cache tree
+	tests := map[string]string{
+		"github":              "github",
+		"GitHub.com":          "github",
+		"https://github.com/": "github",
+		"www.github.com":      "github",
+		"gitlab.com":          "gitlab",
+		"bitbucket.org":       "bitbucket",
+		"local":               "local",
+		"git.example.com/":    "git.example.com",
+	}
+	for scm, want := range tests {
+		if got := canonicalSCM(scm); got != want {
+			t.Errorf("canonicalSCM(%q) = %q, want %q", scm, got, want)
+		}
+	}
+}
+
+func TestValidateConfigCanonicalizesSCM(t *testing.T) {
+	config := &Config{Clone: []CloneTarget{{SCM: "GitHub.com", Owner: "acme"}, {Owner: "widgets"}}}
+	config.Global.Path = t.TempDir()
+	config.Global.SCM = " Git.Example.com/ "
+	config.Global.Owner = "acme"
+
+	if err := validateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Global.SCM != "git.example.com" {
+		t.Errorf("global.scm %q, want git.example.com", config.Global.SCM)
+	}
+	if config.Clone[0].SCM != "github" || config.Clone[1].SCM != "git.example.com" {
+		t.Errorf("clone scms %q and %q, want github and git.example.com", config.Clone[0].SCM, config.Clone[1].SCM)
+	}
+}
+
+func TestValidateConfigRejectsNegativeCloneDepth(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Global.CloneDepth = -1
+
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "global.clone_depth must not be negative") {
+		t.Errorf("got error %v, want clone_depth rejected", err)
+	}
+}
+
+func TestValidateConfigVisibility(t *testing.T) {
+	for _, tt := range []struct{ visibility, want, wantErr string }{
+		{"", "all", ""},
+		{"private", "private", ""},
+		{"internal", "", "global.visibility must be one of all, public, private"},
+	} {
+		config := &Config{}
+		setValidGlobal(t, config)
+		config.Global.Visibility = tt.visibility
+
+		err := validateConfig(config)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("visibility %q: got error %v, want %q", tt.visibility, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || config.Global.Visibility != tt.want {
+			t.Errorf("visibility %q: got %q, %v, want %q", tt.visibility, config.Global.Visibility, err, tt.want)
+		}
+	}
+}
+
+func TestCheckConfigFileReportsEveryProblem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gs.toml")
+	err := os.WriteFile(path, []byte(`
+[global]
+scm = "svn"
+visibility = "secret"
+clone_depth = -2
+
+[groups.svc]
+match = "glob"
+values = ["svc-*"]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := checkConfigFile(path)
+	want := []string{
+		"global.path is required",
+		"global.owner is required",
+		"global.scm: unsupported SCM type: svn",
+		"global.visibility must be one of all, public, private",
+		"global.clone_depth must not be negative",
+		`groups.svc: unknown match "glob"`,
+	}
+	if len(problems) != len(want) {
+		t.Errorf("got %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+	for _, w := range want {
+		if !slices.ContainsFunc(problems, func(err error) bool { return strings.Contains(err.Error(), w) }) {
+			t.Errorf("no problem mentions %q in %v", w, problems)
+		}
+	}
+}
+
+func TestValidateConfigRejectsPluginRequestTimeout(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Plugins.RequestTimeout = "soon"
+
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "plugins.request_timeout") {
+		t.Errorf("got error %v, want request_timeout rejected", err)
+	}
+}
+
+func TestValidateConfigRejectsNegativePluginLimits(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Plugins.MaxMemoryMB = -1
+	config.Plugins.MaxRuntimeSeconds = -1
+
+	err := validateConfig(config)
+	for _, key := range []string{"plugins.max_memory_mb", "plugins.max_runtime_seconds"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("got error %v, want %s rejected", err, key)
+		}
+	}
+}
+
+func TestValidateConfigRejectsUnknownPluginLogLevel(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Plugins.LogLevels = map[string]string{"quiet": "error", "noisy": "loud"}
+
+	err := validateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "plugins.log_levels.noisy") {
+		t.Errorf("got error %v, want the noisy level rejected", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "quiet") {
+		t.Errorf("a valid level was rejected: %v", err)
+	}
+}
+
+func TestValidateConfigBaseURL(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Global.SCM = "gitea"
+	config.Global.BaseURL = "gitea.example.com"
+	config.Clone = []CloneTarget{
+		{SCM: "gitea", Owner: "labs", BaseURL: "https://gitea.example.com"},
+		{SCM: "gitea", Owner: "ops", BaseURL: "ftp://gitea.example.com"},
+	}
+
+	err := validateConfig(config)
+	for _, key := range []string{"global.base_url", "clone[1].base_url"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("got error %v, want %s rejected", err, key)
+		}
+	}
+	if err != nil && strings.Contains(err.Error(), "clone[0]") {
+		t.Errorf("a valid base_url was rejected: %v", err)
+	}
+}
+
+func TestValidateConfigLocalBaseURL(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Clone = []CloneTarget{
+		{SCM: "local", Owner: "team", BaseURL: "/srv/git"},
+		{SCM: "local", Owner: "team", BaseURL: "file:///srv/git"},
+		{SCM: "local", Owner: "team", BaseURL: "srv/git"},
+	}
+
+	err := validateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "clone[2].base_url") {
+		t.Errorf("got error %v, want the relative directory rejected", err)
+	}
+	for _, key := range []string{"clone[0]", "clone[1]"} {
+		if err != nil && strings.Contains(err.Error(), key) {
+			t.Errorf("a valid local directory was rejected: %v", err)
+		}
+	}
+}
+
+func TestDeleteCurrentConfig(t *testing.T) {
+	now := time.Now()
+	recent := "config_" + now.Add(-24*time.Hour).Format("20060102_150405") + ".toml"
+	old := "config_" + now.Add(-60*24*time.Hour).Format("20060102_150405") + ".toml"
+	tests := []struct {
+		name        string
+		opts        configDeleteOptions
+		wantSymlink bool
+		wantBackups []string
+		wantRemoved int
+	}{
+		{"active config only", configDeleteOptions{}, true, []string{old, recent}, 1},
+		{"legacy symlink", configDeleteOptions{LegacySymlink: true}, false, []string{old, recent}, 2},
+		{"backups older than 30 days", configDeleteOptions{PurgeBackups: true, BackupMaxAge: 30 * 24 * time.Hour}, true, []string{recent}, 2},
+		{"every backup", configDeleteOptions{PurgeBackups: true}, true, nil, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := newTestLogger(t)
+			home, err := os.UserHomeDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			active := filepath.Join(home, managedConfigDir, activeConfigFile)
+			writeTestConfig(t, active, validTestConfig(t))
+			for _, name := range []string{old, recent} {
+				writeTestConfig(t, filepath.Join(home, configBackupDir, name), "")
+			}
+			symlink, err := legacyConfigSymlink()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Dir(symlink), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(active, symlink); err != nil {
+				t.Fatal(err)
+			}
+
+			removed, err := deleteCurrentConfig(logger, tt.opts)
+			if err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if !slices.Contains(removed, active) {
+				t.Errorf("removed %v, want the active config among them", removed)
+			}
+			if _, err := os.Stat(active); !os.IsNotExist(err) {
+				t.Error("the active config is still there")
+			}
+			if _, err := os.Lstat(symlink); (err == nil) != tt.wantSymlink {
+				t.Errorf("symlink present = %v, want %v", err == nil, tt.wantSymlink)
+			}
+
+			backups, err := listConfigBackups()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, backup := range backups {
+				names = append(names, filepath.Base(backup.Path))
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.wantBackups) {
+				t.Errorf("backups left %v, want %v", names, tt.wantBackups)
+			}
+			if len(removed) != tt.wantRemoved {
+				t.Errorf("removed %v, want %d paths", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
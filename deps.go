@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// repoDependencies returns the depends_on of every group each repository
+// matches, keeping only repositories that are in repos. Dependencies outside
+// the batch, such as ones filtered out, are assumed to be in place already.
+func repoDependencies(logger *logger.RateLimitedLogger, config *Config, repos []lib.RepoInfo) map[string][]string {
+	inBatch := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		inBatch[repo.Name] = true
+	}
+
+	deps := make(map[string][]string)
+	groups := sortedGroups(config)
+	for _, repo := range repos {
+		for _, group := range groups {
+			if len(group.DependsOn) == 0 || !matchesGroup(logger, repo, group.Group) {
+				continue
+			}
+			for _, dep := range group.DependsOn {
+				switch {
+				case dep == repo.Name || slices.Contains(deps[repo.Name], dep):
+				case !inBatch[dep]:
+					logger.Debug("Dependency isn't in this batch", "repo", repo.Name, "group", group.Name, "depends_on", dep)
+				default:
+					deps[repo.Name] = append(deps[repo.Name], dep)
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// orderByDependencies sorts repos so each comes after its dependencies,
+// otherwise keeping their order, and fails if the dependencies form a cycle
+func orderByDependencies(repos []string, deps map[string][]string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(repos))
+	order := make([]string, 0, len(repos))
+	var stack []string
+
+	var visit func(repo string) error
+	visit = func(repo string) error {
+		switch state[repo] {
+		case visited:
+			return nil
+		case visiting:
+			i := slices.Index(stack, repo)
+			cycle := append(slices.Clone(stack[i:]), repo)
+			return fmt.Errorf("depends_on cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[repo] = visiting
+		stack = append(stack, repo)
+		for _, dep := range deps[repo] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[repo] = visited
+		order = append(order, repo)
+		return nil
+	}
+
+	for _, repo := range repos {
+		if err := visit(repo); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
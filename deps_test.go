@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestOrderByDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		repos   []string
+		deps    map[string][]string
+		want    []string
+		wantErr string
+	}{
+		{"no dependencies", []string{"a", "b", "c"}, nil, []string{"a", "b", "c"}, ""},
+		{"dependency listed later", []string{"app", "lib"}, map[string][]string{"app": {"lib"}}, []string{"lib", "app"}, ""},
+		{"chain", []string{"c", "b", "a"}, map[string][]string{"c": {"b"}, "b": {"a"}}, []string{"a", "b", "c"}, ""},
+		{"shared dependency", []string{"x", "y", "core"}, map[string][]string{"x": {"core"}, "y": {"core"}}, []string{"core", "x", "y"}, ""},
+		{"cycle", []string{"a", "b", "c"}, map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}}, nil, "depends_on cycle: b -> c -> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderByDependencies(tt.repos, tt.deps)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoDependencies(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{Groups: map[string]Group{
+		"services": {Match: "startsWith", Values: []string{"svc-"}, DependsOn: []string{"core", "proto", "svc-api"}},
+		"api":      {Match: "startsWith", Values: []string{"svc-api"}, DependsOn: []string{"core"}},
+	}}
+	repos := []lib.RepoInfo{{Name: "svc-api"}, {Name: "svc-web"}, {Name: "core"}}
+
+	deps := repoDependencies(logger, config, repos)
+	// proto isn't in the batch, and svc-api doesn't depend on itself or on core twice
+	if got := deps["svc-api"]; !slices.Equal(got, []string{"core"}) {
+		t.Errorf("svc-api depends on %v, want [core]", got)
+	}
+	if got := deps["svc-web"]; !slices.Equal(got, []string{"core", "svc-api"}) {
+		t.Errorf("svc-web depends on %v, want [core svc-api]", got)
+	}
+	if got, ok := deps["core"]; ok {
+		t.Errorf("core depends on %v, want nothing", got)
+	}
+}
+
+func TestProcessRepositoriesWaitsForDependencies(t *testing.T) {
+	previous := progressOutput
+	progressOutput = io.Discard
+	t.Cleanup(func() { progressOutput = previous })
+
+	deps := map[string][]string{"app": {"lib"}, "cli": {"lib"}}
+	order, err := orderByDependencies([]string{"app", "cli", "lib", "docs"}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	results := processRepositories("test", order, deps, 4, func(repo string, progress io.Writer, result *RepoResult) {
+		if repo == "lib" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range deps[repo] {
+			if !finished[dep] {
+				t.Errorf("%s started before its dependency %s finished", repo, dep)
+			}
+		}
+		finished[repo] = true
+	})
+	if len(results) != 4 {
+		t.Errorf("got %d results, want 4", len(results))
+	}
+}
+
+func TestCloneRejectsDependencyCycles(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha", "beta")
+	config.Groups["alpha"] = Group{Match: "isExactly", Values: []string{"alpha"}, DependsOn: []string{"beta"}, Priority: 1}
+	config.Groups["beta"] = Group{Match: "isExactly", Values: []string{"beta"}, DependsOn: []string{"alpha"}, Priority: 1}
+
+	if _, err := cloneRepositories(logger, config, nil); err == nil || !strings.Contains(err.Error(), "depends_on cycle") {
+		t.Errorf("got error %v, want the cycle reported", err)
+	}
+}
+
+func TestValidateConfigRejectsEmptyDependencies(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	config.Groups = map[string]Group{
+		"api": {Match: "startsWith", Values: []string{"api-"}, DependsOn: []string{"core", " "}},
+	}
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "groups.api.depends_on[1] is empty") {
+		t.Errorf("got error %v, want the empty dependency rejected", err)
+	}
+}
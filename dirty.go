@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Values for global.dirty_policy
+const (
+	dirtyPolicySkip  = "skip"  // Leave the repository alone
+	dirtyPolicyStash = "stash" // Stash the changes, then update
+	dirtyPolicyForce = "force" // Update anyway
+)
+
+// Actions recorded in RepoResult.DirtyAction for a repository with
+// uncommitted changes
+const (
+	dirtySkipped = "skipped"
+	dirtyStashed = "stashed"
+	dirtyForced  = "forced"
+)
+
+// applyDirtyPolicy checks an existing clone for uncommitted changes to
+// tracked files before it is updated, and applies policy to them. It returns
+// the action taken, or "" when there was nothing to do.
+func applyDirtyPolicy(r *git.Repository, repoPath, policy string) (string, error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+	dirty, err := hasLocalChanges(w)
+	if err != nil || !dirty {
+		return "", err
+	}
+
+	switch policy {
+	case dirtyPolicyStash:
+		if err := stashChanges(repoPath); err != nil {
+			return "", err
+		}
+		return dirtyStashed, nil
+	case dirtyPolicyForce:
+		return dirtyForced, nil
+	default:
+		return dirtySkipped, nil
+	}
+}
+
+// stashChanges runs git stash in repoPath. go-git can't stash, so this needs
+// the git command-line tool; the stash shows up in git stash list as usual.
+func stashChanges(repoPath string) error {
+	message := "gitspace sync " + time.Now().Format(time.DateTime)
+	cmd := exec.Command("git", "-C", repoPath, "stash", "push", "--message", message)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stash uncommitted changes: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
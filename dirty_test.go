@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyDirtyPolicyIgnoresUntrackedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "widget")
+	r, _ := initTestRepo(t, path, "main")
+	if err := os.WriteFile(filepath.Join(path, "scratch.txt"), []byte("notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if action, err := applyDirtyPolicy(r, path, dirtyPolicySkip); err != nil || action != "" {
+		t.Errorf("got %q, %v for a repository with only untracked files", action, err)
+	}
+}
+
+func TestApplyDirtyPolicyStashes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("stashing needs the git command-line tool")
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, testSignature.Name)
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, testSignature.Email)
+	}
+	path := filepath.Join(t.TempDir(), "widget")
+	r, _ := initTestRepo(t, path, "main")
+	if err := os.WriteFile(filepath.Join(path, "README.md"), []byte("local change\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if action, err := applyDirtyPolicy(r, path, dirtyPolicyStash); err != nil || action != dirtyStashed {
+		t.Fatalf("got %q, %v", action, err)
+	}
+	output, err := exec.Command("git", "-C", path, "stash", "list").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "gitspace sync") {
+		t.Errorf("stash list is %q, %v", output, err)
+	}
+}
+
+func TestForceFastForwardsOverLocalChanges(t *testing.T) {
+	r, path, pushed := trackingTestRepo(t)
+	// README.md is the same in both commits, so forcing keeps the edit
+	readme := filepath.Join(path, "README.md")
+	if err := os.WriteFile(readme, []byte("local change\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if action, err := applyDirtyPolicy(r, path, dirtyPolicyForce); err != nil || action != dirtyForced {
+		t.Fatalf("got %q, %v", action, err)
+	}
+	if moved, err := updateWorktree(r, syncStrategyFFOnly, true); err != nil || !moved {
+		t.Fatalf("got %v, %v, want a forced fast-forward", moved, err)
+	}
+	if _, commit := readHead(path); commit != pushed.String() {
+		t.Errorf("HEAD is at %s, want %s", commit, pushed)
+	}
+	if data, _ := os.ReadFile(readme); string(data) != "local change\n" {
+		t.Errorf("README.md is %q", data)
+	}
+}
+
+func TestValidateConfigDirtyPolicy(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	if err := validateConfig(config); err != nil || config.Global.DirtyPolicy != dirtyPolicySkip {
+		t.Errorf("got %q, %v, want the skip default", config.Global.DirtyPolicy, err)
+	}
+
+	config.Global.DirtyPolicy = "discard"
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "global.dirty_policy") {
+		t.Errorf("got error %v, want discard rejected", err)
+	}
+}
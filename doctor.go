@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mitchellh/go-homedir"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+	"github.com/ssotops/gitspace/plugin"
+)
+
+// doctorCheck is one line of the doctor checklist
+type doctorCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+	Hint   string // How to fix a failed check
+}
+
+// runDoctorChecks diagnoses the environment without changing anything.
+// config may be nil, in which case configErr explains why it couldn't load.
+func runDoctorChecks(config *Config, configErr error) []doctorCheck {
+	var checks []doctorCheck
+
+	if config == nil {
+		checks = append(checks, doctorCheck{
+			Name:   "Config",
+			Detail: fmt.Sprint(configErr),
+			Hint:   "Load a config from the Gitspace menu or pass --config <path>.",
+		})
+	} else {
+		checks = append(checks, doctorCheck{Name: "Config", OK: true, Detail: "loaded and valid"})
+		checks = append(checks, checkTokens(config)...)
+		checks = append(checks, checkSSHKey(config))
+	}
+
+	checks = append(checks, checkPluginDirectory())
+	checks = append(checks, checkCacheDirectory())
+	return checks
+}
+
+// checkTokens checks that each configured SCM has an API token available
+func checkTokens(config *Config) []doctorCheck {
+	var checks []doctorCheck
+	seen := make(map[lib.SCMType]bool)
+
+	for _, target := range config.CloneTargets() {
+		scmType, err := normalizeSCM(target.SCM)
+		if err != nil || seen[scmType] {
+			continue
+		}
+		seen[scmType] = true
+		if scmType == lib.SCMTypeLocal {
+			continue // Local directories need no token
+		}
+
+		check := doctorCheck{Name: fmt.Sprintf("%s token", scmType)}
+		switch scmType {
+		case lib.SCMTypeGitHub:
+			token, err := resolveToken(config, "GITHUB_TOKEN")
+			switch {
+			case err != nil:
+				check.Detail = err.Error()
+				check.Hint = "Fix auth.token or auth.token_path in your config."
+			case token == "":
+				check.Detail = "no token found"
+				check.Hint = "Set auth.token, auth.token_path, or export GITHUB_TOKEN."
+			default:
+				check.OK = true
+				check.Detail = "found"
+			}
+		case lib.SCMTypeGitLab, lib.SCMTypeGitea, lib.SCMTypeBitbucket:
+			envVar := "GITLAB_TOKEN"
+			switch scmType {
+			case lib.SCMTypeGitea:
+				envVar = "GITEA_TOKEN"
+			case lib.SCMTypeBitbucket:
+				envVar = "BITBUCKET_TOKEN"
+			}
+			if os.Getenv(envVar) == "" {
+				check.Detail = envVar + " is not set"
+				check.Hint = "export " + envVar + "=<token>"
+			} else {
+				check.OK = true
+				check.Detail = envVar + " is set"
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// checkSSHKey checks that auth.key_path resolves to a readable private key
+// that isn't accessible to other users
+func checkSSHKey(config *Config) doctorCheck {
+	check := doctorCheck{Name: "SSH key"}
+	if !needsSSH(config) {
+		check.OK = true
+		check.Detail = "not needed for local repositories"
+		return check
+	}
+
+	keyPath, err := getSSHKeyPath(config.Auth.KeyPath)
+	if err == nil {
+		keyPath, err = homedir.Expand(keyPath)
+	}
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Set auth.key_path to a key file, or export the environment variable it names."
+		return check
+	}
+	if keyPath == "" {
+		check.Detail = "auth.key_path is not set"
+		check.Hint = `Set auth.key_path, e.g. key_path = "~/.ssh/id_ed25519".`
+		return check
+	}
+
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Check that auth.key_path points at your private key."
+		return check
+	}
+	if info.IsDir() {
+		check.Detail = keyPath + " is a directory"
+		check.Hint = "Point auth.key_path at the private key file itself."
+		return check
+	}
+	// Windows doesn't report Unix permission bits
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		check.Detail = fmt.Sprintf("%s has permissions %v", keyPath, info.Mode().Perm())
+		check.Hint = "chmod 600 " + keyPath
+		return check
+	}
+
+	check.OK = true
+	check.Detail = keyPath
+	return check
+}
+
+func checkPluginDirectory() doctorCheck {
+	check := doctorCheck{Name: "Plugin directory permissions"}
+
+	wrong, err := plugin.CheckPluginDirectoryPermissions()
+	switch {
+	case err != nil:
+		check.Detail = err.Error()
+		check.Hint = "Check that ~/.ssot/gitspace/plugins exists and is readable."
+	case len(wrong) > 0:
+		check.Detail = fmt.Sprintf("%d paths are not 0755, e.g. %s", len(wrong), wrong[0])
+		check.Hint = "Run Doctor from the Gitspace menu to fix, or chmod -R 755 ~/.ssot/gitspace/plugins"
+	default:
+		check.OK = true
+		check.Detail = "ok"
+	}
+	return check
+}
+
+func checkCacheDirectory() doctorCheck {
+	check := doctorCheck{Name: "Cache directory"}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Check that your home directory is writable."
+		return check
+	}
+
+	f, err := os.CreateTemp(cacheDir, ".doctor-*")
+	if err != nil {
+		check.Detail = fmt.Sprintf("%s is not writable: %v", cacheDir, err)
+		check.Hint = "Fix the ownership or permissions of " + cacheDir
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	check.OK = true
+	check.Detail = cacheDir
+	return check
+}
+
+// printDoctorReport prints the checklist and reports whether every check passed
+func printDoctorReport(checks []doctorCheck) bool {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+
+	fmt.Println(headerStyle.Render("\nGitspace Doctor:"))
+	fmt.Println()
+
+	allOK := true
+	for _, check := range checks {
+		emoji := "✅"
+		if !check.OK {
+			emoji = "❌"
+			allOK = false
+		}
+		fmt.Println(infoStyle.Render(fmt.Sprintf("%s %s: %s", emoji, check.Name, check.Detail)))
+		if !check.OK && check.Hint != "" {
+			fmt.Println(hintStyle.Render("   " + check.Hint))
+		}
+	}
+	fmt.Println()
+
+	return allOK
+}
+
+// handleDoctorCommand runs the checks from the menu and offers to fix plugin
+// directory permissions, the only problem it can repair itself
+func handleDoctorCommand(logger *logger.RateLimitedLogger, config *Config) {
+	var configErr error
+	if config == nil {
+		configErr = fmt.Errorf("no config loaded")
+	}
+
+	checks := runDoctorChecks(config, configErr)
+	printDoctorReport(checks)
+
+	for _, check := range checks {
+		if check.Name != "Plugin directory permissions" || check.OK {
+			continue
+		}
+
+		var fix bool
+		err := huh.NewConfirm().
+			Title("Fix plugin directory permissions?").
+			Value(&fix).
+			Run()
+		if err != nil {
+			logger.Error("Error getting confirmation", "error", err)
+			return
+		}
+		if fix {
+			if err := plugin.EnsurePluginDirectoryPermissions(logger); err != nil {
+				logger.Error("Failed to fix plugin directory permissions", "error", err)
+			}
+		}
+	}
+}
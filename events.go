@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/ssotops/gitspace/plugin"
+)
+
+// pluginEvents sends clone and sync lifecycle events to the loaded plugins
+// subscribed to them. It is nil for CLI commands, which load no plugins.
+var pluginEvents *plugin.Manager
+
+// emitPreClone tells subscribed plugins which repositories of a scm/owner are
+// about to be cloned or updated. config is scoped to that target.
+func emitPreClone(config *Config, repoDir string, repos []string) {
+	if pluginEvents == nil {
+		return
+	}
+	event := plugin.Event{Name: plugin.EventPreClone, Repositories: []plugin.EventRepo{}}
+	for _, repo := range repos {
+		event.Repositories = append(event.Repositories, plugin.EventRepo{
+			SCM:   config.Global.SCM,
+			Owner: config.Global.Owner,
+			Name:  repo,
+			Path:  filepath.Join(repoDir, repo),
+		})
+	}
+	pluginEvents.DispatchEvent(event)
+}
+
+// emitResults sends the results of a clone or sync to subscribed plugins
+func emitResults(name string, results map[string]*RepoResult) {
+	if pluginEvents == nil {
+		return
+	}
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return
+	}
+	event := plugin.Event{Name: name, Repositories: []plugin.EventRepo{}}
+	for _, result := range sortedResults(results) {
+		repo := plugin.EventRepo{
+			SCM:    result.SCM,
+			Owner:  result.Owner,
+			Name:   result.Name,
+			Path:   filepath.Join(cacheDir, ".repositories", result.SCM, result.Owner, result.Name),
+			Status: resultStatus(result),
+		}
+		if result.Error != nil {
+			repo.Error = result.Error.Error()
+		}
+		event.Repositories = append(event.Repositories, repo)
+	}
+	pluginEvents.DispatchEvent(event)
+}
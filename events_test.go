@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/plugin"
+)
+
+// subscribeStubPlugin loads the stub plugin as the receiver of clone and sync
+// events and returns the file it records them in
+func subscribeStubPlugin(t *testing.T, logger *logger.RateLimitedLogger) string {
+	t.Helper()
+	eventsFile := filepath.Join(t.TempDir(), "events.jsonl")
+	t.Setenv(stubPluginEventsEnv, eventsFile)
+	pluginManager := plugin.NewManager(logger)
+	loadStubPlugin(t, pluginManager)
+	pluginEvents = pluginManager
+	t.Cleanup(func() { pluginEvents = nil })
+	return eventsFile
+}
+
+// readStubPluginEvents returns the events the stub plugin recorded
+func readStubPluginEvents(t *testing.T, path string) []plugin.Event {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("the plugin received no events: %v", err)
+	}
+	var events []plugin.Event
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event plugin.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestCloneSendsEvents(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha", "beta")
+	eventsFile := subscribeStubPlugin(t, logger)
+
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	events := readStubPluginEvents(t, eventsFile)
+	if len(events) != 2 || events[0].Name != plugin.EventPreClone || events[1].Name != plugin.EventPostClone {
+		t.Fatalf("received %+v, want pre_clone then post_clone", events)
+	}
+
+	for _, event := range events {
+		if len(event.Repositories) != 2 {
+			t.Fatalf("%s listed %+v", event.Name, event.Repositories)
+		}
+		for i, name := range []string{"alpha", "beta"} {
+			repo := event.Repositories[i]
+			if repo.SCM != "local" || repo.Owner != "team" || repo.Name != name {
+				t.Errorf("%s repository %d is %+v", event.Name, i, repo)
+			}
+		}
+	}
+	if status := events[0].Repositories[0].Status; status != "" {
+		t.Errorf("pre_clone has status %q", status)
+	}
+	if got, want := events[1].Repositories[0].Path, clonedRepoPath(t, "alpha"); got != want {
+		t.Errorf("post_clone path %q, want the clone at %q", got, want)
+	}
+	if status := events[1].Repositories[0].Status; status != statusCloned {
+		t.Errorf("post_clone status %q, want %q", status, statusCloned)
+	}
+}
+
+func TestEmitResultsReportsFailures(t *testing.T) {
+	eventsFile := subscribeStubPlugin(t, newTestLogger(t))
+	emitResults(plugin.EventPostSync, map[string]*RepoResult{
+		"local/team/alpha": {SCM: "local", Owner: "team", Name: "alpha", Updated: true},
+		"local/team/beta":  {SCM: "local", Owner: "team", Name: "beta", Error: os.ErrPermission},
+	})
+
+	events := readStubPluginEvents(t, eventsFile)
+	if len(events) != 1 || events[0].Name != plugin.EventPostSync {
+		t.Fatalf("received %+v, want one post_sync", events)
+	}
+	repos := events[0].Repositories
+	if len(repos) != 2 || repos[0].Status != statusUpdated || repos[1].Status != statusFailed || repos[1].Error == "" {
+		t.Errorf("post_sync repositories %+v", repos)
+	}
+}
+
+func TestEmitWithoutPlugins(t *testing.T) {
+	// CLI commands load no plugins; emitting must be a no-op
+	emitPreClone(&Config{}, t.TempDir(), []string{"alpha"})
+	emitResults(plugin.EventPostClone, map[string]*RepoResult{"local/team/alpha": {Name: "alpha"}})
+}
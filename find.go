@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// foundRepo is a repository matched by find
+type foundRepo struct {
+	SCM     string
+	Owner   string
+	Name    string
+	Path    string // The clone, or empty if the repository is only in index.toml
+	Symlink string // The global symlink, if it exists
+}
+
+// findRepositories searches every clone under cacheDir/.repositories, and
+// every repository recorded in index.toml, for names matching query with a
+// group match verb. Topics for hasTopic come from index.toml.
+func findRepositories(logger *logger.RateLimitedLogger, cacheDir, match, query string) ([]foundRepo, error) {
+	indexed, err := readIndex(cacheDir)
+	if err != nil {
+		logger.Warn("Searching clones only", "error", err)
+	}
+
+	// Start from the index, then add clones it doesn't know about
+	candidates := make(map[string]*foundRepo)
+	topics := make(map[string][]string)
+	for _, repo := range indexed {
+		key := filepath.Join(repo.SCM, repo.Owner, repo.Name)
+		candidates[key] = &foundRepo{SCM: repo.SCM, Owner: repo.Owner, Name: repo.Name}
+		topics[key] = repo.Metadata.Topics
+	}
+
+	reposDir := filepath.Join(cacheDir, ".repositories")
+	clones, err := filepath.Glob(filepath.Join(reposDir, "*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range clones {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			continue // Such as the cached repo_list.json
+		}
+		ownerDir := filepath.Dir(path)
+		scm, owner, name := filepath.Base(filepath.Dir(ownerDir)), filepath.Base(ownerDir), filepath.Base(path)
+		key := filepath.Join(scm, owner, name)
+		if candidates[key] == nil {
+			candidates[key] = &foundRepo{SCM: scm, Owner: owner, Name: name}
+		}
+		candidates[key].Path = path
+	}
+
+	var found []foundRepo
+	for key, repo := range candidates {
+		// hasTopic shouldn't warn about clones the index has no topics for
+		info := lib.RepoInfo{Name: repo.Name, Topics: topics[key]}
+		if info.Topics == nil {
+			info.Topics = []string{}
+		}
+		if !matchesRule(logger, info, match, []string{query}) {
+			continue
+		}
+		symlink := filepath.Join(cacheDir, repo.SCM, repo.Owner, repo.Name)
+		if _, err := os.Lstat(symlink); err == nil {
+			repo.Symlink = symlink
+		}
+		found = append(found, *repo)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.SCM != b.SCM {
+			return a.SCM < b.SCM
+		}
+		return a.Owner < b.Owner
+	})
+	return found, nil
+}
+
+// checkFindQuery reports a match verb or regex find can't use
+func checkFindQuery(match, query string) error {
+	if !slices.Contains(knownMatchVerbs, match) {
+		return fmt.Errorf("match must be one of %s (got %q)", strings.Join(knownMatchVerbs, ", "), match)
+	}
+	if match == "regex" {
+		if _, err := compileMatchPattern(query); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// printFoundRepositories prints the results of find as a table
+func printFoundRepositories(found []foundRepo) {
+	if len(found) == 0 {
+		fmt.Println("No repositories match.")
+		return
+	}
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("Repository", "SCM", "Owner", "Path", "Symlink")
+	for _, repo := range found {
+		path := repo.Path
+		if path == "" {
+			path = "(not cloned)"
+		}
+		t.Row(repo.Name, repo.SCM, repo.Owner, path, repo.Symlink)
+	}
+	fmt.Println(t)
+	fmt.Printf("Total: %d\n", len(found))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFindFixture indexes widget and api-gateway under github/acme, clones
+// widget with a global symlink and an unindexed local/team/widget-tools, and
+// returns the cache directory
+func writeFindFixture(t *testing.T) string {
+	t.Helper()
+	cacheDir := writeTestIndex(t, `
+[repositories.repositories.github.acme.widget.metadata]
+topics = ["frontend"]
+
+[repositories.repositories.github.acme.api-gateway.metadata]
+topics = ["backend"]
+`)
+	for _, clone := range []string{"github/acme/widget", "local/team/widget-tools"} {
+		if err := os.MkdirAll(filepath.Join(cacheDir, ".repositories", clone), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Cached listings sit beside the clones and aren't repositories
+	if err := os.WriteFile(filepath.Join(cacheDir, ".repositories", "github", "acme", repoListFile), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(cacheDir, "github", "acme"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(cacheDir, ".repositories", "github", "acme", "widget"), filepath.Join(cacheDir, "github", "acme", "widget")); err != nil {
+		t.Fatal(err)
+	}
+	return cacheDir
+}
+
+func TestFindRepositories(t *testing.T) {
+	logger := newTestLogger(t)
+	cacheDir := writeFindFixture(t)
+
+	found, err := findRepositories(logger, cacheDir, "includes", "widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("got %+v, want widget and widget-tools", found)
+	}
+	widget, tools := found[0], found[1]
+	if widget.Name != "widget" || widget.Path != filepath.Join(cacheDir, ".repositories", "github", "acme", "widget") || widget.Symlink != filepath.Join(cacheDir, "github", "acme", "widget") {
+		t.Errorf("got widget %+v", widget)
+	}
+	if tools.Name != "widget-tools" || tools.SCM != "local" || tools.Owner != "team" || tools.Symlink != "" {
+		t.Errorf("got widget-tools %+v", tools)
+	}
+
+	tests := []struct {
+		match, query, want string
+	}{
+		{"isExactly", "widget", "widget"},
+		{"startsWith", "api-", "api-gateway"},
+		{"regex", "^w.*s$", "widget-tools"},
+		{"hasTopic", "backend", "api-gateway"},
+		{"endsWith", "nothing", ""},
+	}
+	for _, tt := range tests {
+		found, err := findRepositories(logger, cacheDir, tt.match, tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, repo := range found {
+			names = append(names, repo.Name)
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("%s %q found %q, want %q", tt.match, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	logger := newTestLogger(t)
+	writeFindFixture(t)
+
+	var code int
+	// Flags may follow the query
+	out := captureStdout(t, func() { code = runCLI(logger, []string{"find", "api", "--match", "startsWith"}) })
+	if code != exitOK || !strings.Contains(out, "api-gateway") || !strings.Contains(out, "(not cloned)") || !strings.Contains(out, "Total: 1") {
+		t.Errorf("exit %d, printed %q", code, out)
+	}
+	out = captureStdout(t, func() { code = runCLI(logger, []string{"find", "nothing"}) })
+	if code != exitOK || !strings.Contains(out, "No repositories match.") {
+		t.Errorf("exit %d, printed %q", code, out)
+	}
+
+	for _, args := range [][]string{
+		{"find"},
+		{"find", "a", "b"},
+		{"find", "--match", "glob", "widget"},
+		{"find", "--match", "regex", "("},
+	} {
+		if code := runCLI(logger, args); code != exitUsage {
+			t.Errorf("%q exited %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// GitClient performs the git operations clone and sync run on repositories,
+// so the orchestration around them can be driven without a real remote
+type GitClient interface {
+	Clone(path string, opts *git.CloneOptions) (*git.Repository, error)
+	Open(path string) (*git.Repository, error)
+	Fetch(r *git.Repository, opts *git.FetchOptions) error
+	Checkout(r *git.Repository, opts *git.CheckoutOptions) error
+	// UpdateSubmodules updates r's submodules, returning how many it has
+	UpdateSubmodules(r *git.Repository, opts *git.SubmoduleUpdateOptions) (int, error)
+}
+
+// gitClient is the GitClient clone and sync use
+var gitClient GitClient = goGitClient{}
+
+// goGitClient implements GitClient with go-git on the local filesystem
+type goGitClient struct{}
+
+func (goGitClient) Clone(path string, opts *git.CloneOptions) (*git.Repository, error) {
+	return git.PlainClone(path, false, opts)
+}
+
+func (goGitClient) Open(path string) (*git.Repository, error) {
+	return git.PlainOpen(path)
+}
+
+func (goGitClient) Fetch(r *git.Repository, opts *git.FetchOptions) error {
+	return r.Fetch(opts)
+}
+
+func (goGitClient) Checkout(r *git.Repository, opts *git.CheckoutOptions) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return w.Checkout(opts)
+}
+
+func (goGitClient) UpdateSubmodules(r *git.Repository, opts *git.SubmoduleUpdateOptions) (int, error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	submodules, err := w.Submodules()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read submodules: %w", err)
+	}
+	if len(submodules) == 0 {
+		return 0, nil
+	}
+	return len(submodules), submodules.Update(opts)
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// fakeGitClient implements GitClient without a remote. Clone initializes a
+// repository with a single commit that origin/<branch> also points at, and
+// Fetch only records the call, so a test moves origin itself with
+// advanceOrigin.
+type fakeGitClient struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	clones    []string // Repository names, in call order
+	cloneOpts []git.CloneOptions
+	fetches   []string
+	fetchOpts []git.FetchOptions
+	checkouts []git.CheckoutOptions
+	fetchErr  error
+}
+
+// installFakeGitClient replaces gitClient with a fake for the rest of the test
+func installFakeGitClient(t *testing.T) *fakeGitClient {
+	fake := &fakeGitClient{t: t}
+	previous := gitClient
+	gitClient = fake
+	t.Cleanup(func() { gitClient = previous })
+	return fake
+}
+
+func (f *fakeGitClient) Clone(path string, opts *git.CloneOptions) (*git.Repository, error) {
+	f.mu.Lock()
+	f.clones = append(f.clones, filepath.Base(path))
+	f.cloneOpts = append(f.cloneOpts, *opts)
+	f.mu.Unlock()
+
+	branch := "main"
+	if opts.ReferenceName != "" {
+		branch = opts.ReferenceName.Short()
+	}
+	r, head := initTestRepo(f.t, path, branch)
+	origin := plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", branch), head)
+	if err := r.Storer.SetReference(origin); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (f *fakeGitClient) Open(path string) (*git.Repository, error) {
+	return git.PlainOpen(path)
+}
+
+func (f *fakeGitClient) Fetch(r *git.Repository, opts *git.FetchOptions) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches = append(f.fetches, filepath.Base(w.Filesystem.Root()))
+	f.fetchOpts = append(f.fetchOpts, *opts)
+	if f.fetchErr != nil {
+		return f.fetchErr
+	}
+	return git.NoErrAlreadyUpToDate
+}
+
+func (f *fakeGitClient) Checkout(r *git.Repository, opts *git.CheckoutOptions) error {
+	f.mu.Lock()
+	f.checkouts = append(f.checkouts, *opts)
+	f.mu.Unlock()
+	return goGitClient{}.Checkout(r, opts)
+}
+
+func (f *fakeGitClient) UpdateSubmodules(r *git.Repository, opts *git.SubmoduleUpdateOptions) (int, error) {
+	return goGitClient{}.UpdateSubmodules(r, opts)
+}
+
+func (f *fakeGitClient) fetched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fetches := slices.Clone(f.fetches)
+	slices.Sort(fetches)
+	return fetches
+}
+
+// advanceOrigin commits name to the clone at repoPath and moves origin/main
+// to that commit while leaving main where it was, as a push and a fetch would
+func advanceOrigin(t *testing.T, repoPath, name, content string) plumbing.Hash {
+	t.Helper()
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", repoPath, err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("failed to read HEAD: %v", err)
+	}
+	pushed := commitTestFile(t, r, name, content)
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "main"), pushed)); err != nil {
+		t.Fatalf("failed to move origin/main: %v", err)
+	}
+	w, _ := r.Worktree()
+	if err := w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset}); err != nil {
+		t.Fatalf("failed to reset main: %v", err)
+	}
+	os.Remove(filepath.Join(repoPath, name))
+	return pushed
+}
+
+// localCloneConfig creates a local scm root holding repos under the owner
+// "team" and returns a config cloning all of them, with extra appended to its
+// [global] table
+func localCloneConfig(t *testing.T, extra string, repos ...string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	for _, repo := range repos {
+		initTestRepo(t, filepath.Join(dir, "root", "team", repo), "main")
+	}
+	return loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = "local"
+base_url = %q
+owner = "team"
+%s
+
+[groups.all]
+match = "regex"
+values = [".*"]
+`, filepath.Join(dir, "work"), filepath.Join(dir, "root"), extra))
+}
+
+// clonedRepoPath returns where clone puts one of localCloneConfig's repositories
+func clonedRepoPath(t *testing.T, repo string) string {
+	t.Helper()
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(cacheDir, ".repositories", "local", "team", repo)
+}
+
+func TestCloneRepositoriesUsesGitClient(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha", "beta")
+
+	results, err := cloneRepositories(logger, config, nil)
+	if err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, result := range results {
+		if !result.Cloned || result.Error != nil {
+			t.Errorf("%s: cloned %v, error %v", result.Name, result.Cloned, result.Error)
+		}
+		if _, err := os.Stat(filepath.Join(clonedRepoPath(t, result.Name), "README.md")); err != nil {
+			t.Errorf("%s: clone has no worktree: %v", result.Name, err)
+		}
+	}
+	clones := slices.Clone(fake.clones)
+	slices.Sort(clones)
+	if !slices.Equal(clones, []string{"alpha", "beta"}) {
+		t.Errorf("cloned %v, want [alpha beta]", clones)
+	}
+	if len(fake.fetched()) != 0 {
+		t.Errorf("a fresh clone fetched %v", fake.fetched())
+	}
+
+	// Cloning again updates the existing clones instead
+	results, err = cloneRepositories(logger, config, nil)
+	if err != nil {
+		t.Fatalf("second clone failed: %v", err)
+	}
+	for _, result := range results {
+		if result.Cloned || !result.Updated {
+			t.Errorf("%s: cloned %v, updated %v on the second clone", result.Name, result.Cloned, result.Updated)
+		}
+	}
+	if got := fake.fetched(); !slices.Equal(got, []string{"alpha", "beta"}) {
+		t.Errorf("fetched %v, want [alpha beta]", got)
+	}
+}
+
+func TestSyncRepositoriesFastForwards(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, `sync_strategy = "ff-only"`, "alpha")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	repoPath := clonedRepoPath(t, "alpha")
+	pushed := advanceOrigin(t, repoPath, "CHANGELOG.md", "v2\n")
+
+	results, err := syncRepositories(logger, config, syncOptions{})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	result := results["local/team/alpha"]
+	if result == nil || !result.Updated || !result.FastForwarded {
+		t.Fatalf("got result %+v, want a fast-forward", result)
+	}
+	if got := fake.fetched(); !slices.Equal(got, []string{"alpha"}) {
+		t.Errorf("fetched %v, want [alpha]", got)
+	}
+	if branch, commit := readHead(repoPath); branch != "main" || commit != pushed.String() {
+		t.Errorf("HEAD is %s at %s, want main at %s", branch, commit, pushed)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "CHANGELOG.md")); err != nil {
+		t.Errorf("fast-forward didn't update the worktree: %v", err)
+	}
+}
+
+func TestSyncRepositoriesFetchError(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, "max_retries = 0", "alpha")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	fake.fetchErr = fmt.Errorf("connection reset")
+
+	results, err := syncRepositories(logger, config, syncOptions{})
+	if err == nil {
+		t.Fatal("sync succeeded despite the failed fetch")
+	}
+	result := results["local/team/alpha"]
+	if result == nil || result.Error == nil || result.Updated {
+		t.Fatalf("got result %+v, want the fetch error", result)
+	}
+}
+
+func TestSyncRepositoriesDirtyPolicy(t *testing.T) {
+	tests := []struct {
+		policy     string
+		wantAction string
+		wantFetch  bool
+		wantClean  bool // Whether the local change is gone from the worktree
+	}{
+		{dirtyPolicySkip, dirtySkipped, false, false},
+		{dirtyPolicyStash, dirtyStashed, true, true},
+		{dirtyPolicyForce, dirtyForced, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			if _, err := exec.LookPath("git"); err != nil && tt.policy == dirtyPolicyStash {
+				t.Skip("stashing needs the git command-line tool")
+			}
+			for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+				t.Setenv(name, testSignature.Name)
+			}
+			for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+				t.Setenv(name, testSignature.Email)
+			}
+
+			logger := newTestLogger(t)
+			fake := installFakeGitClient(t)
+			config := localCloneConfig(t, fmt.Sprintf("dirty_policy = %q\nsync_strategy = %q", tt.policy, syncStrategyFFOnly), "alpha")
+			if _, err := cloneRepositories(logger, config, nil); err != nil {
+				t.Fatalf("clone failed: %v", err)
+			}
+			repoPath := clonedRepoPath(t, "alpha")
+			readme := filepath.Join(repoPath, "README.md")
+			if err := os.WriteFile(readme, []byte("local change\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			results, _ := syncRepositories(logger, config, syncOptions{})
+			result := results["local/team/alpha"]
+			if result == nil {
+				t.Fatal("no result for alpha")
+			}
+			if result.DirtyAction != tt.wantAction {
+				t.Errorf("dirty action %q, want %q", result.DirtyAction, tt.wantAction)
+			}
+			if fetched := len(fake.fetched()) > 0; fetched != tt.wantFetch {
+				t.Errorf("fetched %v, want %v", fetched, tt.wantFetch)
+			}
+			content, err := os.ReadFile(readme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clean := !strings.Contains(string(content), "local change"); clean != tt.wantClean {
+				t.Errorf("README.md is %q", content)
+			}
+		})
+	}
+}
+
+func TestSyncRepositoriesFetchIgnoresDirtyPolicy(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	// A fetch leaves the working tree alone, so even skip doesn't stop it
+	config := localCloneConfig(t, fmt.Sprintf("dirty_policy = %q\nsync_strategy = %q", dirtyPolicySkip, syncStrategyFetch), "alpha")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	readme := filepath.Join(clonedRepoPath(t, "alpha"), "README.md")
+	if err := os.WriteFile(readme, []byte("local change\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := syncRepositories(logger, config, syncOptions{})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	result := results["local/team/alpha"]
+	if result == nil || !result.Updated || result.DirtyAction != "" {
+		t.Fatalf("got result %+v, want a fetch with no dirty action", result)
+	}
+	if got := fake.fetched(); !slices.Equal(got, []string{"alpha"}) {
+		t.Errorf("fetched %v, want [alpha]", got)
+	}
+	if content, err := os.ReadFile(readme); err != nil || string(content) != "local change\n" {
+		t.Errorf("README.md is %q, %v, want the local change kept", content, err)
+	}
+}
+
+func TestSyncRepositoriesPinnedCommit(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, `sync_strategy = "ff-only"`, "alpha", "beta")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	alphaPath := clonedRepoPath(t, "alpha")
+	r, err := git.PlainOpen(alphaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned := head.Hash()
+	advanceOrigin(t, alphaPath, "CHANGELOG.md", "v2\n")
+
+	config.Groups["pinned"] = Group{Match: "isExactly", Values: []string{"alpha"}, Commit: pinned.String()[:10], Priority: 1}
+	results, err := syncRepositories(logger, config, syncOptions{})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	alpha := results["local/team/alpha"]
+	if alpha.PinnedCommit != pinned.String()[:10] {
+		t.Errorf("alpha pinned to %q, want %q", alpha.PinnedCommit, pinned.String()[:10])
+	}
+	if branch, commit := readHead(alphaPath); branch != "" || commit != pinned.String() {
+		t.Errorf("alpha HEAD is %q at %s, want detached at %s", branch, commit, pinned)
+	}
+	if beta := results["local/team/beta"]; beta.PinnedCommit != "" {
+		t.Errorf("beta pinned to %q without a matching group", beta.PinnedCommit)
+	}
+	if !slices.ContainsFunc(fake.checkouts, func(opts git.CheckoutOptions) bool { return opts.Hash == pinned }) {
+		t.Errorf("no checkout of %s through the git client", pinned)
+	}
+
+	// A commit that doesn't exist fails the repository
+	config.Groups["pinned"] = Group{Match: "isExactly", Values: []string{"alpha"}, Commit: "0123456789abcdef", Priority: 1}
+	results, err = syncRepositories(logger, config, syncOptions{})
+	if err == nil {
+		t.Fatal("sync succeeded despite an unknown pinned commit")
+	}
+	if alpha := results["local/team/alpha"]; alpha.Error == nil || !strings.Contains(alpha.Error.Error(), "failed to pin commit") {
+		t.Errorf("got error %v, want a pinning failure", alpha.Error)
+	}
+}
+
+func TestCloneChecksOutGroupBranch(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha")
+	group := config.Groups["all"]
+	group.Branch = "develop"
+	config.Groups["all"] = group
+
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if len(fake.cloneOpts) != 1 {
+		t.Fatalf("cloned %d times, want once", len(fake.cloneOpts))
+	}
+	opts := fake.cloneOpts[0]
+	if opts.ReferenceName != plumbing.NewBranchReferenceName("develop") || !opts.SingleBranch {
+		t.Errorf("cloned %s (single branch %v), want only develop", opts.ReferenceName, opts.SingleBranch)
+	}
+
+	if _, err := syncRepositories(logger, config, syncOptions{}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	// Single-branch clones only track their branch, so it is fetched explicitly
+	if len(fake.fetchOpts) != 1 || len(fake.fetchOpts[0].RefSpecs) != 1 || fake.fetchOpts[0].RefSpecs[0] != "+refs/heads/develop:refs/remotes/origin/develop" {
+		t.Errorf("fetched with %+v, want the develop refspec", fake.fetchOpts)
+	}
+}
+
+func TestSyncSwitchesToGroupBranch(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+
+	// The group gains a branch that origin has but the clone doesn't yet
+	repoPath := clonedRepoPath(t, "alpha")
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "develop"), head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+	config.Global.DefaultBranch = "develop"
+
+	results, err := syncRepositories(logger, config, syncOptions{})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result := results["local/team/alpha"]; result == nil || !result.Updated {
+		t.Fatalf("got result %+v, want an update", result)
+	}
+	if head, err := r.Head(); err != nil || head.Name() != plumbing.NewBranchReferenceName("develop") {
+		t.Errorf("HEAD is %v, want develop", head)
+	}
+
+	// A branch origin doesn't have fails the repository
+	config.Global.DefaultBranch = "missing"
+	results, _ = syncRepositories(logger, config, syncOptions{})
+	if result := results["local/team/alpha"]; result == nil || result.Error == nil {
+		t.Errorf("got result %+v, want a checkout error", result)
+	}
+}
+
+func TestCloneDepth(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, "clone_depth = 1", "alpha")
+
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if _, err := syncRepositories(logger, config, syncOptions{}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if len(fake.cloneOpts) != 1 || fake.cloneOpts[0].Depth != 1 {
+		t.Errorf("cloned with %+v, want depth 1", fake.cloneOpts)
+	}
+	if len(fake.fetchOpts) != 1 || fake.fetchOpts[0].Depth != 1 {
+		t.Errorf("fetched with %+v, want depth 1", fake.fetchOpts)
+	}
+}
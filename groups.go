@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pelletier/go-toml"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+// groupNamePattern keeps new group names usable as bare TOML keys
+var groupNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// saveConfigGroup sets the named group in the config file at path, or removes
+// it when group is nil. The file is backed up first and is only replaced if
+// the result is still a valid config. Groups that come from an included file
+// can be overridden but not removed.
+func saveConfigGroup(logger *logger.RateLimitedLogger, path, name string, group *Group) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	key := []string{"groups", name}
+	if group == nil {
+		if !tree.HasPath(key) {
+			return fmt.Errorf("group %s isn't defined in %s; it may come from an included file", name, path)
+		}
+		if err := tree.DeletePath(key); err != nil {
+			return fmt.Errorf("failed to remove group %s: %w", name, err)
+		}
+	} else {
+		groupData, err := toml.Marshal(*group)
+		if err != nil {
+			return fmt.Errorf("failed to encode group %s: %w", name, err)
+		}
+		groupTree, err := toml.LoadBytes(groupData)
+		if err != nil {
+			return fmt.Errorf("failed to encode group %s: %w", name, err)
+		}
+		tree.SetPath(key, groupTree)
+	}
+
+	updated, err := tree.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	// Validate through a file next to the config, so relative includes still
+	// resolve, then move it into place
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gs-groups-*.toml")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(updated); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if _, err := loadConfig(tmp.Name()); err != nil {
+		return fmt.Errorf("the config would be invalid: %w", err)
+	}
+
+	backupPath, err := writeConfigBackup(data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config: %w", err)
+	}
+
+	logger.Info("Config groups updated", "group", name, "path", path, "backup", backupPath)
+	return nil
+}
+
+// fileGroup reads the named group as written in the config file at path,
+// before includes are merged in and ${VAR}s expanded, so editing it keeps them
+func fileGroup(path, name string) (Group, bool) {
+	var group Group
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return group, false
+	}
+	groupTree, ok := tree.GetPath([]string{"groups", name}).(*toml.Tree)
+	if !ok || groupTree.Unmarshal(&group) != nil {
+		return group, false
+	}
+	return group, true
+}
+
+// groupListModel lists a config's groups and returns which one the user
+// wants to add, edit or delete
+type groupListModel struct {
+	names  []string
+	groups map[string]Group
+	cursor int
+	action string // "add", "edit", "delete", or empty to leave
+}
+
+var (
+	groupTitleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00FFFF"))
+	groupSelectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFD700"))
+	groupDimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
+)
+
+func newGroupListModel(groups map[string]Group) groupListModel {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return groupListModel{names: names, groups: groups}
+}
+
+// selected returns the name of the highlighted group, or "" if there are none
+func (m groupListModel) selected() string {
+	if len(m.names) == 0 {
+		return ""
+	}
+	return m.names[m.cursor]
+}
+
+func (m groupListModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m groupListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.names)-1 {
+			m.cursor++
+		}
+	case "a":
+		m.action = "add"
+		return m, tea.Quit
+	case "e", "enter":
+		if len(m.names) > 0 {
+			m.action = "edit"
+			return m, tea.Quit
+		}
+	case "d", "delete":
+		if len(m.names) > 0 {
+			m.action = "delete"
+			return m, tea.Quit
+		}
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m groupListModel) View() string {
+	var b strings.Builder
+	b.WriteString(groupTitleStyle.Render("Groups") + "\n\n")
+	if len(m.names) == 0 {
+		b.WriteString("  No groups yet.\n")
+	}
+	for i, name := range m.names {
+		group := m.groups[name]
+		line := fmt.Sprintf("%s  %s %s", name, group.Match, strings.Join(group.Values, ", "))
+		if group.Type != "" {
+			line += groupDimStyle.Render("  type: " + group.Type)
+		}
+		if i == m.cursor {
+			b.WriteString(groupSelectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	b.WriteString("\n" + groupDimStyle.Render("↑/↓ move • a add • e edit • d delete • q back") + "\n")
+	return b.String()
+}
+
+// handleManageGroupsCommand lists the active config's groups and adds, edits
+// and deletes them until the user goes back
+func handleManageGroupsCommand(logger *logger.RateLimitedLogger, config **Config) {
+	path, err := getCurrentConfigPath(logger)
+	if err != nil {
+		logger.Error("Error finding the active config", "error", err)
+		return
+	}
+	if path == "" {
+		fmt.Println("There is no active config. Load one first.")
+		return
+	}
+
+	cursor := 0
+	for {
+		current, err := loadConfig(path)
+		if err != nil {
+			logger.Error("Failed to load the active config", "path", path, "error", err)
+			return
+		}
+
+		model := newGroupListModel(current.Groups)
+		model.cursor = min(cursor, max(len(model.names)-1, 0))
+		final, err := tea.NewProgram(model).Run()
+		if err != nil {
+			logger.Error("Error listing groups", "error", err)
+			return
+		}
+		model = final.(groupListModel)
+		cursor = model.cursor
+
+		name := model.selected()
+		switch model.action {
+		case "add":
+			group := Group{Match: knownMatchVerbs[0]}
+			name, err = editGroupForm("", &group, current.Groups)
+			if err == nil {
+				err = saveValidGroup(logger, path, name, &group)
+			}
+		case "edit":
+			group, ok := fileGroup(path, name)
+			if !ok {
+				group = current.Groups[name]
+			}
+			if _, err = editGroupForm(name, &group, current.Groups); err == nil {
+				err = saveValidGroup(logger, path, name, &group)
+			}
+		case "delete":
+			var confirm bool
+			err = huh.NewConfirm().
+				Title(fmt.Sprintf("Delete group %s?", name)).
+				Value(&confirm).
+				Run()
+			if err == nil && confirm {
+				err = saveConfigGroup(logger, path, name, nil)
+			}
+		default:
+			return
+		}
+		if err != nil {
+			fmt.Printf("Group not saved:\n%v\n", err)
+			continue
+		}
+
+		if updated, err := loadConfig(path); err == nil {
+			*config = updated
+		}
+	}
+}
+
+// editGroupForm asks for a group's match, values and type, and for its name
+// when name is empty, returning the name
+func editGroupForm(name string, group *Group, existing map[string]Group) (string, error) {
+	values := strings.Join(group.Values, "\n")
+	var fields []huh.Field
+	if name == "" {
+		fields = append(fields, huh.NewInput().
+			Title("Group name").
+			Value(&name).
+			Validate(func(s string) error {
+				if !groupNamePattern.MatchString(s) {
+					return fmt.Errorf("use letters, digits, '_' and '-'")
+				}
+				if _, ok := existing[s]; ok {
+					return fmt.Errorf("group %s already exists", s)
+				}
+				return nil
+			}))
+	}
+	fields = append(fields,
+		huh.NewSelect[string]().
+			Title("Match").
+			Options(huh.NewOptions(knownMatchVerbs...)...).
+			Value(&group.Match),
+		huh.NewText().
+			Title("Values, one per line").
+			Value(&values),
+		huh.NewInput().
+			Title("Type (optional)").
+			Value(&group.Type),
+	)
+	if err := huh.NewForm(huh.NewGroup(fields...)).Run(); err != nil {
+		return "", err
+	}
+
+	group.Values = nil
+	for _, value := range strings.Split(values, "\n") {
+		if value = strings.TrimSpace(value); value != "" {
+			group.Values = append(group.Values, value)
+		}
+	}
+	group.Type = strings.TrimSpace(group.Type)
+	return name, nil
+}
+
+// saveValidGroup validates a group on its own, for clearer errors, before
+// saving it with saveConfigGroup
+func saveValidGroup(logger *logger.RateLimitedLogger, path, name string, group *Group) error {
+	if errs := validateGroup(name, group); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return saveConfigGroup(logger, path, name, group)
+}
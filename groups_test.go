@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configBackupCount returns how many config backups have been written
+func configBackupCount(t *testing.T) int {
+	t.Helper()
+	backups, err := listConfigBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(backups)
+}
+
+func TestSaveConfigGroup(t *testing.T) {
+	logger := newTestLogger(t)
+	path := filepath.Join(t.TempDir(), "gs.toml")
+	writeTestConfig(t, path, validTestConfig(t))
+
+	api := &Group{Match: "startsWith", Values: []string{"api-"}, Type: "service"}
+	if err := saveConfigGroup(logger, path, "api", api); err != nil {
+		t.Fatalf("failed to add group: %v", err)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("the saved config doesn't load: %v", err)
+	}
+	got, ok := config.Groups["api"]
+	if !ok || got.Match != "startsWith" || !slices.Equal(got.Values, []string{"api-"}) || got.Type != "service" {
+		t.Errorf("saved group = %+v", got)
+	}
+	if _, ok := config.Groups["all"]; !ok {
+		t.Error("adding a group dropped the existing one")
+	}
+	if n := configBackupCount(t); n != 1 {
+		t.Errorf("wrote %d backups, want 1", n)
+	}
+
+	if err := saveConfigGroup(logger, path, "all", nil); err != nil {
+		t.Fatalf("failed to delete group: %v", err)
+	}
+	config, err = loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.Groups["all"]; ok {
+		t.Error("the deleted group is still in the config")
+	}
+}
+
+func TestSaveConfigGroupKeepsInvalidResultOut(t *testing.T) {
+	logger := newTestLogger(t)
+	path := filepath.Join(t.TempDir(), "gs.toml")
+	writeTestConfig(t, path, validTestConfig(t))
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := &Group{Match: "regex", Values: []string{"("}}
+	if err := saveConfigGroup(logger, path, "bad", bad); err == nil || !strings.Contains(err.Error(), "would be invalid") {
+		t.Errorf("got error %v, want the invalid config refused", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("the config changed despite the invalid group")
+	}
+	if n := configBackupCount(t); n != 0 {
+		t.Errorf("wrote %d backups for a refused change", n)
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".gs-groups-*"))
+	if len(matches) != 0 {
+		t.Errorf("left temporary files %v", matches)
+	}
+}
+
+func TestSaveConfigGroupIncludedGroups(t *testing.T) {
+	logger := newTestLogger(t)
+	mainPath := writeIncludeTree(t)
+
+	if err := saveConfigGroup(logger, mainPath, "api", nil); err == nil || !strings.Contains(err.Error(), "included file") {
+		t.Errorf("got error %v, want an included group's removal refused", err)
+	}
+
+	// Overriding an included group is allowed, and relative includes still resolve
+	override := &Group{Match: "startsWith", Values: []string{"rest-"}}
+	if err := saveConfigGroup(logger, mainPath, "api", override); err != nil {
+		t.Fatalf("failed to override included group: %v", err)
+	}
+	config, err := loadConfig(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Groups["api"].Values; !slices.Equal(got, []string{"rest-"}) {
+		t.Errorf("api values = %v, want the override", got)
+	}
+	if _, ok := config.Groups["svc"]; !ok {
+		t.Error("the other included groups were lost")
+	}
+}
+
+func TestFileGroupKeepsVariables(t *testing.T) {
+	t.Setenv("GS_TEST_PREFIX", "api-")
+	path := filepath.Join(t.TempDir(), "gs.toml")
+	writeTestConfig(t, path, validTestConfig(t)+`
+[groups.api]
+match = "startsWith"
+values = ["${GS_TEST_PREFIX}"]
+`)
+
+	group, ok := fileGroup(path, "api")
+	if !ok {
+		t.Fatal("group not found")
+	}
+	if !slices.Equal(group.Values, []string{"${GS_TEST_PREFIX}"}) {
+		t.Errorf("values = %v, want the variable unexpanded", group.Values)
+	}
+	if _, ok := fileGroup(path, "missing"); ok {
+		t.Error("found a group that isn't in the file")
+	}
+}
+
+func TestGroupListModel(t *testing.T) {
+	keys := func(m groupListModel, keys ...string) groupListModel {
+		for _, key := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+			if key == "enter" {
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			next, _ := m.Update(msg)
+			m = next.(groupListModel)
+		}
+		return m
+	}
+	groups := map[string]Group{
+		"svc":  {Match: "startsWith", Values: []string{"svc-"}},
+		"api":  {Match: "startsWith", Values: []string{"api-"}},
+		"tool": {Match: "endsWith", Values: []string{"-tool"}},
+	}
+
+	m := keys(newGroupListModel(groups), "j", "j", "j")
+	if m.selected() != "tool" {
+		t.Errorf("selected %q after moving past the end, want tool", m.selected())
+	}
+	if m = keys(m, "k", "enter"); m.action != "edit" || m.selected() != "svc" {
+		t.Errorf("action %q on %q, want edit on svc", m.action, m.selected())
+	}
+	if m = keys(newGroupListModel(groups), "d"); m.action != "delete" || m.selected() != "api" {
+		t.Errorf("action %q on %q, want delete on api", m.action, m.selected())
+	}
+
+	empty := keys(newGroupListModel(nil), "e", "d")
+	if empty.action != "" || empty.selected() != "" {
+		t.Errorf("an empty list allowed %q on %q", empty.action, empty.selected())
+	}
+	if empty = keys(empty, "a"); empty.action != "add" {
+		t.Errorf("action %q, want add", empty.action)
+	}
+	if !strings.Contains(empty.View(), "No groups yet") {
+		t.Error("an empty list doesn't say so")
+	}
+}
+
+func TestSaveValidGroupReportsGroupErrors(t *testing.T) {
+	logger := newTestLogger(t)
+	path := filepath.Join(t.TempDir(), "gs.toml")
+	writeTestConfig(t, path, validTestConfig(t))
+
+	err := saveValidGroup(logger, path, "bad", &Group{Match: "fuzzy"})
+	if err == nil || !strings.Contains(err.Error(), `unknown match "fuzzy"`) || !strings.Contains(err.Error(), "values must not be empty") {
+		t.Errorf("got error %v, want every problem with the group", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// Hook outcomes recorded in RepoResult.Hook
+const (
+	hookOK     = "ok"
+	hookFailed = "failed"
+)
+
+// hookData is what a post_clone_hook template can refer to
+type hookData struct {
+	Name  string
+	Path  string
+	Owner string
+	SCM   string
+}
+
+// resolvePostCloneHook returns the hook for a repository: that of the
+// highest-priority matching group that sets one, else global.post_clone_hook
+func resolvePostCloneHook(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) string {
+	for _, group := range sortedGroups(config) {
+		if group.PostCloneHook != "" && matchesGroup(logger, repo, group.Group) {
+			return group.PostCloneHook
+		}
+	}
+	return config.Global.PostCloneHook
+}
+
+// runPostCloneHook runs the repository's post-clone hook, if any, in its
+// directory after a successful clone or update, and records the outcome in
+// result. A failed hook is logged but doesn't fail the repository.
+func runPostCloneHook(logger *logger.RateLimitedLogger, config *Config, repoPath string, result *RepoResult) {
+	if result.Error != nil || !(result.Cloned || result.Updated) {
+		return
+	}
+	hook := resolvePostCloneHook(logger, config, result.Info)
+	if hook == "" {
+		return
+	}
+
+	command, err := renderHook(hook, hookData{
+		Name:  result.Name,
+		Path:  repoPath,
+		Owner: result.Owner,
+		SCM:   result.SCM,
+	})
+	if err == nil {
+		cmd := hookCommand(command, repoPath)
+		cmd.Env = append(os.Environ(), hookEnv(logger, config, repoPath, result)...)
+		var output []byte
+		output, err = cmd.CombinedOutput()
+		if out := strings.TrimSpace(string(output)); out != "" {
+			logger.Info("Post-clone hook output", "repo", result.Name, "output", out)
+		}
+	}
+
+	if err != nil {
+		result.Hook = hookFailed
+		result.HookError = err
+		logger.Error("Post-clone hook failed", "repo", result.Name, "error", err)
+		return
+	}
+	result.Hook = hookOK
+	logger.Debug("Post-clone hook succeeded", "repo", result.Name)
+}
+
+// hookEnv describes the repository to its hook, on top of gitspace's own
+// environment
+func hookEnv(logger *logger.RateLimitedLogger, config *Config, repoPath string, result *RepoResult) []string {
+	return []string{
+		"GITSPACE_REPO_NAME=" + result.Name,
+		"GITSPACE_REPO_PATH=" + repoPath,
+		"GITSPACE_REPO_TYPE=" + getRepoType(logger, config, result.Info),
+		"GITSPACE_REPO_LABELS=" + strings.Join(getRepoLabels(logger, config, result.Info), ","),
+	}
+}
+
+// renderHook fills in the {{.Name}}-style placeholders of a hook command
+func renderHook(hook string, data hookData) (string, error) {
+	tmpl, err := template.New("post_clone_hook").Option("missingkey=error").Parse(hook)
+	if err != nil {
+		return "", fmt.Errorf("invalid post_clone_hook: %w", err)
+	}
+	var command bytes.Buffer
+	if err := tmpl.Execute(&command, data); err != nil {
+		return "", fmt.Errorf("invalid post_clone_hook: %w", err)
+	}
+	return command.String(), nil
+}
+
+// hookCommand runs command through the platform shell in dir
+func hookCommand(command, dir string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = dir
+	return cmd
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestRenderHook(t *testing.T) {
+	data := hookData{Name: "widget", Path: "/repos/widget", Owner: "acme", SCM: "github"}
+	got, err := renderHook("make -C {{.Path}} setup # {{.SCM}}/{{.Owner}}/{{.Name}}", data)
+	if err != nil || got != "make -C /repos/widget setup # github/acme/widget" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	// Shell variables pass through untouched
+	if got, err := renderHook("echo $HOME", data); err != nil || got != "echo $HOME" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, hook := range []string{"echo {{.Branch}}", "echo {{.Name"} {
+		if _, err := renderHook(hook, data); err == nil {
+			t.Errorf("renderHook(%q) succeeded", hook)
+		}
+	}
+}
+
+func TestResolvePostCloneHook(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{Groups: map[string]Group{
+		"api":    {Match: "startsWith", Values: []string{"api-"}, PostCloneHook: "make api"},
+		"urgent": {Match: "endsWith", Values: []string{"-core"}, PostCloneHook: "make core", Priority: 10},
+		"plain":  {Match: "startsWith", Values: []string{"lib-"}},
+	}}
+	config.Global.PostCloneHook = "make"
+
+	for name, want := range map[string]string{
+		"api-gateway": "make api",
+		"api-core":    "make core",
+		"lib-strings": "make",
+		"unrelated":   "make",
+	} {
+		if got := resolvePostCloneHook(logger, config, lib.RepoInfo{Name: name}); got != want {
+			t.Errorf("%s: got hook %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCloneRunsPostCloneHook(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, `post_clone_hook = "echo {{.Name}} > hook.txt"`, "alpha")
+	config.Groups["broken"] = Group{Match: "isExactly", Values: []string{"beta"}, PostCloneHook: "exit 3"}
+	initTestRepo(t, filepath.Join(config.Global.BaseURL, "team", "beta"), "main")
+
+	results, err := cloneRepositories(logger, config, nil)
+	if err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	alpha, beta := results["local/team/alpha"], results["local/team/beta"]
+	if alpha == nil || beta == nil {
+		t.Fatalf("got results %v", results)
+	}
+
+	if alpha.Hook != hookOK {
+		t.Errorf("alpha hook %q, error %v", alpha.Hook, alpha.HookError)
+	}
+	// The hook runs in the repository with its placeholders filled in
+	if data, err := os.ReadFile(filepath.Join(clonedRepoPath(t, "alpha"), "hook.txt")); err != nil || strings.TrimSpace(string(data)) != "alpha" {
+		t.Errorf("hook wrote %q, %v", data, err)
+	}
+	// A failing hook is reported without failing the clone
+	if beta.Hook != hookFailed || beta.HookError == nil || beta.Error != nil || !beta.Cloned {
+		t.Errorf("beta: hook %q, hook error %v, error %v, cloned %v", beta.Hook, beta.HookError, beta.Error, beta.Cloned)
+	}
+}
+
+func TestValidateConfigRejectsBadHookTemplates(t *testing.T) {
+	config := &Config{Groups: map[string]Group{
+		"api": {Match: "startsWith", Values: []string{"api-"}, PostCloneHook: "echo {{.Nope}}"},
+	}}
+	setValidGlobal(t, config)
+	config.Global.PostCloneHook = "echo {{"
+
+	err := validateConfig(config)
+	for _, key := range []string{"global.post_clone_hook", "groups.api.post_clone_hook"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("got error %v, want %s rejected", err, key)
+		}
+	}
+}
+
+func TestPostCloneHookEnvironment(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, `post_clone_hook = 'echo "$GITSPACE_REPO_NAME|$GITSPACE_REPO_PATH|$GITSPACE_REPO_TYPE|$GITSPACE_REPO_LABELS" > env.txt'`, "alpha")
+	config.Groups["services"] = Group{Match: "isExactly", Values: []string{"alpha"}, Type: "service", Labels: []string{"backend", "go"}, Priority: 1}
+
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	repoPath := clonedRepoPath(t, "alpha")
+	data, err := os.ReadFile(filepath.Join(repoPath, "env.txt"))
+	if err != nil {
+		t.Fatalf("hook didn't run: %v", err)
+	}
+	want := "alpha|" + repoPath + "|service|backend,go"
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("hook saw %q, want %q", got, want)
+	}
+}
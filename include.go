@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/pelletier/go-toml"
+)
+
+// includeKey is the top-level config key listing files to merge in
+const includeKey = "include"
+
+// loadConfigTree parses the config at path and merges in the files named by
+// its include key. Includes are merged in order, each overriding the ones
+// before it, and the including file overrides them all. Relative include
+// paths are resolved against the including file's directory, and included
+// files may include others. chain holds the files being loaded, to catch
+// cycles.
+func loadConfigTree(path string, chain []string) (*toml.Tree, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, seen := range chain {
+		if seen == absPath {
+			cycle := append(chain[i:], absPath)
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	chain = append(chain, absPath)
+
+	tree, err := toml.LoadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TOML file: %w", err)
+	}
+	if !tree.Has(includeKey) {
+		return tree, nil
+	}
+
+	includes, err := includePaths(tree.Get(includeKey))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", absPath, err)
+	}
+	if err := tree.Delete(includeKey); err != nil {
+		return nil, err
+	}
+
+	merged, err := toml.TreeFromMap(map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	for _, include := range includes {
+		include, err := homedir.Expand(include)
+		if err != nil {
+			return nil, fmt.Errorf("%s: include %s: %w", absPath, include, err)
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(absPath), include)
+		}
+
+		included, err := loadConfigTree(include, chain)
+		if err != nil {
+			return nil, fmt.Errorf("%s: include: %w", absPath, err)
+		}
+		mergeConfigTrees(merged, included)
+	}
+	mergeConfigTrees(merged, tree)
+	return merged, nil
+}
+
+// includePaths reads the include key, a string or an array of strings
+func includePaths(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		paths := make([]string, 0, len(v))
+		for _, item := range v {
+			path, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include must be a list of file paths")
+			}
+			paths = append(paths, path)
+		}
+		return paths, nil
+	}
+	return nil, fmt.Errorf("include must be a list of file paths")
+}
+
+// mergeConfigTrees copies src into dst. Tables are merged key by key, so an
+// included file's groups and a local file's groups combine, while any other
+// value, including arrays such as [[clone]], replaces dst's.
+func mergeConfigTrees(dst, src *toml.Tree) {
+	for _, key := range src.Keys() {
+		path := []string{key}
+		srcValue := src.GetPath(path)
+		if srcTree, ok := srcValue.(*toml.Tree); ok {
+			if dstTree, ok := dst.GetPath(path).(*toml.Tree); ok {
+				mergeConfigTrees(dstTree, srcTree)
+				continue
+			}
+		}
+		dst.SetPath(path, srcValue)
+	}
+}
+
+// absoluteIncludes rewrites relative include paths in a config to absolute
+// ones, so the copy installed as the active config still finds them. Configs
+// without includes are returned unchanged.
+func absoluteIncludes(sourcePath string, data []byte) ([]byte, error) {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if !tree.Has(includeKey) {
+		return data, nil
+	}
+
+	includes, err := includePaths(tree.Get(includeKey))
+	if err != nil {
+		return nil, err
+	}
+	sourceDir, err := filepath.Abs(filepath.Dir(sourcePath))
+	if err != nil {
+		return nil, err
+	}
+	for i, include := range includes {
+		if expanded, err := homedir.Expand(include); err == nil && !filepath.IsAbs(expanded) {
+			includes[i] = filepath.Join(sourceDir, expanded)
+		}
+	}
+	tree.Set(includeKey, includes)
+	return tree.Marshal()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeIncludeTree writes a main config including shared/common.toml, which
+// in turn includes shared/groups.toml, and returns the main config's path
+func writeIncludeTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestConfig(t, filepath.Join(dir, "shared", "groups.toml"), `
+[groups.api]
+match = "startsWith"
+values = ["api-"]
+
+[groups.svc]
+match = "startsWith"
+values = ["svc-"]
+`)
+	writeTestConfig(t, filepath.Join(dir, "shared", "common.toml"), fmt.Sprintf(`
+include = ["groups.toml"]
+
+[global]
+path = %q
+scm = "github"
+owner = "shared-owner"
+concurrency = 4
+
+[groups.svc]
+values = ["service-"]
+
+[[clone]]
+scm = "github"
+owner = "shared-extra"
+`, filepath.Join(dir, "work")))
+	mainPath := filepath.Join(dir, "gs.toml")
+	writeTestConfig(t, mainPath, `
+include = "shared/common.toml"
+
+[global]
+owner = "acme"
+
+[groups.tools]
+match = "endsWith"
+values = ["-tool"]
+
+[[clone]]
+scm = "github"
+owner = "acme-labs"
+`)
+	return mainPath
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	config, err := loadConfig(writeIncludeTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// [global] merges key by key, the including file winning
+	if config.Global.Owner != "acme" || config.Global.Concurrency != 4 || config.Global.SCM != "github" {
+		t.Errorf("got global owner %q, concurrency %d, scm %q", config.Global.Owner, config.Global.Concurrency, config.Global.SCM)
+	}
+	// Groups from every level combine, and a later file overrides single keys
+	names := make([]string, 0, len(config.Groups))
+	for name := range config.Groups {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"api", "svc", "tools"}) {
+		t.Errorf("got groups %v", names)
+	}
+	if svc := config.Groups["svc"]; svc.Match != "startsWith" || !slices.Equal(svc.Values, []string{"service-"}) {
+		t.Errorf("got svc %+v", svc)
+	}
+	// Arrays replace rather than append
+	if len(config.Clone) != 1 || config.Clone[0].Owner != "acme-labs" {
+		t.Errorf("got clone targets %+v", config.Clone)
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.toml"), filepath.Join(dir, "b.toml")
+	writeTestConfig(t, a, `include = ["b.toml"]`)
+	writeTestConfig(t, b, `include = ["a.toml"]`)
+	if _, err := loadConfig(a); err == nil || !strings.Contains(err.Error(), "include cycle: "+a+" -> "+b+" -> "+a) {
+		t.Errorf("got error %v, want the cycle named", err)
+	}
+
+	for name, text := range map[string]string{
+		"not a list":      "include = 3",
+		"not all strings": `include = ["a.toml", 3]`,
+		"missing file":    `include = ["missing.toml"]`,
+	} {
+		path := filepath.Join(dir, "bad.toml")
+		writeTestConfig(t, path, text)
+		if _, err := loadConfig(path); err == nil {
+			t.Errorf("%s: loaded without an error", name)
+		}
+	}
+}
+
+func TestInstallConfigMakesIncludesAbsolute(t *testing.T) {
+	logger := newTestLogger(t)
+	mainPath := writeIncludeTree(t)
+	if err := installConfig(logger, mainPath); err != nil {
+		t.Fatal(err)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := filepath.Join(home, managedConfigDir, activeConfigFile)
+	data, err := os.ReadFile(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), filepath.Join(filepath.Dir(mainPath), "shared", "common.toml")) {
+		t.Errorf("installed config kept a relative include:\n%s", data)
+	}
+	// The installed copy lives elsewhere but still finds its includes
+	config, err := loadConfig(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Global.Owner != "acme" || len(config.Groups) != 3 {
+		t.Errorf("got owner %q and groups %v", config.Global.Owner, config.Groups)
+	}
+
+	// Configs without includes are installed byte for byte
+	plain := []byte("[global]\nowner = \"acme\"\n")
+	if got, err := absoluteIncludes(mainPath, plain); err != nil || string(got) != string(plain) {
+		t.Errorf("absoluteIncludes changed a config without includes to %q, %v", got, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// clockSkewMargin is subtracted from the last sync time before comparing it
+// with push times, which come from the SCM's clock rather than ours
+const clockSkewMargin = 5 * time.Minute
+
+// syncOptions controls which repositories sync fetches. By default a
+// repository is only fetched when it was pushed to after its last sync.
+type syncOptions struct {
+	ForceAll bool      // Fetch every repository
+	Since    time.Time // Fetch repositories pushed after this instead of after their last sync
+}
+
+// needsFetch reports whether a repository last synced at lastSynced may have
+// changed on the SCM, going by the push time in a listing made at listedAt.
+// Repositories whose provider doesn't report push times, or that were never
+// synced, are always fetched, as are those whose listing predates the cutoff,
+// since pushes after the listing aren't in it.
+func (o syncOptions) needsFetch(info lib.RepoInfo, lastSynced, listedAt time.Time) bool {
+	if o.ForceAll || info.PushedAt.IsZero() {
+		return true
+	}
+	cutoff := lastSynced
+	if !o.Since.IsZero() {
+		cutoff = o.Since
+	}
+	if cutoff.IsZero() || !listedAt.After(cutoff) {
+		return true
+	}
+	return info.PushedAt.After(cutoff.Add(-clockSkewMargin))
+}
+
+// listForSync lists the repositories to sync and when the listing was made.
+// Incremental syncs skip repositories by push time, so they ask the SCM
+// rather than trust a cached listing that misses later pushes. When the SCM
+// can't be reached they fall back to the cache, whose age needsFetch takes
+// into account.
+func listForSync(ctx context.Context, logger *logger.RateLimitedLogger, config *Config, scmType lib.SCMType, opts syncOptions) ([]lib.RepoInfo, time.Time, error) {
+	if opts.ForceAll {
+		repos, err := getRepositoryList(ctx, logger, config, scmType, config.Global.BaseURL, false)
+		return repos, time.Now(), err
+	}
+
+	listedAt := time.Now()
+	repos, err := getRepositoryList(ctx, logger, config, scmType, config.Global.BaseURL, true)
+	if err == nil {
+		return repos, listedAt, nil
+	}
+
+	cachePath, pathErr := getRepoListCachePath(config)
+	if pathErr != nil {
+		return nil, time.Time{}, err
+	}
+	cached, cacheErr := readRepoListCache(cachePath)
+	if cacheErr != nil || cached.Visibility != config.Global.Visibility {
+		return nil, time.Time{}, err
+	}
+	logger.Warn("Failed to refresh the repository list, using the cached one", "owner", config.Global.Owner, "fetched_at", cached.FetchedAt, "error", err)
+	return cached.Repos, cached.FetchedAt, nil
+}
+
+// parseSince parses --since as a duration back from now, such as 24h, or as
+// a date or RFC 3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("duration must be positive (got %s)", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expected a duration such as 24h, a date such as 2024-05-01, or an RFC 3339 time (got %q)", value)
+}
+
+// readLastSynced returns the lastSynced time of every repository in
+// index.toml, keyed like RepoResult.Key. A missing or unreadable index
+// yields an empty map, so everything is fetched.
+func readLastSynced(cacheDir string) map[string]time.Time {
+	lastSynced := make(map[string]time.Time)
+	repos, err := readIndex(cacheDir)
+	if err != nil {
+		return lastSynced
+	}
+	for _, repo := range repos {
+		if t, err := time.Parse(time.RFC3339, repo.LastSynced); err == nil {
+			lastSynced[fmt.Sprintf("%s/%s/%s", repo.SCM, repo.Owner, repo.Name)] = t
+		}
+	}
+	return lastSynced
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestNeedsFetch(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastSynced := now.Add(-time.Hour)
+	tests := []struct {
+		name       string
+		opts       syncOptions
+		pushedAt   time.Time
+		lastSynced time.Time
+		listedAt   time.Time
+		want       bool
+	}{
+		{"pushed after the last sync", syncOptions{}, now.Add(-10 * time.Minute), lastSynced, now, true},
+		{"pushed before the last sync", syncOptions{}, now.Add(-2 * time.Hour), lastSynced, now, false},
+		{"pushed within the clock skew margin", syncOptions{}, lastSynced.Add(-time.Minute), lastSynced, now, true},
+		{"no push time", syncOptions{}, time.Time{}, lastSynced, now, true},
+		{"never synced", syncOptions{}, now.Add(-2 * time.Hour), time.Time{}, now, true},
+		{"force", syncOptions{ForceAll: true}, now.Add(-2 * time.Hour), lastSynced, now, true},
+		{"since overrides the last sync", syncOptions{Since: now.Add(-3 * time.Hour)}, now.Add(-2 * time.Hour), lastSynced, now, true},
+		{"listing older than the last sync", syncOptions{}, now.Add(-2 * time.Hour), lastSynced, lastSynced.Add(-time.Minute), true},
+		{"listing newer than the last sync", syncOptions{}, now.Add(-2 * time.Hour), lastSynced, lastSynced.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := lib.RepoInfo{Name: "widget", PushedAt: tt.pushedAt}
+			if got := tt.opts.needsFetch(info, tt.lastSynced, tt.listedAt); got != tt.want {
+				t.Errorf("needsFetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// gitHubSyncFixture sets up a GitHub config for the org acme with a clone of
+// its repository widget, last synced lastSynced, and a repo_list.json that
+// cached widget's push time as cachedPush at cachedAt
+type gitHubSyncFixture struct {
+	logger     *logger.RateLimitedLogger
+	config     *Config
+	cacheDir   string
+	lastSynced time.Time
+	fake       *fakeGitClient
+}
+
+func newGitHubSyncFixture(t *testing.T, lastSynced, cachedAt, cachedPush time.Time) *gitHubSyncFixture {
+	t.Helper()
+	logger := newTestLogger(t)
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	config := loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = "github"
+owner = "acme"
+owner_type = "org"
+
+[groups.all]
+match = "regex"
+values = [".*"]
+`, t.TempDir()))
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeGitClient(t)
+	repoDir := filepath.Join(cacheDir, ".repositories", "github", "acme")
+	if _, err := fake.Clone(filepath.Join(repoDir, "widget"), &git.CloneOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fake.clones = nil
+
+	cache, err := json.Marshal(repoListCache{
+		FetchedAt:  cachedAt,
+		Visibility: config.Global.Visibility,
+		Repos:      []lib.RepoInfo{{Name: "widget", DefaultBranch: "main", PushedAt: cachedPush}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, repoListFile), cache, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &gitHubSyncFixture{logger: logger, config: config, cacheDir: cacheDir, lastSynced: lastSynced, fake: fake}
+}
+
+func (f *gitHubSyncFixture) sync(t *testing.T) *RepoResult {
+	t.Helper()
+	lastSynced := map[string]time.Time{"github/acme/widget": f.lastSynced}
+	results, err := syncTargetRepositories(f.logger, f.config, f.cacheDir, nil, syncOptions{}, lastSynced)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	result := results["widget"]
+	if result == nil {
+		t.Fatalf("no result for widget in %v", results)
+	}
+	return result
+}
+
+// serveGitHubRepos answers the org listing with widget pushed at pushedAt and
+// counts the listings made
+func serveGitHubRepos(t *testing.T, pushedAt time.Time) *atomic.Int32 {
+	var listings atomic.Int32
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/acme/repos" {
+			http.NotFound(w, r)
+			return
+		}
+		listings.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[{"name": "widget", "default_branch": "main", "pushed_at": %q}]`, pushedAt.Format(time.RFC3339))
+	})
+	return &listings
+}
+
+func TestSyncRefreshesStaleRepoList(t *testing.T) {
+	now := time.Now()
+	// The cached listing predates a push that landed after the last sync
+	fixture := newGitHubSyncFixture(t, now.Add(-30*time.Minute), now.Add(-10*time.Minute), now.Add(-2*time.Hour))
+	listings := serveGitHubRepos(t, now.Add(-5*time.Minute))
+
+	result := fixture.sync(t)
+	if listings.Load() == 0 {
+		t.Error("incremental sync used the cached repository list")
+	}
+	if result.UpToDate {
+		t.Error("widget was skipped despite a push after the last sync")
+	}
+	if got := fixture.fake.fetched(); !slices.Equal(got, []string{"widget"}) {
+		t.Errorf("fetched %v, want [widget]", got)
+	}
+}
+
+func TestSyncSkipsRepositoriesWithoutPushes(t *testing.T) {
+	now := time.Now()
+	fixture := newGitHubSyncFixture(t, now.Add(-30*time.Minute), now.Add(-10*time.Minute), now.Add(-2*time.Hour))
+	serveGitHubRepos(t, now.Add(-2*time.Hour))
+
+	if result := fixture.sync(t); !result.UpToDate {
+		t.Error("widget was fetched without a push since the last sync")
+	}
+	if got := fixture.fake.fetched(); len(got) != 0 {
+		t.Errorf("fetched %v, want nothing", got)
+	}
+}
+
+func TestSyncFallsBackToCachedRepoList(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		cachedAt  time.Time
+		wantFetch bool
+	}{
+		// A cache from before the last sync can't rule out pushes since
+		{"cache older than the last sync", now.Add(-time.Hour), true},
+		{"cache newer than the last sync", now.Add(-10 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newGitHubSyncFixture(t, now.Add(-30*time.Minute), tt.cachedAt, now.Add(-2*time.Hour))
+			stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"message": "unavailable"}`, http.StatusServiceUnavailable)
+			})
+
+			fixture.sync(t)
+			if fetched := len(fixture.fake.fetched()) > 0; fetched != tt.wantFetch {
+				t.Errorf("fetched %v, want %v", fetched, tt.wantFetch)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// indexFile is the typed form of index.toml, for reading it
+type indexFile struct {
+	LastUpdated  string `toml:"lastUpdated"`
+	Repositories struct {
+		// scm, then owner, then repository name
+		Repositories map[string]map[string]map[string]indexEntry `toml:"repositories"`
+	} `toml:"repositories"`
+}
+
+// indexEntry is a repository's entry in index.toml
+type indexEntry struct {
+	ConfigPath string   `toml:"configPath"`
+	BackupPath string   `toml:"backupPath"`
+	LastCloned string   `toml:"lastCloned"`
+	LastSynced string   `toml:"lastSynced"`
+	Type       string   `toml:"type"`
+	Labels     []string `toml:"labels"`
+	Metadata   struct {
+		URL           string   `toml:"url"`
+		Description   string   `toml:"description"`
+		Topics        []string `toml:"topics"`
+		DefaultBranch string   `toml:"defaultBranch"`
+		Archived      bool     `toml:"archived"`
+		Fork          bool     `toml:"fork"`
+		UpdatedAt     string   `toml:"updatedAt"`
+		PushedAt      string   `toml:"pushedAt"`
+		Branch        string   `toml:"branch"`
+		Commit        string   `toml:"commit"`
+	} `toml:"metadata"`
+}
+
+// indexedRepo is an index entry together with where it sits in the index
+type indexedRepo struct {
+	SCM   string
+	Owner string
+	Name  string
+	indexEntry
+}
+
+// lastSynced returns when the repository was last synced, or cloned if it
+// never was; zero when neither is recorded
+func (r indexedRepo) lastSynced() time.Time {
+	for _, value := range []string{r.LastSynced, r.LastCloned} {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// readIndex returns every repository in the index.toml under cacheDir,
+// sorted by scm, owner and name. A missing index has no repositories.
+func readIndex(cacheDir string) ([]indexedRepo, error) {
+	data, err := os.ReadFile(filepath.Join(cacheDir, "index.toml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index.toml: %w", err)
+	}
+	var index indexFile
+	if err := toml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode index.toml: %w", err)
+	}
+
+	var repos []indexedRepo
+	for scm, owners := range index.Repositories.Repositories {
+		for owner, entries := range owners {
+			for name, entry := range entries {
+				repos = append(repos, indexedRepo{SCM: scm, Owner: owner, Name: name, indexEntry: entry})
+			}
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		a, b := repos[i], repos[j]
+		if a.SCM != b.SCM {
+			return a.SCM < b.SCM
+		}
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		return a.Name < b.Name
+	})
+	return repos, nil
+}
+
+// indexFilter selects repositories from the index. Empty fields match
+// everything.
+type indexFilter struct {
+	SCM          string
+	Owner        string
+	Type         string
+	Label        string
+	SyncedWithin time.Duration // Synced (or cloned) at most this long ago
+	NotSyncedFor time.Duration // Not synced for at least this long, or never
+}
+
+func (f indexFilter) matches(repo indexedRepo, now time.Time) bool {
+	if f.SCM != "" && canonicalSCM(repo.SCM) != canonicalSCM(f.SCM) {
+		return false
+	}
+	if f.Owner != "" && !strings.EqualFold(repo.Owner, f.Owner) {
+		return false
+	}
+	if f.Type != "" && repo.Type != f.Type {
+		return false
+	}
+	if f.Label != "" && !slices.Contains(repo.Labels, f.Label) {
+		return false
+	}
+	lastSynced := repo.lastSynced()
+	if f.SyncedWithin > 0 && (lastSynced.IsZero() || now.Sub(lastSynced) > f.SyncedWithin) {
+		return false
+	}
+	if f.NotSyncedFor > 0 && !lastSynced.IsZero() && now.Sub(lastSynced) < f.NotSyncedFor {
+		return false
+	}
+	return true
+}
+
+// filterIndex returns the repositories matching filter
+func filterIndex(repos []indexedRepo, filter indexFilter, now time.Time) []indexedRepo {
+	var matched []indexedRepo
+	for _, repo := range repos {
+		if filter.matches(repo, now) {
+			matched = append(matched, repo)
+		}
+	}
+	return matched
+}
+
+// printIndexTable prints repositories from the index as a table
+func printIndexTable(repos []indexedRepo) {
+	if len(repos) == 0 {
+		fmt.Println("No cached repositories match.")
+		return
+	}
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("SCM", "Owner", "Repository", "Type", "Labels", "Branch", "Last synced")
+	for _, repo := range repos {
+		lastSynced := "never"
+		if synced := repo.lastSynced(); !synced.IsZero() {
+			lastSynced = synced.Local().Format("2006-01-02 15:04")
+		}
+		t.Row(repo.SCM, repo.Owner, repo.Name, repo.Type, strings.Join(repo.Labels, ", "), repo.Metadata.Branch, lastSynced)
+	}
+	fmt.Println(t)
+	fmt.Printf("Total: %d\n", len(repos))
+}
+
+// loadIndexData reads index.toml as a generic tree, so that rewriting it
+// keeps entries this run didn't touch. A missing file yields an empty tree.
+func loadIndexData(indexPath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return make(map[string]interface{}), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index.toml: %w", err)
+	}
+
+	indexData := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &indexData); err != nil {
+		return nil, fmt.Errorf("failed to decode index.toml: %w", err)
+	}
+	return indexData, nil
+}
+
+// indexSCMs returns the repositories.repositories table of index data, which
+// maps scm to owner to repository name to its entry, creating it if needed
+func indexSCMs(indexData map[string]interface{}) map[string]interface{} {
+	return childTable(childTable(indexData, "repositories"), "repositories")
+}
+
+// childTable returns parent[key] as a table, creating it if it's missing or
+// not a table
+func childTable(parent map[string]interface{}, key string) map[string]interface{} {
+	if table, ok := parent[key].(map[string]interface{}); ok {
+		return table
+	}
+	table := make(map[string]interface{})
+	parent[key] = table
+	return table
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIndex is an index.toml holding three repositories across two scms
+const testIndex = `
+lastUpdated = "2024-05-01T12:00:00Z"
+
+[repositories.repositories.github.acme.widget]
+lastSynced = "2024-05-01T11:00:00Z"
+type = "service"
+labels = ["backend"]
+[repositories.repositories.github.acme.widget.metadata]
+branch = "main"
+
+[repositories.repositories.github.acme.api]
+lastCloned = "2024-04-01T12:00:00Z"
+type = "service"
+
+[repositories.repositories.gitea.labs.tool]
+labels = ["cli", "backend"]
+`
+
+// writeTestIndex writes index.toml into the cache directory under HOME
+func writeTestIndex(t *testing.T, text string) string {
+	t.Helper()
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "index.toml"), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return cacheDir
+}
+
+func TestReadIndex(t *testing.T) {
+	newTestLogger(t)
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repos, err := readIndex(cacheDir); err != nil || len(repos) != 0 {
+		t.Errorf("a missing index got %v, %v", repos, err)
+	}
+
+	writeTestIndex(t, testIndex)
+	repos, err := readIndex(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, repo := range repos {
+		keys = append(keys, repo.SCM+"/"+repo.Owner+"/"+repo.Name)
+	}
+	if got := strings.Join(keys, " "); got != "gitea/labs/tool github/acme/api github/acme/widget" {
+		t.Errorf("got %s, want sorted by scm, owner and name", got)
+	}
+	if widget := repos[2]; widget.Type != "service" || widget.Metadata.Branch != "main" {
+		t.Errorf("got widget %+v", widget)
+	}
+	// lastSynced falls back to lastCloned
+	if got := repos[1].lastSynced(); !got.Equal(time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("api last synced %v", got)
+	}
+	if got := repos[0].lastSynced(); !got.IsZero() {
+		t.Errorf("tool last synced %v, want never", got)
+	}
+
+	writeTestIndex(t, "not = [toml")
+	if _, err := readIndex(cacheDir); err == nil {
+		t.Error("a corrupt index read without an error")
+	}
+}
+
+func TestFilterIndex(t *testing.T) {
+	newTestLogger(t)
+	repos, err := readIndex(writeTestIndex(t, testIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter indexFilter
+		want   string
+	}{
+		{"everything", indexFilter{}, "tool api widget"},
+		{"scm, any case", indexFilter{SCM: "GitHub"}, "api widget"},
+		{"owner", indexFilter{Owner: "labs"}, "tool"},
+		{"type", indexFilter{Type: "service"}, "api widget"},
+		{"label", indexFilter{Label: "backend"}, "tool widget"},
+		{"synced within", indexFilter{SyncedWithin: 24 * time.Hour}, "widget"},
+		{"not synced for, including never", indexFilter{NotSyncedFor: 7 * 24 * time.Hour}, "tool api"},
+		{"combined", indexFilter{SCM: "github", Label: "backend"}, "widget"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, repo := range filterIndex(repos, tt.filter, now) {
+				names = append(names, repo.Name)
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexListCommand(t *testing.T) {
+	logger := newTestLogger(t)
+	writeTestIndex(t, testIndex)
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI(logger, []string{"index", "list", "--label", "backend"}) })
+	if code != exitOK || !strings.Contains(out, "widget") || !strings.Contains(out, "tool") || strings.Contains(out, "api") || !strings.Contains(out, "Total: 2") {
+		t.Errorf("exit %d, printed %q", code, out)
+	}
+	out = captureStdout(t, func() { code = runCLI(logger, []string{"index", "list", "--owner", "nobody"}) })
+	if code != exitOK || !strings.Contains(out, "No cached repositories match.") {
+		t.Errorf("exit %d, printed %q", code, out)
+	}
+
+	for _, args := range [][]string{{"index"}, {"index", "list", "--synced-within", "soon"}} {
+		if code := runCLI(logger, args); code != exitUsage {
+			t.Errorf("%q exited %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/charmbracelet/huh"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+const defaultInitPath = "gs.toml"
+
+// errInitCancelled is returned when the user declines to overwrite a config
+var errInitCancelled = errors.New("init cancelled")
+
+// initAnswers are the wizard's answers, rendered into initTemplate
+type initAnswers struct {
+	SCM      string
+	BaseURL  string
+	Owner    string
+	Path     string
+	AuthType string
+	KeyPath  string
+}
+
+var initTemplate = template.Must(template.New("gs.toml").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+}).Parse(`# Gitspace config, created by "gitspace init".
+# See https://github.com/ssotops/gitspace#configuration-explanation
+
+[global]
+# Directory where symlinks to the cloned repositories are created
+path = {{quote .Path}}
+# Where to clone from: github, gitlab, gitea, or a self-hosted Gitea hostname.
+# Self-hosted GitLab is "gitlab" with base_url set.
+scm = {{quote .SCM}}
+{{- if .BaseURL}}
+base_url = {{quote .BaseURL}}
+{{- end}}
+# The organization, group or user owning the repositories
+owner = {{quote .Owner}}
+
+[auth]
+# ssh, https or token
+type = {{quote .AuthType}}
+{{- if .KeyPath}}
+# Private key used for SSH clones; "$VAR" reads the path from a variable
+key_path = {{quote .KeyPath}}
+{{- end}}
+
+# Groups select which repositories are cloned. A repository is cloned when it
+# matches any group. match is one of startsWith, endsWith, includes,
+# isExactly, regex or hasTopic.
+
+# Example: every repository whose name starts with "api-"
+[groups.api]
+match = "startsWith"
+values = ["api-"]
+labels = ["backend"]
+
+# Example: two specific repositories
+[groups.docs]
+match = "isExactly"
+values = ["docs", "website"]
+`))
+
+// runInitWizard asks for the basic settings and writes a commented config to
+// path. An existing file is only replaced after confirmation. The written
+// file is loaded back to make sure it is valid.
+func runInitWizard(logger *logger.RateLimitedLogger, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		var overwrite bool
+		err := huh.NewConfirm().
+			Title(fmt.Sprintf("%s already exists. Overwrite it?", path)).
+			Value(&overwrite).
+			Run()
+		if err != nil {
+			return fmt.Errorf("error getting confirmation: %w", err)
+		}
+		if !overwrite {
+			return errInitCancelled
+		}
+	}
+
+	answers := initAnswers{SCM: "github", Path: "gs", AuthType: "ssh", KeyPath: "~/.ssh/id_ed25519"}
+	required := func(name string) func(string) error {
+		return func(value string) error {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("%s is required", name)
+			}
+			return nil
+		}
+	}
+
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Where are your repositories hosted?").
+				Options(
+					huh.NewOption("GitHub", "github"),
+					huh.NewOption("GitLab", "gitlab"),
+					huh.NewOption("Gitea", "gitea"),
+				).
+				Value(&answers.SCM),
+			huh.NewInput().
+				Title("Organization, group or user that owns them").
+				Validate(required("owner")).
+				Value(&answers.Owner),
+			huh.NewInput().
+				Title("Directory for the repository symlinks").
+				Validate(required("path")).
+				Value(&answers.Path),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Base URL of your instance (leave empty for gitlab.com)").
+				Value(&answers.BaseURL),
+		).WithHideFunc(func() bool { return answers.SCM == "github" }),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("How should repositories be cloned?").
+				Options(
+					huh.NewOption("SSH", "ssh"),
+					huh.NewOption("HTTPS", "https"),
+					huh.NewOption("Token", "token"),
+				).
+				Value(&answers.AuthType),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Path to your SSH private key").
+				Validate(required("key path")).
+				Value(&answers.KeyPath),
+		).WithHideFunc(func() bool { return answers.AuthType != "ssh" }),
+	).Run()
+	if err != nil {
+		return fmt.Errorf("error getting answers: %w", err)
+	}
+	if answers.AuthType != "ssh" {
+		answers.KeyPath = ""
+	}
+	answers.BaseURL = strings.TrimSpace(answers.BaseURL)
+
+	var buf bytes.Buffer
+	if err := initTemplate.Execute(&buf, answers); err != nil {
+		return fmt.Errorf("failed to render config: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if _, err := loadConfig(path); err != nil {
+		return fmt.Errorf("wrote %s, but it doesn't load: %w", path, err)
+	}
+	logger.Info("Config created", "path", path)
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// expandConfigEnv replaces $VAR and ${VAR} references in every string of the
+// config with the variable's value, recursing into groups, clone targets and
+// exclude rules. $$ stands for a literal $. Values of regex matches are left
+// alone, since $ is an anchor there, as are fields tagged expand:"false",
+// such as hooks the shell expands itself. Each unset variable is reported as an
+// error naming the field it appeared in.
+func expandConfigEnv(config *Config) []error {
+	var errs []error
+	expandValue(reflect.ValueOf(config).Elem(), "", false, &errs)
+	return errs
+}
+
+func expandValue(v reflect.Value, path string, regex bool, errs *[]error) {
+	switch v.Kind() {
+	case reflect.String:
+		if regex {
+			return
+		}
+		expanded, err := expandEnv(v.String())
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		v.SetString(expanded)
+
+	case reflect.Struct:
+		// Regex patterns are the values of groups and rules matching by regex
+		isRegex := false
+		if match := v.FieldByName("Match"); match.IsValid() && match.Kind() == reflect.String {
+			isRegex = match.String() == "regex"
+		}
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("expand") == "false" {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+			if name == "" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			expandValue(v.Field(i), name, isRegex && field.Name == "Values", errs)
+		}
+
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), regex, errs)
+		}
+
+	case reflect.Map:
+		// Map values aren't addressable, so expand a copy and store it back
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			expandValue(elem, fmt.Sprintf("%s.%v", path, iter.Key()), regex, errs)
+			v.SetMapIndex(iter.Key(), elem)
+		}
+
+	case reflect.Pointer:
+		if !v.IsNil() {
+			expandValue(v.Elem(), path, regex, errs)
+		}
+	}
+}
+
+// expandEnv expands $VAR and ${VAR} in s. A $ not followed by a variable
+// name is kept as is, and $$ becomes a single $.
+func expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		var name string
+		next := i + 1
+		switch {
+		case s[next] == '$':
+			out.WriteByte('$')
+			i = next
+			continue
+		case s[next] == '{':
+			end := strings.IndexByte(s[next:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", s)
+			}
+			name = s[next+1 : next+end]
+			if !isEnvName(name) {
+				return "", fmt.Errorf("invalid variable name %q", name)
+			}
+			i = next + end
+		default:
+			end := next
+			for end < len(s) && isEnvNameChar(s[end], end == next) {
+				end++
+			}
+			if end == next {
+				out.WriteByte('$')
+				continue
+			}
+			name = s[next:end]
+			i = end - 1
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		out.WriteString(value)
+	}
+	return out.String(), nil
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isEnvNameChar(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEnvNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
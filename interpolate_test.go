@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GS_OWNER", "acme")
+	t.Setenv("GS_EMPTY", "")
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"$GS_OWNER", "acme"},
+		{"${GS_OWNER}-tools", "acme-tools"},
+		{"$GS_OWNER/repos", "acme/repos"},
+		{"pre${GS_EMPTY}post", "prepost"},
+		{"cost $$5", "cost $5"},
+		{"trailing $", "trailing $"},
+		{"$ alone", "$ alone"},
+		{"$1", "$1"},
+	}
+	for _, tt := range tests {
+		got, err := expandEnv(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for in, want := range map[string]string{
+		"$GS_UNSET_VARIABLE": "GS_UNSET_VARIABLE is not set",
+		"${GS_OWNER":         "unterminated ${",
+		"${not-a-name}":      "invalid variable name",
+	} {
+		if _, err := expandEnv(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expandEnv(%q) got error %v, want %q", in, err, want)
+		}
+	}
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	workDir := t.TempDir()
+	t.Setenv("GS_WORK", workDir)
+	t.Setenv("GS_OWNER", "acme")
+	t.Setenv("GS_TOKEN", "secret")
+	config := loadTestConfig(t, `
+[global]
+path = "${GS_WORK}/repos"
+scm = "github"
+owner = "$GS_OWNER"
+post_clone_hook = "echo $REPO_NAME"
+
+[auth]
+token = "$GS_TOKEN"
+
+[groups.api]
+match = "startsWith"
+values = ["$GS_OWNER-"]
+exclude = [{match = "isExactly", values = ["${GS_OWNER}-legacy"]}]
+
+[groups.anchored]
+match = "regex"
+values = ["^svc-$"]
+
+[[clone]]
+scm = "github"
+owner = "${GS_OWNER}-labs"
+`)
+
+	if config.Global.Path != filepath.Join(workDir, "repos") || config.Global.Owner != "acme" || config.Auth.Token != "secret" {
+		t.Errorf("got path %q, owner %q, token %q", config.Global.Path, config.Global.Owner, config.Auth.Token)
+	}
+	api := config.Groups["api"]
+	if !slices.Equal(api.Values, []string{"acme-"}) || !slices.Equal(api.Exclude[0].Values, []string{"acme-legacy"}) {
+		t.Errorf("got group %+v", api)
+	}
+	if config.Clone[0].Owner != "acme-labs" {
+		t.Errorf("got clone owner %q", config.Clone[0].Owner)
+	}
+	// Regex anchors and shell hooks keep their $
+	if got := config.Groups["anchored"].Values; !slices.Equal(got, []string{"^svc-$"}) {
+		t.Errorf("regex values became %q", got)
+	}
+	if config.Global.PostCloneHook != "echo $REPO_NAME" {
+		t.Errorf("post_clone_hook became %q", config.Global.PostCloneHook)
+	}
+}
+
+func TestLoadConfigReportsUnsetVariables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gs.toml")
+	writeTestConfig(t, path, fmt.Sprintf(`
+[global]
+path = %q
+scm = "github"
+owner = "$GS_UNSET_OWNER"
+
+[groups.all]
+match = "startsWith"
+values = ["${GS_UNSET_PREFIX}"]
+`, t.TempDir()))
+
+	problems := checkConfigFile(path)
+	for _, want := range []string{
+		"global.owner: environment variable GS_UNSET_OWNER is not set",
+		"groups.all.values[0]: environment variable GS_UNSET_PREFIX is not set",
+	} {
+		if !slices.ContainsFunc(problems, func(err error) bool { return strings.Contains(err.Error(), want) }) {
+			t.Errorf("no problem mentions %q in %v", want, problems)
+		}
+	}
+}
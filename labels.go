@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// getRepoLabels returns the global labels followed by the labels of every
+// group the repository matches, in priority order, with duplicates removed
+func getRepoLabels(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) []string {
+	seen := make(map[string]bool)
+	var labels []string
+
+	add := func(values []string) {
+		for _, label := range values {
+			if label == "" || seen[label] {
+				continue
+			}
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+
+	add(config.Global.Labels)
+	for _, group := range sortedGroups(config) {
+		if matchesGroup(logger, repo, group.Group) {
+			add(group.Labels)
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestGetRepoLabels(t *testing.T) {
+	logger := newTestLogger(t)
+	config := loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = "github"
+owner = "acme"
+labels = [" managed ", "gitspace"]
+
+[groups.services]
+match = "startsWith"
+values = ["svc-"]
+labels = ["backend", "managed"]
+
+[groups.billing]
+match = "includes"
+values = ["billing"]
+priority = 10
+labels = ["payments"]
+`, t.TempDir()))
+
+	tests := []struct {
+		repo string
+		want []string
+	}{
+		// Global labels come first, then groups by priority, without duplicates
+		{"svc-billing", []string{"managed", "gitspace", "payments", "backend"}},
+		{"svc-api", []string{"managed", "gitspace", "backend"}},
+		{"web", []string{"managed", "gitspace"}},
+	}
+	for _, tt := range tests {
+		if got := getRepoLabels(logger, config, lib.RepoInfo{Name: tt.repo}); !slices.Equal(got, tt.want) {
+			t.Errorf("getRepoLabels(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestValidateConfigRejectsEmptyLabels(t *testing.T) {
+	config := &Config{Groups: map[string]Group{"svc": {Match: "startsWith", Values: []string{"svc-"}, Labels: []string{"backend", "  "}}}}
+	setValidGlobal(t, config)
+	config.Global.Labels = []string{""}
+
+	err := validateConfig(config)
+	for _, want := range []string{"global.labels: label 0 is empty", "groups.svc.labels: label 1 is empty"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %v, want one containing %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// Values for global.local_symlink_layout
+const (
+	layoutFlat    = "flat"     // Links go where path_template puts them
+	layoutByOwner = "by-owner" // The same, inside a directory per owner
+)
+
+// defaultPathTemplate links every repository directly under global.path
+const defaultPathTemplate = "{{.Name}}"
+
+// pathData is what a path_template can refer to
+type pathData struct {
+	Name  string
+	Type  string
+	Owner string
+	SCM   string
+}
+
+// renderPathTemplate fills in a path_template, which must give a relative
+// path that stays inside global.path
+func renderPathTemplate(pathTemplate string, data pathData) (string, error) {
+	tmpl, err := template.New("path_template").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid path_template: %w", err)
+	}
+	var path bytes.Buffer
+	if err := tmpl.Execute(&path, data); err != nil {
+		return "", fmt.Errorf("invalid path_template: %w", err)
+	}
+
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimSpace(path.String())))
+	if rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path_template must give a relative path inside global.path (got %q)", path.String())
+	}
+	return rel, nil
+}
+
+// localLinkPath returns where repo is linked under global.path, following
+// global.path_template
+func localLinkPath(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) (string, error) {
+	pathTemplate := config.Global.PathTemplate
+	if pathTemplate == "" {
+		pathTemplate = defaultPathTemplate
+	}
+	rel, err := renderPathTemplate(pathTemplate, pathData{
+		Name:  repo.Name,
+		Type:  getRepoType(logger, config, repo),
+		Owner: config.Global.Owner,
+		SCM:   config.Global.SCM,
+	})
+	if err != nil {
+		return "", err
+	}
+	if config.Global.LocalSymlinkLayout == layoutByOwner {
+		rel = filepath.Join(config.Global.Owner, rel)
+	}
+	return filepath.Join(config.Global.Path, rel), nil
+}
+
+// linkLocally links the clone at repoPath under global.path and records the
+// link in result. A symlink already there to another clone is left alone.
+func linkLocally(logger *logger.RateLimitedLogger, config *Config, repoPath string, result *RepoResult) {
+	link, err := localLinkPath(logger, config, result.Info)
+	if err == nil {
+		err = checkLinkCollision(link, repoPath)
+		if err != nil {
+			logger.Warn("Not replacing local symlink", "repo", result.Name, "error", err)
+			return
+		}
+		err = createLink(config, repoPath, link)
+	}
+	if err != nil {
+		logger.Error("Error creating local symlink", "repo", result.Name, "error", err)
+		return
+	}
+	result.LocalSymlink = link
+}
+
+// checkLinkCollision returns an error if link is a symlink or a gitspace
+// copy of a directory other than source that still exists, such as a
+// same-named repository of another owner or config sharing global.path. A
+// link whose target is gone may be repointed. Anything else already at link,
+// such as a directory gitspace didn't create, is an error too.
+func checkLinkCollision(link, source string) error {
+	info, err := os.Lstat(link)
+	if err != nil {
+		return nil
+	}
+
+	var target string
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err = os.Readlink(link)
+		if err != nil {
+			return nil
+		}
+	} else {
+		var ok bool
+		if target, ok = readLinkMarker(link); !ok {
+			return fmt.Errorf("%s exists and was not created by gitspace", link)
+		}
+	}
+
+	if target == source {
+		return nil
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return nil
+	}
+	return fmt.Errorf("%s already links to %s; set local_symlink_layout = \"by-owner\" or a path_template with {{.Owner}} to keep both", link, target)
+}
+
+// indexedRepoInfo returns what index.toml recorded about an owner's
+// repositories, for placing them by path_template without asking the SCM
+func indexedRepoInfo(cacheDir, scm, owner string) map[string]lib.RepoInfo {
+	repos, err := readIndex(cacheDir)
+	if err != nil {
+		return nil
+	}
+	info := make(map[string]lib.RepoInfo)
+	for _, repo := range repos {
+		if repo.SCM == scm && repo.Owner == owner {
+			info[repo.Name] = lib.RepoInfo{Name: repo.Name, Topics: repo.Metadata.Topics}
+		}
+	}
+	return info
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestRenderPathTemplate(t *testing.T) {
+	data := pathData{Name: "api", Type: "service", Owner: "acme", SCM: "github"}
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{defaultPathTemplate, "api", false},
+		{"{{.Type}}/{{.Name}}", filepath.Join("service", "api"), false},
+		{"{{.SCM}}/{{.Owner}}/{{.Name}}/", filepath.Join("github", "acme", "api"), false},
+		{"{{.Owner}}/../{{.Name}}", "api", false},
+		{"../{{.Name}}", "", true},
+		{"/srv/{{.Name}}", "", true},
+		{"{{.Name}}/..", "", true},
+		{"{{.Missing}}", "", true},
+		{"{{.Name", "", true},
+	}
+	for _, tt := range tests {
+		got, err := renderPathTemplate(tt.template, data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("renderPathTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("renderPathTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestLocalLinkPath(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{Groups: map[string]Group{
+		"services": {Match: "startsWith", Values: []string{"svc-"}, Type: "service"},
+	}}
+	config.Global.Path = "/work"
+	config.Global.Owner = "acme"
+	config.Global.SCM = "github"
+	config.Global.PathTemplate = "{{.Type}}/{{.Name}}"
+
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"svc-api", "/work/service/svc-api"},
+		{"docs", "/work/default/docs"},
+	}
+	for _, tt := range tests {
+		got, err := localLinkPath(logger, config, lib.RepoInfo{Name: tt.repo})
+		if err != nil || got != filepath.FromSlash(tt.want) {
+			t.Errorf("localLinkPath(%s) = %q, %v, want %q", tt.repo, got, err, tt.want)
+		}
+	}
+
+	config.Global.PathTemplate = ""
+	if got, _ := localLinkPath(logger, config, lib.RepoInfo{Name: "docs"}); got != filepath.FromSlash("/work/docs") {
+		t.Errorf("without a path_template linked docs at %q", got)
+	}
+}
+
+func TestValidateConfigPathTemplate(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	if err := validateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Global.PathTemplate != defaultPathTemplate {
+		t.Errorf("path_template defaulted to %q", config.Global.PathTemplate)
+	}
+
+	config.Global.PathTemplate = "../{{.Name}}"
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "global.path_template") {
+		t.Errorf("got error %v, want the escaping template rejected", err)
+	}
+}
+
+func TestCloneLinksByPathTemplate(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, `path_template = "{{.Owner}}/{{.Name}}"`, "alpha")
+
+	results, err := cloneRepositories(logger, config, nil)
+	if err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	want := filepath.Join(config.Global.Path, "team", "alpha")
+	if got := results["local/team/alpha"].LocalSymlink; got != want {
+		t.Errorf("linked at %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(want, "README.md")); err != nil {
+		t.Errorf("the link doesn't lead to the clone: %v", err)
+	}
+}
+
+func TestCheckLinkCollision(t *testing.T) {
+	dir := t.TempDir()
+	mine := filepath.Join(dir, "team", "api")
+	theirs := filepath.Join(dir, "other", "api")
+	for _, path := range []string{mine, theirs} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(name, target string) string {
+		path := filepath.Join(dir, name)
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if err := checkLinkCollision(filepath.Join(dir, "missing"), mine); err != nil {
+		t.Errorf("no link yet: %v", err)
+	}
+	if err := checkLinkCollision(link("same", mine), mine); err != nil {
+		t.Errorf("link to the same clone: %v", err)
+	}
+	if err := checkLinkCollision(link("stale", filepath.Join(dir, "gone")), mine); err != nil {
+		t.Errorf("link to a removed clone: %v", err)
+	}
+	if err := checkLinkCollision(link("taken", theirs), mine); err == nil || !strings.Contains(err.Error(), "by-owner") {
+		t.Errorf("got error %v, want the collision reported", err)
+	}
+}
+
+func TestCheckLinkCollisionCopies(t *testing.T) {
+	dir := t.TempDir()
+	mine := filepath.Join(dir, "team", "api")
+	theirs := filepath.Join(dir, "other", "api")
+	for _, path := range []string{mine, theirs} {
+		writeTestTree(t, path)
+	}
+	copied := func(name, target string) string {
+		path := filepath.Join(dir, name)
+		if _, err := ensureLink(linkModeCopy, target, path); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if err := checkLinkCollision(copied("same", mine), mine); err != nil {
+		t.Errorf("copy of the same clone: %v", err)
+	}
+	if err := checkLinkCollision(copied("taken", theirs), mine); err == nil || !strings.Contains(err.Error(), "by-owner") {
+		t.Errorf("got error %v, want the collision with another copy reported", err)
+	}
+
+	unmarked := filepath.Join(dir, "unmarked")
+	writeTestTree(t, unmarked)
+	if err := checkLinkCollision(unmarked, mine); err == nil || !strings.Contains(err.Error(), "not created by gitspace") {
+		t.Errorf("got error %v, want the unmarked directory reported", err)
+	}
+}
+
+func TestValidateConfigLocalSymlinkLayout(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	if err := validateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Global.LocalSymlinkLayout != layoutFlat {
+		t.Errorf("local_symlink_layout defaulted to %q", config.Global.LocalSymlinkLayout)
+	}
+	config.Global.LocalSymlinkLayout = "nested"
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "global.local_symlink_layout") {
+		t.Errorf("got error %v, want the unknown layout rejected", err)
+	}
+}
+
+func TestCloneSameNameAcrossOwners(t *testing.T) {
+	tests := []struct {
+		layout    string
+		wantLinks int
+	}{
+		{layoutFlat, 1},
+		{layoutByOwner, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			logger := newTestLogger(t)
+			installFakeGitClient(t)
+			config := localCloneConfig(t, `local_symlink_layout = "`+tt.layout+`"`, "api")
+			root := config.Global.BaseURL
+			initTestRepo(t, filepath.Join(root, "other", "api"), "main")
+			config.Clone = []CloneTarget{{SCM: "local", Owner: "team", BaseURL: root}, {SCM: "local", Owner: "other", BaseURL: root}}
+
+			results, err := cloneRepositories(logger, config, nil)
+			if err != nil {
+				t.Fatalf("clone failed: %v", err)
+			}
+			links := make(map[string]bool)
+			for _, key := range []string{"local/team/api", "local/other/api"} {
+				result := results[key]
+				if result == nil || result.Error != nil {
+					t.Fatalf("%s: %+v", key, result)
+				}
+				if result.LocalSymlink != "" {
+					links[result.LocalSymlink] = true
+				}
+			}
+			// A flat layout keeps the first link rather than repointing it
+			if len(links) != tt.wantLinks {
+				t.Errorf("got links %v, want %d", links, tt.wantLinks)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// LFS outcomes recorded in RepoResult.LFS
+const (
+	lfsOK     = "ok"
+	lfsFailed = "failed"
+)
+
+// errLFSNotInstalled explains what to do when lfs is set but git-lfs is missing
+var errLFSNotInstalled = errors.New("git-lfs is not installed; install it from https://git-lfs.com or turn off lfs for this repository")
+
+// resolveLFS reports whether to pull LFS files for a repository: the setting
+// of the highest-priority matching group that sets lfs, else global.lfs
+func resolveLFS(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) bool {
+	for _, group := range sortedGroups(config) {
+		if group.LFS != nil && matchesGroup(logger, repo, group.Group) {
+			return *group.LFS
+		}
+	}
+	return config.Global.LFS
+}
+
+// pullLFS runs git lfs pull in the repository after a successful clone or
+// update when lfs applies to it, since go-git leaves LFS pointers in place of
+// the files. Like a post-clone hook, a failure is recorded in result but
+// doesn't fail the repository.
+func pullLFS(logger *logger.RateLimitedLogger, config *Config, repoPath string, result *RepoResult) {
+	if result.Error != nil || !(result.Cloned || result.Updated) || !resolveLFS(logger, config, result.Info) {
+		return
+	}
+
+	err := runLFSPull(repoPath)
+	if err != nil {
+		result.LFS = lfsFailed
+		result.LFSError = err
+		logger.Error("Git LFS pull failed", "repo", result.Name, "error", err)
+		return
+	}
+	result.LFS = lfsOK
+	logger.Info("Pulled Git LFS files", "repo", result.Name)
+}
+
+// runLFSPull runs git lfs pull in repoPath, which needs the git and git-lfs
+// command-line tools
+func runLFSPull(repoPath string) error {
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		return errLFSNotInstalled
+	}
+	cmd := exec.Command("git", "-C", repoPath, "lfs", "pull")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git lfs pull: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
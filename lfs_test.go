@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestResolveLFS(t *testing.T) {
+	logger := newTestLogger(t)
+	on, off := true, false
+	config := &Config{Groups: map[string]Group{
+		"media":   {Match: "startsWith", Values: []string{"media-"}, LFS: &on, Priority: 1},
+		"archive": {Match: "endsWith", Values: []string{"-archive"}, LFS: &off, Priority: 2},
+		"all":     {Match: "regex", Values: []string{".*"}},
+	}}
+	tests := []struct {
+		repo   string
+		global bool
+		want   bool
+	}{
+		{"api", false, false},
+		{"api", true, true},
+		{"media-assets", false, true},
+		{"docs-archive", true, false},
+		// archive has the higher priority
+		{"media-archive", false, false},
+	}
+	for _, tt := range tests {
+		config.Global.LFS = tt.global
+		if got := resolveLFS(logger, config, lib.RepoInfo{Name: tt.repo}); got != tt.want {
+			t.Errorf("resolveLFS(%s) with global.lfs %v = %v, want %v", tt.repo, tt.global, got, tt.want)
+		}
+	}
+}
+
+// stubLFSTools puts scripts named git and git-lfs first on PATH. The git one
+// records its arguments in the returned file.
+func stubLFSTools(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	scripts := map[string]string{
+		"git":     "#!/bin/sh\necho \"$@\" > " + argsFile + "\n",
+		"git-lfs": "#!/bin/sh\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return argsFile
+}
+
+func TestPullLFS(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{}
+	config.Global.LFS = true
+
+	t.Run("pulls after a clone", func(t *testing.T) {
+		argsFile := stubLFSTools(t)
+		result := &RepoResult{Name: "media", Cloned: true}
+		pullLFS(logger, config, "/work/media", result)
+		if result.LFS != lfsOK || result.LFSError != nil {
+			t.Errorf("LFS = %q, %v, want ok", result.LFS, result.LFSError)
+		}
+		args, err := os.ReadFile(argsFile)
+		if err != nil || strings.TrimSpace(string(args)) != "-C /work/media lfs pull" {
+			t.Errorf("ran git %q, %v", args, err)
+		}
+	})
+
+	t.Run("skips unchanged repositories", func(t *testing.T) {
+		argsFile := stubLFSTools(t)
+		result := &RepoResult{Name: "media", UpToDate: true}
+		pullLFS(logger, config, "/work/media", result)
+		if result.LFS != "" {
+			t.Errorf("LFS = %q for an unchanged repository", result.LFS)
+		}
+		if _, err := os.Stat(argsFile); !os.IsNotExist(err) {
+			t.Error("git lfs pull ran for an unchanged repository")
+		}
+	})
+
+	t.Run("git-lfs missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		result := &RepoResult{Name: "media", Updated: true}
+		pullLFS(logger, config, "/work/media", result)
+		if result.LFS != lfsFailed || !errors.Is(result.LFSError, errLFSNotInstalled) {
+			t.Errorf("LFS = %q, %v, want the missing tool reported", result.LFS, result.LFSError)
+		}
+		if result.Error != nil {
+			t.Errorf("a failed LFS pull failed the repository: %v", result.Error)
+		}
+	})
+}
+
+func TestSummarizeLFSResults(t *testing.T) {
+	summary := summarizeResults(map[string]*RepoResult{
+		"a": {Name: "a", LFS: lfsOK},
+		"b": {Name: "b", LFS: lfsOK},
+		"c": {Name: "c", LFS: lfsFailed, LFSError: errLFSNotInstalled},
+		"d": {Name: "d"},
+	})
+	if summary.LFSPulled != 2 || summary.LFSFailed != 1 {
+		t.Errorf("summary counted %d pulled and %d failed, want 2 and 1", summary.LFSPulled, summary.LFSFailed)
+	}
+}
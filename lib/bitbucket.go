@@ -0,0 +1,285 @@
+// lib/bitbucket.go
+
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const defaultBitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// BitbucketProvider talks to the Bitbucket Cloud REST API (2.0) directly.
+// Owners are workspaces.
+type BitbucketProvider struct {
+	client     *http.Client
+	apiURL     string
+	user       string
+	token      string
+	visibility Visibility
+}
+
+type bitbucketRepository struct {
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	IsPrivate   bool            `json:"is_private"`
+	Parent      json.RawMessage `json:"parent"`
+	MainBranch  *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+type bitbucketTag struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Target  struct {
+		Date time.Time `json:"date"`
+	} `json:"target"`
+}
+
+type bitbucketSrcEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"` // commit_file or commit_directory
+}
+
+// bitbucketPage is the envelope of every paginated Bitbucket response
+type bitbucketPage[T any] struct {
+	Values []T    `json:"values"`
+	Next   string `json:"next"`
+}
+
+// NewBitbucketProvider authenticates with BITBUCKET_TOKEN: an app password
+// when BITBUCKET_USER is also set, otherwise an access token
+func NewBitbucketProvider(opts ProviderOptions) (*BitbucketProvider, error) {
+	token := os.Getenv("BITBUCKET_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("BITBUCKET_TOKEN environment variable not set")
+	}
+
+	apiURL := defaultBitbucketAPIURL
+	if opts.BaseURL != "" {
+		apiURL = strings.TrimSuffix(opts.BaseURL, "/")
+	}
+
+	return &BitbucketProvider{
+		client:     http.DefaultClient,
+		apiURL:     apiURL,
+		user:       os.Getenv("BITBUCKET_USER"),
+		token:      token,
+		visibility: opts.Visibility,
+	}, nil
+}
+
+// GetLatestRelease returns the most recent tag, since Bitbucket has no releases
+func (b *BitbucketProvider) GetLatestRelease(ctx context.Context, owner, repo string) (*Release, error) {
+	var tags bitbucketPage[bitbucketTag]
+	query := url.Values{"sort": {"-target.date"}, "pagelen": {"1"}}
+	if err := b.getJSON(ctx, b.endpoint(repoPath(owner, repo)+"/refs/tags", query), &tags); err != nil {
+		return nil, err
+	}
+
+	if len(tags.Values) == 0 {
+		return nil, fmt.Errorf("no releases found")
+	}
+
+	return &Release{
+		TagName:     tags.Values[0].Name,
+		PublishedAt: tags.Values[0].Target.Date,
+		Body:        tags.Values[0].Message,
+	}, nil
+}
+
+func (b *BitbucketProvider) FetchRepositories(ctx context.Context, owner string) ([]string, error) {
+	repos, err := b.FetchRepositoriesDetailed(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return RepoNames(repos), nil
+}
+
+// FetchRepositoriesDetailed lists the workspace's repositories with metadata.
+// Bitbucket has neither topics nor archiving, so Topics is left nil and no
+// repository is archived.
+func (b *BitbucketProvider) FetchRepositoriesDetailed(ctx context.Context, owner string) ([]RepoInfo, error) {
+	var allRepos []RepoInfo
+	next := b.endpoint("/repositories/"+url.PathEscape(owner), url.Values{"pagelen": {"100"}})
+
+	for next != "" {
+		var page bitbucketPage[bitbucketRepository]
+		if err := b.getJSON(ctx, next, &page); err != nil {
+			return nil, fmt.Errorf("error fetching repositories: %w", err)
+		}
+
+		for _, repo := range page.Values {
+			if !b.visibility.Allows(repo.IsPrivate) {
+				continue
+			}
+			info := RepoInfo{
+				Name:        repo.Slug,
+				Description: repo.Description,
+				Fork:        len(repo.Parent) > 0 && string(repo.Parent) != "null",
+				UpdatedAt:   repo.UpdatedOn,
+			}
+			if repo.MainBranch != nil {
+				info.DefaultBranch = repo.MainBranch.Name
+			}
+			allRepos = append(allRepos, info)
+		}
+		next = page.Next
+	}
+
+	return allRepos, nil
+}
+
+func (b *BitbucketProvider) FetchCatalog(ctx context.Context, owner, repo string) (*Catalog, error) {
+	branch, err := b.defaultBranch(ctx, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+
+	fileContent, err := b.getRawFile(ctx, owner, repo, branch, "gitspace-catalog.toml")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+
+	var catalog Catalog
+	err = toml.Unmarshal(fileContent, &catalog)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding TOML: %v", err)
+	}
+
+	return &catalog, nil
+}
+
+func (b *BitbucketProvider) DownloadDirectory(ctx context.Context, owner, repo, path, destDir string) error {
+	branch, err := b.defaultBranch(ctx, owner, repo)
+	if err != nil {
+		return fmt.Errorf("error fetching repository tree: %w", err)
+	}
+	return b.downloadDirectory(ctx, owner, repo, branch, path, path, destDir)
+}
+
+// downloadDirectory writes the files under dir to destDir, keeping their
+// paths relative to root
+func (b *BitbucketProvider) downloadDirectory(ctx context.Context, owner, repo, branch, root, dir, destDir string) error {
+	next := b.endpoint(srcPath(owner, repo, branch, dir)+"/", url.Values{"pagelen": {"100"}})
+
+	for next != "" {
+		var page bitbucketPage[bitbucketSrcEntry]
+		if err := b.getJSON(ctx, next, &page); err != nil {
+			return fmt.Errorf("error fetching repository tree: %w", err)
+		}
+
+		for _, entry := range page.Values {
+			if entry.Type == "commit_directory" {
+				if err := b.downloadDirectory(ctx, owner, repo, branch, root, entry.Path, destDir); err != nil {
+					return err
+				}
+				continue
+			}
+
+			fileContent, err := b.getRawFile(ctx, owner, repo, branch, entry.Path)
+			if err != nil {
+				return fmt.Errorf("error fetching file content: %w", err)
+			}
+
+			filePath := filepath.Join(destDir, strings.TrimPrefix(entry.Path, root))
+			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+				return fmt.Errorf("error creating directories: %v", err)
+			}
+
+			if err := os.WriteFile(filePath, fileContent, 0644); err != nil {
+				return fmt.Errorf("error writing file: %v", err)
+			}
+		}
+		next = page.Next
+	}
+
+	return nil
+}
+
+func (b *BitbucketProvider) defaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var repository bitbucketRepository
+	if err := b.getJSON(ctx, b.endpoint(repoPath(owner, repo), nil), &repository); err != nil {
+		return "", err
+	}
+	if repository.MainBranch == nil || repository.MainBranch.Name == "" {
+		return "", fmt.Errorf("repository %s/%s has no main branch", owner, repo)
+	}
+	return repository.MainBranch.Name, nil
+}
+
+func (b *BitbucketProvider) getRawFile(ctx context.Context, owner, repo, ref, path string) ([]byte, error) {
+	resp, err := b.do(ctx, b.endpoint(srcPath(owner, repo, ref, path), nil))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// endpoint returns the full URL of an API path. Pagination hands out full
+// URLs in next, which getJSON takes as they are.
+func (b *BitbucketProvider) endpoint(path string, query url.Values) string {
+	reqURL := b.apiURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	return reqURL
+}
+
+func (b *BitbucketProvider) getJSON(ctx context.Context, reqURL string, out interface{}) error {
+	resp, err := b.do(ctx, reqURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response from %s: %v", reqURL, err)
+	}
+	return nil
+}
+
+func (b *BitbucketProvider) do(ctx context.Context, reqURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.user != "" {
+		req.SetBasicAuth(b.user, b.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected status %d", strings.TrimPrefix(reqURL, b.apiURL), resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// repoPath returns the API path for a repository in a workspace
+func repoPath(owner, repo string) string {
+	return "/repositories/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// srcPath returns the API path for a file or directory at ref
+func srcPath(owner, repo, ref, path string) string {
+	return repoPath(owner, repo) + "/src/" + url.PathEscape(ref) + "/" + strings.TrimPrefix(path, "/")
+}
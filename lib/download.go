@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeVerifiedFile writes a downloaded file to path through a temporary file
+// in the same directory, which is renamed into place only once everything was
+// written. wantSize, unless negative, is the size the server reported, and
+// wantSHA, unless empty, the file's git blob hash. A failed check or write
+// leaves nothing behind.
+func writeVerifiedFile(path string, data []byte, wantSize int64, wantSHA string) error {
+	if wantSize >= 0 && int64(len(data)) != wantSize {
+		return fmt.Errorf("incomplete download of %s: expected %d bytes, got %d", filepath.Base(path), wantSize, len(data))
+	}
+	if wantSHA != "" {
+		if sha := gitBlobSHA(data); sha != wantSHA {
+			return fmt.Errorf("corrupt download of %s: expected blob %s, got %s", filepath.Base(path), wantSHA, sha)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := tmp.Write(data)
+	if err == nil && n != len(data) {
+		err = fmt.Errorf("wrote %d of %d bytes", n, len(data))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// gitBlobSHA returns the git object hash of a blob with the given content, as
+// reported by the GitHub contents API
+func gitBlobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
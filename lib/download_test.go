@@ -0,0 +1,105 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitBlobSHA(t *testing.T) {
+	// Hashes from git hash-object
+	tests := map[string]string{
+		"":        "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"hello\n": "ce013625030ba8dba906f756967f9e9ca394464a",
+	}
+	for content, want := range tests {
+		if got := gitBlobSHA([]byte(content)); got != want {
+			t.Errorf("gitBlobSHA(%q) = %s, want %s", content, got, want)
+		}
+	}
+}
+
+func TestWriteVerifiedFile(t *testing.T) {
+	data := []byte("hello\n")
+	tests := []struct {
+		name     string
+		wantSize int64
+		wantSHA  string
+		wantErr  string
+	}{
+		{"verified", 6, gitBlobSHA(data), ""},
+		{"unchecked", -1, "", ""},
+		{"truncated", 12, "", "expected 12 bytes, got 6"},
+		{"corrupt", 6, gitBlobSHA([]byte("other\n")), "corrupt download"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.go")
+			err := writeVerifiedFile(path, data, tt.wantSize, tt.wantSHA)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := os.ReadFile(path); err != nil || string(got) != string(data) {
+					t.Errorf("wrote %q, %v", got, err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+			// Only the verified file is left, never a temporary one
+			wantFiles := 0
+			if tt.wantErr == "" {
+				wantFiles = 1
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != wantFiles {
+				t.Errorf("directory holds %d files, want %d", len(entries), wantFiles)
+			}
+		})
+	}
+}
+
+func TestWriteVerifiedFileKeepsOldFileOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeVerifiedFile(path, []byte("ne"), 4, ""); err == nil {
+		t.Fatal("a truncated download was written")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old\n" {
+		t.Errorf("a failed download replaced the file with %q", got)
+	}
+}
+
+func TestGitHubDownloadRejectsTruncatedFile(t *testing.T) {
+	const source = "package main\n"
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/acme/catalog/contents/plugins/hello":
+			fmt.Fprint(w, `[{"type": "file", "name": "main.go", "path": "plugins/hello/main.go"}]`)
+		case "/repos/acme/catalog/contents/plugins/hello/main.go":
+			// The content was cut short of the size GitHub reports
+			fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q, "size": %d, "sha": %q}`,
+				base64.StdEncoding.EncodeToString([]byte(source[:5])), len(source), gitBlobSHA([]byte(source)))
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	})
+	provider := newTestGitHubProvider(t, ProviderOptions{})
+
+	dest := t.TempDir()
+	err := provider.DownloadDirectory(context.Background(), "acme", "catalog", "plugins/hello", dest)
+	if err == nil || !strings.Contains(err.Error(), "incomplete download") {
+		t.Fatalf("got error %v, want the incomplete download reported", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Errorf("a partial download was left at the destination: %v", entries)
+	}
+}
@@ -0,0 +1,168 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// etagTransport caches GET responses in dir and revalidates them with
+// If-None-Match and If-Modified-Since, serving the cached body on a 304.
+// Responses can hold private repository data, so dir and its files are only
+// readable by the user.
+type etagTransport struct {
+	dir  string
+	base http.RoundTripper
+}
+
+// cachedResponse is the on-disk form of a cached response
+type cachedResponse struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+func newETagTransport(dir string, base http.RoundTripper) *etagTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &etagTransport{dir: dir, base: base}
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.cachePath(req)
+	cached := t.read(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		return cached.response(req, resp.Header), nil
+	case resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""):
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		// A response that can't be cached is still returned
+		t.write(path, &cachedResponse{
+			URL:          req.URL.String(),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Header:       resp.Header,
+			Body:         body,
+		})
+	}
+	return resp, nil
+}
+
+// cachePath is the cache file of a request, named by a hash of its URL
+func (t *etagTransport) cachePath(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// read returns the cached response at path, or nil if there is none
+func (t *etagTransport) read(path string) *cachedResponse {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// write stores cached at path through a uniquely named temporary file, so
+// concurrent writers and readers never see a partial file
+func (t *etagTransport) write(path string, cached *cachedResponse) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return
+	}
+	if err := os.Chmod(t.dir, 0700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(t.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
+
+// response rebuilds the cached response as a 200 for req. The rate limit
+// headers come from fresh, the headers of the 304 that revalidated it, since
+// the cached ones describe the quota when the response was first fetched.
+func (c *cachedResponse) response(req *http.Request, fresh http.Header) *http.Response {
+	header := c.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	for key := range header {
+		if isRateLimitHeader(key) {
+			header.Del(key)
+		}
+	}
+	for key, values := range fresh {
+		if isRateLimitHeader(key) {
+			header[key] = values
+		}
+	}
+	header.Set("Content-Length", strconv.Itoa(len(c.Body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// isRateLimitHeader reports whether key is one of the X-RateLimit-* headers
+func isRateLimitHeader(key string) bool {
+	return strings.HasPrefix(http.CanonicalHeaderKey(key), "X-Ratelimit-")
+}
@@ -0,0 +1,182 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// etagServer answers with body and ETag "v1", or a 304 when the request
+// revalidates "v1", counting down remaining in X-RateLimit-Remaining
+type etagServer struct {
+	body        string
+	remaining   int
+	revalidated int
+}
+
+func (s *etagServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	s.remaining--
+	recorder.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+	recorder.Header().Set("ETag", `"v1"`)
+	if req.Header.Get("If-None-Match") == `"v1"` {
+		s.revalidated++
+		recorder.WriteHeader(http.StatusNotModified)
+	} else {
+		io.WriteString(recorder, s.body)
+	}
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// getThrough makes a GET request for url through transport and reads the body
+func getThrough(t *testing.T, transport http.RoundTripper, url string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestETagTransportReplaysCachedResponse(t *testing.T) {
+	server := &etagServer{body: `[{"name": "widget"}]`, remaining: 50}
+	transport := newETagTransport(filepath.Join(t.TempDir(), "cache"), server)
+
+	first, body := getThrough(t, transport, "https://api.github.com/orgs/acme/repos")
+	if first.StatusCode != http.StatusOK || body != server.body {
+		t.Fatalf("first request returned %d %q", first.StatusCode, body)
+	}
+
+	second, body := getThrough(t, transport, "https://api.github.com/orgs/acme/repos")
+	if server.revalidated != 1 {
+		t.Fatalf("the second request revalidated %d times, want once", server.revalidated)
+	}
+	if second.StatusCode != http.StatusOK || body != server.body {
+		t.Errorf("revalidated request returned %d %q, want the cached body", second.StatusCode, body)
+	}
+	// The quota comes from the 304, not from the cached 200
+	if got := second.Header.Get("X-RateLimit-Remaining"); got != "48" {
+		t.Errorf("X-RateLimit-Remaining %q, want the 304's 48", got)
+	}
+	if got := second.Header.Get("ETag"); got != `"v1"` {
+		t.Errorf("ETag %q, want the cached one", got)
+	}
+}
+
+func TestETagTransportCachePermissions(t *testing.T) {
+	server := &etagServer{body: "private", remaining: 50}
+	dir := filepath.Join(t.TempDir(), "cache")
+	// A directory left world-readable by an older version is tightened
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	transport := newETagTransport(dir, server)
+	getThrough(t, transport, "https://api.github.com/repos/acme/secret")
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("cache directory mode %o, want 700", perm)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("cache holds %d files, want one and no temporary files", len(entries))
+	}
+	info, err = entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("cache file mode %o, want 600", perm)
+	}
+}
+
+func TestETagTransportSkipsOtherMethods(t *testing.T) {
+	server := &etagServer{body: "created", remaining: 50}
+	dir := filepath.Join(t.TempDir(), "cache")
+	transport := newETagTransport(dir, server)
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/orgs/acme/repos", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("a POST response was cached: %v", err)
+	}
+}
+
+func TestGitHubProviderRevalidatesCachedCatalog(t *testing.T) {
+	const catalog = "[plugins.hello]\npath = \"plugins/hello\"\n"
+	var requests, revalidated int
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Last-Modified", "Mon, 12 Oct 2026 10:00:00 GMT")
+		if r.Header.Get("If-Modified-Since") != "" {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, base64.StdEncoding.EncodeToString([]byte(catalog)))
+	})
+	provider := newTestGitHubProvider(t, ProviderOptions{CacheDir: filepath.Join(t.TempDir(), "catalog")})
+
+	for i := 0; i < 2; i++ {
+		got, err := provider.FetchCatalog(context.Background(), "ssotops", "gitspace-catalog")
+		if err != nil {
+			t.Fatalf("fetch %d: %v", i+1, err)
+		}
+		if got.Plugins["hello"].Path != "plugins/hello" {
+			t.Errorf("fetch %d returned %+v", i+1, got.Plugins)
+		}
+	}
+	if requests != 2 || revalidated != 1 {
+		t.Errorf("made %d requests, %d revalidated, want the second revalidated by Last-Modified", requests, revalidated)
+	}
+}
+
+func TestETagTransportIgnoresCorruptCache(t *testing.T) {
+	server := &etagServer{body: "fresh", remaining: 50}
+	dir := t.TempDir()
+	transport := newETagTransport(dir, server)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/acme/api", nil)
+	if err := os.WriteFile(transport.cachePath(req), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := getThrough(t, transport, req.URL.String())
+	if resp.StatusCode != http.StatusOK || body != "fresh" || server.revalidated != 0 {
+		t.Errorf("returned %d %q after %d revalidations, want a fresh fetch", resp.StatusCode, body, server.revalidated)
+	}
+	if cached := transport.read(transport.cachePath(req)); cached == nil || string(cached.Body) != "fresh" {
+		t.Errorf("the corrupt entry wasn't replaced: %+v", cached)
+	}
+}
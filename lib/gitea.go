@@ -14,21 +14,27 @@ import (
 )
 
 type GiteaProvider struct {
-	client *gitea.Client
+	client     *gitea.Client
+	visibility Visibility
 }
 
-func NewGiteaProvider(baseURL string) (*GiteaProvider, error) {
+func NewGiteaProvider(opts ProviderOptions) (*GiteaProvider, error) {
+	// Gitea has no default instance, so the base URL is required
+	if err := ValidateBaseURL(opts.BaseURL); err != nil {
+		return nil, fmt.Errorf("gitea needs the base URL of your instance, such as https://gitea.example.com: %w", err)
+	}
+
 	token := os.Getenv("GITEA_TOKEN")
 	if token == "" {
 		return nil, fmt.Errorf("GITEA_TOKEN environment variable not set")
 	}
 
-	client, err := gitea.NewClient(baseURL, gitea.SetToken(token))
+	client, err := gitea.NewClient(opts.BaseURL, gitea.SetToken(token))
 	if err != nil {
 		return nil, fmt.Errorf("error creating Gitea client: %v", err)
 	}
 
-	return &GiteaProvider{client: client}, nil
+	return &GiteaProvider{client: client, visibility: opts.Visibility}, nil
 }
 
 func (g *GiteaProvider) GetLatestRelease(ctx context.Context, owner, repo string) (*Release, error) {
@@ -50,7 +56,19 @@ func (g *GiteaProvider) GetLatestRelease(ctx context.Context, owner, repo string
 }
 
 func (g *GiteaProvider) FetchRepositories(ctx context.Context, owner string) ([]string, error) {
-	var allRepos []string
+	repos, err := g.FetchRepositoriesDetailed(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return RepoNames(repos), nil
+}
+
+// FetchRepositoriesDetailed lists the owner's repositories with metadata.
+// Gitea doesn't include topics in repository listings, so Topics is left empty.
+func (g *GiteaProvider) FetchRepositoriesDetailed(ctx context.Context, owner string) ([]RepoInfo, error) {
+	// The SDK takes the context on the client rather than per call
+	g.client.SetContext(ctx)
+	var allRepos []RepoInfo
 	page := 1
 	perPage := 50
 
@@ -77,11 +95,21 @@ func (g *GiteaProvider) FetchRepositories(ctx context.Context, owner string) ([]
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf("error fetching repositories: %v", err)
+			return nil, fmt.Errorf("error fetching repositories: %w", err)
 		}
 
 		for _, repo := range repos {
-			allRepos = append(allRepos, repo.Name)
+			if g.visibility.Allows(repo.Private) {
+				allRepos = append(allRepos, RepoInfo{
+					Name:          repo.Name,
+					Description:   repo.Description,
+					Archived:      repo.Archived,
+					Fork:          repo.Fork,
+					DefaultBranch: repo.DefaultBranch,
+					UpdatedAt:     repo.Updated,
+					PushedAt:      repo.Updated, // Gitea bumps updated_at on every push
+				})
+			}
 		}
 
 		if len(repos) < perPage {
@@ -93,10 +121,28 @@ func (g *GiteaProvider) FetchRepositories(ctx context.Context, owner string) ([]
 	return allRepos, nil
 }
 
+// defaultBranch returns the branch a repository's files are read from: its
+// default branch, which each Gitea repository chooses for itself
+func (g *GiteaProvider) defaultBranch(owner, repo string) (string, error) {
+	repository, _, err := g.client.GetRepo(owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("error fetching repository %s/%s: %w", owner, repo, err)
+	}
+	if repository.DefaultBranch == "" {
+		return "", fmt.Errorf("repository %s/%s has no default branch", owner, repo)
+	}
+	return repository.DefaultBranch, nil
+}
+
 func (g *GiteaProvider) FetchCatalog(ctx context.Context, owner, repo string) (*Catalog, error) {
-	fileContent, _, err := g.client.GetFile(owner, repo, "master", "gitspace-catalog.toml")
+	g.client.SetContext(ctx)
+	branch, err := g.defaultBranch(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	fileContent, _, err := g.client.GetFile(owner, repo, branch, "gitspace-catalog.toml")
 	if err != nil {
-		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %v", err)
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
 	}
 
 	var catalog Catalog
@@ -109,9 +155,14 @@ func (g *GiteaProvider) FetchCatalog(ctx context.Context, owner, repo string) (*
 }
 
 func (g *GiteaProvider) DownloadDirectory(ctx context.Context, owner, repo, path, destDir string) error {
-	tree, _, err := g.client.GetTrees(owner, repo, "master", true)
+	g.client.SetContext(ctx)
+	branch, err := g.defaultBranch(owner, repo)
+	if err != nil {
+		return err
+	}
+	tree, _, err := g.client.GetTrees(owner, repo, branch, true)
 	if err != nil {
-		return fmt.Errorf("error fetching repository tree: %v", err)
+		return fmt.Errorf("error fetching repository tree: %w", err)
 	}
 
 	for _, entry := range tree.Entries {
@@ -123,9 +174,9 @@ func (g *GiteaProvider) DownloadDirectory(ctx context.Context, owner, repo, path
 			continue
 		}
 
-		fileContent, _, err := g.client.GetFile(owner, repo, "master", entry.Path)
+		fileContent, _, err := g.client.GetFile(owner, repo, branch, entry.Path)
 		if err != nil {
-			return fmt.Errorf("error fetching file content: %v", err)
+			return fmt.Errorf("error fetching file content: %w", err)
 		}
 
 		filePath := filepath.Join(destDir, strings.TrimPrefix(entry.Path, path))
@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewGiteaProviderRequiresBaseURL(t *testing.T) {
+	t.Setenv("GITEA_TOKEN", "test-token")
+	for _, baseURL := range []string{"", "gitea.example.com"} {
+		if _, err := NewGiteaProvider(ProviderOptions{BaseURL: baseURL}); err == nil || !strings.Contains(err.Error(), "base URL of your instance") {
+			t.Errorf("base URL %q got error %v", baseURL, err)
+		}
+	}
+}
+
+func TestGiteaFetchRepositoriesUsesBaseURL(t *testing.T) {
+	t.Setenv("GITEA_TOKEN", "test-token")
+	var hosts []string
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/version":
+			fmt.Fprint(w, `{"version": "1.21.0"}`)
+		case "/api/v1/users/acme/repos":
+			fmt.Fprint(w, `[{"name": "widget", "private": false}, {"name": "secret", "private": true}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	provider, err := NewGiteaProvider(ProviderOptions{BaseURL: "https://gitea.example.com", Visibility: VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := provider.FetchRepositories(context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"widget"}) {
+		t.Errorf("got %v, want the public repository", names)
+	}
+	for _, host := range hosts {
+		if host != "gitea.example.com" {
+			t.Errorf("request went to %s", host)
+		}
+	}
+}
+
+// giteaBranchStub serves acme/catalog with trunk as its default branch and
+// fails any request for a file or tree on another ref
+func giteaBranchStub(t *testing.T) *GiteaProvider {
+	t.Helper()
+	t.Setenv("GITEA_TOKEN", "test-token")
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/version":
+			fmt.Fprint(w, `{"version": "1.21.0"}`)
+		case r.URL.Path == "/api/v1/repos/acme/catalog":
+			fmt.Fprint(w, `{"name": "catalog", "default_branch": "trunk"}`)
+		case r.URL.Path == "/api/v1/repos/acme/catalog/git/trees/trunk":
+			fmt.Fprint(w, `{"sha": "abc", "tree": [
+				{"path": "plugins", "type": "tree"},
+				{"path": "plugins/hello/main.go", "type": "blob"},
+				{"path": "README.md", "type": "blob"}
+			]}`)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/repos/acme/catalog/raw/"):
+			if ref := r.URL.Query().Get("ref"); ref != "trunk" {
+				t.Errorf("read %s from ref %q, want trunk", r.URL.Path, ref)
+				http.NotFound(w, r)
+				return
+			}
+			switch strings.TrimPrefix(r.URL.Path, "/api/v1/repos/acme/catalog/raw/") {
+			case "gitspace-catalog.toml":
+				fmt.Fprint(w, "[catalog]\nname = \"acme\"\n")
+			case "plugins/hello/main.go":
+				fmt.Fprint(w, "package main\n")
+			default:
+				http.NotFound(w, r)
+			}
+		default:
+			t.Errorf("unexpected request for %s", r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	provider, err := NewGiteaProvider(ProviderOptions{BaseURL: "https://gitea.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestGiteaFetchCatalogUsesDefaultBranch(t *testing.T) {
+	provider := giteaBranchStub(t)
+	catalog, err := provider.FetchCatalog(context.Background(), "acme", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catalog.Catalog.Name != "acme" {
+		t.Errorf("got catalog %q, want acme", catalog.Catalog.Name)
+	}
+}
+
+func TestGiteaDownloadDirectoryUsesDefaultBranch(t *testing.T) {
+	provider := giteaBranchStub(t)
+	dest := t.TempDir()
+	if err := provider.DownloadDirectory(context.Background(), "acme", "catalog", "plugins/hello/", dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "main.go"))
+	if err != nil {
+		t.Fatalf("directory wasn't downloaded: %v", err)
+	}
+	if string(data) != "package main\n" {
+		t.Errorf("got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "README.md")); !os.IsNotExist(err) {
+		t.Error("a file outside the directory was downloaded")
+	}
+}
@@ -2,30 +2,55 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v39/github"
 	"github.com/pelletier/go-toml/v2"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	"golang.org/x/oauth2"
 )
 
+// rateLimitLowWater is the remaining-request count below which listing
+// pauses until the quota resets instead of running into the limit
+const rateLimitLowWater = 10
+
 type GitHubProvider struct {
-	client *github.Client
+	client        *github.Client
+	ownerType     OwnerType
+	visibility    Visibility
+	rateLimitWait time.Duration
+	logger        *logger.RateLimitedLogger
 }
 
-func NewGitHubProvider() (*GitHubProvider, error) {
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable not set")
+// NewGitHubProvider creates a provider authenticated with opts.Token. Callers
+// are responsible for resolving the token from config or the environment.
+func NewGitHubProvider(opts ProviderOptions) (*GitHubProvider, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("no GitHub token provided")
 	}
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
+	ctx := context.Background()
+	if opts.CacheDir != "" {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: newETagTransport(opts.CacheDir, nil)})
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.Token})
+	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
 
-	return &GitHubProvider{client: client}, nil
+	return &GitHubProvider{
+		client:        client,
+		ownerType:     opts.OwnerType,
+		visibility:    opts.Visibility,
+		rateLimitWait: opts.RateLimitWait,
+		logger:        opts.Logger,
+	}, nil
 }
 
 func (g *GitHubProvider) GetLatestRelease(ctx context.Context, owner, repo string) (*Release, error) {
@@ -42,17 +67,60 @@ func (g *GitHubProvider) GetLatestRelease(ctx context.Context, owner, repo strin
 }
 
 func (g *GitHubProvider) FetchRepositories(ctx context.Context, owner string) ([]string, error) {
-	var allRepos []string
+	repos, err := g.FetchRepositoriesDetailed(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return RepoNames(repos), nil
+}
+
+func (g *GitHubProvider) FetchRepositoriesDetailed(ctx context.Context, owner string) ([]RepoInfo, error) {
+	var repos []RepoInfo
+	var err error
+
+	switch g.ownerType {
+	case OwnerTypeUser:
+		repos, err = g.fetchUserRepositories(ctx, owner)
+	case OwnerTypeOrg:
+		repos, err = g.fetchOrgRepositories(ctx, owner)
+	default:
+		// Probe the org endpoint first and fall back to the user endpoint on a 404
+		repos, err = g.fetchOrgRepositories(ctx, owner)
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+			repos, err = g.fetchUserRepositories(ctx, owner)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error fetching repositories: %w", err)
+	}
+	return repos, nil
+}
+
+func (g *GitHubProvider) fetchOrgRepositories(ctx context.Context, owner string) ([]RepoInfo, error) {
+	var allRepos []RepoInfo
 	opts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	if g.visibility == VisibilityPublic || g.visibility == VisibilityPrivate {
+		opts.Type = string(g.visibility)
+	}
 
 	for {
-		repos, resp, err := g.client.Repositories.ListByOrg(ctx, owner, opts)
+		var repos []*github.Repository
+		resp, err := g.withRateLimit(ctx, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			repos, resp, err = g.client.Repositories.ListByOrg(ctx, owner, opts)
+			return resp, err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("error fetching repositories: %v", err)
+			return nil, err
 		}
 
 		for _, repo := range repos {
-			allRepos = append(allRepos, repo.GetName())
+			if g.visibility.Allows(repo.GetPrivate()) {
+				allRepos = append(allRepos, gitHubRepoInfo(repo))
+			}
 		}
 
 		if resp.NextPage == 0 {
@@ -64,10 +132,201 @@ func (g *GitHubProvider) FetchRepositories(ctx context.Context, owner string) ([
 	return allRepos, nil
 }
 
+func (g *GitHubProvider) fetchUserRepositories(ctx context.Context, owner string) ([]RepoInfo, error) {
+	// Listing by name only returns public repos, so list the authenticated
+	// user's own repos (including private ones) when the owner is that user
+	user := owner
+	opts := &github.RepositoryListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	if me, _, err := g.client.Users.Get(ctx, ""); err == nil && strings.EqualFold(me.GetLogin(), owner) {
+		user = ""
+		opts.Affiliation = "owner"
+		if g.visibility == VisibilityPublic || g.visibility == VisibilityPrivate {
+			opts.Visibility = string(g.visibility)
+		}
+	}
+
+	var allRepos []RepoInfo
+	for {
+		var repos []*github.Repository
+		resp, err := g.withRateLimit(ctx, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			repos, resp, err = g.client.Repositories.List(ctx, user, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range repos {
+			if g.visibility.Allows(repo.GetPrivate()) {
+				allRepos = append(allRepos, gitHubRepoInfo(repo))
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allRepos, nil
+}
+
+// withRateLimit runs one API call, waiting out the rate limit when the call
+// is rejected by it or when the remaining quota runs low. Waits longer than
+// rateLimitWait are not attempted; the rate limit error is returned instead,
+// as it is once the call has been rejected rateLimitMaxAttempts times.
+func (g *GitHubProvider) withRateLimit(ctx context.Context, call func() (*github.Response, error)) (*github.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := call()
+
+		if wait, limited := rateLimitDelay(err, attempt); limited {
+			if attempt >= rateLimitMaxAttempts {
+				return resp, fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+			if waitErr := g.wait(ctx, wait); waitErr != nil {
+				return resp, fmt.Errorf("%w (%v)", err, waitErr)
+			}
+			continue
+		}
+		if err != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			g.debug("GitHub API quota", "remaining", resp.Rate.Remaining, "limit", resp.Rate.Limit, "reset", resp.Rate.Reset.Time.Format(time.RFC3339))
+			if wait := time.Until(resp.Rate.Reset.Time); resp.Rate.Limit > 0 && resp.Rate.Remaining < rateLimitLowWater && wait > 0 {
+				// Not fatal: the next call will hit the limit and wait or fail there
+				if waitErr := g.wait(ctx, wait); waitErr != nil && ctx.Err() != nil {
+					return resp, waitErr
+				}
+			}
+		}
+		return resp, nil
+	}
+}
+
+// Retries of calls rejected by a rate limit
+const (
+	// rateLimitMaxAttempts is how often a call is made before its rate
+	// limit error is returned
+	rateLimitMaxAttempts = 5
+	// abuseRateLimitBackoff is the wait for a secondary rate limit that
+	// comes without a Retry-After header, as GitHub's documentation advises
+	abuseRateLimitBackoff = time.Minute
+)
+
+// rateLimitMinBackoff is the shortest wait before a retry, doubled on every
+// attempt, so a reset time already in the past can't spin. Tests shorten it.
+var rateLimitMinBackoff = time.Second
+
+// rateLimitDelay reports whether err is a rate limit rejection and how long
+// to wait before the given attempt is retried. Retry-After takes precedence
+// over the reset time of the primary rate limit.
+func rateLimitDelay(err error, attempt int) (time.Duration, bool) {
+	var wait time.Duration
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var errResp *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateErr):
+		wait = time.Until(rateErr.Rate.Reset.Time)
+		if retryAfter, ok := parseRetryAfter(rateErr.Response); ok {
+			wait = retryAfter
+		}
+	case errors.As(err, &abuseErr):
+		wait = abuseRateLimitBackoff
+		if abuseErr.RetryAfter != nil {
+			wait = *abuseErr.RetryAfter
+		}
+	case errors.As(err, &errResp) && isSecondaryRateLimit(errResp):
+		wait = abuseRateLimitBackoff
+		if retryAfter, ok := parseRetryAfter(errResp.Response); ok {
+			wait = retryAfter
+		}
+	default:
+		return 0, false
+	}
+	if backoff := rateLimitMinBackoff << (attempt - 1); wait < backoff {
+		wait = backoff
+	}
+	return wait, true
+}
+
+// isSecondaryRateLimit reports whether errResp is a secondary rate limit that
+// go-github doesn't recognize as an AbuseRateLimitError, which it only does
+// for the documentation URL GitHub used before renaming them
+func isSecondaryRateLimit(errResp *github.ErrorResponse) bool {
+	resp := errResp.Response
+	if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return false
+	}
+	return resp.Header.Get("Retry-After") != "" || strings.HasSuffix(errResp.DocumentationURL, "#secondary-rate-limits")
+}
+
+// parseRetryAfter returns the Retry-After header of resp, given in seconds
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// wait sleeps for the given time, unless that is longer than rateLimitWait or
+// ctx ends first
+func (g *GitHubProvider) wait(ctx context.Context, wait time.Duration) error {
+	if wait > g.rateLimitWait {
+		return fmt.Errorf("rate limit resets in %s, more than the %s rate_limit_wait", wait.Round(time.Second), g.rateLimitWait)
+	}
+
+	if g.logger != nil {
+		g.logger.Info("GitHub rate limit reached, waiting for reset", "wait", wait.Round(time.Second))
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *GitHubProvider) debug(message string, keyvals ...interface{}) {
+	if g.logger != nil {
+		g.logger.Debug(message, keyvals...)
+	}
+}
+
+func gitHubRepoInfo(repo *github.Repository) RepoInfo {
+	// GitHub omits topics when a repository has none
+	topics := repo.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+
+	return RepoInfo{
+		Name:          repo.GetName(),
+		Description:   repo.GetDescription(),
+		Topics:        topics,
+		Archived:      repo.GetArchived(),
+		Fork:          repo.GetFork(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		UpdatedAt:     repo.GetUpdatedAt().Time,
+		PushedAt:      repo.GetPushedAt().Time,
+	}
+}
+
 func (g *GitHubProvider) FetchCatalog(ctx context.Context, owner, repo string) (*Catalog, error) {
 	fileContent, _, _, err := g.client.Repositories.GetContents(ctx, owner, repo, "gitspace-catalog.toml", nil)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %v", err)
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
 	}
 
 	content, err := fileContent.GetContent()
@@ -85,9 +344,19 @@ func (g *GitHubProvider) FetchCatalog(ctx context.Context, owner, repo string) (
 }
 
 func (g *GitHubProvider) DownloadDirectory(ctx context.Context, owner, repo, path, destDir string) error {
-	_, directoryContent, _, err := g.client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	return g.DownloadDirectoryAtRef(ctx, owner, repo, "", path, destDir)
+}
+
+// DownloadDirectoryAtRef is DownloadDirectory at a branch, tag or commit. An
+// empty ref means the default branch.
+func (g *GitHubProvider) DownloadDirectoryAtRef(ctx context.Context, owner, repo, ref, path, destDir string) error {
+	var opts *github.RepositoryContentGetOptions
+	if ref != "" {
+		opts = &github.RepositoryContentGetOptions{Ref: ref}
+	}
+	_, directoryContent, _, err := g.client.Repositories.GetContents(ctx, owner, repo, path, opts)
 	if err != nil {
-		return fmt.Errorf("error fetching directory contents: %v", err)
+		return fmt.Errorf("error fetching directory contents: %w", err)
 	}
 
 	// Create the destination directory if it doesn't exist
@@ -103,14 +372,14 @@ func (g *GitHubProvider) DownloadDirectory(ctx context.Context, owner, repo, pat
 				return fmt.Errorf("failed to create subdirectory %s: %w", newDestDir, err)
 			}
 			
-			err = g.DownloadDirectory(ctx, owner, repo, *file.Path, newDestDir)
+			err = g.DownloadDirectoryAtRef(ctx, owner, repo, ref, *file.Path, newDestDir)
 			if err != nil {
 				return err
 			}
 		} else {
-			fileContent, _, _, err := g.client.Repositories.GetContents(ctx, owner, repo, *file.Path, nil)
+			fileContent, _, _, err := g.client.Repositories.GetContents(ctx, owner, repo, *file.Path, opts)
 			if err != nil {
-				return fmt.Errorf("error fetching file content: %v", err)
+				return fmt.Errorf("error fetching file content: %w", err)
 			}
 
 			content, err := fileContent.GetContent()
@@ -125,9 +394,9 @@ func (g *GitHubProvider) DownloadDirectory(ctx context.Context, owner, repo, pat
 				return fmt.Errorf("failed to create parent directory for %s: %w", filePath, err)
 			}
 
-			err = os.WriteFile(filePath, []byte(content), 0644)
+			err = writeVerifiedFile(filePath, []byte(content), int64(fileContent.GetSize()), fileContent.GetSHA())
 			if err != nil {
-				return fmt.Errorf("error writing file: %v", err)
+				return err
 			}
 		}
 	}
@@ -0,0 +1,349 @@
+package lib
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestRateLimitDelay(t *testing.T) {
+	retryAfter := 30 * time.Second
+	withRetryAfter := &http.Response{Header: http.Header{"Retry-After": []string{"20"}}}
+	tests := []struct {
+		name    string
+		err     error
+		attempt int
+		want    time.Duration
+		limited bool
+	}{
+		{"not a rate limit", errors.New("boom"), 1, 0, false},
+		{"no error", nil, 1, 0, false},
+		{"reset in the past", &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(-time.Hour)}}}, 1, time.Second, true},
+		{"backoff doubles", &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(-time.Hour)}}}, 3, 4 * time.Second, true},
+		{"retry after", &github.RateLimitError{Response: withRetryAfter, Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}}, 1, 20 * time.Second, true},
+		{"abuse with retry after", &github.AbuseRateLimitError{RetryAfter: &retryAfter}, 1, retryAfter, true},
+		{"abuse without retry after", &github.AbuseRateLimitError{}, 1, abuseRateLimitBackoff, true},
+		{"wrapped", fmt.Errorf("listing: %w", &github.AbuseRateLimitError{RetryAfter: &retryAfter}), 1, retryAfter, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, limited := rateLimitDelay(tt.err, tt.attempt)
+			if limited != tt.limited || got != tt.want {
+				t.Errorf("rateLimitDelay() = %s, %v, want %s, %v", got, limited, tt.want, tt.limited)
+			}
+		})
+	}
+
+	reset := time.Now().Add(time.Minute)
+	got, _ := rateLimitDelay(&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: reset}}}, 1)
+	if got < 50*time.Second || got > time.Minute {
+		t.Errorf("waited %s for a reset a minute away", got)
+	}
+}
+
+// Documentation URLs of secondary rate limit errors, before and after GitHub
+// renamed them
+const (
+	abuseRateLimitDocs     = "https://docs.github.com/rest/overview/resources-in-the-rest-api#abuse-rate-limits"
+	secondaryRateLimitDocs = "https://docs.github.com/rest/overview/resources-in-the-rest-api#secondary-rate-limits"
+)
+
+// serveRateLimited rejects every org listing with status and the given
+// headers, counting the requests
+func serveRateLimited(t *testing.T, status int, header http.Header) *atomic.Int32 {
+	var requests atomic.Int32
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"message": "rate limited", "documentation_url": %q}`, secondaryRateLimitDocs)
+	})
+	return &requests
+}
+
+// newTestGitHubProvider returns a provider for opts with a test token and
+// rate limit backoffs short enough for tests
+func newTestGitHubProvider(t *testing.T, opts ProviderOptions) *GitHubProvider {
+	t.Helper()
+	previous := rateLimitMinBackoff
+	rateLimitMinBackoff = time.Millisecond
+	t.Cleanup(func() { rateLimitMinBackoff = previous })
+
+	opts.Token = "test-token"
+	provider, err := NewGitHubProvider(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestWithRateLimitGivesUp(t *testing.T) {
+	// A reset time in the past used to retry immediately, forever
+	requests := serveRateLimited(t, http.StatusForbidden, http.Header{
+		"X-Ratelimit-Limit":     {"5000"},
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)},
+	})
+	provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: OwnerTypeOrg, RateLimitWait: time.Minute})
+
+	_, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+	var rateErr *github.RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("got error %v, want a rate limit error", err)
+	}
+	if got := requests.Load(); got != rateLimitMaxAttempts {
+		t.Errorf("made %d requests, want %d", got, rateLimitMaxAttempts)
+	}
+}
+
+func TestWithRateLimitRetriesSecondaryLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		docs   string
+		header http.Header
+	}{
+		{"abuse rate limit", http.StatusForbidden, abuseRateLimitDocs, http.Header{"Retry-After": {"0"}}},
+		{"secondary rate limit", http.StatusForbidden, secondaryRateLimitDocs, nil},
+		{"too many requests", http.StatusTooManyRequests, "", http.Header{"Retry-After": {"0"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if requests.Add(1) == 1 {
+					for key, values := range tt.header {
+						w.Header()[key] = values
+					}
+					w.WriteHeader(tt.status)
+					fmt.Fprintf(w, `{"message": "slow down", "documentation_url": %q}`, tt.docs)
+					return
+				}
+				fmt.Fprint(w, `[{"name": "widget"}]`)
+			})
+			// Without Retry-After the wait is abuseRateLimitBackoff
+			provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: OwnerTypeOrg, RateLimitWait: 2 * abuseRateLimitBackoff})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.header == nil {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			repos, err := provider.FetchRepositoriesDetailed(ctx, "acme")
+			if tt.header == nil {
+				if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) || requests.Load() != 1 {
+					t.Errorf("got %v after %d requests, want to be waiting for the backoff", err, requests.Load())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(repos) != 1 || repos[0].Name != "widget" || requests.Load() != 2 {
+				t.Errorf("got %v after %d requests, want widget after a retry", repos, requests.Load())
+			}
+		})
+	}
+}
+
+func TestWithRateLimitRespectsRateLimitWait(t *testing.T) {
+	// Retry-After beyond rate_limit_wait fails without retrying
+	requests := serveRateLimited(t, http.StatusForbidden, http.Header{"Retry-After": {"120"}})
+	provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: OwnerTypeOrg, RateLimitWait: time.Minute})
+
+	_, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+	if err == nil || !strings.Contains(err.Error(), "rate_limit_wait") {
+		t.Fatalf("got error %v, want the wait rejected", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
+
+// serveGitHubOwners answers as GitHub would for the organization acme, the
+// authenticated user jane and the user bob, recording the paths requested
+func serveGitHubOwners(t *testing.T) *[]string {
+	var mu sync.Mutex
+	var paths []string
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/orgs/acme/repos":
+			fmt.Fprint(w, `[{"name": "api"}]`)
+		case "/user":
+			fmt.Fprint(w, `{"login": "Jane"}`)
+		case "/user/repos":
+			if r.URL.Query().Get("affiliation") != "owner" {
+				t.Errorf("listed the authenticated user's repositories with %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[{"name": "dotfiles", "private": true}]`)
+		case "/users/bob/repos":
+			fmt.Fprint(w, `[{"name": "blog"}]`)
+		case "/orgs/broken/repos":
+			http.Error(w, `{"message": "Server Error"}`, http.StatusInternalServerError)
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	})
+	return &paths
+}
+
+func TestGitHubFetchRepositoriesOwnerTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		ownerType OwnerType
+		owner     string
+		want      []string
+		wantPaths []string
+	}{
+		{"org", OwnerTypeOrg, "acme", []string{"api"}, []string{"/orgs/acme/repos"}},
+		{"auto org", OwnerTypeAuto, "acme", []string{"api"}, []string{"/orgs/acme/repos"}},
+		{"auto user", OwnerTypeAuto, "bob", []string{"blog"}, []string{"/orgs/bob/repos", "/user", "/users/bob/repos"}},
+		{"authenticated user", OwnerTypeUser, "jane", []string{"dotfiles"}, []string{"/user", "/user/repos"}},
+		{"other user", OwnerTypeUser, "bob", []string{"blog"}, []string{"/user", "/users/bob/repos"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := serveGitHubOwners(t)
+			provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: tt.ownerType})
+
+			repos, err := provider.FetchRepositoriesDetailed(context.Background(), tt.owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := RepoNames(repos); !slices.Equal(names, tt.want) {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+			if !slices.Equal(*paths, tt.wantPaths) {
+				t.Errorf("requested %v, want %v", *paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestGitHubFetchRepositoriesOnlyFallsBackOnNotFound(t *testing.T) {
+	paths := serveGitHubOwners(t)
+	provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: OwnerTypeAuto})
+
+	if _, err := provider.FetchRepositoriesDetailed(context.Background(), "broken"); err == nil {
+		t.Fatal("a failed org listing succeeded")
+	}
+	if !slices.Equal(*paths, []string{"/orgs/broken/repos"}) {
+		t.Errorf("requested %v, want only the org listing", *paths)
+	}
+}
+
+func TestGitHubFetchRepositoriesVisibility(t *testing.T) {
+	for _, visibility := range []Visibility{VisibilityAll, VisibilityPublic, VisibilityPrivate} {
+		t.Run(string(visibility), func(t *testing.T) {
+			var query string
+			stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("type")
+				w.Header().Set("Content-Type", "application/json")
+				// The listing isn't trusted to have applied the type filter
+				fmt.Fprint(w, `[{"name": "api", "private": true}, {"name": "site", "private": false}]`)
+			})
+			provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: OwnerTypeOrg, Visibility: visibility})
+
+			repos, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[Visibility][]string{
+				VisibilityAll:     {"api", "site"},
+				VisibilityPublic:  {"site"},
+				VisibilityPrivate: {"api"},
+			}[visibility]
+			if names := RepoNames(repos); !slices.Equal(names, want) {
+				t.Errorf("got %v, want %v", names, want)
+			}
+			if wantQuery := map[Visibility]string{VisibilityPublic: "public", VisibilityPrivate: "private"}[visibility]; query != wantQuery {
+				t.Errorf("listed with type=%q, want %q", query, wantQuery)
+			}
+		})
+	}
+}
+
+func TestGitHubRepoInfoTopics(t *testing.T) {
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"name": "api", "topics": ["backend", "go"], "archived": true}, {"name": "site"}]`)
+	})
+	provider := newTestGitHubProvider(t, ProviderOptions{OwnerType: OwnerTypeOrg})
+
+	repos, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("got %d repositories, want 2", len(repos))
+	}
+	if !slices.Equal(repos[0].Topics, []string{"backend", "go"}) || !repos[0].Archived {
+		t.Errorf("got %+v, want api's topics and archived flag", repos[0])
+	}
+	// No topics is known to be none, unlike nil from providers that can't list them
+	if repos[1].Topics == nil || len(repos[1].Topics) != 0 {
+		t.Errorf("got topics %#v for a repository without any, want an empty slice", repos[1].Topics)
+	}
+}
+
+func TestGitHubDownloadDirectoryAtRef(t *testing.T) {
+	const source = "package main\n"
+	var mu sync.Mutex
+	var refs []string
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		refs = append(refs, r.URL.Query().Get("ref"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/acme/catalog/contents/plugins/hello":
+			fmt.Fprint(w, `[{"type": "file", "name": "main.go", "path": "plugins/hello/main.go"}]`)
+		case "/repos/acme/catalog/contents/plugins/hello/main.go":
+			fmt.Fprintf(w, `{"type": "file", "name": "main.go", "path": "plugins/hello/main.go", "encoding": "base64", "content": %q, "size": %d, "sha": %q}`,
+				base64.StdEncoding.EncodeToString([]byte(source)), len(source), gitBlobSHA([]byte(source)))
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	})
+	provider := newTestGitHubProvider(t, ProviderOptions{})
+
+	dest := t.TempDir()
+	if err := provider.DownloadDirectoryAtRef(context.Background(), "acme", "catalog", "v1.2.0", "plugins/hello", dest); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "main.go")); err != nil || string(data) != source {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+	if !slices.Equal(refs, []string{"v1.2.0", "v1.2.0"}) {
+		t.Errorf("requested refs %q, want v1.2.0 for every request", refs)
+	}
+
+	refs = nil
+	if err := provider.DownloadDirectory(context.Background(), "acme", "catalog", "plugins/hello", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(refs, []string{"", ""}) {
+		t.Errorf("DownloadDirectory requested refs %q, want the default branch", refs)
+	}
+}
@@ -0,0 +1,292 @@
+// lib/gitlab.go
+
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const defaultGitLabBaseURL = "https://gitlab.com"
+
+// GitLabProvider talks to the GitLab REST API (v4) directly. It needs a
+// handful of GET endpoints, which doesn't justify pulling in a client library.
+// Self-hosted instances are reached through ProviderOptions.BaseURL.
+type GitLabProvider struct {
+	client     *http.Client
+	apiURL     string
+	token      string
+	visibility Visibility
+}
+
+type gitLabProject struct {
+	Name              string          `json:"name"`
+	Path              string          `json:"path"`
+	Description       string          `json:"description"`
+	Topics            []string        `json:"topics"`
+	Archived          bool            `json:"archived"`
+	ForkedFromProject json.RawMessage `json:"forked_from_project"`
+	DefaultBranch     string          `json:"default_branch"`
+	Visibility        string          `json:"visibility"`
+	LastActivityAt    time.Time       `json:"last_activity_at"`
+}
+
+type gitLabRelease struct {
+	TagName     string    `json:"tag_name"`
+	ReleasedAt  time.Time `json:"released_at"`
+	Description string    `json:"description"`
+}
+
+type gitLabTreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+func NewGitLabProvider(opts ProviderOptions) (*GitLabProvider, error) {
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("GITLAB_TOKEN environment variable not set")
+	}
+
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultGitLabBaseURL
+	}
+
+	return &GitLabProvider{
+		client:     http.DefaultClient,
+		apiURL:     strings.TrimSuffix(baseURL, "/") + "/api/v4",
+		token:      token,
+		visibility: opts.Visibility,
+	}, nil
+}
+
+func (g *GitLabProvider) GetLatestRelease(ctx context.Context, owner, repo string) (*Release, error) {
+	var releases []gitLabRelease
+	query := url.Values{"per_page": {"1"}}
+	if _, err := g.getJSON(ctx, projectPath(owner, repo)+"/releases", query, &releases); err != nil {
+		return nil, err
+	}
+
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases found")
+	}
+
+	return &Release{
+		TagName:     releases[0].TagName,
+		PublishedAt: releases[0].ReleasedAt,
+		Body:        releases[0].Description,
+	}, nil
+}
+
+func (g *GitLabProvider) FetchRepositories(ctx context.Context, owner string) ([]string, error) {
+	repos, err := g.FetchRepositoriesDetailed(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return RepoNames(repos), nil
+}
+
+func (g *GitLabProvider) FetchRepositoriesDetailed(ctx context.Context, owner string) ([]RepoInfo, error) {
+	// Owners can be groups or users; try the group endpoint first and fall
+	// back to the user endpoint on a 404, like GitHub's org probe
+	repos, err := g.fetchProjects(ctx, "/groups/"+url.PathEscape(owner)+"/projects")
+	var statusErr *gitLabStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		repos, err = g.fetchProjects(ctx, "/users/"+url.PathEscape(owner)+"/projects")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching repositories: %w", err)
+	}
+	return repos, nil
+}
+
+func (g *GitLabProvider) fetchProjects(ctx context.Context, path string) ([]RepoInfo, error) {
+	var allRepos []RepoInfo
+	query := url.Values{"per_page": {"100"}, "page": {"1"}}
+
+	for {
+		var projects []gitLabProject
+		resp, err := g.getJSON(ctx, path, query, &projects)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, project := range projects {
+			// Internal projects aren't publicly readable, so count them as private
+			if g.visibility.Allows(project.Visibility != "public") {
+				allRepos = append(allRepos, RepoInfo{
+					Name:          project.Path,
+					Description:   project.Description,
+					Topics:        project.Topics,
+					Archived:      project.Archived,
+					Fork:          len(project.ForkedFromProject) > 0 && string(project.ForkedFromProject) != "null",
+					DefaultBranch: project.DefaultBranch,
+					UpdatedAt:     project.LastActivityAt, // Bumped at most hourly, too coarse for PushedAt
+				})
+			}
+		}
+
+		nextPage := resp.Header.Get("X-Next-Page")
+		if nextPage == "" {
+			break
+		}
+		query.Set("page", nextPage)
+	}
+
+	return allRepos, nil
+}
+
+func (g *GitLabProvider) FetchCatalog(ctx context.Context, owner, repo string) (*Catalog, error) {
+	branch, err := g.defaultBranch(ctx, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+
+	fileContent, err := g.getRawFile(ctx, owner, repo, branch, "gitspace-catalog.toml")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+
+	var catalog Catalog
+	err = toml.Unmarshal(fileContent, &catalog)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding TOML: %v", err)
+	}
+
+	return &catalog, nil
+}
+
+func (g *GitLabProvider) DownloadDirectory(ctx context.Context, owner, repo, path, destDir string) error {
+	branch, err := g.defaultBranch(ctx, owner, repo)
+	if err != nil {
+		return fmt.Errorf("error fetching repository tree: %w", err)
+	}
+
+	query := url.Values{
+		"path":      {path},
+		"ref":       {branch},
+		"recursive": {"true"},
+		"per_page":  {"100"},
+		"page":      {"1"},
+	}
+
+	for {
+		var entries []gitLabTreeEntry
+		resp, err := g.getJSON(ctx, projectPath(owner, repo)+"/repository/tree", query, &entries)
+		if err != nil {
+			return fmt.Errorf("error fetching repository tree: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type != "blob" {
+				continue
+			}
+
+			fileContent, err := g.getRawFile(ctx, owner, repo, branch, entry.Path)
+			if err != nil {
+				return fmt.Errorf("error fetching file content: %w", err)
+			}
+
+			filePath := filepath.Join(destDir, strings.TrimPrefix(entry.Path, path))
+			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+				return fmt.Errorf("error creating directories: %v", err)
+			}
+
+			if err := os.WriteFile(filePath, fileContent, 0644); err != nil {
+				return fmt.Errorf("error writing file: %v", err)
+			}
+		}
+
+		nextPage := resp.Header.Get("X-Next-Page")
+		if nextPage == "" {
+			break
+		}
+		query.Set("page", nextPage)
+	}
+
+	return nil
+}
+
+func (g *GitLabProvider) defaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var project gitLabProject
+	if _, err := g.getJSON(ctx, projectPath(owner, repo), nil, &project); err != nil {
+		return "", err
+	}
+	if project.DefaultBranch == "" {
+		return "", fmt.Errorf("project %s/%s has no default branch", owner, repo)
+	}
+	return project.DefaultBranch, nil
+}
+
+func (g *GitLabProvider) getRawFile(ctx context.Context, owner, repo, ref, path string) ([]byte, error) {
+	endpoint := projectPath(owner, repo) + "/repository/files/" + url.PathEscape(path) + "/raw"
+	resp, err := g.do(ctx, endpoint, url.Values{"ref": {ref}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (g *GitLabProvider) getJSON(ctx context.Context, endpoint string, query url.Values, out interface{}) (*http.Response, error) {
+	resp, err := g.do(ctx, endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("error decoding response from %s: %v", endpoint, err)
+	}
+	return resp, nil
+}
+
+func (g *GitLabProvider) do(ctx context.Context, endpoint string, query url.Values) (*http.Response, error) {
+	reqURL := g.apiURL + endpoint
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &gitLabStatusError{Endpoint: endpoint, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// gitLabStatusError is a GitLab API response other than 200 OK
+type gitLabStatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *gitLabStatusError) Error() string {
+	return fmt.Sprintf("GET %s: unexpected status %d", e.Endpoint, e.StatusCode)
+}
+
+// projectPath returns the API path for a project, addressed by its URL-encoded full path
+func projectPath(owner, repo string) string {
+	return "/projects/" + url.PathEscape(owner+"/"+repo)
+}
@@ -0,0 +1,135 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// gitLabStub serves GitLab project listings from projects, keyed by API path,
+// and records every request
+type gitLabStub struct {
+	mu       sync.Mutex
+	requests []string // Host and path of every request
+	projects map[string]string
+	status   int // Status for paths missing from projects; 404 if zero
+}
+
+func (s *gitLabStub) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Host+r.URL.EscapedPath())
+	s.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+		http.Error(w, `{"message": "401 Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	body, ok := s.projects[r.URL.EscapedPath()]
+	if !ok {
+		status := s.status
+		if status == 0 {
+			status = http.StatusNotFound
+		}
+		http.Error(w, `{"message": "error"}`, status)
+		return
+	}
+	if r.URL.Query().Get("page") == "1" && s.projects[r.URL.EscapedPath()+"?page=2"] != "" {
+		w.Header().Set("X-Next-Page", "2")
+	}
+	if r.URL.Query().Get("page") == "2" {
+		body = s.projects[r.URL.EscapedPath()+"?page=2"]
+	}
+	fmt.Fprint(w, body)
+}
+
+func newTestGitLabProvider(t *testing.T, stub *gitLabStub, baseURL string, visibility Visibility) *GitLabProvider {
+	t.Helper()
+	t.Setenv("GITLAB_TOKEN", "test-token")
+	stubTransport(t, stub.serve)
+	provider, err := NewGitLabProvider(ProviderOptions{BaseURL: baseURL, Visibility: visibility})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestGitLabFetchRepositoriesFromGroup(t *testing.T) {
+	stub := &gitLabStub{projects: map[string]string{
+		"/api/v4/groups/acme/projects":        `[{"path": "api", "visibility": "private", "topics": ["go"]}, {"path": "web", "visibility": "public", "forked_from_project": {"id": 1}}]`,
+		"/api/v4/groups/acme/projects?page=2": `[{"path": "docs", "visibility": "internal", "archived": true}]`,
+	}}
+	provider := newTestGitLabProvider(t, stub, "https://gitlab.example.com/", VisibilityAll)
+
+	repos, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := RepoNames(repos); !slices.Equal(names, []string{"api", "web", "docs"}) {
+		t.Errorf("got %v, want [api web docs]", names)
+	}
+	if !repos[1].Fork || repos[0].Fork || !repos[2].Archived {
+		t.Errorf("fork and archived flags wrong: %+v", repos)
+	}
+	// A self-hosted base URL is used for every request
+	for _, request := range stub.requests {
+		if request != "gitlab.example.com/api/v4/groups/acme/projects" {
+			t.Errorf("unexpected request %s", request)
+		}
+	}
+}
+
+func TestGitLabFetchRepositoriesVisibility(t *testing.T) {
+	stub := &gitLabStub{projects: map[string]string{
+		"/api/v4/groups/acme/projects": `[{"path": "api", "visibility": "private"}, {"path": "web", "visibility": "public"}, {"path": "docs", "visibility": "internal"}]`,
+	}}
+	provider := newTestGitLabProvider(t, stub, "", VisibilityPrivate)
+
+	repos, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Internal projects count as private
+	if names := RepoNames(repos); !slices.Equal(names, []string{"api", "docs"}) {
+		t.Errorf("got %v, want [api docs]", names)
+	}
+	if len(stub.requests) == 0 || stub.requests[0] != "gitlab.com/api/v4/groups/acme/projects" {
+		t.Errorf("requests %v, want gitlab.com by default", stub.requests)
+	}
+}
+
+func TestGitLabFetchRepositoriesFallsBackToUser(t *testing.T) {
+	stub := &gitLabStub{projects: map[string]string{
+		"/api/v4/users/jane/projects": `[{"path": "dotfiles", "visibility": "public"}]`,
+	}}
+	provider := newTestGitLabProvider(t, stub, "", VisibilityAll)
+
+	repos, err := provider.FetchRepositoriesDetailed(context.Background(), "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := RepoNames(repos); !slices.Equal(names, []string{"dotfiles"}) {
+		t.Errorf("got %v, want [dotfiles]", names)
+	}
+}
+
+func TestGitLabFetchRepositoriesOnlyFallsBackOnNotFound(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			stub := &gitLabStub{status: status, projects: map[string]string{
+				"/api/v4/users/acme/projects": `[{"path": "wrong", "visibility": "public"}]`,
+			}}
+			provider := newTestGitLabProvider(t, stub, "", VisibilityAll)
+
+			_, err := provider.FetchRepositoriesDetailed(context.Background(), "acme")
+			if err == nil {
+				t.Fatal("a failed group listing fell back to the user's projects")
+			}
+			if len(stub.requests) != 1 {
+				t.Errorf("made requests %v, want only the group listing", stub.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// lib/local.go
+
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// LocalProvider serves repositories from a directory instead of an SCM, for
+// offline and air-gapped use. The base URL is the root directory, each owner
+// is a subdirectory of it, and each repository in there is a git repository,
+// bare (name.git) or not.
+type LocalProvider struct {
+	root string
+}
+
+// NewLocalProvider reads repositories under the directory opts.BaseURL names
+func NewLocalProvider(opts ProviderOptions) (*LocalProvider, error) {
+	root, err := LocalRoot(opts.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("the local scm needs base_url to be the directory holding your repositories, such as /srv/git: %w", err)
+	}
+	return &LocalProvider{root: root}, nil
+}
+
+// LocalRoot returns the directory a local base URL names: an absolute path,
+// a path starting with ~, or a file:// URL
+func LocalRoot(baseURL string) (string, error) {
+	if baseURL == "" {
+		return "", fmt.Errorf("base URL is empty")
+	}
+	path := baseURL
+	if strings.HasPrefix(baseURL, "file://") {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+		}
+		path = u.Path
+	}
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("base URL %q must be an absolute path or a file:// URL", baseURL)
+	}
+	return filepath.Clean(path), nil
+}
+
+// LocalRepoPath returns the directory of an owner's repository under root,
+// preferring a bare name.git over name
+func LocalRepoPath(root, owner, repo string) string {
+	bare := filepath.Join(root, owner, repo+".git")
+	if _, err := os.Stat(bare); err == nil {
+		return bare
+	}
+	return filepath.Join(root, owner, repo)
+}
+
+// GetLatestRelease returns the most recent tag, since a directory has no releases
+func (l *LocalProvider) GetLatestRelease(ctx context.Context, owner, repo string) (*Release, error) {
+	r, err := l.open(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := r.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags: %w", err)
+	}
+
+	var latest *Release
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		release := Release{TagName: ref.Name().Short()}
+		if tag, err := r.TagObject(ref.Hash()); err == nil {
+			release.PublishedAt = tag.Tagger.When
+			release.Body = tag.Message
+		} else if commit, err := r.CommitObject(ref.Hash()); err == nil {
+			release.PublishedAt = commit.Committer.When
+		}
+		if latest == nil || release.PublishedAt.After(latest.PublishedAt) {
+			latest = &release
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags: %w", err)
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no releases found")
+	}
+	return latest, nil
+}
+
+func (l *LocalProvider) FetchRepositories(ctx context.Context, owner string) ([]string, error) {
+	repos, err := l.FetchRepositoriesDetailed(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return RepoNames(repos), nil
+}
+
+// FetchRepositoriesDetailed lists the git repositories in the owner's
+// directory. There are no topics, and UpdatedAt is the time of the last
+// commit on HEAD.
+func (l *LocalProvider) FetchRepositoriesDetailed(ctx context.Context, owner string) ([]RepoInfo, error) {
+	entries, err := os.ReadDir(filepath.Join(l.root, owner))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching repositories: %w", err)
+	}
+
+	var allRepos []RepoInfo
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		path := filepath.Join(l.root, owner, entry.Name())
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			continue
+		}
+		r, err := git.PlainOpen(path)
+		if err != nil {
+			continue // Not a git repository
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".git")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		info := RepoInfo{Name: name}
+		if head, err := r.Reference(plumbing.HEAD, false); err == nil && head.Type() == plumbing.SymbolicReference {
+			info.DefaultBranch = head.Target().Short()
+		}
+		if commit, err := headCommit(r); err == nil {
+			info.UpdatedAt = commit.Committer.When.In(time.UTC)
+		}
+		allRepos = append(allRepos, info)
+	}
+
+	return allRepos, nil
+}
+
+func (l *LocalProvider) FetchCatalog(ctx context.Context, owner, repo string) (*Catalog, error) {
+	tree, err := l.headTree(owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+	file, err := tree.File("gitspace-catalog.toml")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+	fileContent, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching gitspace-catalog.toml: %w", err)
+	}
+
+	var catalog Catalog
+	err = toml.Unmarshal([]byte(fileContent), &catalog)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding TOML: %v", err)
+	}
+
+	return &catalog, nil
+}
+
+func (l *LocalProvider) DownloadDirectory(ctx context.Context, owner, repo, path, destDir string) error {
+	tree, err := l.headTree(owner, repo)
+	if err != nil {
+		return fmt.Errorf("error fetching repository tree: %w", err)
+	}
+	subtree, err := tree.Tree(path)
+	if err != nil {
+		return fmt.Errorf("error fetching repository tree: %w", err)
+	}
+
+	return subtree.Files().ForEach(func(file *object.File) error {
+		fileContent, err := file.Contents()
+		if err != nil {
+			return fmt.Errorf("error fetching file content: %w", err)
+		}
+
+		filePath := filepath.Join(destDir, file.Name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("error creating directories: %v", err)
+		}
+
+		if err := os.WriteFile(filePath, []byte(fileContent), 0644); err != nil {
+			return fmt.Errorf("error writing file: %v", err)
+		}
+		return nil
+	})
+}
+
+func (l *LocalProvider) open(owner, repo string) (*git.Repository, error) {
+	r, err := git.PlainOpen(LocalRepoPath(l.root, owner, repo))
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s/%s: %w", owner, repo, err)
+	}
+	return r, nil
+}
+
+// headTree returns the tree of the repository's HEAD commit
+func (l *LocalProvider) headTree(owner, repo string) (*object.Tree, error) {
+	r, err := l.open(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := headCommit(r)
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+func headCommit(r *git.Repository) (*object.Commit, error) {
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("error reading HEAD: %w", err)
+	}
+	return r.CommitObject(head.Hash())
+}
@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// initLocalRepo creates a repository at path on branch, committing files
+// (name to content) at when
+func initLocalRepo(t *testing.T, path, branch string, when time.Time, files map[string]string) *git.Repository {
+	t.Helper()
+	r, err := git.PlainInitWithOptions(path, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName(branch)},
+	})
+	if err != nil {
+		t.Fatalf("failed to init repository: %v", err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(path, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "gitspace", Email: "gitspace@example.com", When: when}
+	if _, err := w.Commit("Initial commit", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	return r
+}
+
+func TestLocalRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tests := []struct {
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{"/srv/git", "/srv/git", false},
+		{"/srv/git/", "/srv/git", false},
+		{"file:///srv/git", "/srv/git", false},
+		{"~/git", filepath.Join(home, "git"), false},
+		{"", "", true},
+		{"srv/git", "", true},
+		{"https://git.example.com", "", true},
+	}
+	for _, tt := range tests {
+		got, err := LocalRoot(tt.baseURL)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("LocalRoot(%q) error = %v, wantErr %v", tt.baseURL, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("LocalRoot(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestLocalFetchRepositoriesDetailed(t *testing.T) {
+	root := t.TempDir()
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	initLocalRepo(t, filepath.Join(root, "team", "alpha"), "main", when, map[string]string{"README.md": "alpha"})
+	initLocalRepo(t, filepath.Join(root, "team", "beta-src"), "trunk", when, map[string]string{"README.md": "beta"})
+	if _, err := git.PlainClone(filepath.Join(root, "team", "beta.git"), true, &git.CloneOptions{URL: filepath.Join(root, "team", "beta-src")}); err != nil {
+		t.Fatalf("failed to make a bare clone: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "team", "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewLocalProvider(ProviderOptions{BaseURL: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, err := provider.FetchRepositoriesDetailed(context.Background(), "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]RepoInfo)
+	for _, repo := range repos {
+		byName[repo.Name] = repo
+	}
+	names := RepoNames(repos)
+	slices.Sort(names)
+	if want := []string{"alpha", "beta", "beta-src"}; !slices.Equal(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	if got := byName["alpha"].DefaultBranch; got != "main" {
+		t.Errorf("alpha default branch = %q, want main", got)
+	}
+	if got := byName["beta"].DefaultBranch; got != "trunk" {
+		t.Errorf("bare beta default branch = %q, want trunk", got)
+	}
+	if got := byName["alpha"].UpdatedAt; !got.Equal(when) {
+		t.Errorf("alpha updated at %v, want %v", got, when)
+	}
+	if got := LocalRepoPath(root, "team", "beta"); got != filepath.Join(root, "team", "beta.git") {
+		t.Errorf("LocalRepoPath preferred %s over the bare repository", got)
+	}
+}
+
+func TestNewLocalProviderRequiresRoot(t *testing.T) {
+	if _, err := NewLocalProvider(ProviderOptions{BaseURL: "relative/path"}); err == nil {
+		t.Error("a relative base URL was accepted")
+	}
+}
+
+func TestLocalCatalogAndDirectory(t *testing.T) {
+	root := t.TempDir()
+	initLocalRepo(t, filepath.Join(root, "acme", "catalog"), "main", time.Now(), map[string]string{
+		"gitspace-catalog.toml":    "[catalog]\nname = \"acme\"\n",
+		"plugins/hello/main.go":    "package main\n",
+		"plugins/hello/sub/doc.go": "package sub\n",
+		"README.md":                "# catalog\n",
+	})
+	provider, err := NewLocalProvider(ProviderOptions{BaseURL: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := provider.FetchCatalog(context.Background(), "acme", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catalog.Catalog.Name != "acme" {
+		t.Errorf("got catalog %q, want acme", catalog.Catalog.Name)
+	}
+
+	dest := t.TempDir()
+	if err := provider.DownloadDirectory(context.Background(), "acme", "catalog", "plugins/hello", dest); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"main.go": "package main\n", "sub/doc.go": "package sub\n"} {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("%s wasn't downloaded: %v", name, err)
+		} else if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "README.md")); !os.IsNotExist(err) {
+		t.Error("a file outside the directory was downloaded")
+	}
+}
+
+func TestLocalGetLatestRelease(t *testing.T) {
+	root := t.TempDir()
+	r := initLocalRepo(t, filepath.Join(root, "acme", "tool"), "main", time.Now(), map[string]string{"README.md": "tool"})
+	provider, err := NewLocalProvider(ProviderOptions{BaseURL: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.GetLatestRelease(context.Background(), "acme", "tool"); err == nil {
+		t.Error("a repository without tags reported a release")
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tag := range []string{"v1.0.0", "v1.1.0"} {
+		tagger := &object.Signature{Name: "gitspace", Email: "gitspace@example.com", When: time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC)}
+		if _, err := r.CreateTag(tag, head.Hash(), &git.CreateTagOptions{Tagger: tagger, Message: "Release " + tag}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	release, err := provider.GetLatestRelease(context.Background(), "acme", "tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.TagName != "v1.1.0" {
+		t.Errorf("latest release = %s, want v1.1.0", release.TagName)
+	}
+}
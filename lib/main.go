@@ -2,22 +2,141 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
 )
 
 type SCMType string
 
 const (
-	SCMTypeGitHub SCMType = "github"
-	SCMTypeGitea  SCMType = "gitea"
+	SCMTypeGitHub    SCMType = "github"
+	SCMTypeGitea     SCMType = "gitea"
+	SCMTypeGitLab    SCMType = "gitlab"
+	SCMTypeBitbucket SCMType = "bitbucket"
+	SCMTypeLocal     SCMType = "local"
 )
 
+// OwnerType tells providers whether an owner is a user or an organization
+type OwnerType string
+
+const (
+	OwnerTypeAuto OwnerType = "auto"
+	OwnerTypeUser OwnerType = "user"
+	OwnerTypeOrg  OwnerType = "org"
+)
+
+// Visibility restricts which repositories a provider lists
+type Visibility string
+
+const (
+	VisibilityAll     Visibility = "all"
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+// Allows reports whether a repository with the given privacy is included.
+// An empty Visibility behaves like VisibilityAll.
+func (v Visibility) Allows(private bool) bool {
+	switch v {
+	case VisibilityPublic:
+		return !private
+	case VisibilityPrivate:
+		return private
+	default:
+		return true
+	}
+}
+
+// DefaultNetworkTimeout bounds SCM API calls when no timeout is configured
+const DefaultNetworkTimeout = 30 * time.Second
+
+// WithNetworkTimeout derives a context for one SCM operation. A timeout of
+// zero or less uses DefaultNetworkTimeout.
+func WithNetworkTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultNetworkTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// TimeoutError replaces a context deadline error with one naming the
+// operation that timed out; other errors are returned unchanged
+func TimeoutError(err error, operation string, timeout time.Duration) error {
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = DefaultNetworkTimeout
+	}
+	return fmt.Errorf("%s timed out after %s: %w", operation, timeout, err)
+}
+
+// ProviderOptions carries the optional, config-driven settings for a provider
+type ProviderOptions struct {
+	BaseURL    string
+	OwnerType  OwnerType
+	Visibility Visibility
+	// Token is the already-resolved API token for providers that take one
+	Token string
+	// RateLimitWait caps how long a provider sleeps waiting for its API
+	// rate limit to reset; zero never waits
+	RateLimitWait time.Duration
+	// Logger is optional and receives quota and wait messages
+	Logger *logger.RateLimitedLogger
+	// CacheDir, if set, caches GitHub API responses and revalidates them
+	// with their ETag, so unchanged content costs no rate limit
+	CacheDir string
+}
+
+// ValidateBaseURL checks that a self-hosted SCM's base URL is an absolute
+// http or https URL with a host
+func ValidateBaseURL(baseURL string) error {
+	if baseURL == "" {
+		return fmt.Errorf("base URL is empty")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("base URL %q must start with http:// or https://", baseURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("base URL %q has no host", baseURL)
+	}
+	return nil
+}
+
+// GetSCMProvider creates a provider without config-driven options, taking the
+// token from the provider's environment variable
 func GetSCMProvider(scmType SCMType, baseURL string) (SCMProvider, error) {
+	opts := ProviderOptions{BaseURL: baseURL}
+	if scmType == SCMTypeGitHub {
+		opts.Token = os.Getenv("GITHUB_TOKEN")
+	}
+	return NewSCMProvider(scmType, opts)
+}
+
+func NewSCMProvider(scmType SCMType, opts ProviderOptions) (SCMProvider, error) {
+	if Offline() && scmType != SCMTypeLocal {
+		return nil, fmt.Errorf("%s: %w", scmType, ErrOffline)
+	}
 	switch scmType {
 	case SCMTypeGitHub:
-		return NewGitHubProvider()
+		return NewGitHubProvider(opts)
 	case SCMTypeGitea:
-		return NewGiteaProvider(baseURL)
+		return NewGiteaProvider(opts)
+	case SCMTypeGitLab:
+		return NewGitLabProvider(opts)
+	case SCMTypeBitbucket:
+		return NewBitbucketProvider(opts)
+	case SCMTypeLocal:
+		return NewLocalProvider(opts)
 	default:
 		return nil, fmt.Errorf("unsupported SCM type: %s", scmType)
 	}
@@ -33,14 +152,31 @@ func GetLatestRelease(ctx context.Context, scmType SCMType, baseURL, owner, repo
 	return provider.GetLatestRelease(ctx, owner, repo)
 }
 
-func GetRepositories(ctx context.Context, scmType SCMType, baseURL, owner string) ([]string, error) {
-	provider, err := GetSCMProvider(scmType, baseURL)
+func GetRepositories(ctx context.Context, scmType SCMType, opts ProviderOptions, owner string) ([]string, error) {
+	provider, err := NewSCMProvider(scmType, opts)
 	if err != nil {
 		return nil, err
 	}
 	return provider.FetchRepositories(ctx, owner)
 }
 
+func GetRepositoriesDetailed(ctx context.Context, scmType SCMType, opts ProviderOptions, owner string) ([]RepoInfo, error) {
+	provider, err := NewSCMProvider(scmType, opts)
+	if err != nil {
+		return nil, err
+	}
+	return provider.FetchRepositoriesDetailed(ctx, owner)
+}
+
+// RepoNames flattens repository metadata to just the names
+func RepoNames(repos []RepoInfo) []string {
+	names := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+	return names
+}
+
 func FetchGitspaceCatalog(ctx context.Context, scmType SCMType, baseURL, owner, repo string) (*Catalog, error) {
 	provider, err := GetSCMProvider(scmType, baseURL)
 	if err != nil {
@@ -56,3 +192,22 @@ func DownloadDirectory(ctx context.Context, scmType SCMType, baseURL, owner, rep
 	}
 	return provider.DownloadDirectory(ctx, owner, repo, path, destDir)
 }
+
+// refDownloader is implemented by providers that can download a directory at
+// a given ref rather than the default branch
+type refDownloader interface {
+	DownloadDirectoryAtRef(ctx context.Context, owner, repo, ref, path, destDir string) error
+}
+
+// DownloadDirectoryAtRef is DownloadDirectory at a branch, tag or commit. An
+// empty ref means the default branch, which every provider supports.
+func DownloadDirectoryAtRef(ctx context.Context, provider SCMProvider, owner, repo, ref, path, destDir string) error {
+	if ref == "" {
+		return provider.DownloadDirectory(ctx, owner, repo, path, destDir)
+	}
+	downloader, ok := provider.(refDownloader)
+	if !ok {
+		return fmt.Errorf("downloading from a ref isn't supported by %T", provider)
+	}
+	return downloader.DownloadDirectoryAtRef(ctx, owner, repo, ref, path, destDir)
+}
@@ -0,0 +1,81 @@
+package lib
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubTransport serves every request made through http.DefaultTransport with
+// handler for the rest of the test, so nothing reaches the network
+func stubTransport(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	previous := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = previous })
+}
+
+func TestVisibilityAllows(t *testing.T) {
+	tests := []struct {
+		visibility Visibility
+		private    bool
+		want       bool
+	}{
+		{VisibilityAll, true, true},
+		{VisibilityAll, false, true},
+		{"", true, true},
+		{VisibilityPublic, false, true},
+		{VisibilityPublic, true, false},
+		{VisibilityPrivate, true, true},
+		{VisibilityPrivate, false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.visibility.Allows(tt.private); got != tt.want {
+			t.Errorf("%q.Allows(private=%v) = %v, want %v", tt.visibility, tt.private, got, tt.want)
+		}
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	for baseURL, valid := range map[string]bool{
+		"https://gitea.example.com":      true,
+		"http://localhost:3000":          true,
+		"https://git.example.com/gitea/": true,
+		"":                               false,
+		"gitea.example.com":              false,
+		"ftp://gitea.example.com":        false,
+		"https://":                       false,
+		"https://bad host":               false,
+	} {
+		if err := ValidateBaseURL(baseURL); (err == nil) != valid {
+			t.Errorf("ValidateBaseURL(%q) = %v, want valid %v", baseURL, err, valid)
+		}
+	}
+}
+
+func TestNewSCMProviderOffline(t *testing.T) {
+	SetOffline(true)
+	t.Cleanup(func() { SetOffline(false) })
+
+	for _, scmType := range []SCMType{SCMTypeGitHub, SCMTypeGitLab, SCMTypeGitea} {
+		if _, err := NewSCMProvider(scmType, ProviderOptions{Token: "token", BaseURL: "https://scm.example.com"}); !errors.Is(err, ErrOffline) {
+			t.Errorf("%s provider offline: got %v, want %v", scmType, err, ErrOffline)
+		}
+	}
+	if _, err := NewSCMProvider(SCMTypeLocal, ProviderOptions{BaseURL: t.TempDir()}); err != nil {
+		t.Errorf("local provider offline: %v", err)
+	}
+}
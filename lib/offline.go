@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrOffline is returned instead of making a network request in offline mode
+var ErrOffline = errors.New("not available in offline mode (--offline or GITSPACE_OFFLINE)")
+
+var offline atomic.Bool
+
+// SetOffline turns offline mode on or off. While it is on, providers other
+// than local ones can't be created, so nothing reaches an SCM.
+func SetOffline(on bool) {
+	offline.Store(on)
+}
+
+// Offline reports whether offline mode is on
+func Offline() bool {
+	return offline.Load()
+}
@@ -5,6 +5,7 @@ import "context"
 type SCMProvider interface {
 	GetLatestRelease(ctx context.Context, owner, repo string) (*Release, error)
 	FetchRepositories(ctx context.Context, owner string) ([]string, error)
+	FetchRepositoriesDetailed(ctx context.Context, owner string) ([]RepoInfo, error)
 	FetchCatalog(ctx context.Context, owner, repo string) (*Catalog, error)
 	DownloadDirectory(ctx context.Context, owner, repo, path, destDir string) error
 }
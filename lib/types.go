@@ -8,6 +8,21 @@ type Release struct {
 	Body        string    `json:"body"`
 }
 
+// RepoInfo is the metadata providers return for a listed repository. Topics
+// is nil when the provider couldn't report topics, as opposed to empty when
+// the repository has none. PushedAt is zero when the provider doesn't report
+// when the repository was last pushed to.
+type RepoInfo struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	Topics        []string  `json:"topics"`
+	Archived      bool      `json:"archived,omitempty"`
+	Fork          bool      `json:"fork,omitempty"`
+	DefaultBranch string    `json:"default_branch,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	PushedAt      time.Time `json:"pushed_at,omitempty"`
+}
+
 type Catalog struct {
 	Catalog struct {
 		Name        string `toml:"name"`
@@ -23,9 +38,10 @@ type Catalog struct {
 }
 
 type Plugin struct {
-	Version     string `toml:"version"`
-	Description string `toml:"description"`
-	Path        string `toml:"path"`
+	Version     string   `toml:"version"`
+	Description string   `toml:"description"`
+	Tags        []string `toml:"tags,omitempty"`
+	Path        string   `toml:"path"`
 	Repository  struct {
 		Type string `toml:"type"`
 		URL  string `toml:"url"`
@@ -33,9 +49,10 @@ type Plugin struct {
 }
 
 type Template struct {
-	Version     string `toml:"version,omitempty"`
-	Description string `toml:"description,omitempty"`
-	Path        string `toml:"path"`
+	Version     string   `toml:"version,omitempty"`
+	Description string   `toml:"description,omitempty"`
+	Tags        []string `toml:"tags,omitempty"`
+	Path        string   `toml:"path"`
 	Repository  struct {
 		Type string `toml:"type"`
 		URL  string `toml:"url"`
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// logLevelEnv sets the log level when --log-level isn't given
+const logLevelEnv = "GITSPACE_LOG_LEVEL"
+
+// logLevel is the level of the main logger. Plugin loggers use it too unless
+// plugins.log_levels names them.
+var logLevel = log.InfoLevel
+
+// verbosity is how much --quiet or --verbose asked gitspace to print
+type verbosity int
+
+const (
+	verbosityNormal  verbosity = iota
+	verbosityQuiet             // Errors and final summaries only
+	verbosityVerbose           // Debug logs and every detail
+)
+
+// outputVerbosity is the verbosity of this run
+var outputVerbosity = verbosityNormal
+
+// extractVerbosity removes --quiet/-q and --verbose/-v from args, wherever
+// they appear, like --log-level
+func extractVerbosity(args []string) (verbosity, []string, error) {
+	v := verbosityNormal
+	var rest []string
+	for _, arg := range args {
+		var flagged verbosity
+		switch arg {
+		case "--quiet", "-quiet", "-q":
+			flagged = verbosityQuiet
+		case "--verbose", "-verbose", "-v":
+			flagged = verbosityVerbose
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if v != verbosityNormal && v != flagged {
+			return 0, nil, fmt.Errorf("--quiet and --verbose can't be used together")
+		}
+		v = flagged
+	}
+	return v, rest, nil
+}
+
+// extractLogLevel removes --log-level from args, wherever it appears, since
+// it applies to the interactive menu and every command alike. The level comes
+// from the flag, then --quiet (error) or --verbose (debug), then
+// GITSPACE_LOG_LEVEL, and defaults to info.
+func extractLogLevel(args []string, v verbosity) (log.Level, []string, error) {
+	value := os.Getenv(logLevelEnv)
+	source := logLevelEnv
+
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--log-level" || arg == "-log-level":
+			if i+1 >= len(args) {
+				return 0, nil, fmt.Errorf("--log-level needs a value")
+			}
+			value, source = args[i+1], "--log-level"
+			i++
+		case strings.HasPrefix(arg, "--log-level="), strings.HasPrefix(arg, "-log-level="):
+			_, value, _ = strings.Cut(arg, "=")
+			source = "--log-level"
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	if source != "--log-level" {
+		switch v {
+		case verbosityQuiet:
+			return log.ErrorLevel, rest, nil
+		case verbosityVerbose:
+			return log.DebugLevel, rest, nil
+		}
+	}
+	if value == "" {
+		return log.InfoLevel, rest, nil
+	}
+	level, err := log.ParseLevel(value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w (use debug, info, warn, error or fatal)", source, err)
+	}
+	return level, rest, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestExtractLogLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		args      []string
+		wantLevel log.Level
+		wantRest  []string
+	}{
+		{"default", "", []string{"sync"}, log.InfoLevel, []string{"sync"}},
+		{"environment", "debug", []string{"sync"}, log.DebugLevel, []string{"sync"}},
+		{"flag", "", []string{"--log-level", "warn", "sync"}, log.WarnLevel, []string{"sync"}},
+		{"flag after the command", "", []string{"sync", "--log-level=error"}, log.ErrorLevel, []string{"sync"}},
+		{"single dash", "", []string{"-log-level", "debug"}, log.DebugLevel, nil},
+		{"flag overrides the environment", "debug", []string{"--log-level", "error", "clone", "--output", "json"}, log.ErrorLevel, []string{"clone", "--output", "json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(logLevelEnv, tt.env)
+			level, rest, err := extractLogLevel(tt.args, verbosityNormal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if level != tt.wantLevel || !slices.Equal(rest, tt.wantRest) {
+				t.Errorf("extractLogLevel(%q) = %v, %q, want %v, %q", tt.args, level, rest, tt.wantLevel, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestExtractLogLevelErrors(t *testing.T) {
+	tests := []struct {
+		name, env string
+		args      []string
+		want      string
+	}{
+		{"missing value", "", []string{"sync", "--log-level"}, "needs a value"},
+		{"unknown level", "", []string{"--log-level=loud"}, "--log-level"},
+		{"unknown level in the environment", "loud", nil, logLevelEnv},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(logLevelEnv, tt.env)
+			if _, _, err := extractLogLevel(tt.args, verbosityNormal); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractVerbosity(t *testing.T) {
+	tests := []struct {
+		args     []string
+		want     verbosity
+		wantRest []string
+		wantErr  bool
+	}{
+		{[]string{"sync"}, verbosityNormal, []string{"sync"}, false},
+		{[]string{"-q", "sync"}, verbosityQuiet, []string{"sync"}, false},
+		{[]string{"sync", "--verbose", "--output", "json"}, verbosityVerbose, []string{"sync", "--output", "json"}, false},
+		{[]string{"--quiet", "-q", "clone"}, verbosityQuiet, []string{"clone"}, false},
+		{[]string{"-q", "-v"}, 0, nil, true},
+	}
+	for _, tt := range tests {
+		v, rest, err := extractVerbosity(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractVerbosity(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if v != tt.want || !slices.Equal(rest, tt.wantRest) {
+			t.Errorf("extractVerbosity(%q) = %v, %q, want %v, %q", tt.args, v, rest, tt.want, tt.wantRest)
+		}
+	}
+}
+
+func TestVerbositySetsLogLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		v    verbosity
+		want log.Level
+	}{
+		{"quiet", "", nil, verbosityQuiet, log.ErrorLevel},
+		{"verbose", "", nil, verbosityVerbose, log.DebugLevel},
+		{"verbose over the environment", "warn", nil, verbosityVerbose, log.DebugLevel},
+		{"log level flag over quiet", "", []string{"--log-level", "warn"}, verbosityQuiet, log.WarnLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(logLevelEnv, tt.env)
+			level, _, err := extractLogLevel(tt.args, tt.v)
+			if err != nil || level != tt.want {
+				t.Errorf("got %v, %v, want %v", level, err, tt.want)
+			}
+		})
+	}
+}
+
+// useVerbosity sets outputVerbosity for the rest of the test
+func useVerbosity(t *testing.T, v verbosity) {
+	previous := outputVerbosity
+	outputVerbosity = v
+	t.Cleanup(func() { outputVerbosity = previous })
+}
+
+func TestQuietSummaryShowsOnlyFailures(t *testing.T) {
+	useVerbosity(t, verbosityQuiet)
+	results := map[string]*RepoResult{
+		"local/team/alpha": {Name: "alpha", SCM: "local", Owner: "team", Cloned: true},
+		"local/team/beta":  {Name: "beta", SCM: "local", Owner: "team", Error: errors.New("clone failed")},
+	}
+	out := captureStdout(t, func() { printSummaryTable(results) })
+	if strings.Contains(out, "alpha") || !strings.Contains(out, "beta") {
+		t.Errorf("quiet summary printed:\n%s", out)
+	}
+}
+
+func TestQuietHidesProgress(t *testing.T) {
+	useVerbosity(t, verbosityQuiet)
+	var progress bytes.Buffer
+	previous := progressOutput
+	progressOutput = &progress
+	t.Cleanup(func() { progressOutput = previous })
+
+	processRepositories("local/team", []string{"alpha", "beta"}, nil, 2, func(repo string, w io.Writer, result *RepoResult) {
+		result.Cloned = true
+	})
+	if progress.Len() != 0 {
+		t.Errorf("quiet run printed progress %q", progress.String())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+// logFile is one log file in a logger's directory
+type logFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// pruneLogFiles applies the [logging] retention settings to every logger's
+// directory under ~/.ssot/gitspace/logs. Each run of gitspace and of each
+// plugin writes a new file, so this is what keeps the directory from growing
+// forever. The newest file of each logger is always kept, since it may be the
+// one being written to.
+func pruneLogFiles(logger *logger.RateLimitedLogger, config *Config) {
+	settings := config.Logging
+	if settings.MaxBackups == 0 && settings.MaxAgeDays == 0 && settings.MaxSizeMB == 0 {
+		return
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		logger.Warn("Failed to get cache directory, not pruning logs", "error", err)
+		return
+	}
+	logsDir := filepath.Join(cacheDir, "logs")
+	loggerDirs, err := os.ReadDir(logsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Failed to read logs directory", "path", logsDir, "error", err)
+		}
+		return
+	}
+
+	removed := 0
+	for _, dir := range loggerDirs {
+		if !dir.IsDir() {
+			continue
+		}
+		files, err := listLogFiles(filepath.Join(logsDir, dir.Name()))
+		if err != nil {
+			logger.Warn("Failed to list log files", "logger", dir.Name(), "error", err)
+			continue
+		}
+		for _, file := range expiredLogFiles(files, settings.MaxBackups, settings.MaxAgeDays, settings.MaxSizeMB, time.Now()) {
+			if err := os.Remove(file.path); err != nil {
+				logger.Warn("Failed to remove old log file", "path", file.path, "error", err)
+				continue
+			}
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		logger.Debug("Removed old log files", "count", removed)
+	}
+}
+
+// listLogFiles returns the .log files in dir, newest first
+func listLogFiles(dir string) ([]logFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []logFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{
+			path:    filepath.Join(dir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	return files, nil
+}
+
+// expiredLogFiles picks the files, sorted newest first, that fall outside the
+// retention limits. The newest file is never picked. A limit of 0 is ignored.
+func expiredLogFiles(files []logFile, maxBackups, maxAgeDays, maxSizeMB int, now time.Time) []logFile {
+	var expired []logFile
+	var total int64
+	for i, file := range files {
+		total += file.size
+		if i == 0 {
+			continue
+		}
+		if (maxBackups > 0 && i > maxBackups) ||
+			(maxAgeDays > 0 && now.Sub(file.modTime) > time.Duration(maxAgeDays)*24*time.Hour) ||
+			(maxSizeMB > 0 && total > int64(maxSizeMB)*1024*1024) {
+			expired = append(expired, file)
+		}
+	}
+	return expired
+}
@@ -6,20 +6,55 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/charmbracelet/log"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
 	"github.com/ssotops/gitspace/plugin"
 )
 
 func main() {
+	v, args, err := extractVerbosity(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	outputVerbosity = v
+
+	offline, args := extractOffline(args)
+	lib.SetOffline(offline)
+
+	level, args, err := extractLogLevel(args, v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	logLevel = level
+
+	profile, args, err := extractProfile(args)
+	if err == nil && profile != "" {
+		profileConfigPath, err = existingProfilePath(profile)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
 	mainLogger, err := logger.NewRateLimitedLogger("gitspace")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
 		os.Exit(1)
 	}
 
+	mainLogger.SetLogLevel(logLevel)
 	mainLogger.Info("Gitspace starting up")
-	mainLogger.SetLogLevel(log.DebugLevel)
+	if offline {
+		mainLogger.Info("Offline mode: cloning, syncing, upgrades and the Gitspace Catalog are disabled; cached repository lists are used as is")
+	}
+
+	// Any arguments besides --config select a non-interactive command
+	configPath, configSource, menuOnly := menuConfigPath(args)
+	if len(args) > 0 && !menuOnly {
+		os.Exit(runCLI(mainLogger, args))
+	}
 
 	var allLoggers []*logger.RateLimitedLogger
 	allLoggers = append(allLoggers, mainLogger)
@@ -27,19 +62,32 @@ func main() {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 
-	printWelcomeMessage()
+	if outputVerbosity != verbosityQuiet {
+		printWelcomeMessage()
+	}
 
 	// Initialize variables to track configuration state
 	var config *Config
 
-	// Try to load existing config
-	currentPath, err := getCurrentConfigPath(mainLogger)
-	if err != nil {
-		mainLogger.Warn("Error checking for existing config", "error", err)
-		// Continue to prompt user
+	// An explicit config is loaded as is; otherwise try the active config
+	var currentPath string
+	if configPath == "" {
+		currentPath, err = getCurrentConfigPath(mainLogger)
+		if err != nil {
+			mainLogger.Warn("Error checking for existing config", "error", err)
+			// Continue to prompt user
+		}
 	}
 
-	if currentPath == "" {
+	if configPath != "" {
+		config, err = loadConfig(configPath)
+		if err != nil {
+			mainLogger.Error("Failed to load config", "source", configSource, "path", configPath, "error", err)
+			fmt.Fprintf(os.Stderr, "%s: failed to load config %s: %v\n", configSource, configPath, err)
+			os.Exit(exitError)
+		}
+		mainLogger.Info("Loaded config", "source", configSource, "path", configPath)
+	} else if currentPath == "" {
 		mainLogger.Debug("No valid config found, prompting user")
 		config, err = getConfigFromUser(mainLogger)
 		if err != nil {
@@ -64,16 +112,20 @@ func main() {
 	// Only proceed with plugin initialization if we have a valid config
 	if config != nil {
 		mainLogger.Debug("Config loaded successfully", "config_path", config.Global.Path)
+		pruneLogFiles(mainLogger, config)
 
 		// Initialize the plugin manager
-		pluginManager := plugin.NewManager(mainLogger)
+		pluginManager := newPluginManager(mainLogger, config)
+		pluginEvents = pluginManager
 		err = pluginManager.DiscoverPlugins()
 		if err != nil {
 			mainLogger.Error("Failed to discover plugins", "error", err)
 		}
 
-		// Set up a deferred function to print the log summary
+		// Set up a deferred function to print the session and log summaries
 		defer func() {
+			finishSession(mainLogger, config, true)
+
 			// Add plugin loggers to allLoggers
 			for _, p := range pluginManager.GetLoadedPlugins() {
 				allLoggers = append(allLoggers, p.Logger)
@@ -97,7 +149,8 @@ func main() {
 		}
 	} else {
 		// If we have no config, still allow access to limited functionality
-		pluginManager := plugin.NewManager(mainLogger)
+		pluginManager := newPluginManager(mainLogger, config)
+		pluginEvents = pluginManager
 		defer func() {
 			for _, p := range pluginManager.GetLoadedPlugins() {
 				allLoggers = append(allLoggers, p.Logger)
@@ -131,6 +184,14 @@ func main() {
 	}
 }
 
+// newPluginManager creates the plugin manager with the config's plugin
+// settings applied, before anything can load a plugin through it
+func newPluginManager(logger *logger.RateLimitedLogger, config *Config) *plugin.Manager {
+	pluginManager := plugin.NewManager(logger)
+	configurePluginManager(pluginManager, config)
+	return pluginManager
+}
+
 func printConfigPath(config *Config) {
 	if config != nil && config.Global.Path != "" {
 		fmt.Printf("Current config path: %s\n\n", config.Global.Path)
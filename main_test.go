@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/mitchellh/go-homedir"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+func init() {
+	// Tests point HOME at their own directory, which a cached home would miss
+	homedir.DisableCache = true
+}
+
+// newTestLogger points HOME at a temporary directory, so the cache, index
+// and logs a test writes stay out of the real one, and returns a logger
+// writing there
+func newTestLogger(t *testing.T) *logger.RateLimitedLogger {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	l, err := logger.NewRateLimitedLogger("gitspace-test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	return l
+}
+
+// loadTestConfig writes text to a config file and loads it like any other
+func loadTestConfig(t *testing.T, text string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gitspace.toml")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	return config
+}
+
+var testSignature = object.Signature{Name: "gitspace", Email: "gitspace@example.com", When: time.Unix(1700000000, 0)}
+
+// initTestRepo creates a repository at path on branch with a single commit
+func initTestRepo(t *testing.T, path, branch string) (*git.Repository, plumbing.Hash) {
+	t.Helper()
+	r, err := git.PlainInitWithOptions(path, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName(branch)},
+	})
+	if err != nil {
+		t.Fatalf("failed to init repository: %v", err)
+	}
+	return r, commitTestFile(t, r, "README.md", "# "+filepath.Base(path)+"\n")
+}
+
+// commitTestFile writes name to r's worktree and commits it on the current branch
+func commitTestFile(t *testing.T, r *git.Repository, name, content string) plumbing.Hash {
+	t.Helper()
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.Filesystem.Root(), name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if _, err := w.Add(name); err != nil {
+		t.Fatalf("failed to add %s: %v", name, err)
+	}
+	hash, err := w.Commit("Update "+name, &git.CommitOptions{Author: &testSignature})
+	if err != nil {
+		t.Fatalf("failed to commit %s: %v", name, err)
+	}
+	return hash
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubTransport serves every request made through http.DefaultTransport with
+// handler for the rest of the test, so nothing reaches the network
+func stubTransport(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	previous := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = previous })
+}
+
+// captureStdout runs fn with os.Stdout redirected and returns what it wrote
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	defer func() { os.Stdout = previous }()
+	fn()
+	w.Close()
+	return <-output
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	gotoml "github.com/pelletier/go-toml"
+	tomlv2 "github.com/pelletier/go-toml/v2"
+)
+
+// legacyMatchVerbs are the match blocks of the old gitspace.hcl and JSON
+// configs, in the order their groups are written out
+var legacyMatchVerbs = []string{"isExactly", "startsWith", "endsWith", "includes"}
+
+// migratedConfig is the part of Config that legacy configs can express. It
+// is encoded instead of Config so the output only holds what was migrated.
+type migratedConfig struct {
+	Global struct {
+		Path  string `toml:"path"`
+		SCM   string `toml:"scm"`
+		Owner string `toml:"owner"`
+	} `toml:"global"`
+	Auth *struct {
+		Type    string `toml:"type,omitempty"`
+		KeyPath string `toml:"key_path,omitempty"`
+	} `toml:"auth,omitempty"`
+	Groups map[string]Group `toml:"groups,omitempty"`
+}
+
+// migrateLegacyConfig converts a legacy HCL or JSON config to TOML. The
+// returned problems are validation errors in the converted config, such as a
+// field the legacy file never had; they don't stop the migration.
+func migrateLegacyConfig(data []byte) ([]byte, []error, error) {
+	var doc map[string]interface{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse JSON config: %w", err)
+		}
+	} else {
+		parsed, err := parseLegacyHCL(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse HCL config: %w", err)
+		}
+		doc = parsed
+	}
+
+	migrated, err := convertLegacyConfig(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := tomlv2.Marshal(migrated)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode TOML: %w", err)
+	}
+
+	// Check the result the way loadConfig would
+	config := &Config{}
+	if err := gotoml.Unmarshal(out, config); err != nil {
+		return nil, nil, fmt.Errorf("migrated config doesn't load: %w", err)
+	}
+	return out, splitErrors(validateConfig(config)), nil
+}
+
+// convertLegacyConfig maps the decoded legacy document, which looks the same
+// whether it came from HCL or JSON, onto the current config layout:
+//
+//	gitspace {
+//	  path = "gs"
+//	  clone {
+//	    scm   = "github.com"
+//	    owner = "ssotops"
+//	    endsWith {
+//	      values = ["-plugin"]
+//	      repository { type = "gitspace-plugin", labels = ["feature"] }
+//	    }
+//	    group "bots" { isExactly = ["bots"] }
+//	  }
+//	}
+func convertLegacyConfig(doc map[string]interface{}) (*migratedConfig, error) {
+	root := doc
+	if gitspace, ok := legacyBlock(doc["gitspace"]); ok {
+		root = gitspace
+	}
+	clone, _ := legacyBlock(root["clone"])
+
+	migrated := &migratedConfig{Groups: make(map[string]Group)}
+	for _, block := range []map[string]interface{}{root, clone} {
+		if block == nil {
+			continue
+		}
+		if v := legacyString(block, "path"); v != "" {
+			migrated.Global.Path = v
+		}
+		if v := legacyString(block, "scm"); v != "" {
+			migrated.Global.SCM = v
+		}
+		if v := legacyString(block, "owner"); v != "" {
+			migrated.Global.Owner = v
+		}
+		if auth, ok := legacyBlock(block["auth"]); ok {
+			migrated.Auth = &struct {
+				Type    string `toml:"type,omitempty"`
+				KeyPath string `toml:"key_path,omitempty"`
+			}{
+				Type:    legacyString(auth, "type"),
+				KeyPath: legacyString(auth, "key_path", "keyPath"),
+			}
+		}
+
+		// Bare match blocks become groups named after their verb
+		for _, verb := range legacyMatchVerbs {
+			for i, value := range legacyList(block[verb]) {
+				group, err := legacyGroup(verb, value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", verb, err)
+				}
+				name := legacyGroupName(verb)
+				if i > 0 {
+					name = fmt.Sprintf("%s_%d", name, i+1)
+				}
+				migrated.Groups[name] = group
+			}
+		}
+
+		// group "name" { ... } keeps its name
+		if groups, ok := legacyBlock(block["group"]); ok {
+			for name, body := range groups {
+				group, err := legacyNamedGroup(body)
+				if err != nil {
+					return nil, fmt.Errorf("group %q: %w", name, err)
+				}
+				migrated.Groups[name] = group
+			}
+		}
+	}
+
+	if migrated.Global.SCM != "" {
+		if _, err := normalizeSCM(migrated.Global.SCM); err != nil {
+			return nil, err
+		}
+	}
+	return migrated, nil
+}
+
+// legacyGroup converts one match block: either a plain list of values, or a
+// block with values and an optional repository { type, labels }
+func legacyGroup(verb string, value interface{}) (Group, error) {
+	group := Group{Match: verb}
+	if values, ok := legacyStrings(value); ok {
+		group.Values = values
+		return group, nil
+	}
+
+	body, ok := legacyBlock(value)
+	if !ok {
+		return group, fmt.Errorf("expected a list of values or a block")
+	}
+	group.Values, _ = legacyStrings(body["values"])
+	applyLegacyRepository(&group, body)
+	if len(group.Values) == 0 {
+		return group, fmt.Errorf("no values")
+	}
+	return group, nil
+}
+
+// legacyNamedGroup converts a group "name" block, which holds either match
+// and values, or exactly one match verb
+func legacyNamedGroup(value interface{}) (Group, error) {
+	body, ok := legacyBlock(value)
+	if !ok {
+		return Group{}, fmt.Errorf("expected a block")
+	}
+
+	if match := legacyString(body, "match"); match != "" {
+		group := Group{Match: match}
+		group.Values, _ = legacyStrings(body["values"])
+		applyLegacyRepository(&group, body)
+		return group, nil
+	}
+
+	for _, verb := range legacyMatchVerbs {
+		if verbValue, ok := body[verb]; ok {
+			group, err := legacyGroup(verb, verbValue)
+			if err != nil {
+				return group, err
+			}
+			applyLegacyRepository(&group, body)
+			return group, nil
+		}
+	}
+	return Group{}, fmt.Errorf("no match verb (one of %s)", strings.Join(legacyMatchVerbs, ", "))
+}
+
+// applyLegacyRepository copies type and labels, from a nested repository
+// block or from the block itself
+func applyLegacyRepository(group *Group, body map[string]interface{}) {
+	source := body
+	if repository, ok := legacyBlock(body["repository"]); ok {
+		source = repository
+	}
+	if v := legacyString(source, "type"); v != "" {
+		group.Type = v
+	}
+	if labels, ok := legacyStrings(source["labels"]); ok {
+		group.Labels = labels
+	}
+}
+
+// legacyGroupName turns a match verb into a group name, e.g. starts_with
+func legacyGroupName(verb string) string {
+	var name strings.Builder
+	for _, r := range verb {
+		if unicode.IsUpper(r) {
+			name.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
+
+// legacyBlock returns value as a block. A block repeated in HCL decodes to a
+// list; only the first one is used.
+func legacyBlock(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case []interface{}:
+		if len(v) > 0 {
+			return legacyBlock(v[0])
+		}
+	}
+	return nil, false
+}
+
+// legacyList returns the repeated values of a key: a list of blocks, or a
+// single value
+func legacyList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		if _, ok := legacyStrings(v); ok {
+			return []interface{}{v}
+		}
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+func legacyString(block map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := block[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func legacyStrings(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// parseLegacyHCL parses the subset of HCL the old configs used: attributes,
+// strings, numbers, booleans, lists, and blocks with optional labels.
+// Blocks decode like JSON objects; group "bots" { ... } becomes
+// {"group": {"bots": {...}}}, and a repeated block becomes a list.
+func parseLegacyHCL(data []byte) (map[string]interface{}, error) {
+	p := &hclParser{src: []rune(string(data)), line: 1}
+	body, err := p.parseBody(false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return body, nil
+}
+
+type hclParser struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+func (p *hclParser) parseBody(nested bool) (map[string]interface{}, error) {
+	body := make(map[string]interface{})
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			if nested {
+				return nil, fmt.Errorf("unexpected end of file, missing }")
+			}
+			return body, nil
+		}
+		if p.src[p.pos] == '}' {
+			if !nested {
+				return nil, fmt.Errorf("unexpected }")
+			}
+			p.pos++
+			return body, nil
+		}
+		if p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+
+		if p.peek() == '=' || p.peek() == ':' {
+			p.pos++
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			body[key] = value
+			continue
+		}
+
+		// A block, with any labels before its opening brace
+		var labels []string
+		for p.peek() != '{' {
+			if p.pos >= len(p.src) {
+				return nil, fmt.Errorf("%s: expected = or {", key)
+			}
+			label, err := p.parseKey()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			labels = append(labels, label)
+			p.skipSpace()
+		}
+		p.pos++
+		block, err := p.parseBody(true)
+		if err != nil {
+			return nil, err
+		}
+		addHCLBlock(body, key, labels, block)
+	}
+}
+
+// addHCLBlock stores a block under its type and labels
+func addHCLBlock(body map[string]interface{}, key string, labels []string, block map[string]interface{}) {
+	if len(labels) > 0 {
+		inner, ok := body[key].(map[string]interface{})
+		if !ok {
+			inner = make(map[string]interface{})
+			body[key] = inner
+		}
+		addHCLBlock(inner, labels[0], labels[1:], block)
+		return
+	}
+
+	switch existing := body[key].(type) {
+	case nil:
+		body[key] = block
+	case []interface{}:
+		body[key] = append(existing, block)
+	default:
+		body[key] = []interface{}{existing, block}
+	}
+}
+
+// parseKey reads an identifier or a quoted string
+func (p *hclParser) parseKey() (string, error) {
+	if p.peek() == '"' {
+		return p.parseString()
+	}
+	start := p.pos
+	for p.pos < len(p.src) && (unicode.IsLetter(p.src[p.pos]) || unicode.IsDigit(p.src[p.pos]) || strings.ContainsRune("_-.", p.src[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", fmt.Errorf("unexpected %q", p.peek())
+	}
+	return string(p.src[start:p.pos]), nil
+}
+
+func (p *hclParser) parseValue() (interface{}, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '"':
+		return p.parseString()
+	case c == '[':
+		p.pos++
+		var list []interface{}
+		for {
+			p.skipSpace()
+			if p.peek() == ']' {
+				p.pos++
+				return list, nil
+			}
+			if p.peek() == ',' {
+				p.pos++
+				continue
+			}
+			if p.pos >= len(p.src) {
+				return nil, fmt.Errorf("unexpected end of file, missing ]")
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+	case c == '{':
+		p.pos++
+		return p.parseBody(true)
+	default:
+		word, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if n, err := strconv.ParseFloat(word, 64); err == nil {
+			return n, nil
+		}
+		return nil, fmt.Errorf("unexpected %q", word)
+	}
+}
+
+func (p *hclParser) parseString() (string, error) {
+	start := p.pos
+	p.pos++ // Opening quote
+	for p.pos < len(p.src) && p.src[p.pos] != '"' {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("unterminated string")
+	}
+	p.pos++ // Closing quote
+	s, err := strconv.Unquote(string(p.src[start:p.pos]))
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", string(p.src[start:p.pos]))
+	}
+	return s, nil
+}
+
+// skipSpace skips whitespace and #, // and /* */ comments
+func (p *hclParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case unicode.IsSpace(c):
+			p.pos++
+		case c == '#' || (c == '/' && p.at(1) == '/'):
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.at(1) == '*':
+			p.pos += 2
+			for p.pos < len(p.src) && !(p.src[p.pos] == '*' && p.at(1) == '/') {
+				if p.src[p.pos] == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+			p.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+func (p *hclParser) peek() rune {
+	return p.at(0)
+}
+
+func (p *hclParser) at(offset int) rune {
+	if p.pos+offset < len(p.src) {
+		return p.src[p.pos+offset]
+	}
+	return 0
+}
+
+// writeMigratedConfig writes the migrated TOML to path, refusing to replace
+// an existing file
+func writeMigratedConfig(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const legacyHCLConfig = `
+# The old layout
+gitspace {
+  path = "gs"
+  clone {
+    scm   = "github.com"
+    owner = "ssotops"
+    auth {
+      type     = "ssh"
+      key_path = "$SSH_KEY_PATH"
+    }
+    endsWith {
+      values = ["-plugin"]
+      repository { type = "gitspace-plugin", labels = ["feature"] }
+    }
+    startsWith = ["api-", "svc-"]
+    group "bots" { isExactly = ["bots"] } // A named group
+    /* Repeated blocks become numbered groups */
+    includes { values = ["lib"] }
+    includes { values = ["tool"] }
+  }
+}
+`
+
+const legacyJSONConfig = `{
+  "gitspace": {
+    "path": "gs",
+    "clone": {
+      "scm": "github.com",
+      "owner": "ssotops",
+      "auth": {"type": "ssh", "keyPath": "$SSH_KEY_PATH"},
+      "endsWith": {"values": ["-plugin"], "repository": {"type": "gitspace-plugin", "labels": ["feature"]}},
+      "startsWith": ["api-", "svc-"],
+      "group": {"bots": {"isExactly": ["bots"]}},
+      "includes": [{"values": ["lib"]}, {"values": ["tool"]}]
+    }
+  }
+}`
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	wantGroups := map[string]Group{
+		"ends_with":   {Match: "endsWith", Values: []string{"-plugin"}, Type: "gitspace-plugin", Labels: []string{"feature"}},
+		"starts_with": {Match: "startsWith", Values: []string{"api-", "svc-"}},
+		"bots":        {Match: "isExactly", Values: []string{"bots"}},
+		"includes":    {Match: "includes", Values: []string{"lib"}},
+		"includes_2":  {Match: "includes", Values: []string{"tool"}},
+	}
+	t.Setenv("SSH_KEY_PATH", "/keys/id_ed25519")
+	for name, legacy := range map[string]string{"hcl": legacyHCLConfig, "json": legacyJSONConfig} {
+		t.Run(name, func(t *testing.T) {
+			out, problems, err := migrateLegacyConfig([]byte(legacy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != 0 {
+				t.Errorf("got problems %v", problems)
+			}
+
+			path := filepath.Join(t.TempDir(), "gs.toml")
+			writeTestConfig(t, path, string(out))
+			config, err := loadConfig(path)
+			if err != nil {
+				t.Fatalf("migrated config doesn't load: %v\n%s", err, out)
+			}
+			if config.Global.Path != "gs" || config.Global.SCM != "github" || config.Global.Owner != "ssotops" {
+				t.Errorf("got global %+v", config.Global)
+			}
+			// Variables are left for loadConfig to expand
+			if !strings.Contains(string(out), "$SSH_KEY_PATH") {
+				t.Errorf("key_path lost its variable:\n%s", out)
+			}
+			if config.Auth.Type != "ssh" || config.Auth.KeyPath != "/keys/id_ed25519" {
+				t.Errorf("got auth %+v", config.Auth)
+			}
+			if len(config.Groups) != len(wantGroups) {
+				t.Errorf("got groups %v", config.Groups)
+			}
+			for name, want := range wantGroups {
+				got := config.Groups[name]
+				if got.Match != want.Match || !slices.Equal(got.Values, want.Values) || got.Type != want.Type || !slices.Equal(got.Labels, want.Labels) {
+					t.Errorf("group %s = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyConfigProblems(t *testing.T) {
+	// Converts fine, but the TOML needs an owner the legacy file never had
+	_, problems, err := migrateLegacyConfig([]byte(`path = "gs"
+scm = "gitlab"
+isExactly = ["app"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(problems, func(err error) bool { return strings.Contains(err.Error(), "global.owner is required") }) {
+		t.Errorf("got problems %v, want the missing owner", problems)
+	}
+
+	for name, legacy := range map[string]string{
+		"unsupported scm":      `scm = "svn"`,
+		"empty group":          `endsWith { values = [] }`,
+		"group without a verb": `group "x" { type = "y" }`,
+		"bad JSON":             `{"path": }`,
+	} {
+		if _, _, err := migrateLegacyConfig([]byte(legacy)); err == nil {
+			t.Errorf("%s: migrated without an error", name)
+		}
+	}
+}
+
+func TestParseLegacyHCL(t *testing.T) {
+	got, err := parseLegacyHCL([]byte(`
+name = "x" // comment
+count = 3
+enabled = true
+list = ["a", "b",]
+block "one" "two" { inner = "v" }
+repeat { n = 1 }
+repeat { n = 2 }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":    "x",
+		"count":   3.0,
+		"enabled": true,
+		"list":    []interface{}{"a", "b"},
+		"block":   map[string]interface{}{"one": map[string]interface{}{"two": map[string]interface{}{"inner": "v"}}},
+		"repeat":  []interface{}{map[string]interface{}{"n": 1.0}, map[string]interface{}{"n": 2.0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v", got)
+	}
+
+	errs := []struct {
+		src, want string
+	}{
+		{"a {\n  b = 1\n", "missing }"},
+		{"a = \"open\n", "line 1: a: unterminated string"},
+		{"\n\n}", "line 3: unexpected }"},
+		{"a = [1, 2", "missing ]"},
+		{"a = nope", `unexpected "nope"`},
+	}
+	for _, tt := range errs {
+		if _, err := parseLegacyHCL([]byte(tt.src)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseLegacyHCL(%q) got error %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestConfigMigrateCommand(t *testing.T) {
+	logger := newTestLogger(t)
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "gitspace.hcl")
+	writeTestConfig(t, legacy, legacyHCLConfig)
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI(logger, []string{"config", "migrate", legacy}) })
+	if code != exitOK || !strings.Contains(out, "[global]") {
+		t.Errorf("exit %d, printed %q", code, out)
+	}
+
+	target := filepath.Join(dir, "gs.toml")
+	if code := runCLI(logger, []string{"config", "migrate", legacy, "--out", target}); code != exitOK {
+		t.Fatalf("--out exited %d", code)
+	}
+	written, err := os.ReadFile(target)
+	if err != nil || string(written) != out {
+		t.Errorf("--out wrote %q, %v, want what stdout got", written, err)
+	}
+	// An existing file is never replaced
+	if code := runCLI(logger, []string{"config", "migrate", legacy, "--out", target}); code != exitError {
+		t.Errorf("migrating over an existing file exited %d, want %d", code, exitError)
+	}
+
+	if code := runCLI(logger, []string{"config", "migrate"}); code != exitUsage {
+		t.Errorf("migrate without a file exited %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// offlineEnv turns on offline mode like --offline when set to a true value
+const offlineEnv = "GITSPACE_OFFLINE"
+
+// extractOffline removes --offline from args and reports whether offline
+// mode is on, from the flag or GITSPACE_OFFLINE
+func extractOffline(args []string) (bool, []string) {
+	offline, _ := strconv.ParseBool(os.Getenv(offlineEnv))
+	var rest []string
+	for _, arg := range args {
+		if arg == "--offline" || arg == "-offline" {
+			offline = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return offline, rest
+}
+
+// requireNetwork fails in offline mode unless scmType works without the
+// network, as local repositories do
+func requireNetwork(scmType lib.SCMType, operation string) error {
+	if lib.Offline() && scmType != lib.SCMTypeLocal {
+		return fmt.Errorf("%s %s repositories: %w", operation, scmType, lib.ErrOffline)
+	}
+	return nil
+}
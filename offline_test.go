@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// useOffline turns offline mode on for the test and fails it on any HTTP
+// request made through http.DefaultTransport
+func useOffline(t *testing.T) {
+	t.Helper()
+	lib.SetOffline(true)
+	t.Cleanup(func() { lib.SetOffline(false) })
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("offline mode requested %s", r.URL)
+		http.Error(w, "offline", http.StatusServiceUnavailable)
+	})
+}
+
+func TestExtractOffline(t *testing.T) {
+	tests := []struct {
+		env      string
+		args     []string
+		want     bool
+		wantRest []string
+	}{
+		{"", []string{"sync", "--offline"}, true, []string{"sync"}},
+		{"", []string{"-offline", "status"}, true, []string{"status"}},
+		{"", []string{"sync"}, false, []string{"sync"}},
+		{"1", []string{"sync"}, true, []string{"sync"}},
+		{"true", nil, true, nil},
+		{"0", []string{"sync"}, false, []string{"sync"}},
+		{"maybe", []string{"sync"}, false, []string{"sync"}},
+	}
+	for _, tt := range tests {
+		t.Setenv(offlineEnv, tt.env)
+		got, rest := extractOffline(tt.args)
+		if got != tt.want || strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+			t.Errorf("extractOffline(%v) with %s=%q = %v, %v", tt.args, offlineEnv, tt.env, got, rest)
+		}
+	}
+}
+
+func TestOfflineReleaseLookups(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	useOffline(t)
+
+	if err := upgradeGitspace(logger, "", alwaysConfirm); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("upgrade returned %v, want %v", err, lib.ErrOffline)
+	}
+	if code := runCLI(logger, []string{"--check-update"}); code != exitError {
+		t.Errorf("--check-update exited %d, want %d", code, exitError)
+	}
+	if _, err := fetchReleases("ssotops/gitspace"); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("listing releases returned %v", err)
+	}
+
+	printVersionInfo(logger)
+	log, err := os.ReadFile(logger.GetLogFileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "skipping the latest version check") {
+		t.Errorf("version info didn't say it skipped the check:\n%s", log)
+	}
+}
+
+func TestOfflineRepositoryListUsesCache(t *testing.T) {
+	logger := newTestLogger(t)
+	useOffline(t)
+	config := &Config{}
+	config.Global.SCM = "github"
+	config.Global.Owner = "acme"
+
+	_, err := getRepositoryList(context.Background(), logger, config, lib.SCMTypeGitHub, "", false)
+	if !errors.Is(err, lib.ErrOffline) || !strings.Contains(err.Error(), "run once without --offline") {
+		t.Errorf("without a cache got %v, want to be told to run online", err)
+	}
+
+	cachePath, err := getRepoListCachePath(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRepoListCache(cachePath, config.Global.Visibility, []lib.RepoInfo{{Name: "api"}}); err != nil {
+		t.Fatal(err)
+	}
+	repos, err := getRepositoryList(context.Background(), logger, config, lib.SCMTypeGitHub, "", false)
+	if err != nil || len(repos) != 1 || repos[0].Name != "api" {
+		t.Errorf("got %v, %v, want the cached list", repos, err)
+	}
+	if _, err := getRepositoryList(context.Background(), logger, config, lib.SCMTypeGitHub, "", true); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("a forced refresh returned %v, want %v", err, lib.ErrOffline)
+	}
+}
+
+func TestOfflineClone(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	useOffline(t)
+
+	// Local repositories need no network
+	config := localCloneConfig(t, "", "alpha")
+	results, err := cloneRepositories(logger, config, nil)
+	if err != nil || results["local/team/alpha"] == nil || !results["local/team/alpha"].Cloned {
+		t.Errorf("offline clone of a local repository: %+v, %v", results, err)
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	github := config.forTarget(CloneTarget{SCM: "github", Owner: "acme"})
+	if _, err := cloneTargetRepositories(logger, github, cacheDir, nil, "", nil); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("offline clone from GitHub returned %v, want %v", err, lib.ErrOffline)
+	}
+	if _, err := syncTargetRepositories(logger, github, cacheDir, nil, syncOptions{}, nil); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("offline sync from GitHub returned %v, want %v", err, lib.ErrOffline)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Repository statuses shared by the pretty printer and the serializers
+const (
+	statusFailed    = "failed"
+	statusCloned    = "cloned"
+	statusUpdated   = "updated"
+	statusSkipped   = "skipped"
+	statusUnchanged = "unchanged"
+)
+
+// Output formats for command results
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// resultsSummary holds the counts reported after cloning or syncing
+type resultsSummary struct {
+	LinkMode       string `json:"link_mode,omitempty" yaml:"link_mode,omitempty"`
+	Total          int    `json:"total" yaml:"total"`
+	Cloned         int    `json:"cloned" yaml:"cloned"`
+	Updated        int    `json:"updated" yaml:"updated"`
+	Failed         int    `json:"failed" yaml:"failed"`
+	Skipped        int    `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	UpToDate       int    `json:"up_to_date,omitempty" yaml:"up_to_date,omitempty"`
+	LocalSymlinks  int    `json:"local_symlinks" yaml:"local_symlinks"`
+	GlobalSymlinks int    `json:"global_symlinks" yaml:"global_symlinks"`
+	HooksFailed    int    `json:"hooks_failed,omitempty" yaml:"hooks_failed,omitempty"`
+	LFSPulled      int    `json:"lfs_pulled,omitempty" yaml:"lfs_pulled,omitempty"`
+	LFSFailed      int    `json:"lfs_failed,omitempty" yaml:"lfs_failed,omitempty"`
+}
+
+// repoReport is the serialized form of a RepoResult
+type repoReport struct {
+	Name          string `json:"name" yaml:"name"`
+	SCM           string `json:"scm" yaml:"scm"`
+	Owner         string `json:"owner" yaml:"owner"`
+	Status        string `json:"status" yaml:"status"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+	Retries       int    `json:"retries,omitempty" yaml:"retries,omitempty"`
+	FastForwarded bool   `json:"fast_forwarded,omitempty" yaml:"fast_forwarded,omitempty"`
+	DirtyAction   string `json:"dirty_action,omitempty" yaml:"dirty_action,omitempty"`
+	UpToDate      bool   `json:"up_to_date,omitempty" yaml:"up_to_date,omitempty"`
+	PinnedCommit  string `json:"pinned_commit,omitempty" yaml:"pinned_commit,omitempty"`
+	Submodules    bool   `json:"submodules_updated,omitempty" yaml:"submodules_updated,omitempty"`
+	LocalSymlink  string `json:"local_symlink,omitempty" yaml:"local_symlink,omitempty"`
+	GlobalSymlink string `json:"global_symlink,omitempty" yaml:"global_symlink,omitempty"`
+	Hook          string `json:"hook,omitempty" yaml:"hook,omitempty"`
+	HookError     string `json:"hook_error,omitempty" yaml:"hook_error,omitempty"`
+	LFS           string `json:"lfs,omitempty" yaml:"lfs,omitempty"`
+	LFSError      string `json:"lfs_error,omitempty" yaml:"lfs_error,omitempty"`
+}
+
+// resultsReport is the document written by --output json/yaml
+type resultsReport struct {
+	Repositories []repoReport   `json:"repositories" yaml:"repositories"`
+	Summary      resultsSummary `json:"summary" yaml:"summary"`
+}
+
+func resultStatus(result *RepoResult) string {
+	switch {
+	case result.Error != nil:
+		return statusFailed
+	case result.DirtyAction == dirtySkipped:
+		return statusSkipped
+	case result.Cloned:
+		return statusCloned
+	case result.Updated:
+		return statusUpdated
+	default:
+		return statusUnchanged
+	}
+}
+
+func summarizeResults(results map[string]*RepoResult) resultsSummary {
+	summary := resultsSummary{Total: len(results)}
+	for _, result := range results {
+		summary.LinkMode = result.LinkMode
+		switch resultStatus(result) {
+		case statusFailed:
+			summary.Failed++
+		case statusCloned:
+			summary.Cloned++
+		case statusUpdated:
+			summary.Updated++
+		case statusSkipped:
+			summary.Skipped++
+		}
+		if result.LocalSymlink != "" {
+			summary.LocalSymlinks++
+		}
+		if result.GlobalSymlink != "" {
+			summary.GlobalSymlinks++
+		}
+		if result.UpToDate {
+			summary.UpToDate++
+		}
+		if result.Hook == hookFailed {
+			summary.HooksFailed++
+		}
+		switch result.LFS {
+		case lfsOK:
+			summary.LFSPulled++
+		case lfsFailed:
+			summary.LFSFailed++
+		}
+	}
+	return summary
+}
+
+// sortedResults returns the results grouped by scm/owner, then by name
+func sortedResults(results map[string]*RepoResult) []*RepoResult {
+	sorted := make([]*RepoResult, 0, len(results))
+	for _, result := range results {
+		sorted = append(sorted, result)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key() < sorted[j].Key()
+	})
+	return sorted
+}
+
+func buildResultsReport(results map[string]*RepoResult) resultsReport {
+	report := resultsReport{
+		Repositories: []repoReport{},
+		Summary:      summarizeResults(results),
+	}
+	for _, result := range sortedResults(results) {
+		entry := repoReport{
+			Name:          result.Name,
+			SCM:           result.SCM,
+			Owner:         result.Owner,
+			Status:        resultStatus(result),
+			Retries:       result.Retries,
+			FastForwarded: result.FastForwarded,
+			DirtyAction:   result.DirtyAction,
+			UpToDate:      result.UpToDate,
+			PinnedCommit:  result.PinnedCommit,
+			Submodules:    result.Submodules,
+			LocalSymlink:  result.LocalSymlink,
+			GlobalSymlink: result.GlobalSymlink,
+			Hook:          result.Hook,
+			LFS:           result.LFS,
+		}
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+		if result.LFSError != nil {
+			entry.LFSError = result.LFSError.Error()
+		}
+		if result.HookError != nil {
+			entry.HookError = result.HookError.Error()
+		}
+		report.Repositories = append(report.Repositories, entry)
+	}
+	return report
+}
+
+// writeResults writes the results in the given format; text renders the
+// styled summary table
+func writeResults(w io.Writer, format string, results map[string]*RepoResult) error {
+	switch format {
+	case outputText:
+		printSummaryTable(results)
+		return nil
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(buildResultsReport(results))
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(buildResultsReport(results)); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
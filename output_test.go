@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testResults are a clone run's results covering each status
+func testResults() map[string]*RepoResult {
+	return map[string]*RepoResult{
+		"github/acme/web":   {Name: "web", SCM: "github", Owner: "acme", Updated: true, FastForwarded: true, LocalSymlink: "/work/web"},
+		"github/acme/api":   {Name: "api", SCM: "github", Owner: "acme", Cloned: true, Retries: 2, LocalSymlink: "/work/api", GlobalSymlink: "/cache/github/acme/api"},
+		"github/acme/old":   {Name: "old", SCM: "github", Owner: "acme", Error: errors.New("clone failed")},
+		"gitea/team/tools":  {Name: "tools", SCM: "gitea", Owner: "team", DirtyAction: dirtySkipped},
+		"github/acme/quiet": {Name: "quiet", SCM: "github", Owner: "acme", UpToDate: true},
+	}
+}
+
+func TestBuildResultsReport(t *testing.T) {
+	report := buildResultsReport(testResults())
+
+	var order, statuses []string
+	for _, repo := range report.Repositories {
+		order = append(order, repo.SCM+"/"+repo.Owner+"/"+repo.Name)
+		statuses = append(statuses, repo.Status)
+	}
+	wantOrder := []string{"gitea/team/tools", "github/acme/api", "github/acme/old", "github/acme/quiet", "github/acme/web"}
+	if !slices.Equal(order, wantOrder) {
+		t.Errorf("repositories in order %v, want %v", order, wantOrder)
+	}
+	wantStatuses := []string{statusSkipped, statusCloned, statusFailed, statusUnchanged, statusUpdated}
+	if !slices.Equal(statuses, wantStatuses) {
+		t.Errorf("statuses %v, want %v", statuses, wantStatuses)
+	}
+	if report.Repositories[2].Error != "clone failed" {
+		t.Errorf("error %q, want the clone error", report.Repositories[2].Error)
+	}
+
+	want := resultsSummary{Total: 5, Cloned: 1, Updated: 1, Failed: 1, Skipped: 1, UpToDate: 1, LocalSymlinks: 2, GlobalSymlinks: 1}
+	if report.Summary != want {
+		t.Errorf("summary %+v, want %+v", report.Summary, want)
+	}
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputJSON, testResults()); err != nil {
+		t.Fatal(err)
+	}
+	var decoded resultsReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(decoded.Repositories) != 5 || decoded.Summary.Total != 5 {
+		t.Errorf("decoded %+v, want five repositories", decoded)
+	}
+
+	// An empty run is an empty list, not null, so scripts can iterate it
+	buf.Reset()
+	if err := writeResults(&buf, outputJSON, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"repositories": []`) {
+		t.Errorf("empty results encoded as %s", buf.String())
+	}
+}
+
+func TestWriteResultsYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, outputYAML, testResults()); err != nil {
+		t.Fatal(err)
+	}
+	var decoded resultsReport
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid YAML %q: %v", buf.String(), err)
+	}
+	if len(decoded.Repositories) != 5 || decoded.Repositories[1].Retries != 2 {
+		t.Errorf("decoded %+v, want five repositories with api's retries", decoded)
+	}
+}
+
+func TestWriteResultsRejectsUnknownFormat(t *testing.T) {
+	if err := writeResults(&bytes.Buffer{}, "xml", testResults()); err == nil {
+		t.Error("xml output was accepted")
+	}
+}
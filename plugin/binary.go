@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// binaryFor returns the manifest's pre-built binary for goos/goarch, if any
+func (m *PluginManifest) binaryFor(goos, goarch string) (BinaryAsset, bool) {
+	asset, ok := m.Binaries[goos+"/"+goarch]
+	return asset, ok && asset.URL != ""
+}
+
+// downloadPluginBinary downloads a pre-built binary to path. The binary is
+// checked against its SHA-256 before it replaces anything at path.
+func downloadPluginBinary(asset BinaryAsset, path string, timeout time.Duration) error {
+	want := strings.ToLower(strings.TrimPrefix(asset.SHA256, "sha256:"))
+	if want == "" {
+		return fmt.Errorf("pre-built binary %s has no sha256 in the manifest", asset.URL)
+	}
+
+	ctx, cancel := lib.WithNetworkTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid binary URL %s: %w", asset.URL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", asset.URL, lib.TimeoutError(err, "downloading the plugin binary", timeout))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", asset.URL, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gitspace-binary-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", asset.URL, lib.TimeoutError(err, "downloading the plugin binary", timeout))
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("%s doesn't match the manifest checksum (expected %s, got %s)", asset.URL, want, got)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to make plugin executable: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save plugin binary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBinary = "#!/bin/sh\n"
+
+// serveTestBinary serves testBinary at /plugin and a 404 everywhere else
+func serveTestBinary(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plugin" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, testBinary)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testBinarySum() string {
+	sum := sha256.Sum256([]byte(testBinary))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBinaryFor(t *testing.T) {
+	manifest := &PluginManifest{Binaries: map[string]BinaryAsset{
+		"linux/amd64":  {URL: "https://example.com/linux", SHA256: "abc"},
+		"darwin/arm64": {SHA256: "abc"},
+	}}
+	if asset, ok := manifest.binaryFor("linux", "amd64"); !ok || asset.URL != "https://example.com/linux" {
+		t.Errorf("linux/amd64 = %+v, %v", asset, ok)
+	}
+	if _, ok := manifest.binaryFor("darwin", "arm64"); ok {
+		t.Error("an asset without a URL was used")
+	}
+	if _, ok := manifest.binaryFor("windows", "amd64"); ok {
+		t.Error("found a binary for an unlisted platform")
+	}
+}
+
+func TestDownloadPluginBinary(t *testing.T) {
+	server := serveTestBinary(t)
+	tests := []struct {
+		name    string
+		asset   BinaryAsset
+		wantErr string
+	}{
+		{"verified", BinaryAsset{URL: server.URL + "/plugin", SHA256: "sha256:" + testBinarySum()}, ""},
+		{"no checksum", BinaryAsset{URL: server.URL + "/plugin"}, "has no sha256"},
+		{"checksum mismatch", BinaryAsset{URL: server.URL + "/plugin", SHA256: strings.Repeat("0", 64)}, "doesn't match the manifest checksum"},
+		{"not found", BinaryAsset{URL: server.URL + "/missing", SHA256: testBinarySum()}, "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "hello")
+			if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := downloadPluginBinary(tt.asset, path, time.Second)
+
+			data, _ := os.ReadFile(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != testBinary {
+					t.Errorf("downloaded %q", data)
+				}
+				if info, _ := os.Stat(path); info.Mode().Perm()&0111 == 0 {
+					t.Error("the binary isn't executable")
+				}
+			} else {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				if string(data) != "old" {
+					t.Errorf("a failed download replaced the binary with %q", data)
+				}
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("left %d files behind, want only the binary", len(entries))
+			}
+		})
+	}
+}
+
+func TestInstallPluginFromPrebuiltBinary(t *testing.T) {
+	m := newTestManager(t)
+	server := serveTestBinary(t)
+	// The binary path mustn't need a Go toolchain
+	stubGo(t, "", "")
+
+	source := t.TempDir()
+	manifest := fmt.Sprintf("[metadata]\nname = \"hello\"\n\n[binaries.%q]\nurl = %q\nsha256 = %q\n",
+		runtime.GOOS+"/"+runtime.GOARCH, server.URL+"/plugin", testBinarySum())
+	if err := os.WriteFile(filepath.Join(source, manifestFileName), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InstallPlugin(m.logger, m, source); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	pluginsDir, _ := getPluginsDir()
+	installed := filepath.Join(pluginsDir, "hello", "hello")
+	if data, err := os.ReadFile(installed); err != nil || string(data) != testBinary {
+		t.Errorf("installed %q, %v", data, err)
+	}
+	meta, err := ReadPluginMeta("hello")
+	if err != nil || meta.SHA256 != testBinarySum() || meta.SourceType != SourceTypeLocal {
+		t.Errorf("plugin meta %+v, %v", meta, err)
+	}
+	if m.discoveredPlugins["hello"] != installed {
+		t.Errorf("the installed plugin wasn't discovered: %v", m.discoveredPlugins)
+	}
+}
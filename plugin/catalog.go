@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// Kinds of catalog entries
+const (
+	catalogKindPlugin   = "plugin"
+	catalogKindTemplate = "template"
+)
+
+// CatalogEntry is a plugin or template listed in the Gitspace Catalog
+type CatalogEntry struct {
+	Kind        string
+	Name        string
+	Version     string
+	Description string
+	Tags        []string
+	Path        string
+}
+
+// IsTemplate reports whether the entry is a template rather than a plugin
+func (e CatalogEntry) IsTemplate() bool {
+	return e.Kind == catalogKindTemplate
+}
+
+// catalogEntries lists the catalog's plugins and then its templates, each
+// sorted by name
+func catalogEntries(catalog *lib.Catalog) []CatalogEntry {
+	var plugins, templates []CatalogEntry
+	for name, p := range catalog.Plugins {
+		plugins = append(plugins, CatalogEntry{catalogKindPlugin, name, p.Version, p.Description, p.Tags, p.Path})
+	}
+	for name, t := range catalog.Templates {
+		templates = append(templates, CatalogEntry{catalogKindTemplate, name, t.Version, t.Description, t.Tags, t.Path})
+	}
+	byName := func(entries []CatalogEntry) {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	}
+	byName(plugins)
+	byName(templates)
+	return append(plugins, templates...)
+}
+
+// filterCatalog keeps the entries matching every word of query, ignoring
+// case. A word matches part of an entry's name or the start of one of its
+// tags; a word like tag:go only matches tags.
+func filterCatalog(entries []CatalogEntry, query string) []CatalogEntry {
+	words := strings.Fields(strings.ToLower(query))
+	var matched []CatalogEntry
+	for _, entry := range entries {
+		if matchesAll(entry, words) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+func matchesAll(entry CatalogEntry, words []string) bool {
+	for _, word := range words {
+		tag, tagOnly := strings.CutPrefix(word, "tag:")
+		found := !tagOnly && strings.Contains(strings.ToLower(entry.Name), word)
+		for _, t := range entry.Tags {
+			if strings.HasPrefix(strings.ToLower(t), tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// catalogOptions renders entries as select options with aligned name,
+// version and description columns, grouped under plugin and template headings
+func catalogOptions(entries []CatalogEntry) []huh.Option[int] {
+	nameWidth, versionWidth := 0, 0
+	for _, entry := range entries {
+		nameWidth = max(nameWidth, len(entry.Name))
+		versionWidth = max(versionWidth, len(entry.Version))
+	}
+
+	options := make([]huh.Option[int], 0, len(entries))
+	for i, entry := range entries {
+		label := fmt.Sprintf("%-10s %-*s  %-*s  %s", "["+entry.Kind+"]", nameWidth, entry.Name, versionWidth, entry.Version, entry.Description)
+		if len(entry.Tags) > 0 {
+			label += " (" + strings.Join(entry.Tags, ", ") + ")"
+		}
+		options = append(options, huh.NewOption(strings.TrimRight(label, " "), i))
+	}
+	return options
+}
+
+// installCatalogTemplate downloads a catalog template into a new directory
+// the user picks, through the same download as catalog plugins
+func installCatalogTemplate(logger *logger.RateLimitedLogger, entry CatalogEntry, timeout time.Duration) error {
+	dest := entry.Name
+	err := huh.NewInput().
+		Title(fmt.Sprintf("Directory to create for template %s", entry.Name)).
+		Value(&dest).
+		Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("enter a directory")
+			}
+			if _, err := os.Stat(strings.TrimSpace(s)); err == nil {
+				return fmt.Errorf("%s already exists", s)
+			}
+			return nil
+		}).
+		Run()
+	if err != nil {
+		return err
+	}
+	dest, err = filepath.Abs(strings.TrimSpace(dest))
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// Download next to the destination, so it only appears once complete
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+	tempDir, err := os.MkdirTemp(filepath.Dir(dest), ".gitspace-template-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := downloadFromGitspaceCatalog(logger, catalogURL(entry.Path), tempDir, timeout); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempDir, 0755); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", dest, err)
+	}
+	if err := os.Rename(tempDir, dest); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+
+	logger.Info("Template installed", "name", entry.Name, "path", dest)
+	return nil
+}
@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// testCatalog has two plugins and two templates
+func testCatalog() *lib.Catalog {
+	return &lib.Catalog{
+		Plugins: map[string]lib.Plugin{
+			"scmtea": {Version: "1.0.0", Description: "Gitea tools", Tags: []string{"scm", "gitea"}, Path: "plugins/scmtea"},
+			"hello":  {Version: "0.1.0", Description: "Says hello", Tags: []string{"example"}, Path: "plugins/hello"},
+		},
+		Templates: map[string]lib.Template{
+			"go-service": {Version: "2.0.0", Description: "A Go service", Tags: []string{"go", "service"}, Path: "templates/go-service"},
+			"docs-site":  {Description: "A docs site", Path: "templates/docs-site"},
+		},
+	}
+}
+
+// entryNames returns the names of entries, in order
+func entryNames(entries []CatalogEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+	return names
+}
+
+func TestCatalogEntries(t *testing.T) {
+	entries := catalogEntries(testCatalog())
+	want := []string{"hello", "scmtea", "docs-site", "go-service"}
+	if got := entryNames(entries); !slices.Equal(got, want) {
+		t.Fatalf("entries %v, want plugins then templates by name %v", got, want)
+	}
+	if entries[1].IsTemplate() || !entries[3].IsTemplate() {
+		t.Errorf("kinds %q and %q", entries[1].Kind, entries[3].Kind)
+	}
+	if entries[3].Path != "templates/go-service" || entries[3].Version != "2.0.0" {
+		t.Errorf("template entry %+v", entries[3])
+	}
+}
+
+func TestFilterCatalog(t *testing.T) {
+	entries := catalogEntries(testCatalog())
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"hello", "scmtea", "docs-site", "go-service"}},
+		{"HEL", []string{"hello"}},
+		{"s", []string{"scmtea", "docs-site", "go-service"}},
+		{"go", []string{"go-service"}},
+		{"gi", []string{"scmtea"}},
+		{"tag:go", []string{"go-service"}},
+		{"tag:service go", []string{"go-service"}},
+		{"tag:tea", nil},
+		{"hello tag:scm", nil},
+	}
+	for _, tt := range tests {
+		if got := entryNames(filterCatalog(entries, tt.query)); !slices.Equal(got, tt.want) {
+			t.Errorf("filterCatalog(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogOptionsFollowFilter(t *testing.T) {
+	entries := catalogEntries(testCatalog())
+	all := catalogOptions(entries)
+	filtered := catalogOptions(filterCatalog(entries, "tag:scm"))
+	if len(all) != 4 || len(filtered) != 1 {
+		t.Fatalf("%d options unfiltered and %d filtered, want 4 and 1", len(all), len(filtered))
+	}
+
+	label := filtered[0].Key
+	for _, column := range []string{"[plugin]", "scmtea", "1.0.0", "Gitea tools", "(scm, gitea)"} {
+		if !strings.Contains(label, column) {
+			t.Errorf("option %q is missing %q", label, column)
+		}
+	}
+	if filtered[0].Value != 0 {
+		t.Errorf("option value %d, want the index into the filtered entries", filtered[0].Value)
+	}
+	// Names are padded so the version column lines up
+	if strings.Index(all[0].Key, "0.1.0") != strings.Index(all[3].Key, "2.0.0") {
+		t.Errorf("versions don't line up:\n%s\n%s", all[0].Key, all[3].Key)
+	}
+	if strings.HasSuffix(all[2].Key, " ") {
+		t.Errorf("option %q has trailing spaces", all[2].Key)
+	}
+}
+
+// serveTestCatalog answers GitHub contents API requests for files, a map of
+// catalog repository paths to contents, and any other URL from others. Every
+// request goes through http.DefaultTransport, which it replaces for the test.
+func serveTestCatalog(t *testing.T, files map[string]string, others map[string]string) {
+	t.Helper()
+	const contents = "/repos/" + catalogOwner + "/" + catalogRepo + "/contents/"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if body, ok := others[r.URL.String()]; ok {
+			io.WriteString(w, body)
+			return
+		}
+		path, ok := strings.CutPrefix(r.URL.Path, contents)
+		if r.URL.Host != "api.github.com" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", fmt.Sprintf("%q", path))
+		if content, ok := files[path]; ok {
+			fmt.Fprintf(w, `{"type": "file", "name": %q, "path": %q, "encoding": "base64", "content": %q, "size": %d}`,
+				filepath.Base(path), path, base64.StdEncoding.EncodeToString([]byte(content)), len(content))
+			return
+		}
+		var listing []string
+		for file := range files {
+			if filepath.Dir(file) == path {
+				listing = append(listing, fmt.Sprintf(`{"type": "file", "name": %q, "path": %q}`, filepath.Base(file), file))
+			}
+		}
+		if listing == nil {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "["+strings.Join(listing, ",")+"]")
+	}
+
+	previous := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = previous })
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestInstallFromCatalog(t *testing.T) {
+	m := newTestManager(t)
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	const binary = "#!/bin/sh\n"
+	sum := sha256.Sum256([]byte(binary))
+	manifest := fmt.Sprintf("[metadata]\nname = \"hello\"\nversion = \"0.1.0\"\n\n[binaries.%q]\nurl = \"https://example.com/hello\"\nsha256 = %q\n",
+		runtime.GOOS+"/"+runtime.GOARCH, hex.EncodeToString(sum[:]))
+	serveTestCatalog(t,
+		map[string]string{"plugins/hello/" + manifestFileName: manifest},
+		map[string]string{"https://example.com/hello": binary})
+
+	if err := InstallPlugin(m.logger, m, catalogURL("plugins/hello")); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	pluginsDir, _ := getPluginsDir()
+	if data, err := os.ReadFile(filepath.Join(pluginsDir, "hello", "hello")); err != nil || string(data) != binary {
+		t.Errorf("installed binary %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(pluginsDir, "data", "hello", manifestFileName)); err != nil {
+		t.Errorf("the manifest wasn't kept with the plugin: %v", err)
+	}
+	meta, err := ReadPluginMeta("hello")
+	if err != nil || meta.SourceType != SourceTypeCatalog || meta.Version != "0.1.0" {
+		t.Errorf("plugin meta %+v, %v", meta, err)
+	}
+	// The catalog download goes through the provider cached under the home directory
+	home, _ := os.UserHomeDir()
+	if entries, _ := os.ReadDir(filepath.Join(home, ".ssot", "gitspace", ".cache", "catalog")); len(entries) == 0 {
+		t.Error("the catalog download wasn't cached")
+	}
+}
+
+func TestCatalogPluginSource(t *testing.T) {
+	newTestManager(t)
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	serveTestCatalog(t, map[string]string{
+		"gitspace-catalog.toml": "[plugins.hello]\npath = \"plugins/hello\"\n",
+	}, nil)
+
+	source, err := catalogPluginSource("hello", time.Minute)
+	if err != nil || source != catalogURL("plugins/hello") {
+		t.Errorf("catalogPluginSource(hello) = %q, %v", source, err)
+	}
+	if _, err := catalogPluginSource("missing", time.Minute); err == nil || !strings.Contains(err.Error(), "isn't in the Gitspace Catalog") {
+		t.Errorf("got error %v for a plugin missing from the catalog", err)
+	}
+}
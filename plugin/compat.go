@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const sdkModulePath = "github.com/ssotops/gitspace-plugin-sdk"
+
+// HostSDKVersion returns the plugin SDK version gitspace was built with, or
+// "" when the build carries no module information
+func HostSDKVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return sdkVersionFromDeps(info.Deps)
+}
+
+// PluginSDKVersion reads the plugin SDK version a plugin binary was built
+// with from its embedded Go build information. It returns "" for binaries
+// that aren't Go or were built without module information.
+func PluginSDKVersion(path string) string {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return sdkVersionFromDeps(info.Deps)
+}
+
+func sdkVersionFromDeps(deps []*debug.Module) string {
+	for _, dep := range deps {
+		if dep.Path != sdkModulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return ""
+}
+
+// checkSDKCompatibility compares the SDK version a plugin was built with
+// against gitspace's own. It returns an error when the protocol is likely to
+// differ (a different major version, or minor version before v1), and a
+// warning when the plugin's SDK is merely older. Unknown or unparseable
+// versions are let through, since there is nothing to compare.
+func checkSDKCompatibility(hostSDK, pluginSDK string) (warning string, err error) {
+	if hostSDK == "" || pluginSDK == "" {
+		return "", nil
+	}
+
+	host, hostErr := semver.NewVersion(hostSDK)
+	plugin, pluginErr := semver.NewVersion(pluginSDK)
+	if hostErr != nil || pluginErr != nil {
+		return "", nil
+	}
+
+	if host.Major() != plugin.Major() || (host.Major() == 0 && host.Minor() != plugin.Minor()) {
+		return "", fmt.Errorf("plugin was built with SDK %s, which is incompatible with gitspace's SDK %s", pluginSDK, hostSDK)
+	}
+	if plugin.LessThan(host) {
+		return fmt.Sprintf("plugin was built with SDK %s, older than gitspace's SDK %s; rebuild it if it misbehaves", pluginSDK, hostSDK), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"os"
+	"runtime/debug"
+	"testing"
+)
+
+func TestCheckSDKCompatibility(t *testing.T) {
+	tests := []struct {
+		host, plugin string
+		wantWarning  bool
+		wantErr      bool
+	}{
+		{"v0.4.2", "v0.4.2", false, false},
+		{"v0.4.2", "v0.4.0", true, false},
+		{"v0.4.0", "v0.4.2", false, false}, // Newer patch releases are fine
+		{"v0.4.2", "v0.3.9", false, true},  // Minor versions differ before v1
+		{"v1.2.0", "v1.0.0", true, false},
+		{"v2.0.0", "v1.9.0", false, true},
+		{"", "v0.4.0", false, false},
+		{"v0.4.0", "", false, false},
+		{"v0.4.0", "(devel)", false, false},
+		{"v0.4.1-0.20240101000000-abcdef123456", "v0.4.0", true, false},
+	}
+	for _, tt := range tests {
+		warning, err := checkSDKCompatibility(tt.host, tt.plugin)
+		if (warning != "") != tt.wantWarning || (err != nil) != tt.wantErr {
+			t.Errorf("checkSDKCompatibility(%q, %q) = %q, %v", tt.host, tt.plugin, warning, err)
+		}
+	}
+}
+
+func TestSDKVersionFromDeps(t *testing.T) {
+	deps := []*debug.Module{
+		{Path: "github.com/other/module", Version: "v9.9.9"},
+		{Path: sdkModulePath, Version: "v0.4.0"},
+	}
+	if got := sdkVersionFromDeps(deps); got != "v0.4.0" {
+		t.Errorf("got %q, want v0.4.0", got)
+	}
+	// A replaced SDK reports the replacement's version
+	deps[1].Replace = &debug.Module{Path: "../sdk", Version: "v0.5.0"}
+	if got := sdkVersionFromDeps(deps); got != "v0.5.0" {
+		t.Errorf("got %q for a replaced SDK, want v0.5.0", got)
+	}
+	if got := sdkVersionFromDeps(deps[:1]); got != "" {
+		t.Errorf("got %q without the SDK, want nothing", got)
+	}
+}
+
+func TestPluginSDKVersion(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test binary links the SDK like any plugin would
+	if got, want := PluginSDKVersion(executable), HostSDKVersion(); got != want {
+		t.Errorf("PluginSDKVersion() = %q, want the host's %q", got, want)
+	}
+	if got := PluginSDKVersion(t.TempDir()); got != "" {
+		t.Errorf("got %q for something that isn't a Go binary", got)
+	}
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// msgTypeConfig is a plugin asking for the active config while it handles a
+// request. The request has no payload and the reply is a JSON ConfigReply.
+const msgTypeConfig uint32 = 5
+
+// ConfigView is the read-only view of the active config served to plugins.
+// It leaves out tokens, SSH keys and hooks.
+type ConfigView struct {
+	SCM          string                 `json:"scm"`
+	Owner        string                 `json:"owner"`
+	Path         string                 `json:"path"`
+	Labels       []string               `json:"labels,omitempty"`
+	Groups       map[string]ConfigGroup `json:"groups"`
+	Repositories []ConfigRepo           `json:"repositories"`
+}
+
+// ConfigGroup is a group as a plugin sees it
+type ConfigGroup struct {
+	Match  string   `json:"match"`
+	Values []string `json:"values"`
+	Type   string   `json:"type,omitempty"`
+	Branch string   `json:"branch,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// ConfigRepo is a repository from the cached listing of one of the config's
+// scm/owners
+type ConfigRepo struct {
+	SCM   string `json:"scm"`
+	Owner string `json:"owner"`
+	lib.RepoInfo
+}
+
+// ConfigReply answers a msgTypeConfig request. Error is set instead of
+// Config when there is no active config.
+type ConfigReply struct {
+	Config *ConfigView `json:"config,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// SetConfigView sets how plugins get the active config, including plugins
+// that are already loaded. A nil view answers that there is none.
+func (m *Manager) SetConfigView(view func() (*ConfigView, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configView = view
+}
+
+func (m *Manager) currentConfigView() (*ConfigView, error) {
+	m.mu.RLock()
+	view := m.configView
+	m.mu.RUnlock()
+	if view == nil {
+		return nil, errors.New("no active config")
+	}
+	return view()
+}
+
+// serveConfig answers a plugin's msgTypeConfig request. It runs while a
+// request is waiting for its response, so the frame goes out in between.
+func (p *Plugin) serveConfig() {
+	var reply ConfigReply
+	if view, err := p.configView(); err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.Config = view
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		data, _ = json.Marshal(ConfigReply{Error: fmt.Sprintf("failed to encode config: %v", err)})
+	}
+	if err := p.writeFrame(msgTypeConfig, data); err != nil {
+		p.Logger.Warn("Failed to send the config to the plugin", "name", p.Name, "error", err)
+	}
+}
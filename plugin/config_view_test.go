@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+// requestTestConfig runs the test plugin's config command, which asks for
+// the config and returns the reply it got
+func requestTestConfig(t *testing.T, m *Manager) ConfigReply {
+	t.Helper()
+	result, err := m.ExecuteCommand("testplugin", "config", nil)
+	if err != nil {
+		t.Fatalf("config command failed: %v", err)
+	}
+	var reply ConfigReply
+	if err := json.Unmarshal([]byte(result), &reply); err != nil {
+		t.Fatalf("plugin got %q: %v", result, err)
+	}
+	return reply
+}
+
+func TestPluginRequestsConfig(t *testing.T) {
+	m := newTestManager(t)
+	loadTestPlugin(t, m, "testplugin")
+
+	if reply := requestTestConfig(t, m); reply.Config != nil || reply.Error != "no active config" {
+		t.Errorf("without a config the plugin got %+v", reply)
+	}
+
+	// A view set after loading reaches the running plugin
+	view := &ConfigView{
+		SCM:    "github",
+		Owner:  "acme",
+		Path:   "/work",
+		Groups: map[string]ConfigGroup{"api": {Match: "startsWith", Values: []string{"api-"}, Type: "service"}},
+		Repositories: []ConfigRepo{
+			{SCM: "github", Owner: "acme", RepoInfo: lib.RepoInfo{Name: "api-gateway", DefaultBranch: "main"}},
+		},
+	}
+	m.SetConfigView(func() (*ConfigView, error) { return view, nil })
+	reply := requestTestConfig(t, m)
+	if reply.Error != "" || reply.Config == nil {
+		t.Fatalf("plugin got %+v", reply)
+	}
+	if !reflect.DeepEqual(reply.Config, view) {
+		t.Errorf("plugin got %+v, want %+v", reply.Config, view)
+	}
+
+	// The request is answered in between, so later commands still work
+	if result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "after"}); err != nil || result != "after" {
+		t.Errorf("echo after a config request returned %q, %v", result, err)
+	}
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// MissingDependenciesError is returned by InstallPlugin when plugins named in
+// the manifest's dependencies aren't installed
+type MissingDependenciesError struct {
+	Plugin  string
+	Missing []string
+}
+
+func (e *MissingDependenciesError) Error() string {
+	return fmt.Sprintf("plugin %s depends on plugins that aren't installed: %s", e.Plugin, strings.Join(e.Missing, ", "))
+}
+
+// missingDependencies returns the manifest's dependencies that aren't installed
+func missingDependencies(pluginsDir string, manifest *PluginManifest) []string {
+	var missing []string
+	for _, dep := range manifest.Metadata.Dependencies {
+		if dep == manifest.Metadata.Name {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(pluginsDir, dep)); err != nil || !info.IsDir() {
+			missing = append(missing, dep)
+		}
+	}
+	return missing
+}
+
+// Dependents returns the installed plugins whose manifests depend on the
+// named plugin, sorted
+func Dependents(logger *logger.RateLimitedLogger, name string) ([]string, error) {
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugins directory: %w", err)
+	}
+	installed, err := ListInstalledPlugins(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependents []string
+	for _, other := range installed {
+		if other == name {
+			continue
+		}
+		// The manifest is kept with the plugin's support files
+		manifest, err := loadPluginManifest(filepath.Join(pluginsDir, "data", other, manifestFileName))
+		if err != nil {
+			continue
+		}
+		for _, dep := range manifest.Metadata.Dependencies {
+			if dep == name {
+				dependents = append(dependents, other)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents, nil
+}
+
+// installWithDependencies installs the plugin at source. If it depends on
+// plugins that aren't installed, it offers to install them from the Gitspace
+// Catalog first. installing holds the plugins already being installed this way.
+func installWithDependencies(logger *logger.RateLimitedLogger, manager *Manager, source string, installing map[string]bool) error {
+	err := InstallPlugin(logger, manager, source)
+	var missingErr *MissingDependenciesError
+	if !errors.As(err, &missingErr) {
+		return err
+	}
+
+	var install bool
+	confirmErr := huh.NewConfirm().
+		Title(fmt.Sprintf("%s needs %s. Install from the Gitspace Catalog?", missingErr.Plugin, strings.Join(missingErr.Missing, ", "))).
+		Value(&install).
+		Run()
+	if confirmErr != nil || !install {
+		return err
+	}
+
+	installing[missingErr.Plugin] = true
+	for _, dep := range missingErr.Missing {
+		if installing[dep] {
+			return fmt.Errorf("plugins %s and %s depend on each other", missingErr.Plugin, dep)
+		}
+		depSource, err := catalogPluginSource(dep, manager.NetworkTimeout())
+		if err != nil {
+			return fmt.Errorf("failed to install dependency %s: %w", dep, err)
+		}
+		if err := installWithDependencies(logger, manager, depSource, installing); err != nil {
+			return fmt.Errorf("failed to install dependency %s: %w", dep, err)
+		}
+	}
+	return InstallPlugin(logger, manager, source)
+}
+
+// catalogPluginSource returns the install source of the named plugin in the
+// Gitspace Catalog
+func catalogPluginSource(name string, timeout time.Duration) (string, error) {
+	ctx, cancel := lib.WithNetworkTimeout(context.Background(), timeout)
+	defer cancel()
+	provider, err := catalogProvider()
+	if err != nil {
+		return "", err
+	}
+	catalog, err := provider.FetchCatalog(ctx, catalogOwner, catalogRepo)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch Gitspace Catalog: %w", lib.TimeoutError(err, "fetching the Gitspace Catalog", timeout))
+	}
+	plugin, ok := catalog.Plugins[name]
+	if !ok {
+		return "", fmt.Errorf("plugin %s isn't in the Gitspace Catalog", name)
+	}
+	return catalogURL(plugin.Path), nil
+}
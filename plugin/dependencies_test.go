@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTestManifest writes a manifest for the named plugin with the given
+// dependencies into dir
+func writeTestManifest(t *testing.T, dir, name string, deps ...string) {
+	t.Helper()
+	text := "[metadata]\nname = \"" + name + "\"\nversion = \"1.0.0\"\ndependencies = ["
+	for i, dep := range deps {
+		if i > 0 {
+			text += ", "
+		}
+		text += `"` + dep + `"`
+	}
+	text += "]\n"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFileName), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMissingDependencies(t *testing.T) {
+	pluginsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(pluginsDir, "base"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A file named like a plugin isn't an installed plugin
+	if err := os.WriteFile(filepath.Join(pluginsDir, "stray"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &PluginManifest{}
+	manifest.Metadata.Name = "tools"
+	manifest.Metadata.Dependencies = []string{"base", "tools", "stray", "extra"}
+
+	if got := missingDependencies(pluginsDir, manifest); !slices.Equal(got, []string{"stray", "extra"}) {
+		t.Errorf("missing %v, want [stray extra]", got)
+	}
+}
+
+func TestInstallPluginRequiresDependencies(t *testing.T) {
+	m := newTestManager(t)
+	source := filepath.Join(t.TempDir(), "tools")
+	writeTestManifest(t, source, "tools", "base")
+
+	err := InstallPlugin(m.logger, m, source)
+	var missingErr *MissingDependenciesError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("got error %v, want a MissingDependenciesError", err)
+	}
+	if missingErr.Plugin != "tools" || !slices.Equal(missingErr.Missing, []string{"base"}) {
+		t.Errorf("got %+v", missingErr)
+	}
+	pluginsDir, _ := getPluginsDir()
+	if _, err := os.Stat(filepath.Join(pluginsDir, "tools")); !os.IsNotExist(err) {
+		t.Error("a plugin with missing dependencies was installed")
+	}
+}
+
+func TestDependents(t *testing.T) {
+	m := newTestManager(t)
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, deps := range map[string][]string{
+		"base":      nil,
+		"zeta":      {"base"},
+		"alpha":     {"other", "base"},
+		"unrelated": {"other"},
+	} {
+		if err := os.MkdirAll(filepath.Join(pluginsDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeTestManifest(t, filepath.Join(pluginsDir, "data", name), name, deps...)
+	}
+	// An installed plugin without a manifest depends on nothing
+	if err := os.MkdirAll(filepath.Join(pluginsDir, "bare"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Dependents(m.logger, "base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"alpha", "zeta"}) {
+		t.Errorf("dependents of base are %v, want [alpha zeta]", got)
+	}
+	if got, _ := Dependents(m.logger, "zeta"); len(got) != 0 {
+		t.Errorf("dependents of zeta are %v, want none", got)
+	}
+}
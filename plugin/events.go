@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
+)
+
+// msgTypeEvent delivers a lifecycle event to a subscribed plugin. The
+// request is a JSON Event and the plugin answers with a type 6 frame holding
+// a JSON EventReply.
+const msgTypeEvent uint32 = 6
+
+// EventsCommand is the command of a top-level option in a plugin's menu
+// subscribing the plugin to lifecycle events, one parameter per event name.
+// The option itself isn't shown in the plugin's menu.
+const EventsCommand = "gitspace.events"
+
+// Lifecycle events plugins can subscribe to
+const (
+	EventPreClone  = "pre_clone"  // Before a scm/owner's repositories are cloned or updated
+	EventPostClone = "post_clone" // After a clone, with every repository's result
+	EventPostSync  = "post_sync"  // After a sync, with every repository's result
+)
+
+// Event is a lifecycle event sent to subscribed plugins
+type Event struct {
+	Name         string      `json:"name"`
+	Repositories []EventRepo `json:"repositories"`
+}
+
+// EventRepo is a repository an event is about. Status and Error are empty in
+// pre_clone events.
+type EventRepo struct {
+	SCM    string `json:"scm"`
+	Owner  string `json:"owner"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// EventReply is a plugin's answer to an event. A non-empty Error is logged.
+type EventReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// subscribedEvents returns the events a plugin's menu subscribes to
+func subscribedEvents(menu []gsplug.MenuOption) []string {
+	var events []string
+	for _, opt := range menu {
+		if opt.Command != EventsCommand {
+			continue
+		}
+		for _, param := range opt.Parameters {
+			events = append(events, param.Name)
+		}
+	}
+	return events
+}
+
+// DispatchEvent sends an event to every loaded plugin subscribed to it, one
+// after the other. A plugin failing to handle it is logged and doesn't stop
+// the others.
+func (m *Manager) DispatchEvent(event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		m.logger.Error("Failed to encode plugin event", "event", event.Name, "error", err)
+		return
+	}
+
+	m.mu.RLock()
+	var names []string
+	subscribers := make(map[string]*Plugin)
+	for name, plugin := range m.plugins {
+		if slices.Contains(plugin.Events, event.Name) {
+			names = append(names, name)
+			subscribers[name] = plugin
+		}
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		plugin := subscribers[name]
+		if err := plugin.sendEvent(data); err != nil {
+			plugin.Logger.Warn("Plugin failed to handle event", "name", name, "event", event.Name, "error", err)
+			switch {
+			case isPluginGone(err):
+				plugin.stop()
+			case errors.Is(err, errRequestTimeout):
+				m.dropPlugin(name, plugin)
+			}
+		}
+	}
+}
+
+// sendEvent delivers an encoded Event and reads the plugin's EventReply
+func (p *Plugin) sendEvent(data []byte) error {
+	respType, respData, err := p.roundTrip(msgTypeEvent, data)
+	if err != nil {
+		return err
+	}
+	if respType != msgTypeEvent {
+		return fmt.Errorf("unexpected response type: %d", respType)
+	}
+	var reply EventReply
+	if len(respData) > 0 {
+		if err := json.Unmarshal(respData, &reply); err != nil {
+			return fmt.Errorf("failed to decode event reply: %w", err)
+		}
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readTestPluginEvents returns the events a test plugin wrote to path
+func readTestPluginEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestDispatchEventReachesSubscribers(t *testing.T) {
+	m := newTestManager(t)
+	dir := t.TempDir()
+	eventFiles := map[string]string{}
+	for _, plugin := range []struct{ name, mode string }{
+		{"alpha", testPluginFailEvents}, // Dispatched to first; its failure mustn't stop the others
+		{"beta", "1"},
+		{"gamma", testPluginUnsubscribed},
+	} {
+		eventFiles[plugin.name] = filepath.Join(dir, plugin.name+".jsonl")
+		t.Setenv(testPluginEventsEnv, eventFiles[plugin.name])
+		loadTestPluginMode(t, m, plugin.name, plugin.mode)
+	}
+
+	m.DispatchEvent(Event{Name: EventPreClone, Repositories: []EventRepo{{Name: "api"}}})
+	m.DispatchEvent(Event{
+		Name: EventPostClone,
+		Repositories: []EventRepo{
+			{SCM: "github", Owner: "acme", Name: "api", Path: "/cache/api", Status: "cloned"},
+			{SCM: "github", Owner: "acme", Name: "web", Path: "/cache/web", Status: "failed", Error: "timeout"},
+		},
+	})
+
+	for _, name := range []string{"alpha", "beta"} {
+		events := readTestPluginEvents(t, eventFiles[name])
+		if len(events) != 1 || events[0].Name != EventPostClone {
+			t.Fatalf("%s received %+v, want the post_clone event only", name, events)
+		}
+		repos := events[0].Repositories
+		if len(repos) != 2 || repos[0].Name != "api" || repos[1].Error != "timeout" {
+			t.Errorf("%s received repositories %+v", name, repos)
+		}
+	}
+	if events := readTestPluginEvents(t, eventFiles["gamma"]); len(events) != 0 {
+		t.Errorf("an unsubscribed plugin received %+v", events)
+	}
+	// A handler error is logged, not fatal
+	if !m.IsPluginLoaded("alpha") || !m.IsPluginRunning("alpha") {
+		t.Error("a plugin whose handler failed was unloaded")
+	}
+}
+
+func TestSubscribedEvents(t *testing.T) {
+	got := subscribedEvents(testPluginMenu("1"))
+	if len(got) != 2 || got[0] != EventPostClone || got[1] != EventPostSync {
+		t.Errorf("subscribed to %v, want [post_clone post_sync]", got)
+	}
+	if got := subscribedEvents(testPluginMenu(testPluginUnsubscribed)); len(got) != 0 {
+		t.Errorf("subscribed to %v, want nothing", got)
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
 	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	pb "github.com/ssotops/gitspace-plugin-sdk/proto"
@@ -40,11 +45,19 @@ func HandleInstallPlugin(logger *logger.RateLimitedLogger, manager *Manager) err
 	switch installChoice {
 	case "catalog":
 		logger.Debug("Handling Gitspace Catalog installation")
-		source, err = HandleGitspaceCatalogInstall(logger)
+		entry, err := HandleGitspaceCatalogInstall(logger, manager.NetworkTimeout())
 		if err != nil {
 			logger.Error("Error selecting from Gitspace Catalog", "error", err)
 			return fmt.Errorf("error selecting from Gitspace Catalog: %w", err)
 		}
+		if entry.IsTemplate() {
+			if err := installCatalogTemplate(logger, entry, manager.NetworkTimeout()); err != nil {
+				logger.Error("Failed to install template", "name", entry.Name, "error", err)
+				return fmt.Errorf("failed to install template: %w", err)
+			}
+			return nil
+		}
+		source = catalogURL(entry.Path)
 	case "local":
 		source, err = getPathWithCompletion("Enter the local plugin source directory")
 		if err != nil {
@@ -61,7 +74,7 @@ func HandleInstallPlugin(logger *logger.RateLimitedLogger, manager *Manager) err
 	}
 
 	logger.Debug("Proceeding with plugin installation", "source", source)
-	err = InstallPlugin(logger, manager, source)
+	err = installWithDependencies(logger, manager, source, make(map[string]bool))
 	if err != nil {
 		logger.Error("Failed to install plugin", "error", err)
 		return fmt.Errorf("failed to install plugin: %w", err)
@@ -101,6 +114,22 @@ func HandleUninstallPlugin(logger *logger.RateLimitedLogger, manager *Manager) e
 		return fmt.Errorf("error selecting plugin to uninstall: %w", err)
 	}
 
+	dependents, err := Dependents(logger, selectedPlugin)
+	if err != nil {
+		logger.Warn("Failed to check which plugins depend on this one", "name", selectedPlugin, "error", err)
+	}
+	if len(dependents) > 0 {
+		logger.Warn("Other plugins depend on this one", "name", selectedPlugin, "dependents", strings.Join(dependents, ", "))
+		var confirm bool
+		err = huh.NewConfirm().
+			Title(fmt.Sprintf("%s depend on %s. Uninstall it anyway?", strings.Join(dependents, ", "), selectedPlugin)).
+			Value(&confirm).
+			Run()
+		if err != nil || !confirm {
+			return nil
+		}
+	}
+
 	err = UninstallPlugin(logger, selectedPlugin)
 	if err != nil {
 		return fmt.Errorf("failed to uninstall plugin: %w", err)
@@ -131,64 +160,109 @@ func HandleListInstalledPlugins(logger *logger.RateLimitedLogger) error {
 
 	if len(userPlugins) == 0 {
 		logger.Info("No plugins installed")
-	} else {
-		logger.Info("Installed plugins:")
-		for _, plugin := range userPlugins {
-			logger.Info("- " + plugin)
+		return nil
+	}
+
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return fmt.Errorf("failed to get plugins directory: %w", err)
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("Plugin", "Version", "SDK", "Source type", "Source", "Installed")
+	for _, plugin := range userPlugins {
+		row := []string{plugin, "", "unknown", "", "", ""}
+		if sdkVersion := PluginSDKVersion(filepath.Join(pluginsDir, plugin, plugin)); sdkVersion != "" {
+			row[2] = sdkVersion
 		}
+
+		meta, err := ReadPluginMeta(plugin)
+		switch {
+		case err == nil:
+			row[1] = meta.Version
+			row[3] = meta.SourceType
+			row[4] = meta.Source
+			row[5] = meta.InstalledAt.Local().Format("2006-01-02 15:04")
+		case os.IsNotExist(err):
+			// Installed before install metadata was recorded
+			row[3] = "unknown"
+		default:
+			logger.Warn("Failed to read plugin metadata", "name", plugin, "error", err)
+		}
+		t.Row(row...)
 	}
 
+	fmt.Println("Installed plugins:")
+	fmt.Println(t.Render())
 	return nil
 }
 
-func HandleGitspaceCatalogInstall(logger *logger.RateLimitedLogger) (string, error) {
+// HandleGitspaceCatalogInstall lets the user search the Gitspace Catalog and
+// returns the plugin or template they picked
+func HandleGitspaceCatalogInstall(logger *logger.RateLimitedLogger, timeout time.Duration) (CatalogEntry, error) {
 	logger.Debug("Entering handleGitspaceCatalogInstall")
-	owner := "ssotops"
-	repo := "gitspace-catalog"
+	owner := catalogOwner
+	repo := catalogRepo
 	logger.Debug("Fetching Gitspace Catalog", "owner", owner, "repo", repo)
 
-	ctx := context.Background()
-	catalog, err := lib.FetchGitspaceCatalog(ctx, lib.SCMTypeGitHub, "", owner, repo)
+	ctx, cancel := lib.WithNetworkTimeout(context.Background(), timeout)
+	defer cancel()
+	provider, err := catalogProvider()
 	if err != nil {
+		return CatalogEntry{}, err
+	}
+	catalog, err := provider.FetchCatalog(ctx, owner, repo)
+	if err != nil {
+		err = lib.TimeoutError(err, "fetching the Gitspace Catalog", timeout)
 		logger.Error("Failed to fetch Gitspace Catalog", "error", err)
-		return "", fmt.Errorf("failed to fetch Gitspace Catalog: %w", err)
+		return CatalogEntry{}, fmt.Errorf("failed to fetch Gitspace Catalog: %w", err)
 	}
 
 	logger.Debug("Successfully fetched Gitspace Catalog")
 
-	var options []huh.Option[string]
-	for name, plugin := range catalog.Plugins {
-		options = append(options, huh.NewOption(fmt.Sprintf("%s (%s)", name, plugin.Description), name))
+	entries := catalogEntries(catalog)
+	if len(entries) == 0 {
+		logger.Warn("No plugins or templates found in the catalog")
+		return CatalogEntry{}, fmt.Errorf("no plugins or templates found in the catalog")
 	}
 
-	if len(options) == 0 {
-		logger.Warn("No plugins found in the catalog")
-		return "", fmt.Errorf("no plugins found in the catalog")
-	}
-
-	logger.Debug("Presenting plugin options to user", "optionCount", len(options))
-
-	var selectedItem string
-	err = huh.NewSelect[string]().
-		Title("Select a plugin to install").
-		Options(options...).
-		Value(&selectedItem).
-		Run()
-
-	if err != nil {
-		logger.Error("Failed to select item", "error", err)
-		return "", fmt.Errorf("failed to select item: %w", err)
-	}
+	for {
+		var query string
+		err = huh.NewInput().
+			Title("Search the catalog").
+			Description("Words match names or tags, tag:<tag> only tags; leave empty to list everything").
+			Value(&query).
+			Run()
+		if err != nil {
+			return CatalogEntry{}, fmt.Errorf("failed to read search: %w", err)
+		}
 
-	logger.Debug("User selected plugin", "selectedItem", selectedItem)
+		matched := filterCatalog(entries, query)
+		if len(matched) == 0 {
+			fmt.Printf("Nothing in the catalog matches %q.\n", query)
+			continue
+		}
 
-	// Construct the full GitHub URL for the selected plugin
-	selectedPlugin := catalog.Plugins[selectedItem]
-	pluginURL := fmt.Sprintf("https://github.com/%s/%s/tree/main/%s", owner, repo, selectedPlugin.Path)
+		logger.Debug("Presenting catalog options to user", "optionCount", len(matched))
 
-	logger.Debug("Constructed plugin URL", "url", pluginURL)
+		selected := -1
+		err = huh.NewSelect[int]().
+			Title("Select a plugin or template to install").
+			Options(append(catalogOptions(matched), huh.NewOption("Search again", -1))...).
+			Value(&selected).
+			Run()
+		if err != nil {
+			logger.Error("Failed to select item", "error", err)
+			return CatalogEntry{}, fmt.Errorf("failed to select item: %w", err)
+		}
+		if selected < 0 {
+			continue
+		}
 
-	return pluginURL, nil
+		logger.Debug("User selected catalog entry", "kind", matched[selected].Kind, "name", matched[selected].Name)
+		return matched[selected], nil
+	}
 }
 
 func HandleRunPlugin(logger *logger.RateLimitedLogger, manager *Manager) error {
@@ -241,7 +315,7 @@ func runPluginLoop(ctx context.Context, logger *logger.RateLimitedLogger, manage
 	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(interruptChan)
 
-	plugin, ok := manager.plugins[selectedPlugin]
+	plugin, ok := manager.GetPlugin(selectedPlugin)
 	if !ok {
 		return fmt.Errorf("plugin not found: %s", selectedPlugin)
 	}
@@ -253,10 +327,20 @@ func runPluginLoop(ctx context.Context, logger *logger.RateLimitedLogger, manage
 	menuStack := [][]gsplug.MenuOption{}
 
 	for {
-		// Check if the plugin is still running
+		// Check if the plugin is still running, and bring it back if not
 		if !manager.IsPluginRunning(selectedPlugin) {
-			pluginLogger.Error("Plugin has terminated unexpectedly", "plugin", selectedPlugin)
-			return fmt.Errorf("plugin %s has terminated unexpectedly", selectedPlugin)
+			pluginLogger.Warn("Plugin has terminated unexpectedly", "plugin", selectedPlugin)
+			if err := manager.RestartPlugin(selectedPlugin); err != nil {
+				pluginLogger.Error("Giving up on plugin", "plugin", selectedPlugin, "error", err)
+				return err
+			}
+			// The restarted plugin is a new instance with its own logger
+			if plugin, ok = manager.GetPlugin(selectedPlugin); !ok {
+				return fmt.Errorf("plugin not found after restart: %s", selectedPlugin)
+			}
+			pluginLogger = plugin.Logger
+			currentMenu = nil
+			menuStack = nil
 		}
 
 		// Create a channel for menu selection
@@ -284,6 +368,7 @@ func runPluginLoop(ctx context.Context, logger *logger.RateLimitedLogger, manage
 					pluginLogger.Error("Error unmarshalling menu data", "error", err)
 					return
 				}
+				currentMenu = withoutReservedOptions(currentMenu)
 			}
 
 			pluginLogger.Debug("Presenting menu options to user", "optionsCount", len(currentMenu))
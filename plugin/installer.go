@@ -4,31 +4,27 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/pelletier/go-toml/v2"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	"github.com/ssotops/gitspace/lib"
 )
 
-type PluginManifest struct {
-	Metadata struct {
-		Name        string `toml:"name"`
-		Version     string `toml:"version"`
-		Description string `toml:"description"`
-	} `toml:"metadata"`
-	Sources []struct {
-		Path       string `toml:"path"`
-		EntryPoint string `toml:"entry_point"`
-	} `toml:"sources"`
-}
-
 func InstallPlugin(logger *logger.RateLimitedLogger, manager *Manager, source string) error {
 	logger.Debug("Starting plugin installation", "source", source)
 
+	// Even local plugins fetch the plugin SDK or a pre-built binary
+	if lib.Offline() {
+		return fmt.Errorf("installing plugins is %w", lib.ErrOffline)
+	}
+
 	// Ensure plugin directory permissions
 	if err := EnsurePluginDirectoryPermissions(logger); err != nil {
 		return fmt.Errorf("failed to ensure plugin directory permissions: %w", err)
@@ -40,10 +36,18 @@ func InstallPlugin(logger *logger.RateLimitedLogger, manager *Manager, source st
 		return fmt.Errorf("failed to get plugins directory: %w", err)
 	}
 
-	isGitspaceCatalog := strings.HasPrefix(source, "https://github.com/ssotops/gitspace-catalog/tree/main/")
+	isGitspaceCatalog := strings.HasPrefix(source, fmt.Sprintf("https://github.com/%s/%s/tree/", catalogOwner, catalogRepo))
 	isRemote := strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
 
-	var sourceDir string
+	var sourceDir, ref string
+	sourceType := SourceTypeLocal
+
+	if isRemote && !isGitspaceCatalog {
+		source, ref = splitSourceRef(source)
+		if err := validateRef(ref); err != nil {
+			return err
+		}
+	}
 
 	if isRemote {
 		tempDir, err := os.MkdirTemp("", "gitspace-plugin-*")
@@ -52,12 +56,14 @@ func InstallPlugin(logger *logger.RateLimitedLogger, manager *Manager, source st
 		}
 		defer os.RemoveAll(tempDir)
 
+		sourceType = SourceTypeRemote
 		if isGitspaceCatalog {
-			if err := downloadFromGitspaceCatalog(logger, source, tempDir); err != nil {
+			sourceType = SourceTypeCatalog
+			if err := downloadFromGitspaceCatalog(logger, source, tempDir, manager.NetworkTimeout()); err != nil {
 				return err
 			}
 		} else {
-			if err := gitClone(source, tempDir); err != nil {
+			if err := gitClone(source, tempDir, ref); err != nil {
 				return err
 			}
 		}
@@ -68,10 +74,11 @@ func InstallPlugin(logger *logger.RateLimitedLogger, manager *Manager, source st
 			return fmt.Errorf("failed to get absolute path: %w", err)
 		}
 		sourceDir = absSource
+		source = absSource
 	}
 
 	// Load and validate manifest
-	manifest, err := loadPluginManifest(filepath.Join(sourceDir, "gitspace-plugin.toml"))
+	manifest, err := loadPluginManifest(filepath.Join(sourceDir, manifestFileName))
 	if err != nil {
 		return fmt.Errorf("failed to load plugin manifest: %w", err)
 	}
@@ -79,6 +86,78 @@ func InstallPlugin(logger *logger.RateLimitedLogger, manager *Manager, source st
 	pluginName := manifest.Metadata.Name
 	destDir := filepath.Join(pluginsDir, pluginName)
 
+	if err := verifyChecksums(sourceDir, manifest.Checksums); err != nil {
+		return err
+	}
+
+	if missing := missingDependencies(pluginsDir, manifest); len(missing) > 0 {
+		return &MissingDependenciesError{Plugin: pluginName, Missing: missing}
+	}
+
+	binaryPath := filepath.Join(sourceDir, pluginName)
+	if asset, ok := manifest.binaryFor(runtime.GOOS, runtime.GOARCH); ok {
+		logger.Info("Downloading pre-built plugin", "name", pluginName, "platform", runtime.GOOS+"/"+runtime.GOARCH)
+		if err := downloadPluginBinary(asset, binaryPath, manager.NetworkTimeout()); err != nil {
+			return err
+		}
+	} else if err := buildPlugin(logger, sourceDir, pluginName, manager.NetworkTimeout()); err != nil {
+		return err
+	}
+
+	// Create plugin directory and install files
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	// Copy and make executable the plugin binary
+	destBinaryPath := filepath.Join(destDir, pluginName)
+	if err := copyFile(binaryPath, destBinaryPath); err != nil {
+		return fmt.Errorf("failed to copy plugin binary: %w", err)
+	}
+	if err := os.Chmod(destBinaryPath, 0755); err != nil {
+		return fmt.Errorf("failed to make plugin executable: %w", err)
+	}
+
+	// Create data directory and copy support files
+	dataDir := filepath.Join(pluginsDir, "data", pluginName)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := copyDir(sourceDir, dataDir); err != nil {
+		return fmt.Errorf("failed to copy plugin files: %w", err)
+	}
+
+	checksum, err := fileSHA256(destBinaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to checksum plugin binary: %w", err)
+	}
+
+	meta := PluginMeta{
+		Source:      source,
+		SourceType:  sourceType,
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+		Version:     manifest.Metadata.Version,
+		Ref:         ref,
+		SHA256:      checksum,
+	}
+	if err := writePluginMeta(destDir, meta); err != nil {
+		return err
+	}
+
+	// Add to discovered plugins
+	manager.AddDiscoveredPlugin(pluginName, destBinaryPath)
+
+	logger.Info("Plugin installed successfully", "name", pluginName)
+	return nil
+}
+
+// buildPlugin builds the plugin in sourceDir into a binary named pluginName
+// there, with the latest plugin SDK
+func buildPlugin(logger *logger.RateLimitedLogger, sourceDir, pluginName string, timeout time.Duration) error {
+	if err := checkGoToolchain(timeout); err != nil {
+		return err
+	}
+
 	// Set up Go module
 	logger.Debug("Setting up Go module", "dir", sourceDir)
 	modInit := exec.Command("go", "mod", "init", fmt.Sprintf("github.com/ssotops/gitspace-catalog/plugins/%s", pluginName))
@@ -119,79 +198,142 @@ func InstallPlugin(logger *logger.RateLimitedLogger, manager *Manager, source st
 	buildCmd.Dir = sourceDir
 	buildCmd.Env = append(os.Environ(), "GO111MODULE=on")
 
-	output, err := buildCmd.CombinedOutput()
-	if err != nil {
+	if output, err := buildCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to build plugin: %w\nOutput: %s", err, output)
 	}
+	return nil
+}
 
-	// Create plugin directory and install files
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return fmt.Errorf("failed to create plugin directory: %w", err)
+// verifyChecksums checks the files in dir against the SHA-256 checksums a
+// manifest declares for them, optionally prefixed with "sha256:"
+func verifyChecksums(dir string, checksums map[string]string) error {
+	paths := make([]string, 0, len(checksums))
+	for path := range checksums {
+		paths = append(paths, path)
 	}
+	sort.Strings(paths)
 
-	// Copy and make executable the plugin binary
-	binaryPath := filepath.Join(sourceDir, pluginName)
-	destBinaryPath := filepath.Join(destDir, pluginName)
-	if err := copyFile(binaryPath, destBinaryPath); err != nil {
-		return fmt.Errorf("failed to copy plugin binary: %w", err)
-	}
-	if err := os.Chmod(destBinaryPath, 0755); err != nil {
-		return fmt.Errorf("failed to make plugin executable: %w", err)
+	for _, path := range paths {
+		if !filepath.IsLocal(path) {
+			return fmt.Errorf("manifest checksum for %s: path must be inside the plugin", path)
+		}
+		want := strings.ToLower(strings.TrimPrefix(checksums[path], "sha256:"))
+		got, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", path, err)
+		}
+		if got != want {
+			return fmt.Errorf("%s doesn't match the manifest checksum (expected %s, got %s); the download may be incomplete", path, want, got)
+		}
 	}
+	return nil
+}
 
-	// Create data directory and copy support files
-	dataDir := filepath.Join(pluginsDir, "data", pluginName)
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
+// splitSourceRef splits a remote plugin URL like
+// https://github.com/org/plugin#v1.2.0 into the URL and the git ref to check
+// out. The ref is empty when the URL has no fragment.
+func splitSourceRef(source string) (string, string) {
+	url, ref, _ := strings.Cut(source, "#")
+	return url, strings.TrimSpace(ref)
+}
+
+// The repository holding the Gitspace Catalog
+const (
+	catalogOwner = "ssotops"
+	catalogRepo  = "gitspace-catalog"
+)
+
+// catalogProvider returns the GitHub provider used for the Gitspace Catalog.
+// Its responses are cached in ~/.ssot/gitspace/.cache/catalog and
+// revalidated with ETags, so browsing and reinstalling skip unchanged files.
+func catalogProvider() (lib.SCMProvider, error) {
+	if lib.Offline() {
+		return nil, fmt.Errorf("the Gitspace Catalog is %w", lib.ErrOffline)
 	}
-	if err := copyDir(sourceDir, dataDir); err != nil {
-		return fmt.Errorf("failed to copy plugin files: %w", err)
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
 	}
+	return lib.NewSCMProvider(lib.SCMTypeGitHub, lib.ProviderOptions{
+		Token:    os.Getenv("GITHUB_TOKEN"),
+		CacheDir: filepath.Join(homeDir, ".ssot", "gitspace", ".cache", "catalog"),
+	})
+}
 
-	// Add to discovered plugins
-	manager.AddDiscoveredPlugin(pluginName, destBinaryPath)
+// catalogURL returns the install source of a catalog plugin or template at
+// path in the catalog repository
+func catalogURL(path string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/tree/main/%s", catalogOwner, catalogRepo, path)
+}
 
-	logger.Info("Plugin installed successfully", "name", pluginName)
-	return nil
+// treeURL is a directory in a GitHub repository, from a URL like
+// https://github.com/owner/repo/tree/ref/path
+type treeURL struct {
+	Owner string
+	Repo  string
+	Ref   string
+	Path  string
 }
 
-func loadPluginManifest(path string) (*PluginManifest, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+// parseTreeURL parses a GitHub tree URL. The ref is a single path segment,
+// so branches with a / in their name aren't supported.
+func parseTreeURL(source string) (treeURL, error) {
+	invalid := func(reason string) (treeURL, error) {
+		return treeURL{}, fmt.Errorf("invalid GitHub tree URL %q: %s (expected https://github.com/<owner>/<repo>/tree/<ref>/<path>)", source, reason)
 	}
 
-	var manifest PluginManifest
-	err = toml.Unmarshal(data, &manifest)
+	u, err := url.Parse(strings.TrimSpace(source))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		return invalid(err.Error())
 	}
-
-	if manifest.Metadata.Name == "" {
-		return nil, fmt.Errorf("plugin name is missing in the manifest file")
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return invalid("not an http(s) URL")
+	}
+	if host := strings.ToLower(u.Host); host != "github.com" && host != "www.github.com" {
+		return invalid("not a github.com URL")
 	}
 
-	return &manifest, nil
-}
-
-func downloadFromGitspaceCatalog(logger *logger.RateLimitedLogger, source, tempDir string) error {
-	parts := strings.Split(strings.TrimPrefix(source, "https://github.com/"), "/")
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
 	if len(parts) < 5 {
-		return fmt.Errorf("invalid Gitspace Catalog URL: %s", source)
+		return invalid("missing the ref or path")
+	}
+	if parts[2] != "tree" {
+		return invalid("not a tree URL")
+	}
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return invalid("empty or relative path segment")
+		}
 	}
+	return treeURL{
+		Owner: parts[0],
+		Repo:  strings.TrimSuffix(parts[1], ".git"),
+		Ref:   parts[3],
+		Path:  strings.Join(parts[4:], "/"),
+	}, nil
+}
 
-	owner := parts[0]
-	repo := parts[1]
-	path := strings.Join(parts[4:], "/")
+func downloadFromGitspaceCatalog(logger *logger.RateLimitedLogger, source, tempDir string, timeout time.Duration) error {
+	tree, err := parseTreeURL(source)
+	if err != nil {
+		return err
+	}
 
 	logger.Debug("Downloading from Gitspace Catalog",
-		"owner", owner,
-		"repo", repo,
-		"path", path,
+		"owner", tree.Owner,
+		"repo", tree.Repo,
+		"ref", tree.Ref,
+		"path", tree.Path,
 		"dest", tempDir)
 
-	ctx := context.Background()
-	return lib.DownloadDirectory(ctx, lib.SCMTypeGitHub, "", owner, repo, path, tempDir)
+	ctx, cancel := lib.WithNetworkTimeout(context.Background(), timeout)
+	defer cancel()
+	provider, err := catalogProvider()
+	if err != nil {
+		return err
+	}
+	err = lib.DownloadDirectoryAtRef(ctx, provider, tree.Owner, tree.Repo, tree.Ref, tree.Path, tempDir)
+	return lib.TimeoutError(err, "downloading "+tree.Path+" from the Gitspace Catalog", timeout)
 }
 
 func copyFile(src, dst string) error {
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestSplitSourceRef(t *testing.T) {
+	tests := []struct {
+		source, wantURL, wantRef string
+	}{
+		{"https://github.com/org/plugin", "https://github.com/org/plugin", ""},
+		{"https://github.com/org/plugin#v1.2.0", "https://github.com/org/plugin", "v1.2.0"},
+		{"https://github.com/org/plugin# main ", "https://github.com/org/plugin", "main"},
+		{"https://github.com/org/plugin#", "https://github.com/org/plugin", ""},
+	}
+	for _, tt := range tests {
+		url, ref := splitSourceRef(tt.source)
+		if url != tt.wantURL || ref != tt.wantRef {
+			t.Errorf("splitSourceRef(%q) = %q, %q, want %q, %q", tt.source, url, ref, tt.wantURL, tt.wantRef)
+		}
+	}
+}
+
+func TestParseTreeURL(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    treeURL
+		wantErr string
+	}{
+		{"https://github.com/ssotops/gitspace-catalog/tree/main/plugins/hello", treeURL{"ssotops", "gitspace-catalog", "main", "plugins/hello"}, ""},
+		{" https://www.github.com/acme/catalog.git/tree/v1.2.0/hello/ ", treeURL{"acme", "catalog", "v1.2.0", "hello"}, ""},
+		{"https://github.com/acme/catalog/tree/main", treeURL{}, "missing the ref or path"},
+		{"https://github.com/acme/catalog/blob/main/hello", treeURL{}, "not a tree URL"},
+		{"https://gitlab.com/acme/catalog/tree/main/hello", treeURL{}, "not a github.com URL"},
+		{"ftp://github.com/acme/catalog/tree/main/hello", treeURL{}, "not an http(s) URL"},
+		{"https://github.com/acme/catalog/tree/main/../hello", treeURL{}, "relative path segment"},
+		{"https://github.com/acme/catalog/tree/main//hello", treeURL{}, "empty or relative"},
+	}
+	for _, tt := range tests {
+		got, err := parseTreeURL(tt.source)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseTreeURL(%q) error = %v, want %q", tt.source, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseTreeURL(%q) = %+v, %v, want %+v", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+// runGit runs git in dir and fails the test if it does
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=gitspace", "-c", "user.email=gitspace@example.com"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestGitCloneChecksOutRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	origin := t.TempDir()
+	runGit(t, origin, "init", "--quiet")
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		if err := os.WriteFile(filepath.Join(origin, "VERSION"), []byte(version), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, origin, "add", "VERSION")
+		runGit(t, origin, "commit", "--quiet", "-m", version)
+		runGit(t, origin, "tag", "v"+version)
+	}
+
+	tests := []struct {
+		ref, want string
+	}{
+		{"", "2.0.0"},
+		{"v1.0.0", "1.0.0"},
+		{runGit(t, origin, "rev-parse", "v1.0.0"), "1.0.0"},
+	}
+	for _, tt := range tests {
+		dest := filepath.Join(t.TempDir(), "plugin")
+		if err := gitClone(origin, dest, tt.ref); err != nil {
+			t.Fatalf("gitClone with ref %q: %v", tt.ref, err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dest, "VERSION")); string(got) != tt.want {
+			t.Errorf("ref %q checked out %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+
+	if err := gitClone(origin, filepath.Join(t.TempDir(), "plugin"), "v9.9.9"); err == nil {
+		t.Error("cloning an unknown ref succeeded")
+	}
+	// A ref can't smuggle options into git checkout, and is refused before
+	// anything is cloned
+	dest := filepath.Join(t.TempDir(), "plugin")
+	if err := gitClone(origin, dest, "--orphan=x"); err == nil || !strings.Contains(err.Error(), "invalid git ref") {
+		t.Errorf("got error %v for an option-like ref", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("an invalid ref left a clone behind: %v", err)
+	}
+}
+
+func TestInstallPluginRejectsOptionLikeRef(t *testing.T) {
+	m := newTestManager(t)
+	err := InstallPlugin(m.logger, m, "https://example.invalid/plugin#--upload-pack=x")
+	if err == nil || !strings.Contains(err.Error(), "invalid git ref") {
+		t.Errorf("got error %v, want the ref refused before cloning", err)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "cmd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cmd", "main.go"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const sum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	tests := []struct {
+		name      string
+		checksums map[string]string
+		wantErr   string
+	}{
+		{"none", nil, ""},
+		{"matching", map[string]string{"cmd/main.go": sum}, ""},
+		{"prefixed and uppercase", map[string]string{"cmd/main.go": "sha256:" + strings.ToUpper(sum)}, ""},
+		{"mismatch", map[string]string{"cmd/main.go": strings.Repeat("0", 64)}, "doesn't match the manifest checksum"},
+		{"missing file", map[string]string{"cmd/gone.go": sum}, "failed to verify cmd/gone.go"},
+		{"outside the plugin", map[string]string{"../main.go": sum}, "must be inside the plugin"},
+	}
+	for _, tt := range tests {
+		err := verifyChecksums(dir, tt.checksums)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInstallPluginOffline(t *testing.T) {
+	m := newTestManager(t)
+	lib.SetOffline(true)
+	t.Cleanup(func() { lib.SetOffline(false) })
+
+	source := filepath.Join(t.TempDir(), "hello")
+	writeTestManifest(t, source, "hello")
+	if err := InstallPlugin(m.logger, m, source); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("offline install returned %v, want %v", err, lib.ErrOffline)
+	}
+	if _, err := catalogProvider(); !errors.Is(err, lib.ErrOffline) {
+		t.Errorf("offline catalog returned %v, want %v", err, lib.ErrOffline)
+	}
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"time"
+)
+
+// memoryCheckInterval is how often a plugin's memory use is sampled
+const memoryCheckInterval = time.Second
+
+// ResourceLimits are guardrails applied to each plugin process. Zero values
+// disable a limit.
+type ResourceLimits struct {
+	MaxMemoryMB int
+	MaxRuntime  time.Duration
+}
+
+// enforceLimits kills the plugin when it outlives MaxRuntime or its resident
+// memory exceeds MaxMemoryMB. It returns once the process has exited.
+func (p *Plugin) enforceLimits(limits ResourceLimits) {
+	var deadline <-chan time.Time
+	if limits.MaxRuntime > 0 {
+		timer := time.NewTimer(limits.MaxRuntime)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var sample <-chan time.Time
+	if limits.MaxMemoryMB > 0 {
+		if !memoryLimitSupported {
+			p.Logger.Warn("max_memory_mb is not supported on this platform, ignoring it", "name", p.Name)
+		} else {
+			ticker := time.NewTicker(memoryCheckInterval)
+			defer ticker.Stop()
+			sample = ticker.C
+		}
+	}
+
+	if deadline == nil && sample == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-p.exited:
+			return
+		case <-deadline:
+			p.Logger.Warn("Plugin exceeded max_runtime_seconds, killing it", "name", p.Name, "limit", limits.MaxRuntime)
+			p.stop()
+			return
+		case <-sample:
+			rss, err := processRSS(p.cmd.Process.Pid)
+			if err != nil {
+				// The process may have just exited; p.exited will tell
+				continue
+			}
+			if rss > uint64(limits.MaxMemoryMB)*1024*1024 {
+				p.Logger.Warn("Plugin exceeded max_memory_mb, killing it", "name", p.Name, "limit_mb", limits.MaxMemoryMB, "rss_mb", rss/1024/1024)
+				p.stop()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package plugin
+
+import "errors"
+
+const memoryLimitSupported = false
+
+func processRSS(pid int) (uint64, error) {
+	return 0, errors.New("memory sampling is only supported on Linux")
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// waitForExit fails the test unless the plugin's process exits within d
+func waitForExit(t *testing.T, p *Plugin, d time.Duration) {
+	t.Helper()
+	select {
+	case <-p.exited:
+	case <-time.After(d):
+		t.Fatalf("plugin still running after %v", d)
+	}
+}
+
+func TestMaxRuntimeKillsPlugin(t *testing.T) {
+	m := newTestManager(t)
+	m.SetResourceLimits(ResourceLimits{MaxRuntime: 200 * time.Millisecond})
+	loadTestPlugin(t, m, "testplugin")
+
+	waitForExit(t, m.GetLoadedPlugins()["testplugin"], 5*time.Second)
+	if m.IsPluginRunning("testplugin") {
+		t.Error("a plugin past max_runtime_seconds is still running")
+	}
+}
+
+func TestMaxMemoryKillsPlugin(t *testing.T) {
+	if !memoryLimitSupported {
+		t.Skip("memory sampling is not supported on this platform")
+	}
+	m := newTestManager(t)
+	// Any Go binary holds more than a megabyte resident
+	m.SetResourceLimits(ResourceLimits{MaxMemoryMB: 1})
+	loadTestPlugin(t, m, "testplugin")
+
+	waitForExit(t, m.GetLoadedPlugins()["testplugin"], 5*memoryCheckInterval)
+}
+
+func TestPluginWithinLimitsKeepsRunning(t *testing.T) {
+	m := newTestManager(t)
+	m.SetResourceLimits(ResourceLimits{MaxMemoryMB: 4096, MaxRuntime: time.Hour})
+	loadTestPlugin(t, m, "testplugin")
+
+	time.Sleep(2 * memoryCheckInterval)
+	if !m.IsPluginRunning("testplugin") {
+		t.Fatal("a plugin within its limits was stopped")
+	}
+	if result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "hi"}); err != nil || result != "hi" {
+		t.Errorf("echo returned %q, %v", result, err)
+	}
+}
+
+func TestProcessRSS(t *testing.T) {
+	if !memoryLimitSupported {
+		t.Skip("memory sampling is not supported on this platform")
+	}
+	rss, err := processRSS(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rss < 1024*1024 {
+		t.Errorf("processRSS() = %d bytes for a running Go test", rss)
+	}
+	if _, err := processRSS(-1); err == nil {
+		t.Error("processRSS() found a process that doesn't exist")
+	}
+}
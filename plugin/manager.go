@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,11 +12,14 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	pb "github.com/ssotops/gitspace-plugin-sdk/proto"
+	"github.com/ssotops/gitspace/lib"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -24,13 +28,75 @@ type Manager struct {
 	discoveredPlugins map[string]string // map of plugin name to path
 	mu                sync.RWMutex
 	logger            *logger.RateLimitedLogger
+	networkTimeout    time.Duration
+	maxRestarts       int
+	requestTimeout    time.Duration
+	allowIncompatible bool
+	skipChecksum      bool
+	maxMessageSize    int
+	limits            ResourceLimits
+	logLevel          log.Level            // Level for plugin loggers without an override
+	logLevels         map[string]log.Level // Per-plugin log level overrides
+	restarts          map[string]int       // Restarts per plugin since it last answered a ping or command
+	configView        func() (*ConfigView, error)
 }
 
+// msgTypePing is a liveness check. The request and the pong have no payload.
+// Only plugins whose menu has a PingCommand option are pinged, since plugins
+// built with the SDK reject message types they don't know.
+const msgTypePing uint32 = 4
+
+// PingCommand is the command of a top-level option in a plugin's menu
+// advertising that the plugin answers pings. The option itself isn't shown
+// in the plugin's menu.
+const PingCommand = "gitspace.ping"
+
+// pingTimeout bounds a ping round trip. Plugins that advertise ping support
+// but don't answer in time when loaded are checked by process state only.
+const pingTimeout = 2 * time.Second
+
+// advertisesPing reports whether a plugin's menu has a PingCommand option
+func advertisesPing(menu []gsplug.MenuOption) bool {
+	for _, opt := range menu {
+		if opt.Command == PingCommand {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRequestTimeout bounds how long a plugin may take to answer a request
+const DefaultRequestTimeout = 10 * time.Second
+
+// errRequestTimeout marks a request the plugin didn't answer in time. The
+// plugin is stopped when this happens, since a late answer would be read as
+// the response to the next request.
+var errRequestTimeout = errors.New("plugin request timed out")
+
+// DefaultMaxMessageSize is the largest frame exchanged with a plugin
+const DefaultMaxMessageSize = 10 * 1024 * 1024
+
+var errMessageTooLarge = errors.New("message too large")
+
+func messageTooLarge(limit int) error {
+	return fmt.Errorf("%w: the limit is %d bytes (plugins.max_message_size)", errMessageTooLarge, limit)
+}
+
+// DefaultMaxRestarts is how many times a crashed plugin is reloaded before
+// the Manager gives up on it
+const DefaultMaxRestarts = 3
+
 func NewManager(l *logger.RateLimitedLogger) *Manager {
 	manager := &Manager{
 		plugins:           make(map[string]*Plugin),
 		discoveredPlugins: make(map[string]string),
 		logger:            l,
+		networkTimeout:    lib.DefaultNetworkTimeout,
+		maxRestarts:       DefaultMaxRestarts,
+		requestTimeout:    DefaultRequestTimeout,
+		maxMessageSize:    DefaultMaxMessageSize,
+		logLevel:          log.InfoLevel,
+		restarts:          make(map[string]int),
 	}
 
 	err := EnsurePluginDirectoryPermissions(l)
@@ -41,17 +107,144 @@ func NewManager(l *logger.RateLimitedLogger) *Manager {
 	return manager
 }
 
-func (m *Manager) LoadPlugin(name string) error {
+// SetNetworkTimeout bounds catalog fetches and plugin downloads
+func (m *Manager) SetNetworkTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.networkTimeout = timeout
+}
+
+// NetworkTimeout returns the timeout set by SetNetworkTimeout
+func (m *Manager) NetworkTimeout() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.networkTimeout
+}
+
+// SetMaxRestarts sets how many times a crashed plugin is reloaded; zero
+// disables restarting
+func (m *Manager) SetMaxRestarts(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxRestarts = n
+}
+
+// SetRequestTimeout sets how long plugins loaded from now on may take to
+// answer a request; zero waits indefinitely
+func (m *Manager) SetRequestTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requestTimeout = timeout
+}
+
+// SetAllowIncompatible lets plugins built with an incompatible SDK load
+// anyway, with a warning
+func (m *Manager) SetAllowIncompatible(allow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowIncompatible = allow
+}
+
+// SetSkipChecksum disables verifying plugin binaries against the checksum
+// recorded at install time. Meant for plugin development, where the binary
+// is rebuilt in place.
+func (m *Manager) SetSkipChecksum(skip bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.skipChecksum = skip
+}
+
+// SetMaxMessageSize sets the largest frame, in bytes, that plugins loaded
+// from now on may send or be sent
+func (m *Manager) SetMaxMessageSize(size int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.maxMessageSize = size
+}
+
+// SetResourceLimits sets the memory and runtime caps for plugins loaded from
+// now on
+func (m *Manager) SetResourceLimits(limits ResourceLimits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+}
+
+// SetLogLevels sets the level of plugin loggers created from now on.
+// Plugins named in overrides get their own level, so a noisy plugin can be
+// quieted or a suspect one turned up to debug.
+func (m *Manager) SetLogLevels(level log.Level, overrides map[string]log.Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logLevel = level
+	m.logLevels = overrides
+}
+
+// RestartPlugin stops what is left of a plugin and loads it again. It fails
+// once the plugin has been restarted more than the configured maximum.
+func (m *Manager) RestartPlugin(name string) error {
+	m.mu.Lock()
+	old := m.plugins[name]
+	delete(m.plugins, name)
+	m.restarts[name]++
+	count, limit := m.restarts[name], m.maxRestarts
+	m.mu.Unlock()
+
+	if old != nil {
+		old.stop()
+	}
+
+	if count > limit {
+		return fmt.Errorf("plugin %s keeps crashing; gave up after %d restarts", name, limit)
+	}
+
+	m.logger.Warn("Restarting plugin", "name", name, "restart", count, "max", limit)
+	if err := m.LoadPlugin(name); err != nil {
+		return fmt.Errorf("failed to restart plugin %s: %w", name, err)
+	}
+	return nil
+}
 
+// LoadPlugin starts a discovered plugin and fetches its info and menu. The
+// Manager's lock is only held while reading settings and storing the result,
+// so several plugins can be loaded at once.
+func (m *Manager) LoadPlugin(name string) error {
+	m.mu.RLock()
 	path, exists := m.discoveredPlugins[name]
+	skipChecksum, allowIncompatible := m.skipChecksum, m.allowIncompatible
+	requestTimeout, maxMessageSize, limits := m.requestTimeout, m.maxMessageSize, m.limits
+	logLevel, overridden := m.logLevels[name]
+	if !overridden {
+		logLevel = m.logLevel
+	}
+	m.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("plugin %s not discovered", name)
 	}
 
 	m.logger.Info("Attempting to load plugin", "name", name, "path", path)
 
+	if skipChecksum {
+		m.logger.Warn("Skipping plugin checksum verification", "name", name)
+	} else if verified, err := verifyPluginChecksum(path); err != nil {
+		return fmt.Errorf("refusing to load plugin %s: %w", name, err)
+	} else if !verified {
+		m.logger.Debug("No install checksum recorded, skipping verification", "name", name)
+	}
+
+	hostSDK, pluginSDK := HostSDKVersion(), PluginSDKVersion(path)
+	warning, err := checkSDKCompatibility(hostSDK, pluginSDK)
+	switch {
+	case err != nil && !allowIncompatible:
+		return fmt.Errorf("refusing to load plugin %s: %w (set allow_incompatible under [plugins] to load it anyway)", name, err)
+	case err != nil:
+		m.logger.Warn("⚠️  Loading incompatible plugin because allow_incompatible is set", "name", name, "error", err)
+	case warning != "":
+		m.logger.Warn("⚠️  "+warning, "name", name)
+	case pluginSDK == "":
+		m.logger.Debug("Plugin SDK version unknown, skipping compatibility check", "name", name)
+	}
+
 	cmd := exec.Command(path)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -90,6 +283,7 @@ func (m *Manager) LoadPlugin(name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create plugin logger: %w", err)
 	}
+	pluginLogger.SetLogLevel(logLevel)
 
 	plugin := &Plugin{
 		Name:   name,
@@ -97,30 +291,80 @@ func (m *Manager) LoadPlugin(name string) error {
 		cmd:    cmd,
 		stdin:  bufferedStdin,
 		stdout: stdout,
+		exited: make(chan struct{}),
 		Logger: pluginLogger,
+
+		responses:      make(chan pluginMessage, 4),
+		readDone:       make(chan struct{}),
+		requestTimeout: requestTimeout,
+		maxMessageSize: maxMessageSize,
+		configView:     m.currentConfigView,
 	}
+	go plugin.readResponses()
+
+	// Reap the process so a crash is noticed instead of leaving a zombie. Wait
+	// closes stdout, so it only runs once the reader has seen the end of it.
+	go func() {
+		<-plugin.readDone
+		err := cmd.Wait()
+		m.logger.Debug("Plugin process exited", "name", name, "error", err)
+		close(plugin.exited)
+	}()
+	go plugin.enforceLimits(limits)
 
 	m.logger.Debug("Sending GetPluginInfo request", "name", name)
 	infoResp, err := plugin.sendRequest(1, &pb.PluginInfoRequest{})
 	if err != nil {
+		plugin.stop()
 		return fmt.Errorf("failed to get plugin info: %w", err)
 	}
 	m.logger.Debug("Received GetPluginInfo response", "name", name, "response", fmt.Sprintf("%+v", infoResp))
+	if info, ok := infoResp.(*pb.PluginInfo); ok {
+		plugin.Version = info.Version
+	}
+	plugin.SDKVersion = pluginSDK
 
 	// Get menu
 	m.logger.Debug("Getting plugin menu", "name", name)
 	menuResp, err := plugin.sendRequest(3, &pb.MenuRequest{})
 	if err != nil {
+		plugin.stop()
 		return fmt.Errorf("failed to get plugin menu: %w", err)
 	}
 	menu, ok := menuResp.(*pb.MenuResponse)
 	if !ok {
+		plugin.stop()
 		return fmt.Errorf("unexpected response type for plugin menu")
 	}
 	m.logger.Debug("Plugin menu received", "name", name, "menuDataSize", len(menu.MenuData))
+	var menuOptions []gsplug.MenuOption
+	if err := json.Unmarshal(menu.MenuData, &menuOptions); err == nil {
+		plugin.MainMenuTitle = mainMenuTitle(menuOptions)
+		plugin.Events = subscribedEvents(menuOptions)
+		plugin.supportsPing.Store(advertisesPing(menuOptions))
+	}
+
+	// Check that a plugin advertising ping support answers, so IsPluginRunning
+	// can rely on pings to detect it hanging later
+	if plugin.supportsPing.Load() {
+		if latency, err := plugin.ping(); err != nil {
+			m.logger.Warn("Plugin advertises pings but didn't answer, checking process state only", "name", name, "error", err)
+			plugin.supportsPing.Store(false)
+		} else {
+			m.logger.Debug("Plugin answered ping", "name", name, "latency", latency)
+		}
+	}
 
-	// Store the plugin
+	// Store the plugin, unless a concurrent load of the same plugin won
+	m.mu.Lock()
+	if _, loaded := m.plugins[name]; loaded {
+		m.mu.Unlock()
+		plugin.stop()
+		m.logger.Debug("Plugin was loaded concurrently, keeping the first instance", "name", name)
+		return nil
+	}
 	m.plugins[name] = plugin
+	m.mu.Unlock()
 
 	m.logger.Info("Plugin loaded successfully", "name", name)
 	return nil
@@ -135,9 +379,7 @@ func (m *Manager) UnloadPlugin(name string) error {
 		return fmt.Errorf("plugin not found: %s", name)
 	}
 
-	if err := plugin.cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("failed to kill plugin process: %w", err)
-	}
+	plugin.stop()
 
 	delete(m.plugins, name)
 	delete(m.discoveredPlugins, name) // Changed from m.installedPlugins to m.discoveredPlugins
@@ -198,13 +440,17 @@ func (m *Manager) ExecuteCommand(pluginName, command string, params map[string]s
 	}
 
 	// Validate that all required parameters are provided
+	var missing []string
 	for _, param := range selectedOption.Parameters {
 		if param.Required {
 			if _, ok := params[param.Name]; !ok {
-				return "", fmt.Errorf("missing required parameter: %s", param.Name)
+				missing = append(missing, param.Name)
 			}
 		}
 	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing required parameters for %s: %s", command, strings.Join(missing, ", "))
+	}
 
 	// Execute the command with provided parameters
 	req := &pb.CommandRequest{
@@ -214,6 +460,14 @@ func (m *Manager) ExecuteCommand(pluginName, command string, params map[string]s
 
 	resp, err := plugin.sendRequest(2, req)
 	if err != nil {
+		if isPluginGone(err) {
+			plugin.stop()
+			return "", fmt.Errorf("plugin %s has terminated unexpectedly: %w", pluginName, err)
+		}
+		if errors.Is(err, errRequestTimeout) {
+			m.dropPlugin(pluginName, plugin)
+			return "", err
+		}
 		return "", fmt.Errorf("error sending request to plugin: %w", err)
 	}
 
@@ -221,6 +475,7 @@ func (m *Manager) ExecuteCommand(pluginName, command string, params map[string]s
 	if !ok {
 		return "", fmt.Errorf("unexpected response type: %T", resp)
 	}
+	m.resetRestarts(pluginName)
 
 	if !cmdResp.Success {
 		return "", fmt.Errorf("command failed: %s", cmdResp.ErrorMessage)
@@ -256,12 +511,14 @@ func (m *Manager) GetPluginMenu(pluginName string) (*pb.MenuResponse, error) {
 
 	resp, err := plugin.sendRequest(3, req)
 	if err != nil {
-		if strings.Contains(err.Error(), "broken pipe") {
-			m.mu.Lock()
-			delete(m.plugins, pluginName)
-			m.mu.Unlock()
+		if isPluginGone(err) {
+			plugin.stop()
 			return nil, fmt.Errorf("plugin %s has terminated unexpectedly", pluginName)
 		}
+		if errors.Is(err, errRequestTimeout) {
+			m.dropPlugin(pluginName, plugin)
+			return nil, err
+		}
 		log.Printf("Error getting menu from plugin %s: %v", pluginName, err)
 		return nil, err
 	}
@@ -284,31 +541,10 @@ func (p *Plugin) sendRequest(msgType uint32, msg proto.Message) (proto.Message,
 	}
 	p.Logger.Debug("Marshaled request", "data", fmt.Sprintf("%x", data))
 
-	p.Logger.Debug("Writing message type", "type", msgType)
-	if _, err := p.stdin.Write([]byte{byte(msgType)}); err != nil {
-		return nil, fmt.Errorf("failed to write message type: %w", err)
-	}
-
-	p.Logger.Debug("Writing message length", "length", len(data))
-	if err := binary.Write(p.stdin, binary.LittleEndian, uint32(len(data))); err != nil {
-		return nil, fmt.Errorf("failed to write message length: %w", err)
-	}
-
-	p.Logger.Debug("Writing message data", "data", fmt.Sprintf("%x", data))
-	if _, err := p.stdin.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to write message data: %w", err)
-	}
-
-	if err := p.stdin.(*bufferedWriteCloser).Flush(); err != nil {
-		p.Logger.Warn("Failed to flush stdin", "error", err)
-	}
-
-	p.Logger.Debug("Waiting for response", "name", p.Name)
-	respType, respData, err := readMessage(p.stdout)
+	respType, respData, err := p.roundTrip(msgType, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
-	p.Logger.Debug("Received response", "type", respType, "dataLength", len(respData), "rawData", fmt.Sprintf("%x", respData))
 
 	var resp proto.Message
 	switch respType {
@@ -331,6 +567,185 @@ func (p *Plugin) sendRequest(msgType uint32, msg proto.Message) (proto.Message,
 	return resp, nil
 }
 
+// roundTrip writes a request frame and returns the plugin's response frame,
+// stopping the plugin if it doesn't answer within its request timeout
+func (p *Plugin) roundTrip(msgType uint32, data []byte) (uint32, []byte, error) {
+	p.requestMu.Lock()
+	defer p.requestMu.Unlock()
+
+	if err := p.writeFrame(msgType, data); err != nil {
+		return 0, nil, err
+	}
+
+	var timeout <-chan time.Time
+	if p.requestTimeout > 0 {
+		timer := time.NewTimer(p.requestTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	p.Logger.Debug("Waiting for response", "name", p.Name)
+	respType, respData, err := p.awaitResponse(timeout, false)
+	if errors.Is(err, errRequestTimeout) {
+		p.Logger.Error("Plugin did not respond in time, stopping it", "name", p.Name, "type", msgType, "timeout", p.requestTimeout)
+		p.stop()
+		return 0, nil, fmt.Errorf("plugin %s did not respond to request type %d within %s: %w", p.Name, msgType, p.requestTimeout, err)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	p.Logger.Debug("Received response", "type", respType, "dataLength", len(respData), "rawData", fmt.Sprintf("%x", respData))
+	return respType, respData, nil
+}
+
+// writeFrame writes one type/length/data frame to the plugin's stdin
+func (p *Plugin) writeFrame(msgType uint32, data []byte) error {
+	if len(data) > p.maxMessageSize {
+		return fmt.Errorf("request of %d bytes: %w", len(data), messageTooLarge(p.maxMessageSize))
+	}
+
+	// Assemble the whole frame so a failed write can't leave half a header
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = byte(msgType)
+	binary.LittleEndian.PutUint32(frame[1:], uint32(len(data)))
+	frame = append(frame, data...)
+
+	p.Logger.Debug("Writing message", "type", msgType, "length", len(data), "data", fmt.Sprintf("%x", data))
+	if err := writeFull(p.stdin, frame); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	if err := p.stdin.(*bufferedWriteCloser).Flush(); err != nil {
+		return fmt.Errorf("failed to flush message: %w", err)
+	}
+	return nil
+}
+
+// writeFull writes all of buf, retrying short writes
+func writeFull(w io.Writer, buf []byte) error {
+	for len(buf) > 0 {
+		n, err := w.Write(buf)
+		buf = buf[n:]
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+	}
+	return nil
+}
+
+// readResponses moves frames from the plugin's stdout onto p.responses until
+// the stream ends, so waiting for a response can be bounded by a timer. It
+// closes p.readDone once it has stopped reading.
+func (p *Plugin) readResponses() {
+	for {
+		msgType, data, err := readMessage(p.stdout, p.maxMessageSize)
+		// An oversized message is skipped whole, so the stream is still in step
+		if err != nil && !errors.Is(err, errMessageTooLarge) {
+			close(p.readDone)
+			p.responses <- pluginMessage{err: err}
+			return
+		}
+		p.responses <- pluginMessage{msgType: msgType, data: data, err: err}
+	}
+}
+
+// awaitResponse returns the next frame from the plugin. A nil timeout waits
+// indefinitely. Late pongs from a ping that already timed out are skipped
+// unless wantPong is set, and config requests are answered on the way.
+func (p *Plugin) awaitResponse(timeout <-chan time.Time, wantPong bool) (uint32, []byte, error) {
+	for {
+		select {
+		case msg := <-p.responses:
+			if msg.err != nil {
+				return 0, nil, msg.err
+			}
+			if msg.msgType == msgTypePing && !wantPong {
+				continue
+			}
+			if msg.msgType == msgTypeConfig {
+				p.serveConfig()
+				continue
+			}
+			return msg.msgType, msg.data, nil
+		case <-p.exited:
+			// Drain whatever the process wrote before exiting
+			select {
+			case msg := <-p.responses:
+				if msg.err != nil {
+					return 0, nil, msg.err
+				}
+				return msg.msgType, msg.data, nil
+			default:
+				return 0, nil, fmt.Errorf("plugin process exited: %w", io.EOF)
+			}
+		case <-timeout:
+			return 0, nil, errRequestTimeout
+		}
+	}
+}
+
+// ping sends a ping and waits up to pingTimeout for the pong
+func (p *Plugin) ping() (time.Duration, error) {
+	p.requestMu.Lock()
+	defer p.requestMu.Unlock()
+
+	start := time.Now()
+	if err := p.writeFrame(msgTypePing, nil); err != nil {
+		return 0, err
+	}
+
+	timer := time.NewTimer(pingTimeout)
+	defer timer.Stop()
+	for {
+		msgType, _, err := p.awaitResponse(timer.C, true)
+		if err != nil {
+			return 0, err
+		}
+		if msgType == msgTypePing {
+			return time.Since(start), nil
+		}
+		p.Logger.Warn("Discarding unexpected message while waiting for pong", "type", msgType)
+	}
+}
+
+// PingPlugin measures the round trip of a ping to a loaded plugin
+func (m *Manager) PingPlugin(name string) (time.Duration, error) {
+	m.mu.RLock()
+	plugin, exists := m.plugins[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return 0, fmt.Errorf("plugin not found: %s", name)
+	}
+	if !plugin.supportsPing.Load() {
+		return 0, fmt.Errorf("plugin %s doesn't answer pings", name)
+	}
+	latency, err := plugin.ping()
+	if err == nil {
+		m.resetRestarts(name)
+	}
+	return latency, err
+}
+
+// GetPlugin returns the loaded plugin with the given name
+func (m *Manager) GetPlugin(name string) (*Plugin, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	plugin, exists := m.plugins[name]
+	return plugin, exists
+}
+
+// resetRestarts clears a plugin's restart count once it has answered again,
+// so max_restarts only counts restarts in a row
+func (m *Manager) resetRestarts(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.restarts, name)
+}
+
 func (m *Manager) GetDiscoveredPlugins() map[string]string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -344,25 +759,77 @@ func (m *Manager) GetDiscoveredPlugins() map[string]string {
 	return discoveredPlugins
 }
 
+// maxConcurrentLoads bounds how many plugin processes are started at once
+const maxConcurrentLoads = 4
+
+// LoadAllPlugins discovers and loads every plugin on a small worker pool, so
+// one slow plugin doesn't hold up the rest. A plugin that fails to load
+// doesn't stop the others; all failures are returned joined together.
 func (m *Manager) LoadAllPlugins() error {
 	err := m.DiscoverPlugins()
 	if err != nil {
 		return fmt.Errorf("failed to discover plugins: %w", err)
 	}
 
-	for name := range m.discoveredPlugins {
-		err := m.LoadPlugin(name)
-		if err != nil {
-			m.logger.Warn("Failed to load plugin", "name", name, "error", err)
-		}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex // guards errs
+		errs []error
+	)
+	names := make(chan string)
+
+	for i := 0; i < maxConcurrentLoads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				if err := m.LoadPlugin(name); err != nil {
+					m.logger.Warn("Failed to load plugin", "name", name, "error", err)
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+					mu.Unlock()
+				}
+			}
+		}()
 	}
 
-	return nil
+	for name := range m.GetFilteredPlugins() {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+
+	return errors.Join(errs...)
 }
 
 // EnsurePluginDirectoryPermissions ensures that the plugins directory has the correct permissions and ownership
 // Without this, we'll see logs like this (which effectively means the plugin is not loaded):
 // WARN <plugin/manager.go:222> Failed to load plugin name=hello-world error="failed to start plugin process: fork/exec /Users/alechp/.ssot/gitspace/plugins/hello-world/hello-world: permission denied"
+// CheckPluginDirectoryPermissions reports the paths in the plugins directory
+// that EnsurePluginDirectoryPermissions would change, without modifying them
+func CheckPluginDirectoryPermissions() ([]string, error) {
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugins directory: %w", err)
+	}
+
+	var wrong []string
+	err = filepath.Walk(pluginsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm() != 0755 {
+			wrong = append(wrong, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions for plugins directory: %w", err)
+	}
+
+	return wrong, nil
+}
+
 func EnsurePluginDirectoryPermissions(logger *logger.RateLimitedLogger) error {
 	pluginsDir, err := getPluginsDir()
 	if err != nil {
@@ -414,23 +881,37 @@ func (m *Manager) DiscoverPlugins() error {
 		return fmt.Errorf("failed to read plugins directory: %w", err)
 	}
 
+	// Stat the plugin binaries concurrently; on network home directories each
+	// stat can be slow
+	var wg sync.WaitGroup
 	for _, entry := range entries {
-		if entry.IsDir() {
-			pluginName := entry.Name()
+		if !entry.IsDir() {
+			continue
+		}
+		wg.Add(1)
+		go func(pluginName string) {
+			defer wg.Done()
 			pluginPath := filepath.Join(pluginsDir, pluginName, pluginName)
-			m.discoveredPlugins[pluginName] = pluginPath
+			if _, err := os.Stat(pluginPath); err != nil {
+				m.logger.Debug("Plugin directory has no binary, skipping", "name", pluginName, "error", err)
+				return
+			}
+			m.AddDiscoveredPlugin(pluginName, pluginPath)
 			m.logger.Debug("Discovered plugin", "name", pluginName, "path", pluginPath)
-		}
+		}(entry.Name())
 	}
+	wg.Wait()
 
-	m.logger.Debug("Total discovered plugins", "count", len(m.discoveredPlugins))
+	m.logger.Debug("Total discovered plugins", "count", len(m.GetDiscoveredPlugins()))
 
 	return nil
 }
 
-func readMessage(r io.Reader) (uint32, []byte, error) {
+// readMessage reads one frame. A frame longer than maxSize is discarded and
+// reported with errMessageTooLarge.
+func readMessage(r io.Reader, maxSize int) (uint32, []byte, error) {
 	var msgTypeByte [1]byte
-	n, err := r.Read(msgTypeByte[:])
+	n, err := io.ReadFull(r, msgTypeByte[:])
 	if err != nil {
 		return 0, nil, fmt.Errorf("failed to read message type: %w", err)
 	}
@@ -444,8 +925,11 @@ func readMessage(r io.Reader) (uint32, []byte, error) {
 	}
 	log.Debug("Read message length", "length", msgLen)
 
-	if msgLen > 10*1024*1024 { // 10 MB limit, adjust as needed
-		return 0, nil, fmt.Errorf("message too large: %d bytes", msgLen)
+	if int64(msgLen) > int64(maxSize) {
+		if _, err := io.CopyN(io.Discard, r, int64(msgLen)); err != nil {
+			return 0, nil, fmt.Errorf("failed to skip oversized message: %w", err)
+		}
+		return 0, nil, fmt.Errorf("response of %d bytes: %w", msgLen, messageTooLarge(maxSize))
 	}
 
 	data := make([]byte, msgLen)
@@ -494,11 +978,59 @@ func (m *Manager) GetFilteredPlugins() map[string]string {
 }
 
 func (m *Manager) IsPluginRunning(pluginName string) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	plugin, exists := m.plugins[pluginName]
+	plugin, exists := m.GetPlugin(pluginName)
 	if !exists {
 		return false
 	}
-	return plugin.cmd.ProcessState == nil || !plugin.cmd.ProcessState.Exited()
+	select {
+	case <-plugin.exited:
+		return false
+	default:
+	}
+
+	// A plugin that answers pings is only running if it still does; a hung
+	// one would otherwise look alive forever
+	if plugin.supportsPing.Load() {
+		if _, err := plugin.ping(); err != nil {
+			m.logger.Warn("Plugin stopped answering pings", "name", pluginName, "error", err)
+			plugin.stop()
+			return false
+		}
+		m.resetRestarts(pluginName)
+	}
+	return true
+}
+
+// dropPlugin forgets a loaded plugin after it was stopped for being
+// unresponsive. It stays discovered, so it can be loaded again.
+func (m *Manager) dropPlugin(name string, plugin *Plugin) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.plugins[name] == plugin {
+		delete(m.plugins, name)
+	}
+}
+
+// stop kills the plugin process, if it is still running, and waits for it
+// to be reaped
+func (p *Plugin) stop() {
+	select {
+	case <-p.exited:
+		return
+	default:
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		p.Logger.Warn("Failed to kill plugin process", "name", p.Name, "error", err)
+	}
+	<-p.exited
+}
+
+// isPluginGone reports whether a request failed because the plugin process
+// went away, as opposed to the plugin returning bad data
+func isPluginGone(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, os.ErrClosed) ||
+		strings.Contains(err.Error(), "broken pipe")
 }
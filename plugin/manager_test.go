@@ -0,0 +1,571 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	pb "github.com/ssotops/gitspace-plugin-sdk/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// testPluginEnv makes the test binary run as a plugin instead of the tests,
+// so the Manager can load it like any installed plugin
+const testPluginEnv = "GITSPACE_TEST_PLUGIN"
+
+// testPluginEventsEnv names a file the test plugin appends the events it
+// receives to, one JSON object per line
+const testPluginEventsEnv = "GITSPACE_TEST_PLUGIN_EVENTS"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		runTestPlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testPluginMenu is the test plugin's menu. It asks for a main menu entry,
+// subscribes to post_clone and post_sync unless the plugin runs in
+// testPluginUnsubscribed mode, and advertises pings unless it runs in
+// testPluginNoPing mode.
+func testPluginMenu(mode string) []gsplug.MenuOption {
+	menu := []gsplug.MenuOption{
+		{Label: "Test Tools", Command: MainMenuCommand},
+		{Label: "Echo", Command: "echo", Parameters: []gsplug.ParameterInfo{{Name: "text", Required: true}}},
+		{Label: "Slow", Command: "slow"},
+		{Label: "Large", Command: "large"},
+		{Label: "Config", Command: "config"},
+		{Label: "Mute", Command: "mute"},
+		{Label: "Last", Command: "last"},
+		{Label: "Exit", Command: "exit"},
+	}
+	if mode != testPluginUnsubscribed {
+		menu = append(menu, gsplug.MenuOption{
+			Label:      "events",
+			Command:    EventsCommand,
+			Parameters: []gsplug.ParameterInfo{{Name: EventPostClone}, {Name: EventPostSync}},
+		})
+	}
+	if mode != testPluginNoPing {
+		menu = append(menu, gsplug.MenuOption{Label: "ping", Command: PingCommand})
+	}
+	return menu
+}
+
+// Values of testPluginEnv besides "1"
+const (
+	testPluginUnsubscribed = "unsubscribed" // Subscribe to no events
+	testPluginFailEvents   = "fail-events"  // Answer every event with an error
+	testPluginNoPing       = "no-ping"      // Neither advertise nor answer pings, like plugins built with the SDK
+	testPluginSilentPing   = "silent-ping"  // Advertise pings but never answer them
+)
+
+// runTestPlugin serves the plugin protocol on stdin and stdout until stdin closes
+func runTestPlugin() {
+	reply := func(msgType uint32, msg proto.Message) {
+		data, _ := proto.Marshal(msg)
+		writeTestFrame(os.Stdout, msgType, data)
+	}
+	mode := os.Getenv(testPluginEnv)
+	muted := mode == testPluginNoPing || mode == testPluginSilentPing
+	for {
+		msgType, data, err := readMessage(os.Stdin, 1<<30)
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case 1:
+			reply(1, &pb.PluginInfo{Name: "testplugin", Version: "1.2.3"})
+		case 3:
+			menu, _ := json.Marshal(testPluginMenu(mode))
+			reply(3, &pb.MenuResponse{MenuData: menu})
+		case msgTypePing:
+			if !muted {
+				writeTestFrame(os.Stdout, msgTypePing, nil)
+			}
+		case msgTypeEvent:
+			if path := os.Getenv(testPluginEventsEnv); path != "" {
+				if f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+					f.Write(append(data, '\n'))
+					f.Close()
+				}
+			}
+			var reply EventReply
+			if mode == testPluginFailEvents {
+				reply.Error = "event handler failed"
+			}
+			data, _ := json.Marshal(reply)
+			writeTestFrame(os.Stdout, msgTypeEvent, data)
+		case 2:
+			var req pb.CommandRequest
+			proto.Unmarshal(data, &req)
+			switch req.Command {
+			case "echo":
+				reply(2, &pb.CommandResponse{Success: true, Result: req.Parameters["text"]})
+			case "slow":
+				time.Sleep(5 * time.Second)
+				reply(2, &pb.CommandResponse{Success: true})
+			case "large":
+				reply(2, &pb.CommandResponse{Success: true, Result: strings.Repeat("x", 64*1024)})
+			case "config":
+				// Ask for the config, then hand back the reply as the result
+				writeTestFrame(os.Stdout, msgTypeConfig, nil)
+				if _, config, err := readMessage(os.Stdin, 1<<30); err == nil {
+					reply(2, &pb.CommandResponse{Success: true, Result: string(config)})
+				}
+			case "mute":
+				// Stop answering pings while still running, as a hung plugin would
+				muted = true
+				reply(2, &pb.CommandResponse{Success: true})
+			case "last":
+				// Answer, then exit straight away
+				reply(2, &pb.CommandResponse{Success: true, Result: "bye"})
+				return
+			case "exit":
+				return
+			default:
+				reply(2, &pb.CommandResponse{ErrorMessage: "unknown command " + req.Command})
+			}
+		}
+	}
+}
+
+// writeTestFrame writes a frame the way plugins built with the SDK do
+func writeTestFrame(w *os.File, msgType uint32, data []byte) {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = byte(msgType)
+	binary.LittleEndian.PutUint32(frame[1:], uint32(len(data)))
+	w.Write(append(frame, data...))
+}
+
+// newTestManager points HOME at a temporary directory and returns a Manager
+// logging there
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	l, err := logger.NewRateLimitedLogger("gitspace-test")
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	return NewManager(l)
+}
+
+// loadTestPlugin loads the test binary as the plugin name, unloading it when
+// the test ends
+func loadTestPlugin(t *testing.T, m *Manager, name string) {
+	t.Helper()
+	loadTestPluginMode(t, m, name, "1")
+}
+
+// loadTestPluginMode loads the test binary as the plugin name, running in
+// mode, such as testPluginUnsubscribed
+func loadTestPluginMode(t *testing.T, m *Manager, name, mode string) {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testPluginEnv, mode)
+	m.AddDiscoveredPlugin(name, executable)
+	if err := m.LoadPlugin(name); err != nil {
+		t.Fatalf("failed to load the test plugin: %v", err)
+	}
+	t.Cleanup(func() { m.UnloadPlugin(name) })
+}
+
+func TestLoadPluginReadsInfoAndMenu(t *testing.T) {
+	m := newTestManager(t)
+	loadTestPlugin(t, m, "testplugin")
+
+	p := m.GetLoadedPlugins()["testplugin"]
+	if p == nil {
+		t.Fatal("plugin not loaded")
+	}
+	if p.Version != "1.2.3" {
+		t.Errorf("version %q, want 1.2.3", p.Version)
+	}
+	if _, err := m.PingPlugin("testplugin"); err != nil {
+		t.Errorf("ping failed: %v", err)
+	}
+	result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "hello"})
+	if err != nil || result != "hello" {
+		t.Errorf("echo returned %q, %v", result, err)
+	}
+	if _, err := m.ExecuteCommand("testplugin", "echo", nil); err == nil || !strings.Contains(err.Error(), "missing required parameters") {
+		t.Errorf("got error %v, want missing parameters", err)
+	}
+}
+
+func TestPromotedPluginUsesManagerSettings(t *testing.T) {
+	m := newTestManager(t)
+	// Settings applied before the plugin loads reach it
+	m.SetRequestTimeout(200 * time.Millisecond)
+	m.SetMaxMessageSize(1024)
+	loadTestPlugin(t, m, "testplugin")
+
+	promoted := m.PromotedPlugins()
+	if len(promoted) != 1 || promoted[0] != (PromotedPlugin{Name: "testplugin", Title: "Test Tools"}) {
+		t.Fatalf("promoted plugins %v, want the test plugin", promoted)
+	}
+
+	if _, err := m.ExecuteCommand("testplugin", "large", nil); !errors.Is(err, errMessageTooLarge) {
+		t.Errorf("got error %v for a response over max_message_size, want %v", err, errMessageTooLarge)
+	}
+
+	started := time.Now()
+	_, err := m.ExecuteCommand("testplugin", "slow", nil)
+	if !errors.Is(err, errRequestTimeout) {
+		t.Errorf("got error %v for a slow command, want %v", err, errRequestTimeout)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("the slow command took %s despite the %s request timeout", elapsed, 200*time.Millisecond)
+	}
+	if m.IsPluginLoaded("testplugin") {
+		t.Error("a plugin that timed out is still loaded")
+	}
+}
+
+func TestRestartCrashedPlugin(t *testing.T) {
+	m := newTestManager(t)
+	m.SetMaxRestarts(1)
+	loadTestPlugin(t, m, "testplugin")
+
+	_, err := m.ExecuteCommand("testplugin", "exit", nil)
+	if err == nil || !strings.Contains(err.Error(), "terminated unexpectedly") {
+		t.Fatalf("got error %v from a crashing command, want the plugin reported gone", err)
+	}
+	if m.IsPluginRunning("testplugin") {
+		t.Fatal("a crashed plugin is reported running")
+	}
+
+	if err := m.RestartPlugin("testplugin"); err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+
+	// Crashing again before answering anything is a second restart in a row,
+	// over max_restarts
+	m.ExecuteCommand("testplugin", "exit", nil)
+	if err := m.RestartPlugin("testplugin"); err == nil || !strings.Contains(err.Error(), "gave up after 1 restarts") {
+		t.Errorf("got error %v, want the restarts to stop", err)
+	}
+	if m.IsPluginLoaded("testplugin") {
+		t.Error("a plugin that gave up is still loaded")
+	}
+}
+
+func TestRestartCountResetsOnSuccess(t *testing.T) {
+	m := newTestManager(t)
+	m.SetMaxRestarts(1)
+	loadTestPlugin(t, m, "testplugin")
+
+	// Each crash is followed by a command that works, so none of the
+	// restarts is one too many
+	for i := 0; i < 3; i++ {
+		m.ExecuteCommand("testplugin", "exit", nil)
+		if err := m.RestartPlugin("testplugin"); err != nil {
+			t.Fatalf("restart %d failed: %v", i+1, err)
+		}
+		if result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "back"}); err != nil || result != "back" {
+			t.Fatalf("restarted plugin returned %q, %v", result, err)
+		}
+	}
+}
+
+func TestResponseBeforeExitIsRead(t *testing.T) {
+	m := newTestManager(t)
+	loadTestPlugin(t, m, "testplugin")
+
+	// The process is only reaped once its stdout has been read to the end,
+	// so the last response it wrote is never lost
+	for i := 0; i < 5; i++ {
+		if result, err := m.ExecuteCommand("testplugin", "last", nil); err != nil || result != "bye" {
+			t.Fatalf("run %d: got %q, %v, want the final response", i+1, result, err)
+		}
+		if err := m.RestartPlugin("testplugin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPingDetectsHungPlugin(t *testing.T) {
+	m := newTestManager(t)
+	loadTestPlugin(t, m, "testplugin")
+
+	if !m.GetLoadedPlugins()["testplugin"].supportsPing.Load() {
+		t.Fatal("the advertised ping support wasn't noticed on load")
+	}
+	if !m.IsPluginRunning("testplugin") {
+		t.Fatal("a responsive plugin isn't running")
+	}
+
+	if _, err := m.ExecuteCommand("testplugin", "mute", nil); err != nil {
+		t.Fatal(err)
+	}
+	if m.IsPluginRunning("testplugin") {
+		t.Error("a plugin that stopped answering pings is still running")
+	}
+	if _, err := m.PingPlugin("testplugin"); err == nil {
+		t.Error("a stopped plugin answered a ping")
+	}
+}
+
+func TestPluginWithoutPingSupport(t *testing.T) {
+	m := newTestManager(t)
+	start := time.Now()
+	loadTestPluginMode(t, m, "testplugin", testPluginNoPing)
+
+	// A plugin that doesn't advertise pings isn't made to wait out a ping
+	if elapsed := time.Since(start); elapsed >= pingTimeout {
+		t.Errorf("loading took %v, want no ping sent", elapsed)
+	}
+	if m.GetLoadedPlugins()["testplugin"].supportsPing.Load() {
+		t.Error("a plugin that doesn't advertise pings was marked as supporting them")
+	}
+	if _, err := m.PingPlugin("testplugin"); err == nil {
+		t.Error("pinged a plugin that doesn't advertise pings")
+	}
+	// Without ping support only the process state counts
+	if !m.IsPluginRunning("testplugin") {
+		t.Error("a running plugin without ping support isn't running")
+	}
+	if result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "hi"}); err != nil || result != "hi" {
+		t.Errorf("echo returned %q, %v", result, err)
+	}
+}
+
+func TestPluginAdvertisingPingWithoutAnswering(t *testing.T) {
+	m := newTestManager(t)
+	loadTestPluginMode(t, m, "testplugin", testPluginSilentPing)
+
+	// The ping on load failed, so only the process state counts from then on
+	if m.GetLoadedPlugins()["testplugin"].supportsPing.Load() {
+		t.Error("a plugin that didn't answer its ping is still marked as supporting them")
+	}
+	if !m.IsPluginRunning("testplugin") {
+		t.Error("a running plugin that didn't answer its ping isn't running")
+	}
+}
+
+func TestRequestTimeoutStopsPlugin(t *testing.T) {
+	m := newTestManager(t)
+	m.SetRequestTimeout(100 * time.Millisecond)
+	loadTestPlugin(t, m, "testplugin")
+
+	if _, err := m.ExecuteCommand("testplugin", "slow", nil); !errors.Is(err, errRequestTimeout) {
+		t.Fatalf("got error %v, want %v", err, errRequestTimeout)
+	}
+	if m.IsPluginLoaded("testplugin") {
+		t.Fatal("a plugin that timed out is still loaded")
+	}
+
+	// It stays discovered, so it can be loaded again
+	if err := m.LoadPlugin("testplugin"); err != nil {
+		t.Fatalf("reloading after a timeout failed: %v", err)
+	}
+	if result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "again"}); err != nil || result != "again" {
+		t.Errorf("reloaded plugin returned %q, %v", result, err)
+	}
+}
+
+// appendTestFrame appends a frame to buf the way plugins write them
+func appendTestFrame(buf *bytes.Buffer, msgType uint32, data string) {
+	header := make([]byte, 5)
+	header[0] = byte(msgType)
+	binary.LittleEndian.PutUint32(header[1:], uint32(len(data)))
+	buf.Write(header)
+	buf.WriteString(data)
+}
+
+func TestReadMessage(t *testing.T) {
+	var buf bytes.Buffer
+	appendTestFrame(&buf, 2, "payload")
+	appendTestFrame(&buf, 2, strings.Repeat("x", 64))
+	appendTestFrame(&buf, 3, "next")
+	appendTestFrame(&buf, 1, "")
+
+	if msgType, data, err := readMessage(&buf, 16); err != nil || msgType != 2 || string(data) != "payload" {
+		t.Errorf("got %d, %q, %v", msgType, data, err)
+	}
+	// An oversized frame is skipped whole, leaving the next one readable
+	if _, _, err := readMessage(&buf, 16); !errors.Is(err, errMessageTooLarge) {
+		t.Errorf("got error %v, want %v", err, errMessageTooLarge)
+	}
+	if msgType, data, err := readMessage(&buf, 16); err != nil || msgType != 3 || string(data) != "next" {
+		t.Errorf("after an oversized frame got %d, %q, %v", msgType, data, err)
+	}
+	if msgType, data, err := readMessage(&buf, 16); err != nil || msgType != 1 || len(data) != 0 {
+		t.Errorf("empty frame got %d, %q, %v", msgType, data, err)
+	}
+
+	// A stream that ends mid-frame is an error, not a short message
+	appendTestFrame(&buf, 2, "payload")
+	buf.Truncate(8)
+	if _, _, err := readMessage(&buf, 1024); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got error %v for a truncated frame, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// shortWriter accepts at most n bytes per Write
+type shortWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		p = p[:w.n]
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestWriteFullRetriesShortWrites(t *testing.T) {
+	w := &shortWriter{n: 3}
+	if err := writeFull(w, []byte("a frame longer than three bytes")); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "a frame longer than three bytes" {
+		t.Errorf("wrote %q", w.String())
+	}
+	if err := writeFull(&shortWriter{n: 0}, []byte("x")); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("got error %v from a writer taking nothing, want %v", err, io.ErrShortWrite)
+	}
+}
+
+func TestWriteFrameRejectsOversizedRequests(t *testing.T) {
+	p := &Plugin{maxMessageSize: 8}
+	err := p.writeFrame(2, []byte(strings.Repeat("x", 9)))
+	if !errors.Is(err, errMessageTooLarge) {
+		t.Errorf("got error %v, want %v", err, errMessageTooLarge)
+	}
+	if err == nil || !strings.Contains(err.Error(), "max_message_size") {
+		t.Errorf("error %v doesn't name the setting", err)
+	}
+}
+
+// installIntoPluginsDir copies the test binary into the plugins directory
+// under name, the way an install lays it out
+func installIntoPluginsDir(t *testing.T, name string) {
+	t.Helper()
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(installTestPlugin(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(pluginsDir, name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginsDir, name, name), data, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAllPlugins(t *testing.T) {
+	m := newTestManager(t)
+	t.Setenv(testPluginEnv, "1")
+	names := []string{"alpha", "beta", "gamma", "delta", "epsilon", "zeta"}
+	for _, name := range names {
+		installIntoPluginsDir(t, name)
+	}
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory without a binary isn't a plugin
+	if err := os.MkdirAll(filepath.Join(pluginsDir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A binary that exits straight away fails to load without holding up the rest
+	if err := os.MkdirAll(filepath.Join(pluginsDir, "broken"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginsDir, "broken", "broken"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for name := range m.GetLoadedPlugins() {
+			m.UnloadPlugin(name)
+		}
+	})
+
+	err = m.LoadAllPlugins()
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("got error %v, want the broken plugin reported", err)
+	}
+	if _, found := m.GetDiscoveredPlugins()["empty"]; found {
+		t.Error("a directory without a binary was discovered")
+	}
+	for _, name := range names {
+		if !m.IsPluginRunning(name) {
+			t.Errorf("%s isn't running", name)
+		}
+	}
+	if m.IsPluginLoaded("broken") {
+		t.Error("the broken plugin is loaded")
+	}
+}
+
+func TestConcurrentLoadsKeepOneInstance(t *testing.T) {
+	m := newTestManager(t)
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testPluginEnv, "1")
+	m.AddDiscoveredPlugin("testplugin", executable)
+	t.Cleanup(func() { m.UnloadPlugin("testplugin") })
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.LoadPlugin("testplugin")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent load failed: %v", err)
+		}
+	}
+
+	if result, err := m.ExecuteCommand("testplugin", "echo", map[string]string{"text": "hi"}); err != nil || result != "hi" {
+		t.Errorf("echo returned %q, %v", result, err)
+	}
+}
+
+func TestPluginLogLevels(t *testing.T) {
+	m := newTestManager(t)
+	m.SetLogLevels(log.WarnLevel, map[string]log.Level{"verbose": log.DebugLevel})
+	loadTestPlugin(t, m, "quiet")
+	loadTestPlugin(t, m, "verbose")
+
+	for name, wantDebug := range map[string]bool{"quiet": false, "verbose": true} {
+		pluginLogger := m.GetLoadedPlugins()[name].Logger
+		pluginLogger.Debug("debug from the test")
+		data, err := os.ReadFile(pluginLogger.GetLogFileName())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(data), "debug from the test"); got != wantDebug {
+			t.Errorf("%s logged debug messages: %v, want %v", name, got, wantDebug)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// manifestFileName is the manifest at the root of a plugin's source, kept
+// with its support files once installed
+const manifestFileName = "gitspace-plugin.toml"
+
+// PluginManifest is a plugin's gitspace-plugin.toml. It is the only manifest
+// type; installing, dependency checks and anything else reading manifests
+// load it with loadPluginManifest.
+type PluginManifest struct {
+	Metadata struct {
+		Name        string   `toml:"name"`
+		Version     string   `toml:"version,omitempty"`
+		Description string   `toml:"description,omitempty"`
+		Tags        []string `toml:"tags,omitempty"` // As listed in the Gitspace Catalog
+		// Plugins that must be installed first
+		Dependencies []string `toml:"dependencies,omitempty"`
+	} `toml:"metadata"`
+	Sources []struct {
+		Path       string `toml:"path"`
+		EntryPoint string `toml:"entry_point,omitempty"`
+	} `toml:"sources,omitempty"`
+	// SHA-256 of plugin files, by path relative to the manifest
+	Checksums map[string]string `toml:"checksums,omitempty"`
+	// Pre-built binaries, by GOOS/GOARCH
+	Binaries map[string]BinaryAsset `toml:"binaries,omitempty"`
+}
+
+// BinaryAsset is a compiled plugin binary, usually a release asset
+type BinaryAsset struct {
+	URL    string `toml:"url"`
+	SHA256 string `toml:"sha256"`
+}
+
+// loadPluginManifest reads and checks the manifest at path
+func loadPluginManifest(path string) (*PluginManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	var manifest PluginManifest
+	err = toml.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if manifest.Metadata.Name == "" {
+		return nil, fmt.Errorf("plugin name is missing in the manifest file")
+	}
+	for i, source := range manifest.Sources {
+		if source.Path == "" {
+			return nil, fmt.Errorf("sources[%d] is missing its path", i)
+		}
+	}
+
+	return &manifest, nil
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadPluginManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), manifestFileName)
+	text := `
+[metadata]
+name = "scmtea"
+version = "1.2.0"
+description = "Gitea tools"
+tags = ["scm", "gitea"]
+dependencies = ["base"]
+
+[[sources]]
+path = "cmd/scmtea"
+entry_point = "main.go"
+
+[[sources]]
+path = "internal"
+
+[checksums]
+"cmd/scmtea/main.go" = "sha256:abc123"
+
+[binaries."linux/amd64"]
+url = "https://example.com/scmtea-linux-amd64"
+sha256 = "def456"
+`
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := loadPluginManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := manifest.Metadata
+	if meta.Name != "scmtea" || meta.Version != "1.2.0" || meta.Description != "Gitea tools" {
+		t.Errorf("metadata %+v", meta)
+	}
+	if !slices.Equal(meta.Tags, []string{"scm", "gitea"}) || !slices.Equal(meta.Dependencies, []string{"base"}) {
+		t.Errorf("tags %v and dependencies %v", meta.Tags, meta.Dependencies)
+	}
+	if len(manifest.Sources) != 2 || manifest.Sources[0].EntryPoint != "main.go" || manifest.Sources[1].Path != "internal" {
+		t.Errorf("sources %+v", manifest.Sources)
+	}
+	if manifest.Checksums["cmd/scmtea/main.go"] != "sha256:abc123" {
+		t.Errorf("checksums %v", manifest.Checksums)
+	}
+	if asset, ok := manifest.binaryFor("linux", "amd64"); !ok || asset.SHA256 != "def456" {
+		t.Errorf("linux/amd64 binary %+v, %v", asset, ok)
+	}
+	if _, ok := manifest.binaryFor("darwin", "arm64"); ok {
+		t.Error("found a binary for a platform the manifest doesn't list")
+	}
+}
+
+func TestLoadPluginManifestMinimal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), manifestFileName)
+	if err := os.WriteFile(path, []byte("[metadata]\nname = \"hello\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := loadPluginManifest(path)
+	if err != nil || manifest.Metadata.Name != "hello" {
+		t.Fatalf("loaded %+v, %v", manifest, err)
+	}
+}
+
+func TestLoadPluginManifestErrors(t *testing.T) {
+	tests := []struct {
+		name, text, wantErr string
+	}{
+		{"no name", "[metadata]\nversion = \"1.0.0\"\n", "plugin name is missing"},
+		{"source without a path", "[metadata]\nname = \"hello\"\n[[sources]]\nentry_point = \"main.go\"\n", "sources[0] is missing its path"},
+		{"invalid TOML", "[metadata\n", "failed to decode manifest"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), manifestFileName)
+		if err := os.WriteFile(path, []byte(tt.text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPluginManifest(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+	if _, err := loadPluginManifest(filepath.Join(t.TempDir(), manifestFileName)); err == nil {
+		t.Error("loading a missing manifest succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const metaFileName = "meta.toml"
+
+// Where an installed plugin came from
+const (
+	SourceTypeCatalog = "catalog"
+	SourceTypeRemote  = "remote"
+	SourceTypeLocal   = "local"
+)
+
+// PluginMeta is written to meta.toml in a plugin's directory at install time
+type PluginMeta struct {
+	Source      string    `toml:"source"`
+	SourceType  string    `toml:"source_type"`
+	InstalledAt time.Time `toml:"installed_at"`
+	Version     string    `toml:"version"`
+	Ref         string    `toml:"ref,omitempty"` // Git ref pinned with a #ref URL fragment
+	SHA256      string    `toml:"sha256"`        // Checksum of the installed binary
+}
+
+func writePluginMeta(pluginDir string, meta PluginMeta) error {
+	data, err := toml.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", metaFileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, metaFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", metaFileName, err)
+	}
+	return nil
+}
+
+// ReadPluginMeta reads the install metadata of the named plugin. Plugins
+// installed before meta.toml existed return an os.IsNotExist error.
+func ReadPluginMeta(name string) (*PluginMeta, error) {
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugins directory: %w", err)
+	}
+	return readPluginMeta(filepath.Join(pluginsDir, name))
+}
+
+func readPluginMeta(pluginDir string) (*PluginMeta, error) {
+	data, err := os.ReadFile(filepath.Join(pluginDir, metaFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var meta PluginMeta
+	if err := toml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode %s in %s: %w", metaFileName, pluginDir, err)
+	}
+	return &meta, nil
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyPluginChecksum checks a plugin binary against the checksum recorded
+// when it was installed. Plugins installed without a checksum can't be
+// verified and are let through; the returned bool reports whether a check
+// actually happened.
+func verifyPluginChecksum(binaryPath string) (bool, error) {
+	meta, err := readPluginMeta(filepath.Dir(binaryPath))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if meta.SHA256 == "" {
+		return false, nil
+	}
+
+	sum, err := fileSHA256(binaryPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to checksum %s: %w", binaryPath, err)
+	}
+	if sum != meta.SHA256 {
+		return true, fmt.Errorf("checksum mismatch for %s: expected %s, got %s; reinstall the plugin", binaryPath, meta.SHA256, sum)
+	}
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPluginMetaRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pluginDir := filepath.Join(pluginsDir, "linter")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadPluginMeta("linter"); !os.IsNotExist(err) {
+		t.Errorf("got error %v for a plugin without meta.toml, want one os.IsNotExist accepts", err)
+	}
+
+	meta := PluginMeta{
+		Source:      "https://github.com/acme/linter",
+		SourceType:  SourceTypeRemote,
+		InstalledAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Version:     "1.2.3",
+		Ref:         "v1.2.3",
+		SHA256:      "abc123",
+	}
+	if err := writePluginMeta(pluginDir, meta); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPluginMeta("linter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != meta {
+		t.Errorf("read back %+v, want %+v", *got, meta)
+	}
+}
+
+// installTestPlugin copies the test binary into its own plugin directory with
+// meta.toml recording sum, and returns the copy's path
+func installTestPlugin(t *testing.T, sum string) string {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "testplugin")
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if sum != "" {
+		if err := writePluginMeta(dir, PluginMeta{SourceType: SourceTypeLocal, SHA256: sum}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestVerifyPluginChecksum(t *testing.T) {
+	path := installTestPlugin(t, "")
+	if verified, err := verifyPluginChecksum(path); verified || err != nil {
+		t.Errorf("without meta.toml got %v, %v, want the check skipped", verified, err)
+	}
+
+	sum, err := fileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = installTestPlugin(t, sum)
+	if verified, err := verifyPluginChecksum(path); !verified || err != nil {
+		t.Errorf("with a matching checksum got %v, %v", verified, err)
+	}
+
+	// The binary changed since it was installed
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := verifyPluginChecksum(path); !verified || err == nil {
+		t.Errorf("with a mismatched checksum got %v, %v, want an error", verified, err)
+	}
+}
+
+func TestLoadPluginVerifiesChecksum(t *testing.T) {
+	m := newTestManager(t)
+	t.Setenv(testPluginEnv, "1")
+	m.AddDiscoveredPlugin("testplugin", installTestPlugin(t, "0000"))
+
+	if err := m.LoadPlugin("testplugin"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("got error %v, want the plugin refused", err)
+	}
+
+	m.SetSkipChecksum(true)
+	if err := m.LoadPlugin("testplugin"); err != nil {
+		t.Fatalf("load with skip_checksum failed: %v", err)
+	}
+	m.UnloadPlugin("testplugin")
+}
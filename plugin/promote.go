@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+// MainMenuCommand is the command of a top-level option in a plugin's menu
+// asking for the plugin to be listed in the main menu, under the option's
+// label. The option itself isn't shown in the plugin's menu.
+const MainMenuCommand = "gitspace.main_menu"
+
+// PromotedPlugin is a loaded plugin listed in the main menu
+type PromotedPlugin struct {
+	Name  string
+	Title string
+}
+
+// mainMenuTitle returns the title a plugin's menu asks to be listed under in
+// the main menu, or "" if it doesn't ask
+func mainMenuTitle(menu []gsplug.MenuOption) string {
+	for _, opt := range menu {
+		if opt.Command == MainMenuCommand {
+			return opt.Label
+		}
+	}
+	return ""
+}
+
+// withoutReservedOptions drops the MainMenuCommand, EventsCommand and
+// PingCommand options from a plugin's menu
+func withoutReservedOptions(menu []gsplug.MenuOption) []gsplug.MenuOption {
+	kept := make([]gsplug.MenuOption, 0, len(menu))
+	for _, opt := range menu {
+		if opt.Command != MainMenuCommand && opt.Command != EventsCommand && opt.Command != PingCommand {
+			kept = append(kept, opt)
+		}
+	}
+	return kept
+}
+
+// PromotedPlugins returns the loaded plugins that asked for a main menu entry,
+// sorted by title
+func (m *Manager) PromotedPlugins() []PromotedPlugin {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var promoted []PromotedPlugin
+	for name, plugin := range m.plugins {
+		if plugin.MainMenuTitle != "" {
+			promoted = append(promoted, PromotedPlugin{Name: name, Title: plugin.MainMenuTitle})
+		}
+	}
+	sort.Slice(promoted, func(i, j int) bool {
+		if promoted[i].Title != promoted[j].Title {
+			return promoted[i].Title < promoted[j].Title
+		}
+		return promoted[i].Name < promoted[j].Name
+	})
+	return promoted
+}
+
+// RunPromotedPlugin runs the menu of a plugin picked from the main menu
+func RunPromotedPlugin(logger *logger.RateLimitedLogger, manager *Manager, name string) error {
+	if !manager.IsPluginLoaded(name) {
+		return fmt.Errorf("plugin %s is no longer loaded", name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return runPluginLoop(ctx, logger, manager, name)
+}
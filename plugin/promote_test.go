@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
+)
+
+func TestMainMenuTitle(t *testing.T) {
+	if got := mainMenuTitle(testPluginMenu("1")); got != "Test Tools" {
+		t.Errorf("mainMenuTitle = %q, want the option's label", got)
+	}
+	if got := mainMenuTitle([]gsplug.MenuOption{{Label: "Echo", Command: "echo"}}); got != "" {
+		t.Errorf("a menu without the option got title %q", got)
+	}
+}
+
+func TestWithoutReservedOptions(t *testing.T) {
+	var commands []string
+	for _, opt := range withoutReservedOptions(testPluginMenu("1")) {
+		commands = append(commands, opt.Command)
+	}
+	want := []string{"echo", "slow", "large", "config", "mute", "last", "exit"}
+	if !slices.Equal(commands, want) {
+		t.Errorf("kept %v, want %v", commands, want)
+	}
+}
+
+func TestPromotedPluginsSortedByTitle(t *testing.T) {
+	m := newTestManager(t)
+	for _, name := range []string{"zeta", "alpha"} {
+		loadTestPlugin(t, m, name)
+	}
+	// Every test plugin asks for the same title, so the names break the tie
+	want := []PromotedPlugin{{Name: "alpha", Title: "Test Tools"}, {Name: "zeta", Title: "Test Tools"}}
+	if got := m.PromotedPlugins(); !slices.Equal(got, want) {
+		t.Errorf("promoted plugins %v, want %v", got, want)
+	}
+
+	m.mu.Lock()
+	m.plugins["zeta"].MainMenuTitle = "Deploy"
+	m.mu.Unlock()
+	want = []PromotedPlugin{{Name: "zeta", Title: "Deploy"}, {Name: "alpha", Title: "Test Tools"}}
+	if got := m.PromotedPlugins(); !slices.Equal(got, want) {
+		t.Errorf("promoted plugins %v, want %v", got, want)
+	}
+
+	m.UnloadPlugin("zeta")
+	if got := m.PromotedPlugins(); len(got) != 1 || got[0].Name != "alpha" {
+		t.Errorf("after unloading zeta promoted plugins are %v", got)
+	}
+}
+
+func TestRunPromotedPluginNotLoaded(t *testing.T) {
+	m := newTestManager(t)
+	if err := RunPromotedPlugin(nil, m, "missing"); err == nil {
+		t.Error("running an unloaded plugin succeeded")
+	}
+}
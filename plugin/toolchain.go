@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/version"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// minGoVersion is the oldest Go that can build plugins. Older releases can't
+// fetch the newer toolchain the plugin SDK asks for.
+const minGoVersion = "go1.21"
+
+// lookPath finds executables on PATH
+var lookPath = exec.LookPath
+
+// checkGoToolchain makes sure a plugin can be built before starting: go must
+// be on PATH and recent enough, and the module proxy must be reachable
+func checkGoToolchain(timeout time.Duration) error {
+	goBin, err := lookPath("go")
+	if err != nil {
+		return fmt.Errorf("Go %s+ is required to build plugins, but go wasn't found on PATH", strings.TrimPrefix(minGoVersion, "go"))
+	}
+
+	output, err := exec.Command(goBin, "env", "GOVERSION", "GOPROXY").Output()
+	if err != nil {
+		return fmt.Errorf("failed to run go env: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	goVersion, goProxy := strings.TrimSpace(lines[0]), ""
+	if len(lines) > 1 {
+		goProxy = strings.TrimSpace(lines[1])
+	}
+
+	if !version.IsValid(goVersion) || version.Compare(goVersion, minGoVersion) < 0 {
+		return fmt.Errorf("Go %s+ is required to build plugins, found %s", strings.TrimPrefix(minGoVersion, "go"), goVersion)
+	}
+	return checkGoProxy(goProxy, timeout)
+}
+
+// checkGoProxy checks that module downloads can reach one of the proxies in
+// a GOPROXY list. Any HTTP response counts, since only reachability matters.
+// Fetching directly from version control isn't checked.
+func checkGoProxy(goProxy string, timeout time.Duration) error {
+	if goProxy == "" {
+		goProxy = "https://proxy.golang.org,direct"
+	}
+
+	client := &http.Client{Timeout: timeout}
+	var firstErr error
+	for _, proxy := range strings.FieldsFunc(goProxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		switch proxy {
+		case "direct":
+			return nil
+		case "off":
+			if firstErr != nil {
+				return firstErr
+			}
+			return errors.New("module downloads are disabled (GOPROXY=off), so the plugin's dependencies can't be fetched")
+		}
+
+		err := pingProxy(client, proxy)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func pingProxy(client *http.Client, proxy string) error {
+	u, err := url.Parse(proxy)
+	if err == nil && u.Scheme == "file" {
+		return nil
+	}
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid GOPROXY entry %q", proxy)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodHead, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("invalid GOPROXY entry %q: %w", proxy, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach %s to download the plugin's dependencies; check your network or GOPROXY: %w", u.Host, err)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubGo makes lookPath find a go that reports goVersion and goProxy from
+// go env, or no go at all when goVersion is empty
+func stubGo(t *testing.T, goVersion, goProxy string) {
+	t.Helper()
+	previous := lookPath
+	t.Cleanup(func() { lookPath = previous })
+	if goVersion == "" {
+		lookPath = func(string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+		return
+	}
+	path := filepath.Join(t.TempDir(), "go")
+	script := "#!/bin/sh\necho " + goVersion + "\necho '" + goProxy + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lookPath = func(string) (string, error) { return path, nil }
+}
+
+func TestCheckGoToolchain(t *testing.T) {
+	proxy := httptest.NewServer(http.NotFoundHandler())
+	defer proxy.Close()
+
+	tests := []struct {
+		name, goVersion, goProxy, wantErr string
+	}{
+		{"missing go", "", "", "Go 1.21+ is required to build plugins, but go wasn't found on PATH"},
+		{"old go", "go1.20.3", proxy.URL, "Go 1.21+ is required to build plugins, found go1.20.3"},
+		{"development build", "devel", proxy.URL, "found devel"},
+		{"supported", "go1.22.1", proxy.URL, ""},
+		{"minimum", "go1.21.0", "direct", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubGo(t, tt.goVersion, tt.goProxy)
+			err := checkGoToolchain(time.Second)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckGoProxy(t *testing.T) {
+	// Any response means the proxy is reachable
+	proxy := httptest.NewServer(http.NotFoundHandler())
+	defer proxy.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		goProxy, wantErr string
+	}{
+		{proxy.URL, ""},
+		{unreachable.URL + "," + proxy.URL, ""},
+		{unreachable.URL + "|direct", ""},
+		{"file:///srv/modules", ""},
+		{unreachable.URL, "cannot reach " + strings.TrimPrefix(unreachable.URL, "http://")},
+		{unreachable.URL + ",off", "cannot reach"},
+		{"off", "GOPROXY=off"},
+		{"proxy.example.com", "invalid GOPROXY entry"},
+	}
+	for _, tt := range tests {
+		err := checkGoProxy(tt.goProxy, time.Second)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("checkGoProxy(%q): %v", tt.goProxy, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("checkGoProxy(%q) error = %v, want %q", tt.goProxy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInstallPluginStopsWithoutGo(t *testing.T) {
+	m := newTestManager(t)
+	stubGo(t, "", "")
+	source := filepath.Join(t.TempDir(), "hello")
+	writeTestManifest(t, source, "hello")
+
+	err := InstallPlugin(m.logger, m, source)
+	if err == nil || !strings.Contains(err.Error(), "go wasn't found on PATH") {
+		t.Errorf("got error %v, want the missing toolchain reported", err)
+	}
+}
@@ -5,50 +5,46 @@ import (
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	"io"
 	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type GitspaceCatalog struct {
-	Catalog struct {
-		Name        string `toml:"name"`
-		Description string `toml:"description"`
-		Version     string `toml:"version"`
-		LastUpdated struct {
-			Date       string `toml:"date"`
-			CommitHash string `toml:"commit_hash"`
-		} `toml:"last_updated"`
-	} `toml:"catalog"`
-	Plugins   map[string]Plugin   `toml:"plugins"`
-	Templates map[string]Template `toml:"templates"`
-}
-
 type Plugin struct {
 	Name        string
 	Path        string
 	Version     string `toml:"version"`
 	Description string `toml:"description"`
+	SDKVersion  string `toml:"-"` // Plugin SDK the binary was built with, if known
 	Repository  struct {
 		Type string `toml:"type"`
 		URL  string `toml:"url"`
 	} `toml:"repository"`
+	// Title of the plugin's main menu entry, if its menu asked for one
+	MainMenuTitle string `toml:"-"`
+	// Lifecycle events the plugin's menu subscribed to
+	Events []string `toml:"-"`
+
 	cmd    *exec.Cmd
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
+	exited chan struct{} // Closed once the process has exited
 	Logger *logger.RateLimitedLogger
-}
 
-type CatalogPlugin struct {
-	Path string
-	// Add other necessary fields
+	responses      chan pluginMessage // Frames read from stdout, in order
+	readDone       chan struct{}      // Closed once nothing more is read from stdout
+	requestTimeout time.Duration      // Zero waits indefinitely
+	maxMessageSize int
+	requestMu      sync.Mutex                  // Serializes request/response round trips
+	supportsPing   atomic.Bool                 // Whether the plugin advertised and answered pings on load
+	configView     func() (*ConfigView, error) // The Manager's current view
 }
 
-type Template struct {
-	Version     string `toml:"version,omitempty"`
-	Description string `toml:"description,omitempty"`
-	Path        string `toml:"path"`
-	Repository  struct {
-		Type string `toml:"type"`
-		URL  string `toml:"url"`
-	} `toml:"repository"`
+// pluginMessage is one frame read from a plugin, or the error that ended the stream
+type pluginMessage struct {
+	msgType uint32
+	data    []byte
+	err     error
 }
 
 type bufferedWriteCloser struct {
@@ -5,6 +5,7 @@ import (
     "os"
     "os/exec"
     "path/filepath"
+    "strings"
 )
 
 // getPluginsDir returns the path to the plugins directory and ensures it exists
@@ -22,13 +23,35 @@ func getPluginsDir() (string, error) {
     return pluginsDir, nil
 }
 
-// gitClone clones a git repository to the specified destination path
-func gitClone(url, destPath string) error {
+// validateRef rejects a ref git would read as an option
+func validateRef(ref string) error {
+    if strings.HasPrefix(ref, "-") {
+        return fmt.Errorf("invalid git ref %q", ref)
+    }
+    return nil
+}
+
+// gitClone clones a git repository to the specified destination path and,
+// if ref is set, checks out that branch, tag or commit. The ref is checked
+// before cloning, so an invalid one leaves nothing behind.
+func gitClone(url, destPath, ref string) error {
+    if err := validateRef(ref); err != nil {
+        return err
+    }
     cmd := exec.Command("git", "clone", url, destPath)
     output, err := cmd.CombinedOutput()
     if err != nil {
         return fmt.Errorf("git clone failed: %w\nOutput: %s", err, string(output))
     }
+
+    if ref == "" {
+        return nil
+    }
+    cmd = exec.Command("git", "-C", destPath, "checkout", "--quiet", ref)
+    output, err = cmd.CombinedOutput()
+    if err != nil {
+        return fmt.Errorf("git checkout %s failed: %w\nOutput: %s", ref, err, string(output))
+    }
     return nil
 }
 
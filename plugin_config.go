@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ssotops/gitspace/plugin"
+)
+
+// pluginConfigView returns the view of config that plugins can ask for: the
+// global scm, owner, path and labels, the groups, and each clone target's
+// cached repository list. Tokens, keys and hooks stay out of it.
+func pluginConfigView(config *Config) func() (*plugin.ConfigView, error) {
+	return func() (*plugin.ConfigView, error) {
+		view := &plugin.ConfigView{
+			SCM:          config.Global.SCM,
+			Owner:        config.Global.Owner,
+			Path:         config.Global.Path,
+			Labels:       config.Global.Labels,
+			Groups:       make(map[string]plugin.ConfigGroup, len(config.Groups)),
+			Repositories: []plugin.ConfigRepo{},
+		}
+		for name, group := range config.Groups {
+			view.Groups[name] = plugin.ConfigGroup{
+				Match:  group.Match,
+				Values: group.Values,
+				Type:   group.Type,
+				Branch: group.Branch,
+				Labels: group.Labels,
+			}
+		}
+
+		for _, target := range config.CloneTargets() {
+			path, err := getRepoListCachePath(config.forTarget(target))
+			if err != nil {
+				return nil, err
+			}
+			cached, err := readRepoListCache(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					// Not listed yet
+					continue
+				}
+				return nil, err
+			}
+			for _, repo := range cached.Repos {
+				view.Repositories = append(view.Repositories, plugin.ConfigRepo{SCM: target.SCM, Owner: target.Owner, RepoInfo: repo})
+			}
+		}
+		return view, nil
+	}
+}
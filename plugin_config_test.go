@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ssotops/gitspace-plugin-sdk/gsplug"
+	pb "github.com/ssotops/gitspace-plugin-sdk/proto"
+	"github.com/ssotops/gitspace/lib"
+	"github.com/ssotops/gitspace/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// stubPluginEnv makes the test binary run as a plugin that asks for a main
+// menu entry, and asks for the config whenever it gets a command, returning
+// the reply as the result
+const stubPluginEnv = "GITSPACE_TEST_STUB_PLUGIN"
+
+// stubPluginEventsEnv names a file the stub plugin appends each event it
+// receives to, one JSON line per event
+const stubPluginEventsEnv = "GITSPACE_TEST_STUB_PLUGIN_EVENTS"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(stubPluginEnv) != "" {
+		runStubPlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runStubPlugin serves the plugin protocol on stdin and stdout until stdin
+// closes. Frames are a type byte, a little-endian length and the payload.
+func runStubPlugin() {
+	write := func(msgType uint32, data []byte) {
+		frame := make([]byte, 5, 5+len(data))
+		frame[0] = byte(msgType)
+		binary.LittleEndian.PutUint32(frame[1:], uint32(len(data)))
+		os.Stdout.Write(append(frame, data...))
+	}
+	read := func() (uint32, []byte, error) {
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(os.Stdin, header); err != nil {
+			return 0, nil, err
+		}
+		data := make([]byte, binary.LittleEndian.Uint32(header[1:]))
+		_, err := io.ReadFull(os.Stdin, data)
+		return uint32(header[0]), data, err
+	}
+	reply := func(msgType uint32, msg proto.Message) {
+		data, _ := proto.Marshal(msg)
+		write(msgType, data)
+	}
+
+	for {
+		msgType, data, err := read()
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case 1:
+			reply(1, &pb.PluginInfo{Name: "stub", Version: "0.0.1"})
+		case 3:
+			menu, _ := json.Marshal([]gsplug.MenuOption{
+				{Label: "Stub Tools", Command: plugin.MainMenuCommand},
+				{Label: "Config", Command: "config"},
+				{Label: "events", Command: plugin.EventsCommand, Parameters: []gsplug.ParameterInfo{
+					{Name: plugin.EventPreClone}, {Name: plugin.EventPostClone}, {Name: plugin.EventPostSync},
+				}},
+			})
+			reply(3, &pb.MenuResponse{MenuData: menu})
+		case 4:
+			write(4, nil)
+		case 6:
+			if f, err := os.OpenFile(os.Getenv(stubPluginEventsEnv), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+				f.Write(append(data, '\n'))
+				f.Close()
+			}
+			write(6, nil)
+		case 2:
+			write(5, nil)
+			if _, config, err := read(); err == nil {
+				reply(2, &pb.CommandResponse{Success: true, Result: string(config)})
+			}
+		}
+	}
+}
+
+// loadStubPlugin loads the test binary as the plugin "stub", unloading it
+// when the test ends
+func loadStubPlugin(t *testing.T, pluginManager *plugin.Manager) {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(stubPluginEnv, "1")
+	pluginManager.AddDiscoveredPlugin("stub", executable)
+	if err := pluginManager.LoadPlugin("stub"); err != nil {
+		t.Fatalf("failed to load the stub plugin: %v", err)
+	}
+	t.Cleanup(func() { pluginManager.UnloadPlugin("stub") })
+}
+
+func TestPluginConfigViewLeavesOutSecrets(t *testing.T) {
+	logger := newTestLogger(t)
+	config := loadTestConfig(t, `
+[global]
+path = "/work"
+scm = "github"
+owner = "acme"
+labels = ["team"]
+post_clone_hook = "deploy --token hook-secret"
+
+[auth]
+key_path = "/keys/id_secret"
+token = "token-secret"
+token_path = "/keys/token-file-secret"
+
+[groups.api]
+match = "startsWith"
+values = ["api-"]
+post_clone_hook = "group-hook-secret"
+`)
+	cachePath, err := getRepoListCachePath(config.forTarget(config.CloneTargets()[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRepoListCache(cachePath, config.Global.Visibility, []lib.RepoInfo{{Name: "api-gateway", DefaultBranch: "main"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginManager := plugin.NewManager(logger)
+	configurePluginManager(pluginManager, config)
+	loadStubPlugin(t, pluginManager)
+
+	result, err := pluginManager.ExecuteCommand("stub", "config", nil)
+	if err != nil {
+		t.Fatalf("config command failed: %v", err)
+	}
+	for _, secret := range []string{"secret", "/keys"} {
+		if strings.Contains(result, secret) {
+			t.Errorf("the plugin saw %q in %s", secret, result)
+		}
+	}
+
+	var reply plugin.ConfigReply
+	if err := json.Unmarshal([]byte(result), &reply); err != nil || reply.Config == nil {
+		t.Fatalf("plugin got %q: %v", result, err)
+	}
+	view := reply.Config
+	if view.SCM != "github" || view.Owner != "acme" || view.Path != "/work" {
+		t.Errorf("plugin got %+v", view)
+	}
+	if group, ok := view.Groups["api"]; !ok || group.Match != "startsWith" {
+		t.Errorf("groups = %+v", view.Groups)
+	}
+	if len(view.Repositories) != 1 || view.Repositories[0].Name != "api-gateway" || view.Repositories[0].Owner != "acme" {
+		t.Errorf("repositories = %+v", view.Repositories)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+// profileNamePattern keeps profile names usable as file names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// profileConfigPath is the config of the profile --profile selected for this
+// run, or empty
+var profileConfigPath string
+
+// extractProfile removes --profile from args, wherever it appears, like
+// --log-level
+func extractProfile(args []string) (string, []string, error) {
+	var profile string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--profile" || arg == "-profile":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("--profile needs a value")
+			}
+			profile = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--profile="), strings.HasPrefix(arg, "-profile="):
+			_, profile, _ = strings.Cut(arg, "=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return profile, rest, nil
+}
+
+// profilePath returns the config file of the named profile
+func profilePath(name string) (string, error) {
+	if !profileNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, profilesDir, name+".toml"), nil
+}
+
+// existingProfilePath is profilePath for a profile that must already exist
+func existingProfilePath(name string) (string, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("profile %q doesn't exist", name)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// listProfiles returns the names of the saved profiles, sorted
+func listProfiles() ([]string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(homeDir, profilesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".toml")
+		if ok && !entry.IsDir() && profileNamePattern.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// saveProfile copies the config at sourcePath into the named profile,
+// replacing any profile of that name
+func saveProfile(logger *logger.RateLimitedLogger, name, sourcePath string) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := loadConfig(sourcePath); err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read source config: %w", err)
+	}
+	// Includes are resolved next to the profile from now on
+	data, err = absoluteIncludes(sourcePath, data)
+	if err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	logger.Info("Profile saved", "profile", name, "source", sourcePath, "path", path)
+	return nil
+}
+
+// getActiveProfile returns the name of the active profile, or "" when the
+// active config is current.toml
+func getActiveProfile() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(homeDir, activeProfileFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read active profile: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// switchProfile makes the named profile the active config
+func switchProfile(logger *logger.RateLimitedLogger, name string) (*Config, error) {
+	path, err := existingProfilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile %s: %w", name, err)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	activePath := filepath.Join(homeDir, activeProfileFile)
+	if err := os.MkdirAll(filepath.Dir(activePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(activePath, []byte(name+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write active profile: %w", err)
+	}
+
+	logger.Info("Switched profile", "profile", name, "path", path)
+	return config, nil
+}
+
+// clearActiveProfile goes back to current.toml as the active config
+func clearActiveProfile() error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	if err := os.Remove(filepath.Join(homeDir, activeProfileFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear active profile: %w", err)
+	}
+	return nil
+}
+
+// deleteProfile removes the named profile, deactivating it if it is active
+func deleteProfile(logger *logger.RateLimitedLogger, name string) error {
+	path, err := existingProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	active, err := getActiveProfile()
+	if err != nil {
+		return err
+	}
+	if active == name {
+		if err := clearActiveProfile(); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Profile deleted", "profile", name, "path", path)
+	return nil
+}
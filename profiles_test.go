@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExtractProfile(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantProfile string
+		wantRest    []string
+		wantErr     bool
+	}{
+		{[]string{"clone"}, "", []string{"clone"}, false},
+		{[]string{"--profile", "work", "clone"}, "work", []string{"clone"}, false},
+		{[]string{"clone", "-profile=home", "--dry-run"}, "home", []string{"clone", "--dry-run"}, false},
+		{[]string{"clone", "--profile"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		profile, rest, err := extractProfile(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractProfile(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if profile != tt.wantProfile || !slices.Equal(rest, tt.wantRest) {
+			t.Errorf("extractProfile(%q) = %q, %q, want %q, %q", tt.args, profile, rest, tt.wantProfile, tt.wantRest)
+		}
+	}
+}
+
+func TestProfilePathRejectsBadNames(t *testing.T) {
+	for _, name := range []string{"", "../escape", ".hidden", "a/b", "with space"} {
+		if _, err := profilePath(name); err == nil {
+			t.Errorf("profile name %q was accepted", name)
+		}
+	}
+	if _, err := profilePath("work-2.0_eu"); err != nil {
+		t.Errorf("a valid profile name was rejected: %v", err)
+	}
+}
+
+func TestProfileLifecycle(t *testing.T) {
+	logger := newTestLogger(t)
+	source := filepath.Join(t.TempDir(), "work.toml")
+	writeTestConfig(t, source, validTestConfig(t))
+
+	for _, name := range []string{"work", "home"} {
+		if err := saveProfile(logger, name, source); err != nil {
+			t.Fatalf("failed to save %s: %v", name, err)
+		}
+	}
+	names, err := listProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"home", "work"}) {
+		t.Errorf("listed %v, want [home work]", names)
+	}
+
+	if _, err := switchProfile(logger, "missing"); err == nil {
+		t.Error("switched to a profile that doesn't exist")
+	}
+	if _, err := switchProfile(logger, "work"); err != nil {
+		t.Fatalf("failed to switch: %v", err)
+	}
+	if active, _ := getActiveProfile(); active != "work" {
+		t.Errorf("active profile = %q, want work", active)
+	}
+	workPath, err := profilePath("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := getCurrentConfigPath(logger); current != workPath {
+		t.Errorf("current config = %q, want the profile's %q", current, workPath)
+	}
+
+	// Deleting another profile leaves the active one alone
+	if err := deleteProfile(logger, "home"); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := getActiveProfile(); active != "work" {
+		t.Errorf("active profile = %q after deleting home, want work", active)
+	}
+	if err := deleteProfile(logger, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := getActiveProfile(); active != "" {
+		t.Errorf("deleted profile %q is still active", active)
+	}
+	if _, err := os.Stat(workPath); !os.IsNotExist(err) {
+		t.Error("the profile's file wasn't removed")
+	}
+}
+
+func TestSaveProfileRejectsInvalidConfig(t *testing.T) {
+	logger := newTestLogger(t)
+	source := filepath.Join(t.TempDir(), "bad.toml")
+	writeTestConfig(t, source, "[global]\nscm = \"svn\"\n")
+	if err := saveProfile(logger, "bad", source); err == nil {
+		t.Error("an invalid config was saved as a profile")
+	}
+	if names, _ := listProfiles(); len(names) != 0 {
+		t.Errorf("listed %v after a failed save", names)
+	}
+}
+
+func TestInstallConfigDeactivatesProfile(t *testing.T) {
+	logger := newTestLogger(t)
+	source := filepath.Join(t.TempDir(), "work.toml")
+	writeTestConfig(t, source, validTestConfig(t))
+	if err := saveProfile(logger, "work", source); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := switchProfile(logger, "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := installConfig(logger, source); err != nil {
+		t.Fatalf("failed to install config: %v", err)
+	}
+	if active, _ := getActiveProfile(); active != "" {
+		t.Errorf("profile %q stayed active after installing a config", active)
+	}
+	if names, _ := listProfiles(); !slices.Equal(names, []string{"work"}) {
+		t.Errorf("listed %v, want the profile kept", names)
+	}
+}
+
+func TestProfileSelectsConfigForRun(t *testing.T) {
+	t.Setenv(configPathEnv, "env.toml")
+	profileConfigPath = "profile.toml"
+	t.Cleanup(func() { profileConfigPath = "" })
+
+	if path, source, menuOnly := menuConfigPath(nil); path != "profile.toml" || source != "--profile" || !menuOnly {
+		t.Errorf("menuConfigPath() = %q, %q, %v, want the profile's config", path, source, menuOnly)
+	}
+	if path, _, _ := menuConfigPath([]string{"--config", "flag.toml"}); path != "flag.toml" {
+		t.Errorf("menuConfigPath(--config) = %q, want flag.toml", path)
+	}
+	fs, configPath := newFlagSet("clone")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if *configPath != "profile.toml" {
+		t.Errorf("--config defaulted to %q, want the profile's config", *configPath)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+)
+
+const progressBarWidth = 30
+
+// repoProgress reports how far processRepositories has got
+type repoProgress interface {
+	// start marks repo as in flight and returns the writer for its git progress
+	start(repo string) io.Writer
+	done(repo string, result *RepoResult)
+	close()
+}
+
+// newRepoProgress shows an aggregated progress view when progressOutput is a
+// terminal, and a line per finished repository otherwise. --quiet hides it.
+func newRepoProgress(title string, total int) repoProgress {
+	if outputVerbosity == verbosityQuiet {
+		return &lineProgress{w: io.Discard, title: title, total: total}
+	}
+	if f, ok := progressOutput.(*os.File); ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())) {
+		return newTerminalProgress(f, title, total)
+	}
+	return &lineProgress{w: progressOutput, title: title, total: total}
+}
+
+// lineProgress prints one line per finished repository, for logs and pipes
+type lineProgress struct {
+	mu       sync.Mutex
+	w        io.Writer
+	title    string
+	total    int
+	finished int
+}
+
+func (p *lineProgress) start(repo string) io.Writer {
+	return io.Discard
+}
+
+func (p *lineProgress) done(repo string, result *RepoResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished++
+	status := resultStatus(result)
+	if result.Error != nil {
+		status += ": " + result.Error.Error()
+	}
+	fmt.Fprintf(p.w, "[%d/%d] %s/%s %s\n", p.finished, p.total, p.title, repo, status)
+}
+
+func (p *lineProgress) close() {}
+
+// terminalProgress runs a bubbletea program showing an overall bar and a
+// spinner with the latest git progress for each repository in flight
+type terminalProgress struct {
+	program  *tea.Program
+	finished chan struct{}
+}
+
+type repoStartedMsg struct{ repo string }
+
+type repoProgressMsg struct{ repo, line string }
+
+type repoDoneMsg struct{ repo string }
+
+func newTerminalProgress(out *os.File, title string, total int) *terminalProgress {
+	model := progressModel{
+		title:    title,
+		total:    total,
+		inFlight: make(map[string]string),
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+	// Ctrl+C keeps its usual meaning, so don't read input or catch signals
+	p := &terminalProgress{
+		program:  tea.NewProgram(model, tea.WithOutput(out), tea.WithInput(nil), tea.WithoutSignalHandler()),
+		finished: make(chan struct{}),
+	}
+	go func() {
+		defer close(p.finished)
+		p.program.Run()
+	}()
+	return p
+}
+
+func (p *terminalProgress) start(repo string) io.Writer {
+	p.program.Send(repoStartedMsg{repo: repo})
+	return &progressLineWriter{repo: repo, program: p.program}
+}
+
+func (p *terminalProgress) done(repo string, result *RepoResult) {
+	p.program.Send(repoDoneMsg{repo: repo})
+	// Finished repositories scroll up above the live view. Printing through
+	// the program rather than a command keeps the lines ahead of close's quit.
+	icon := "✅"
+	switch resultStatus(result) {
+	case statusFailed:
+		icon = "❌"
+	case statusSkipped:
+		icon = "⚠️"
+	}
+	p.program.Println(fmt.Sprintf("%s %s %s", icon, repo, progressDimStyle.Render(resultStatus(result))))
+}
+
+func (p *terminalProgress) close() {
+	p.program.Quit()
+	<-p.finished
+}
+
+// progressLineWriter forwards the last complete line of git progress, which
+// separates updates with \r as well as \n
+type progressLineWriter struct {
+	repo    string
+	program *tea.Program
+	partial []byte
+}
+
+func (w *progressLineWriter) Write(b []byte) (int, error) {
+	w.partial = append(w.partial, b...)
+	end := bytes.LastIndexAny(w.partial, "\r\n")
+	if end < 0 {
+		return len(b), nil
+	}
+	lines := strings.FieldsFunc(string(w.partial[:end]), func(r rune) bool { return r == '\r' || r == '\n' })
+	w.partial = append(w.partial[:0], w.partial[end+1:]...)
+	if len(lines) > 0 {
+		w.program.Send(repoProgressMsg{repo: w.repo, line: lines[len(lines)-1]})
+	}
+	return len(b), nil
+}
+
+type progressModel struct {
+	title    string
+	total    int
+	finished int
+	inFlight map[string]string // Repository to its latest git progress line
+	spinner  spinner.Model
+}
+
+var (
+	progressTitleStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00FFFF"))
+	progressFilledStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+	progressDimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
+)
+
+func (m progressModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case repoStartedMsg:
+		m.inFlight[msg.repo] = ""
+	case repoProgressMsg:
+		if _, ok := m.inFlight[msg.repo]; ok {
+			m.inFlight[msg.repo] = msg.line
+		}
+	case repoDoneMsg:
+		delete(m.inFlight, msg.repo)
+		m.finished++
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m progressModel) View() string {
+	var b strings.Builder
+	filled := 0
+	if m.total > 0 {
+		filled = progressBarWidth * m.finished / m.total
+	}
+	fmt.Fprintf(&b, "%s %s%s %d of %d repositories\n",
+		progressTitleStyle.Render(m.title),
+		progressFilledStyle.Render(strings.Repeat("█", filled)),
+		progressDimStyle.Render(strings.Repeat("░", progressBarWidth-filled)),
+		m.finished, m.total)
+
+	repos := make([]string, 0, len(m.inFlight))
+	for repo := range m.inFlight {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "%s %s %s\n", m.spinner.View(), repo, progressDimStyle.Render(m.inFlight[repo]))
+	}
+	return b.String()
+}
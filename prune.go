@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+// pruneCandidate is a cloned repository that the config no longer selects
+type pruneCandidate struct {
+	SCM           string
+	Owner         string
+	Name          string
+	RepoPath      string
+	LocalSymlink  string
+	GlobalSymlink string
+	LocalWork     string // Work in the clone that isn't on origin, or "" if none
+}
+
+// findPruneCandidates lists the clones under each clone target's
+// .repositories/<scm>/<owner> tree that filterRepositories no longer selects.
+// Nothing outside those trees is considered.
+func findPruneCandidates(logger *logger.RateLimitedLogger, config *Config) ([]pruneCandidate, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting cache directory: %w", err)
+	}
+
+	var candidates []pruneCandidate
+	for _, target := range config.CloneTargets() {
+		targetConfig := config.forTarget(target)
+		scmType, err := normalizeSCM(targetConfig.Global.SCM)
+		if err != nil {
+			return nil, err
+		}
+
+		repos, err := getRepositoryList(context.Background(), logger, targetConfig, scmType, targetConfig.Global.BaseURL, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: error fetching repositories: %w", target.SCM, target.Owner, err)
+		}
+		repos = skipArchivedRepositories(logger, repos, targetConfig)
+		selected := repoInfoByName(filterRepositories(logger, repos, targetConfig))
+		known := repoInfoByName(repos)
+
+		repoDir := filepath.Join(cacheDir, ".repositories", targetConfig.Global.SCM, targetConfig.Global.Owner)
+		entries, err := os.ReadDir(repoDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", repoDir, err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if _, ok := selected[entry.Name()]; ok {
+				continue
+			}
+			info, ok := known[entry.Name()]
+			if !ok {
+				info = lib.RepoInfo{Name: entry.Name()}
+			}
+			localSymlink, err := localLinkPath(logger, targetConfig, info)
+			if err != nil {
+				localSymlink = filepath.Join(config.Global.Path, entry.Name())
+			}
+			candidates = append(candidates, pruneCandidate{
+				SCM:           targetConfig.Global.SCM,
+				Owner:         targetConfig.Global.Owner,
+				Name:          entry.Name(),
+				RepoPath:      filepath.Join(repoDir, entry.Name()),
+				LocalSymlink:  localSymlink,
+				GlobalSymlink: filepath.Join(cacheDir, targetConfig.Global.SCM, targetConfig.Global.Owner, entry.Name()),
+				LocalWork:     localWork(filepath.Join(repoDir, entry.Name())),
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+// localWork describes work in the clone at repoPath that origin doesn't have
+// and that removing the clone would lose: uncommitted changes, or commits on
+// the checked-out branch that aren't pushed. It returns "" when there is none.
+func localWork(repoPath string) string {
+	var status repoStatus
+	if err := readRepoStatus(repoPath, &status); err != nil {
+		return fmt.Sprintf("can't check for local work: %v", err)
+	}
+	switch {
+	case status.Dirty:
+		return "uncommitted changes"
+	case status.Branch != "" && !status.Upstream:
+		return fmt.Sprintf("branch %s isn't on origin", status.Branch)
+	case status.Ahead > 0:
+		return fmt.Sprintf("unpushed commits: %d", status.Ahead)
+	}
+	return ""
+}
+
+// pruneRepositories previews the clones no longer matched by the config,
+// asks for confirmation, then removes them with their links and index
+// entries. Clones with local work are kept unless force is set.
+func pruneRepositories(logger *logger.RateLimitedLogger, config *Config, force bool, confirm func(title string) (bool, error)) error {
+	candidates, err := findPruneCandidates(logger, config)
+	if err != nil {
+		return fmt.Errorf("error finding repositories to prune: %w", err)
+	}
+
+	if len(candidates) == 0 {
+		logger.Info("No repositories to prune")
+		return nil
+	}
+
+	printPrunePreview(candidates, force)
+
+	var removable []pruneCandidate
+	for _, candidate := range candidates {
+		if candidate.LocalWork != "" && !force {
+			logger.Warn("Keeping clone with local work; prune --force removes it anyway", "repo", candidate.Name, "reason", candidate.LocalWork)
+			continue
+		}
+		removable = append(removable, candidate)
+	}
+	if len(removable) == 0 {
+		logger.Info("No repositories to prune")
+		return nil
+	}
+
+	ok, err := confirm(fmt.Sprintf("Remove these %d repositories and their links?", len(removable)))
+	if err != nil {
+		return fmt.Errorf("error getting confirmation: %w", err)
+	}
+	if !ok {
+		logger.Info("Prune cancelled")
+		return nil
+	}
+
+	var pruned []pruneCandidate
+	for _, candidate := range removable {
+		if err := removeClone(candidate); err != nil {
+			logger.Error("Failed to prune repository", "repo", candidate.Name, "error", err)
+			continue
+		}
+		logger.Info("Pruned repository", "repo", candidate.Name, "path", candidate.RepoPath)
+		pruned = append(pruned, candidate)
+	}
+
+	if err := removeFromIndexTOML(pruned); err != nil {
+		logger.Error("Failed to update index.toml", "error", err)
+	}
+
+	failed := len(removable) - len(pruned)
+	logger.Info("Prune complete", "pruned", len(pruned), "kept", len(candidates)-len(removable), "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d repositories failed to prune", failed)
+	}
+	return nil
+}
+
+// confirmPrune asks before removing clones
+func confirmPrune(title string) (bool, error) {
+	var ok bool
+	err := huh.NewConfirm().
+		Title(title).
+		Value(&ok).
+		Run()
+	return ok, err
+}
+
+// printPrunePreview is the dry run shown before anything is removed
+func printPrunePreview(candidates []pruneCandidate, force bool) {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+	fmt.Println(headerStyle.Render("\nRepositories no longer matched by the config:"))
+	fmt.Println()
+	for _, candidate := range candidates {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("  %s/%s/%s", candidate.SCM, candidate.Owner, candidate.Name)))
+		fmt.Println(infoStyle.Render(fmt.Sprintf("    clone: %s", candidate.RepoPath)))
+		if candidate.LocalWork == "" {
+			continue
+		}
+		if force {
+			fmt.Println(warnStyle.Render(fmt.Sprintf("    removed despite local work: %s", candidate.LocalWork)))
+		} else {
+			fmt.Println(warnStyle.Render(fmt.Sprintf("    kept, has local work: %s", candidate.LocalWork)))
+		}
+	}
+	fmt.Println()
+}
+
+// removeClone removes the clone directory and any links to it: symlinks
+// pointing at it and, in copy and hardlink modes, the copies made of it
+func removeClone(candidate pruneCandidate) error {
+	for _, link := range []string{candidate.LocalSymlink, candidate.GlobalSymlink} {
+		if err := removeLinkTo(link, candidate.RepoPath); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(candidate.RepoPath)
+}
+
+// removeLinkTo removes link only if it is a symlink pointing at target or a
+// copy gitspace made of it, so unrelated files that happen to share the name
+// are left alone
+func removeLinkTo(link, target string) error {
+	info, err := os.Lstat(link)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		if source, ok := readLinkMarker(link); ok && filepath.Clean(source) == filepath.Clean(target) {
+			return os.RemoveAll(link)
+		}
+		return nil
+	}
+
+	dest, err := os.Readlink(link)
+	if err != nil {
+		return err
+	}
+	if filepath.Clean(dest) != filepath.Clean(target) {
+		return nil
+	}
+	return os.Remove(link)
+}
+
+// removeFromIndexTOML drops the pruned repositories from index.toml
+func removeFromIndexTOML(pruned []pruneCandidate) error {
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	indexPath := filepath.Join(cacheDir, "index.toml")
+
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		return nil
+	}
+	indexData, err := loadIndexData(indexPath)
+	if err != nil {
+		return err
+	}
+
+	scms := indexSCMs(indexData)
+	for _, candidate := range pruned {
+		owners, _ := scms[candidate.SCM].(map[string]interface{})
+		repos, _ := owners[candidate.Owner].(map[string]interface{})
+		delete(repos, candidate.Name)
+	}
+
+	var sb strings.Builder
+	if err := toml.NewEncoder(&sb).Encode(indexData); err != nil {
+		return fmt.Errorf("failed to encode TOML: %w", err)
+	}
+	return os.WriteFile(indexPath, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestRemoveCloneRemovesLinks(t *testing.T) {
+	dir := t.TempDir()
+	repoPath := filepath.Join(dir, "repos", "api")
+	writeTestTree(t, repoPath)
+	other := filepath.Join(dir, "repos", "web")
+	writeTestTree(t, other)
+
+	symlink := filepath.Join(dir, "work", "api")
+	if _, err := ensureLink(linkModeSymlink, repoPath, symlink); err != nil {
+		t.Fatal(err)
+	}
+	copied := filepath.Join(dir, "global", "api")
+	if _, err := ensureLink(linkModeCopy, repoPath, copied); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeClone(pruneCandidate{RepoPath: repoPath, LocalSymlink: symlink, GlobalSymlink: copied}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{repoPath, symlink, copied} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s is still there: %v", path, err)
+		}
+	}
+
+	// Copies of another clone, and directories gitspace didn't make, stay
+	otherCopy := filepath.Join(dir, "work", "web")
+	if _, err := ensureLink(linkModeCopy, other, otherCopy); err != nil {
+		t.Fatal(err)
+	}
+	unmarked := filepath.Join(dir, "global", "web")
+	writeTestTree(t, unmarked)
+	writeTestTree(t, repoPath)
+	if err := removeClone(pruneCandidate{RepoPath: repoPath, LocalSymlink: otherCopy, GlobalSymlink: unmarked}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{otherCopy, unmarked} {
+		if _, err := os.Stat(filepath.Join(path, "src", "main.go")); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+}
+
+func TestPruneKeepsClonesWithLocalWork(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha", "beta", "gamma")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+
+	// alpha has uncommitted changes and beta an unpushed commit; gamma is clean
+	if err := os.WriteFile(filepath.Join(clonedRepoPath(t, "alpha"), "README.md"), []byte("local change\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	beta, err := git.PlainOpen(clonedRepoPath(t, "beta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFile(t, beta, "NOTES.md", "unpushed\n")
+
+	// Select nothing, so every clone is a prune candidate
+	group := config.Groups["all"]
+	group.Values = []string{"^none$"}
+	config.Groups["all"] = group
+
+	want := map[string]string{"alpha": "uncommitted changes", "beta": "unpushed commits: 1", "gamma": ""}
+	candidates, err := findPruneCandidates(logger, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != len(want) {
+		t.Fatalf("got %d candidates, want %d", len(candidates), len(want))
+	}
+	for _, candidate := range candidates {
+		if candidate.LocalWork != want[candidate.Name] {
+			t.Errorf("%s: local work %q, want %q", candidate.Name, candidate.LocalWork, want[candidate.Name])
+		}
+	}
+
+	exists := func(repo string) bool {
+		_, err := os.Stat(clonedRepoPath(t, repo))
+		return err == nil
+	}
+	if err := pruneRepositories(logger, config, false, alwaysConfirm); err != nil {
+		t.Fatal(err)
+	}
+	if !exists("alpha") || !exists("beta") || exists("gamma") {
+		t.Errorf("after prune: alpha %v, beta %v, gamma %v, want only gamma removed", exists("alpha"), exists("beta"), exists("gamma"))
+	}
+
+	if err := pruneRepositories(logger, config, true, alwaysConfirm); err != nil {
+		t.Fatal(err)
+	}
+	if exists("alpha") || exists("beta") {
+		t.Error("prune --force kept clones with local work")
+	}
+}
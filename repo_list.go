@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+const (
+	repoListFile       = "repo_list.json"
+	defaultRepoListTTL = time.Hour
+
+	defaultRateLimitWait = 5 * time.Minute
+)
+
+// repoListCache is the on-disk form of a cached repository listing
+type repoListCache struct {
+	FetchedAt  time.Time      `json:"fetched_at"`
+	Visibility string         `json:"visibility"`
+	Repos      []lib.RepoInfo `json:"repos"`
+}
+
+func getRepoListCachePath(config *Config) (string, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, ".repositories", config.Global.SCM, config.Global.Owner, repoListFile), nil
+}
+
+// getRepoListTTL returns how long a cached repository list stays fresh.
+// The value is validated in loadConfig, so a parse error falls back to the default.
+func getRepoListTTL(config *Config) time.Duration {
+	if config.Global.RepoListTTL == "" {
+		return defaultRepoListTTL
+	}
+	ttl, err := time.ParseDuration(config.Global.RepoListTTL)
+	if err != nil {
+		return defaultRepoListTTL
+	}
+	return ttl
+}
+
+// getRateLimitWait returns global.rate_limit_wait, falling back to the default
+// when unset or invalid
+func getRateLimitWait(config *Config) time.Duration {
+	if config.Global.RateLimitWait == "" {
+		return defaultRateLimitWait
+	}
+	wait, err := time.ParseDuration(config.Global.RateLimitWait)
+	if err != nil {
+		return defaultRateLimitWait
+	}
+	return wait
+}
+
+// getNetworkTimeout returns global.network_timeout, falling back to the
+// default when unset or invalid
+func getNetworkTimeout(config *Config) time.Duration {
+	if config.Global.NetworkTimeout == "" {
+		return lib.DefaultNetworkTimeout
+	}
+	timeout, err := time.ParseDuration(config.Global.NetworkTimeout)
+	if err != nil || timeout <= 0 {
+		return lib.DefaultNetworkTimeout
+	}
+	return timeout
+}
+
+// getRepositoryList returns the repositories of the configured owner,
+// serving them from the on-disk cache while it is fresh. forceRefresh skips
+// the cache and always asks the SCM.
+func getRepositoryList(ctx context.Context, logger *logger.RateLimitedLogger, config *Config, scmType lib.SCMType, baseURL string, forceRefresh bool) ([]lib.RepoInfo, error) {
+	cachePath, err := getRepoListCachePath(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository list cache path: %w", err)
+	}
+
+	if lib.Offline() && scmType != lib.SCMTypeLocal {
+		if forceRefresh {
+			return nil, fmt.Errorf("refreshing the repository list: %w", lib.ErrOffline)
+		}
+		cached, err := readRepoListCache(cachePath)
+		if err != nil {
+			return nil, fmt.Errorf("no cached repository list for %s/%s; run once without --offline: %w", config.Global.SCM, config.Global.Owner, lib.ErrOffline)
+		}
+		// However old, the cache is all there is offline
+		logger.Debug("Using cached repository list offline", "path", cachePath, "age", time.Since(cached.FetchedAt).Round(time.Second), "count", len(cached.Repos))
+		return cached.Repos, nil
+	}
+
+	if !forceRefresh {
+		cached, err := readRepoListCache(cachePath)
+		if err != nil {
+			logger.Debug("Repository list cache unavailable", "path", cachePath, "error", err)
+		} else if cached.Visibility != config.Global.Visibility {
+			logger.Debug("Repository list cache was fetched with a different visibility", "path", cachePath, "cached", cached.Visibility, "visibility", config.Global.Visibility)
+		} else if age := time.Since(cached.FetchedAt); age < getRepoListTTL(config) {
+			logger.Debug("Using cached repository list", "path", cachePath, "age", age.Round(time.Second), "count", len(cached.Repos))
+			return cached.Repos, nil
+		}
+	}
+
+	opts := lib.ProviderOptions{
+		BaseURL:    baseURL,
+		OwnerType:  lib.OwnerType(config.Global.OwnerType),
+		Visibility: lib.Visibility(config.Global.Visibility),
+	}
+	timeout := getNetworkTimeout(config)
+	if scmType == lib.SCMTypeGitHub {
+		opts.RateLimitWait = getRateLimitWait(config)
+		opts.Logger = logger
+		token, err := resolveToken(config, "GITHUB_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		opts.Token = token
+		// Waiting for a rate limit reset shouldn't count as the network hanging
+		timeout += opts.RateLimitWait
+	}
+
+	ctx, cancel := lib.WithNetworkTimeout(ctx, timeout)
+	defer cancel()
+	repos, err := lib.GetRepositoriesDetailed(ctx, scmType, opts, config.Global.Owner)
+	if err != nil {
+		return nil, lib.TimeoutError(err, "listing repositories of "+config.Global.Owner, timeout)
+	}
+
+	if err := writeRepoListCache(cachePath, config.Global.Visibility, repos); err != nil {
+		logger.Warn("Failed to write repository list cache", "path", cachePath, "error", err)
+	}
+
+	return repos, nil
+}
+
+func readRepoListCache(path string) (*repoListCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached repoListCache
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("failed to decode repository list cache: %w", err)
+	}
+	return &cached, nil
+}
+
+func writeRepoListCache(path, visibility string, repos []lib.RepoInfo) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(repoListCache{FetchedAt: time.Now(), Visibility: visibility, Repos: repos}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode repository list cache: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func refreshRepositoryList(logger *logger.RateLimitedLogger, config *Config) {
+	for _, target := range config.CloneTargets() {
+		targetConfig := config.forTarget(target)
+		scmType, err := normalizeSCM(targetConfig.Global.SCM)
+		if err != nil {
+			logger.Error("Unsupported SCM type", "type", targetConfig.Global.SCM, "error", err)
+			continue
+		}
+
+		ctx := context.Background()
+		repos, err := getRepositoryList(ctx, logger, targetConfig, scmType, targetConfig.Global.BaseURL, true)
+		if err != nil {
+			logger.Error("Error fetching repositories", "owner", targetConfig.Global.Owner, "error", err)
+			continue
+		}
+
+		logger.Info("Repository list refreshed", "owner", targetConfig.Global.Owner, "count", len(repos))
+	}
+}
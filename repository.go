@@ -2,110 +2,255 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	"github.com/ssotops/gitspace/lib"
+	"github.com/ssotops/gitspace/plugin"
 	gossh "golang.org/x/crypto/ssh" // Add this import
 )
 
 type RepoResult struct {
 	Name          string
+	SCM           string
+	Info          lib.RepoInfo
+	Owner         string
 	Cloned        bool
 	Updated       bool
+	FastForwarded bool   // The checked-out branch moved under sync_strategy ff-only or pull
+	DirtyAction   string // What dirty_policy did about uncommitted changes, if there were any
+	UpToDate      bool   // Not fetched, as nothing was pushed since the last sync
+	LastSynced    time.Time
+	LinkMode      string
+	Retries       int
 	LocalSymlink  string
 	GlobalSymlink string
 	Error         error
+	PinnedCommit  string // The commit checked out for a group with commit set
+	Submodules    bool   // Submodules were initialized and updated under init_submodules
+	LFS           string // lfsOK or lfsFailed when git lfs pull ran
+	LFSError      error
+	Hook          string // hookOK or hookFailed when a post-clone hook ran
+	HookError     error
 }
 
-func cloneRepositories(logger *logger.RateLimitedLogger, config *Config) {
+// Key identifies a result across clone targets, since two owners can have
+// repositories with the same name
+func (r *RepoResult) Key() string {
+	return fmt.Sprintf("%s/%s/%s", r.SCM, r.Owner, r.Name)
+}
+
+// cloneRepositories clones or updates the repositories of every clone target.
+// selectRepos, if not nil, picks which of each target's filtered
+// repositories to clone.
+func cloneRepositories(logger *logger.RateLimitedLogger, config *Config, selectRepos repoSelector) (map[string]*RepoResult, error) {
 	cacheDir, err := getCacheDir()
 	if err != nil {
-		logger.Error("Error getting cache directory", "error", err)
-		return
+		return nil, fmt.Errorf("error getting cache directory: %w", err)
 	}
 
-	baseDir := config.Global.Path
-	repoDir := filepath.Join(cacheDir, ".repositories", config.Global.SCM, config.Global.Owner)
-	err = os.MkdirAll(repoDir, 0755)
+	sshAuth, sshKeyPath, err := setupSSHAuth(config)
 	if err != nil {
-		logger.Error("Error creating directories", "error", err)
-		return
+		return nil, err
+	}
+
+	var errs []error
+	results := make(map[string]*RepoResult)
+	for _, target := range config.CloneTargets() {
+		targetResults, err := cloneTargetRepositories(logger, config.forTarget(target), cacheDir, sshAuth, sshKeyPath, selectRepos)
+		if err != nil {
+			logger.Error("Failed to clone repositories", "scm", target.SCM, "owner", target.Owner, "error", err)
+			errs = append(errs, fmt.Errorf("%s/%s: %w", target.SCM, target.Owner, err))
+		}
+		for _, result := range targetResults {
+			results[result.Key()] = result
+		}
+	}
+
+	if len(results) > 0 {
+		err = updateIndexTOML(logger, config, results)
+		if err != nil {
+			logger.Error("Failed to update index.toml", "error", err)
+		}
 	}
 
-	// Setup SSH auth
+	session.record(results)
+	emitResults(plugin.EventPostClone, results)
+	return results, errors.Join(append(errs, resultsError(results))...)
+}
+
+// setupSSHAuth loads the SSH key from auth.key_path, returning the auth method
+// and the expanded key path. When every clone target is local no key is
+// needed, and the auth method is nil.
+func setupSSHAuth(config *Config) (*ssh.PublicKeys, string, error) {
+	if !needsSSH(config) {
+		return nil, "", nil
+	}
 	sshKeyPath, err := getSSHKeyPath(config.Auth.KeyPath)
 	if err != nil {
-		logger.Error("Error getting SSH key path", "error", err)
-		return
+		return nil, "", fmt.Errorf("error getting SSH key path: %w", err)
 	}
 	sshKeyPath, err = homedir.Expand(sshKeyPath)
 	if err != nil {
-		logger.Error("Error expanding SSH key path", "error", err)
-		return
+		return nil, "", fmt.Errorf("error expanding SSH key path: %w", err)
 	}
 	sshAuth, err := ssh.NewPublicKeysFromFile("git", sshKeyPath, "")
 	if err != nil {
-		logger.Error("Error setting up SSH auth", "error", err)
-		return
+		return nil, "", fmt.Errorf("error setting up SSH auth: %w", err)
+	}
+	return sshAuth, sshKeyPath, nil
+}
+
+// needsSSH reports whether any clone target is cloned over SSH
+func needsSSH(config *Config) bool {
+	for _, target := range config.CloneTargets() {
+		if scmType, err := normalizeSCM(target.SCM); err != nil || scmType != lib.SCMTypeLocal {
+			return true
+		}
+	}
+	return false
+}
+
+// resultsError returns an error counting the failed repositories, or nil if
+// none failed
+func resultsError(results map[string]*RepoResult) error {
+	failed := 0
+	for _, result := range results {
+		if result.Error != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed", failed, len(results))
+	}
+	return nil
+}
+
+// cloneTargetRepositories clones or updates the repositories of a single
+// scm/owner. config must already be scoped to that target with forTarget.
+func cloneTargetRepositories(logger *logger.RateLimitedLogger, config *Config, cacheDir string, sshAuth *ssh.PublicKeys, sshKeyPath string, selectRepos repoSelector) (map[string]*RepoResult, error) {
+	repoDir := filepath.Join(cacheDir, ".repositories", config.Global.SCM, config.Global.Owner)
+	err := os.MkdirAll(repoDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating directories: %w", err)
+	}
+
+	scmType, err := normalizeSCM(config.Global.SCM)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireNetwork(scmType, "cloning"); err != nil {
+		return nil, err
 	}
 
 	// Check for appropriate authentication based on SCM type
-	switch lib.SCMType(config.Global.SCM) {
+	switch scmType {
 	case lib.SCMTypeGitHub:
-		if os.Getenv("GITHUB_TOKEN") == "" {
-			logger.Error("GITHUB_TOKEN environment variable not set. Please set it and try again.")
-			return
+		token, err := resolveToken(config, "GITHUB_TOKEN")
+		if err != nil {
+			return nil, fmt.Errorf("error resolving GitHub token: %w", err)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("no GitHub token found; set auth.token, auth.token_path or the GITHUB_TOKEN environment variable")
+		}
+	case lib.SCMTypeGitLab:
+		if os.Getenv("GITLAB_TOKEN") == "" {
+			return nil, fmt.Errorf("GITLAB_TOKEN environment variable not set")
 		}
+	case lib.SCMTypeBitbucket:
+		if os.Getenv("BITBUCKET_TOKEN") == "" {
+			return nil, fmt.Errorf("BITBUCKET_TOKEN environment variable not set")
+		}
+	case lib.SCMTypeLocal:
+		// Local repositories are cloned over file://, which needs no credentials
 	case lib.SCMTypeGitea:
 		// For Gitea, we're using SSH authentication, so we don't need to check for a token
 		// However, we might want to verify the SSH key exists
 		if _, err := os.Stat(sshKeyPath); os.IsNotExist(err) {
-			logger.Error("SSH key not found. Please ensure the key exists at the specified path.", "path", sshKeyPath)
-			return
+			return nil, fmt.Errorf("SSH key not found at %s", sshKeyPath)
 		}
 	default:
-		logger.Error("Unsupported SCM type", "type", config.Global.SCM)
-		return
+		return nil, fmt.Errorf("unsupported SCM type: %s", config.Global.SCM)
 	}
 
 	// Get list of repositories to clone
 	ctx := context.Background()
-	repos, err := lib.GetRepositories(ctx, lib.SCMType(config.Global.SCM), config.Global.BaseURL, config.Global.Owner)
+	repos, err := getRepositoryList(ctx, logger, config, scmType, config.Global.BaseURL, false)
 	if err != nil {
-		logger.Error("Error fetching repositories", "error", err)
-		return
+		return nil, fmt.Errorf("error fetching repositories: %w", err)
 	}
 
-	filteredRepos := filterRepositories(repos, config)
+	repos = skipArchivedRepositories(logger, repos, config)
+	filteredRepos := filterRepositories(logger, repos, config)
 
 	if len(filteredRepos) == 0 {
-		logger.Warn("No repositories match the filter criteria")
-		return
+		logger.Warn("No repositories match the filter criteria", "owner", config.Global.Owner)
+		return nil, nil
 	}
 
-	// Clone or update repositories
-	results := make(map[string]*RepoResult)
+	if selectRepos != nil {
+		filteredRepos, err = selectRepos(config, filteredRepos)
+		if err != nil {
+			return nil, fmt.Errorf("error selecting repositories: %w", err)
+		}
+		if len(filteredRepos) == 0 {
+			logger.Info("No repositories selected", "owner", config.Global.Owner)
+			return nil, nil
+		}
+	}
+
+	// The callback is shared by every worker, so set it once up front
+	configureHostKeyCallback(sshAuth, scmType)
+
+	deps := repoDependencies(logger, config, filteredRepos)
+	order, err := orderByDependencies(lib.RepoNames(filteredRepos), deps)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, repo := range filteredRepos {
+	emitPreClone(config, repoDir, order)
+
+	// Clone or update repositories
+	repoInfo := repoInfoByName(filteredRepos)
+	results := processRepositories(config.Global.SCM+"/"+config.Global.Owner, order, deps, config.Global.Concurrency, func(repo string, progress io.Writer, result *RepoResult) {
+		result.SCM = config.Global.SCM
+		result.Owner = config.Global.Owner
+		result.Info = repoInfo[repo]
+		result.LinkMode = config.Global.LinkMode
 		repoPath := filepath.Join(repoDir, repo)
-		result := &RepoResult{Name: repo}
-		results[repo] = result
+		branch := resolveBranch(logger, config, result.Info)
 
 		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 			// Clone the repository if it doesn't exist
-			err := cloneRepo(repoPath, config.Global.SCM, config.Global.Owner, repo, sshAuth, sshKeyPath, config.Global.EmptyRepoInitialBranch, logger)
+			retries, err := withRetry(logger, getMaxRetries(config), "clone", repo, func() error {
+				err := cloneRepo(repoPath, scmType, config.Global.BaseURL, config.Global.Owner, repo, branch, config.Global.CloneDepth, sshAuth, sshKeyPath, config.Global.EmptyRepoInitialBranch, progress, logger)
+				if err != nil {
+					// Don't let a partial clone turn the retry into a fetch
+					os.RemoveAll(repoPath)
+				}
+				return err
+			})
+			result.Retries = retries
 			if err != nil {
 				result.Error = err
 				logger.Error("Clone failed", "repo", repo, "error", err)
@@ -115,65 +260,41 @@ func cloneRepositories(logger *logger.RateLimitedLogger, config *Config) {
 			}
 		} else {
 			// Update existing repository
-			r, err := git.PlainOpen(repoPath)
+			r, err := gitClient.Open(repoPath)
 			if err != nil {
 				result.Error = err
 				logger.Error("Failed to open existing repository", "repo", repo, "error", err)
-				continue
+				return
 			}
 
-			err = r.Fetch(&git.FetchOptions{
-				Auth:     sshAuth,
-				Progress: os.Stdout,
-			})
-			if err != nil && err != git.NoErrAlreadyUpToDate {
-				result.Error = err
-				logger.Error("Fetch failed", "repo", repo, "error", err)
-			} else {
-				result.Updated = true
-				logger.Info("Fetch successful", "repo", repo)
-			}
+			updateRepo(logger, config, r, repoPath, sshAuth, branch, progress, result)
 		}
 
-		// Create local symlink
-		localSymlinkPath := filepath.Join(baseDir, repo)
-		err = createSymlink(repoPath, localSymlinkPath)
-		if err != nil {
-			logger.Error("Error creating local symlink", "repo", repo, "error", err)
-		} else {
-			result.LocalSymlink = localSymlinkPath
-		}
+		pinCommit(logger, config, repoPath, result)
+		updateSubmodules(logger, config, repoPath, sshAuth, result)
+		pullLFS(logger, config, repoPath, result)
+
+		linkLocally(logger, config, repoPath, result)
 
 		// Create global symlink
 		globalSymlinkPath := filepath.Join(cacheDir, config.Global.SCM, config.Global.Owner, repo)
-		err = createSymlink(repoPath, globalSymlinkPath)
+		err := createLink(config, repoPath, globalSymlinkPath)
 		if err != nil {
 			logger.Error("Error creating global symlink", "repo", repo, "error", err)
 		} else {
 			result.GlobalSymlink = globalSymlinkPath
 		}
-	}
 
-	err = updateIndexTOML(logger, config, results)
-	if err != nil {
-		logger.Error("Failed to update index.toml", "error", err)
-	}
+		runPostCloneHook(logger, config, repoPath, result)
+	})
 
-	// Print summary table
-	printSummaryTable(config, results, repoDir)
+	return results, nil
 }
 
-func cloneRepo(repoPath, scm, owner, repo string, sshAuth *ssh.PublicKeys, sshKeyPath, initialBranch string, logger *logger.RateLimitedLogger) error {
-	var repoURL string
-
-	// Format the repository URL based on SCM type
-	switch lib.SCMType(scm) {
-	case lib.SCMTypeGitHub:
-		repoURL = fmt.Sprintf("git@github.com:%s/%s.git", owner, repo)
-	case lib.SCMTypeGitea:
-		repoURL = fmt.Sprintf("ssh://scmtea/%s/%s.git", owner, repo)
-	default:
-		return fmt.Errorf("unsupported SCM type: %s", scm)
+func cloneRepo(repoPath string, scmType lib.SCMType, baseURL, owner, repo, branch string, depth int, sshAuth *ssh.PublicKeys, sshKeyPath, initialBranch string, progress io.Writer, logger *logger.RateLimitedLogger) error {
+	repoURL, err := getRepoURL(scmType, baseURL, owner, repo)
+	if err != nil {
+		return err
 	}
 
 	logger.Debug("Cloning repo", "url", repoURL, "path", repoPath)
@@ -181,21 +302,18 @@ func cloneRepo(repoPath, scm, owner, repo string, sshAuth *ssh.PublicKeys, sshKe
 	// Configure clone options
 	cloneOptions := &git.CloneOptions{
 		URL:      repoURL,
-		Progress: os.Stdout,
-		Auth:     sshAuth,
+		Progress: progress,
+		Depth:    depth, // 0 means full history
 	}
-
-	// For GitHub, we don't want to skip host key verification
-	if lib.SCMType(scm) == lib.SCMTypeGitHub {
-		sshAuth.HostKeyCallback = nil // Use default host key verification
-	} else {
-		// For Gitea local development, we skip host key verification
-		sshAuth.HostKeyCallback = func(hostname string, remote net.Addr, key gossh.PublicKey) error {
-			return nil
-		}
+	if sshAuth != nil {
+		cloneOptions.Auth = sshAuth
+	}
+	if branch != "" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(branch)
+		cloneOptions.SingleBranch = true
 	}
 
-	_, err := git.PlainClone(repoPath, false, cloneOptions)
+	_, err = gitClient.Clone(repoPath, cloneOptions)
 	if err != nil {
 		if strings.Contains(err.Error(), "remote repository is empty") {
 			logger.Info("Repository is empty, initializing", "repo", repo)
@@ -208,8 +326,312 @@ func cloneRepo(repoPath, scm, owner, repo string, sshAuth *ssh.PublicKeys, sshKe
 	return nil
 }
 
+// fetchRepo fetches from origin. When a branch is configured it is fetched
+// explicitly, since single-branch clones only track the branch they were
+// cloned with. A depth > 0 keeps shallow clones shallow.
+func fetchRepo(r *git.Repository, sshAuth *ssh.PublicKeys, branch string, depth int, progress io.Writer) error {
+	fetchOptions := &git.FetchOptions{
+		Progress: progress,
+		Depth:    depth,
+	}
+	if sshAuth != nil {
+		fetchOptions.Auth = sshAuth
+	}
+	if branch != "" {
+		fetchOptions.RefSpecs = []gitconfig.RefSpec{
+			gitconfig.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch)),
+		}
+	}
+
+	err := gitClient.Fetch(r, fetchOptions)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// updateRepo brings an existing clone up to date: it applies the dirty
+// policy if the update will touch the working tree, fetches, checks out
+// branch and applies the sync strategy, recording the outcome in result
+func updateRepo(logger *logger.RateLimitedLogger, config *Config, r *git.Repository, repoPath string, sshAuth *ssh.PublicKeys, branch string, progress io.Writer, result *RepoResult) {
+	repo := result.Name
+	var dirtyAction string
+	var err error
+	if touchesWorktree(r, config.Global.SyncStrategy, branch, resolveCommit(logger, config, result.Info) != "") {
+		dirtyAction, err = applyDirtyPolicy(r, repoPath, config.Global.DirtyPolicy)
+	}
+	result.DirtyAction = dirtyAction
+	switch {
+	case err != nil:
+		result.Error = err
+		logger.Error("Failed to handle uncommitted changes", "repo", repo, "error", err)
+		return
+	case dirtyAction == dirtySkipped:
+		logger.Warn("Repository has uncommitted changes, skipping", "repo", repo, "dirty_policy", dirtyPolicySkip)
+		return
+	case dirtyAction != "":
+		logger.Warn("Repository has uncommitted changes", "repo", repo, "action", dirtyAction)
+	}
+
+	retries, err := withRetry(logger, getMaxRetries(config), "fetch", repo, func() error {
+		return fetchRepo(r, sshAuth, branch, config.Global.CloneDepth, progress)
+	})
+	result.Retries = retries
+	if err != nil {
+		result.Error = err
+		logger.Error("Fetch failed", "repo", repo, "error", err)
+	} else if err := checkoutBranch(r, branch); err != nil {
+		result.Error = err
+		logger.Error("Checkout failed", "repo", repo, "branch", branch, "error", err)
+	} else if fastForwarded, err := updateWorktree(r, config.Global.SyncStrategy, dirtyAction == dirtyForced); err != nil {
+		result.Error = err
+		logger.Error("Fast-forward failed", "repo", repo, "error", err)
+	} else {
+		result.Updated = true
+		result.FastForwarded = fastForwarded
+		logger.Info("Fetch successful", "repo", repo, "fast_forwarded", fastForwarded)
+	}
+}
+
+// touchesWorktree reports whether updating a clone changes its working tree,
+// so that uncommitted changes are in the way: when the sync strategy
+// fast-forwards, when branch isn't checked out yet, or when a commit is
+// pinned. A plain fetch only updates origin/*.
+func touchesWorktree(r *git.Repository, strategy, branch string, pinned bool) bool {
+	return strategy != syncStrategyFetch || pinned || !onBranch(r, branch)
+}
+
+// onBranch reports whether branch is checked out, or is empty and so leaves
+// whatever is checked out alone
+func onBranch(r *git.Repository, branch string) bool {
+	if branch == "" {
+		return true
+	}
+	head, err := r.Head()
+	return err == nil && head.Name() == plumbing.NewBranchReferenceName(branch)
+}
+
+// checkoutBranch switches the worktree to branch, creating the local branch
+// from origin if it doesn't exist yet. An empty branch, or the branch already
+// checked out, leaves the worktree alone.
+func checkoutBranch(r *git.Repository, branch string) error {
+	if onBranch(r, branch) {
+		return nil
+	}
+	localRef := plumbing.NewBranchReferenceName(branch)
+
+	if _, err := r.Reference(localRef, true); err == nil {
+		return gitClient.Checkout(r, &git.CheckoutOptions{Branch: localRef})
+	}
+
+	remoteRef, err := r.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return fmt.Errorf("branch %s not found on origin: %w", branch, err)
+	}
+	return gitClient.Checkout(r, &git.CheckoutOptions{
+		Branch: localRef,
+		Hash:   remoteRef.Hash(),
+		Create: true,
+	})
+}
+
+// resolveCommit returns the commit to pin a repository to: that of the
+// highest-priority matching group that sets one, or "" to stay on the branch
+func resolveCommit(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) string {
+	for _, group := range sortedGroups(config) {
+		if group.Commit != "" && matchesGroup(logger, repo, group.Group) {
+			return group.Commit
+		}
+	}
+	return ""
+}
+
+// pinCommit checks out the repository's pinned commit, if any, as a detached
+// HEAD after a successful clone or fetch. Failing to pin fails the repository,
+// since it would otherwise be left on a moving branch.
+func pinCommit(logger *logger.RateLimitedLogger, config *Config, repoPath string, result *RepoResult) {
+	if result.Error != nil || result.DirtyAction == dirtySkipped {
+		return
+	}
+	commit := resolveCommit(logger, config, result.Info)
+	if commit == "" {
+		return
+	}
+
+	if err := checkoutCommit(repoPath, commit); err != nil {
+		if config.Global.CloneDepth > 0 {
+			err = fmt.Errorf("%w (clone_depth is %d; the commit may be outside the shallow history)", err, config.Global.CloneDepth)
+		}
+		result.Error = fmt.Errorf("failed to pin commit %s: %w", commit, err)
+		logger.Error("Pinning commit failed", "repo", result.Name, "commit", commit, "error", err)
+		return
+	}
+	result.PinnedCommit = commit
+	logger.Info("Pinned commit", "repo", result.Name, "commit", commit)
+}
+
+// updateSubmodules initializes and updates the repository's submodules when
+// global.init_submodules is set, recursively with submodules_recursive
+func updateSubmodules(logger *logger.RateLimitedLogger, config *Config, repoPath string, sshAuth *ssh.PublicKeys, result *RepoResult) {
+	if !config.Global.InitSubmodules || result.Error != nil || result.DirtyAction == dirtySkipped {
+		return
+	}
+	r, err := gitClient.Open(repoPath)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to update submodules: %w", err)
+		return
+	}
+
+	opts := &git.SubmoduleUpdateOptions{Init: true}
+	if config.Global.SubmodulesRecursive {
+		opts.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
+	}
+	if sshAuth != nil {
+		opts.Auth = sshAuth
+	}
+	count, err := gitClient.UpdateSubmodules(r, opts)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to update submodules: %w", err)
+		logger.Error("Updating submodules failed", "repo", result.Name, "error", err)
+		return
+	}
+	if count > 0 {
+		result.Submodules = true
+		logger.Info("Updated submodules", "repo", result.Name, "count", count)
+	}
+}
+
+// checkoutCommit detaches the worktree at commit, which may be abbreviated
+func checkoutCommit(repoPath, commit string) error {
+	r, err := gitClient.Open(repoPath)
+	if err != nil {
+		return err
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(commit))
+	if err != nil {
+		return fmt.Errorf("commit not found: %w", err)
+	}
+	// Checking out again would reset the worktree for nothing
+	if head, err := r.Head(); err == nil && head.Name() == plumbing.HEAD && head.Hash() == *hash {
+		return nil
+	}
+	return gitClient.Checkout(r, &git.CheckoutOptions{Hash: *hash})
+}
+
+// getRepoURL formats the clone URL for repo based on SCM type: SSH for hosted
+// SCMs, file:// for local directories
+func getRepoURL(scmType lib.SCMType, baseURL, owner, repo string) (string, error) {
+	switch scmType {
+	case lib.SCMTypeGitHub:
+		return fmt.Sprintf("git@github.com:%s/%s.git", owner, repo), nil
+	case lib.SCMTypeGitLab:
+		host := "gitlab.com"
+		if baseURL != "" {
+			u, err := url.Parse(baseURL)
+			if err != nil || u.Host == "" {
+				return "", fmt.Errorf("invalid GitLab base URL: %s", baseURL)
+			}
+			host = u.Hostname()
+		}
+		return fmt.Sprintf("git@%s:%s/%s.git", host, owner, repo), nil
+	case lib.SCMTypeBitbucket:
+		return fmt.Sprintf("git@bitbucket.org:%s/%s.git", owner, repo), nil
+	case lib.SCMTypeGitea:
+		return fmt.Sprintf("ssh://scmtea/%s/%s.git", owner, repo), nil
+	case lib.SCMTypeLocal:
+		root, err := lib.LocalRoot(baseURL)
+		if err != nil {
+			return "", err
+		}
+		return "file://" + filepath.ToSlash(lib.LocalRepoPath(root, owner, repo)), nil
+	default:
+		return "", fmt.Errorf("unsupported SCM type: %s", scmType)
+	}
+}
+
+func configureHostKeyCallback(sshAuth *ssh.PublicKeys, scmType lib.SCMType) {
+	if sshAuth == nil {
+		return // Every target is local
+	}
+	// For hosted SCMs, we don't want to skip host key verification
+	if scmType == lib.SCMTypeGitHub || scmType == lib.SCMTypeGitLab || scmType == lib.SCMTypeBitbucket {
+		sshAuth.HostKeyCallback = nil // Use default host key verification
+	} else {
+		// For Gitea local development, we skip host key verification
+		sshAuth.HostKeyCallback = func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+			return nil
+		}
+	}
+}
+
+// progressOutput receives the progress of clones and fetches. Commands that
+// print machine-readable output to stdout send it to stderr instead.
+var progressOutput io.Writer = os.Stdout
+
+// processRepositories runs process for every repo on a pool of concurrency
+// workers and collects the results once all of them have finished. Progress
+// is reported under title as an aggregated view, so go-git output from
+// concurrent clones doesn't interleave.
+//
+// A repo waits for its dependencies in deps, if they are in repos, to finish
+// first. repos must list dependencies before their dependents, as
+// orderByDependencies does, so a worker only ever waits on jobs already
+// handed out.
+func processRepositories(title string, repos []string, deps map[string][]string, concurrency int, process func(repo string, progress io.Writer, result *RepoResult)) map[string]*RepoResult {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	results := make(map[string]*RepoResult)
+	var mu sync.Mutex // guards results
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	tracker := newRepoProgress(title, len(repos))
+
+	finished := make(map[string]chan struct{}, len(repos))
+	for _, repo := range repos {
+		finished[repo] = make(chan struct{})
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range jobs {
+				for _, dep := range deps[repo] {
+					if done, ok := finished[dep]; ok {
+						<-done
+					}
+				}
+
+				result := &RepoResult{Name: repo}
+				process(repo, tracker.start(repo), result)
+				tracker.done(repo, result)
+
+				mu.Lock()
+				results[repo] = result
+				mu.Unlock()
+				close(finished[repo])
+			}
+		}()
+	}
+
+	for _, repo := range repos {
+		jobs <- repo
+	}
+	close(jobs)
+	wg.Wait()
+	tracker.close()
+
+	return results
+}
+
 func repoExists(scm, owner, repo string) bool {
-	switch lib.SCMType(scm) {
+	scmType, err := normalizeSCM(scm)
+	if err != nil {
+		return false
+	}
+	switch scmType {
 	case lib.SCMTypeGitHub:
 		url := fmt.Sprintf("https://github.com/%s/%s", owner, repo)
 		resp, err := http.Get(url)
@@ -271,18 +693,17 @@ func updateIndexTOML(logger *logger.RateLimitedLogger, config *Config, repoResul
 		return fmt.Errorf("failed to create .configs directory: %w", err)
 	}
 
-	// Create a new TOML tree
-	indexData := make(map[string]interface{})
+	// Merge into the existing index, so other owners and earlier runs are kept
+	indexData, err := loadIndexData(indexPath)
+	if err != nil {
+		logger.Warn("Rebuilding index.toml from scratch", "error", err)
+		indexData = make(map[string]interface{})
+	}
 
 	// Add lastUpdated
 	now := time.Now()
 	indexData["lastUpdated"] = now.Format(time.RFC3339)
-
-	// Create repositories section
-	repositories := make(map[string]interface{})
-	scm := make(map[string]interface{})
-	owners := make(map[string]interface{})
-	repos := make(map[string]interface{})
+	scms := indexSCMs(indexData)
 
 	// Get the current working directory
 	pwd, err := os.Getwd()
@@ -299,54 +720,105 @@ func updateIndexTOML(logger *logger.RateLimitedLogger, config *Config, repoResul
 		// Continue execution, but log the error
 	}
 
-	// Create a single backup file for all repositories
-	backupFileName := fmt.Sprintf("%s_%s_%s.toml", config.Global.SCM, config.Global.Owner, now.Format("20060102_150405"))
-	backupPath := filepath.Join(configsDir, backupFileName)
+	// Create a single backup file per scm/owner for all of its repositories
+	backupPaths := make(map[string]string)
+	getBackupPath := func(scmName, owner string) string {
+		key := scmName + "/" + owner
+		if backupPath, ok := backupPaths[key]; ok {
+			return backupPath
+		}
+
+		backupFileName := fmt.Sprintf("%s_%s_%s.toml", scmName, owner, now.Format("20060102_150405"))
+		backupPath := filepath.Join(configsDir, backupFileName)
+		backupPaths[key] = backupPath
 
-	// Create backup file
-	if len(originalConfigContent) > 0 {
-		if err := os.WriteFile(backupPath, originalConfigContent, 0644); err != nil {
-			logger.Error("Failed to write config backup", "path", backupPath, "error", err)
-			// Continue execution, but log the error
+		// Create backup file
+		if len(originalConfigContent) > 0 {
+			if err := os.WriteFile(backupPath, originalConfigContent, 0644); err != nil {
+				logger.Error("Failed to write config backup", "path", backupPath, "error", err)
+				// Continue execution, but log the error
+			} else {
+				logger.Info("Created backup config file", "path", backupPath)
+			}
 		} else {
-			logger.Info("Created backup config file", "path", backupPath)
+			logger.Warn("Skipped creating backup file due to empty original config")
 		}
-	} else {
-		logger.Warn("Skipped creating backup file due to empty original config")
+		return backupPath
 	}
 
-	for repo, result := range repoResults {
+	for _, result := range repoResults {
+		repo := result.Name
+		backupPath := getBackupPath(result.SCM, result.Owner)
+		repoPath := filepath.Join(cacheDir, ".repositories", result.SCM, result.Owner, repo)
+
+		repos := childTable(childTable(scms, result.SCM), result.Owner)
+		previous, _ := repos[repo].(map[string]interface{})
+
 		repoData := make(map[string]interface{})
 		repoData["configPath"] = originalConfigPath
 		repoData["backupPath"] = backupPath
 
 		if result.Cloned {
 			repoData["lastCloned"] = now.Format(time.RFC3339)
+		} else if lastCloned, ok := previous["lastCloned"]; ok {
+			repoData["lastCloned"] = lastCloned
 		}
-		if result.Updated {
+		if !result.LastSynced.IsZero() {
+			repoData["lastSynced"] = result.LastSynced.Format(time.RFC3339)
+		} else if result.Updated {
 			repoData["lastSynced"] = now.Format(time.RFC3339)
+		} else if lastSynced, ok := previous["lastSynced"]; ok {
+			repoData["lastSynced"] = lastSynced
 		}
 
 		// Add repository type
-		repoType := getRepoType(config, repo)
+		repoType := getRepoType(logger, config, result.Info)
 		repoData["type"] = repoType
 
+		if labels := getRepoLabels(logger, config, result.Info); len(labels) > 0 {
+			repoData["labels"] = labels
+		}
+
 		// Add metadata
 		metadata := make(map[string]interface{})
 
 		// Set url (formerly URI)
-		url := fmt.Sprintf("https://%s/%s/%s", config.Global.SCM, config.Global.Owner, repo)
+		url := fmt.Sprintf("https://%s/%s/%s", result.SCM, result.Owner, repo)
 		metadata["url"] = url
 
+		if result.Info.Description != "" {
+			metadata["description"] = result.Info.Description
+		}
+		if len(result.Info.Topics) > 0 {
+			metadata["topics"] = result.Info.Topics
+		}
+		if result.Info.DefaultBranch != "" {
+			metadata["defaultBranch"] = result.Info.DefaultBranch
+		}
+		if result.Info.Archived {
+			metadata["archived"] = true
+		}
+		if result.Info.Fork {
+			metadata["fork"] = true
+		}
+		if !result.Info.UpdatedAt.IsZero() {
+			metadata["updatedAt"] = result.Info.UpdatedAt.Format(time.RFC3339)
+		}
+		if !result.Info.PushedAt.IsZero() {
+			metadata["pushedAt"] = result.Info.PushedAt.Format(time.RFC3339)
+		}
+		branch, commit := readHead(repoPath)
+		if branch != "" {
+			metadata["branch"] = branch
+		}
+		if commit != "" {
+			metadata["commit"] = commit
+		}
+
 		repoData["metadata"] = metadata
 		repos[repo] = repoData
 	}
 
-	owners[config.Global.Owner] = repos
-	scm[config.Global.SCM] = owners
-	repositories["repositories"] = scm
-	indexData["repositories"] = repositories
-
 	// Write updated index.toml
 	f, err := os.Create(indexPath)
 	if err != nil {
@@ -364,189 +836,364 @@ func updateIndexTOML(logger *logger.RateLimitedLogger, config *Config, repoResul
 	return nil
 }
 
-func syncRepositories(logger *logger.RateLimitedLogger, config *Config) {
+// readHead returns the branch checked out in the clone at repoPath and the
+// commit it points to. branch is empty for a detached HEAD, and commit for an
+// empty repository; both are empty when there is no clone.
+func readHead(repoPath string) (branch, commit string) {
+	r, err := gitClient.Open(repoPath)
+	if err != nil {
+		return "", ""
+	}
+	// An empty repository's HEAD names a branch with no commits yet
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", ""
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		branch = head.Target().Short()
+	}
+	if resolved, err := r.Head(); err == nil {
+		commit = resolved.Hash().String()
+	}
+	return branch, commit
+}
+
+// syncRepositories fetches the already-cloned repositories of every clone
+// target, skipping those opts says can't have changed
+func syncRepositories(logger *logger.RateLimitedLogger, config *Config, opts syncOptions) (map[string]*RepoResult, error) {
 	logger.Info("Syncing repositories...")
 
-	if config == nil || config.Global.SCM == "" || config.Global.Owner == "" {
-		logger.Error("No valid config loaded. Please load a config file first.")
-		return
+	if config == nil || len(config.CloneTargets()) == 0 {
+		return nil, fmt.Errorf("no valid config loaded")
 	}
 
 	cacheDir, err := getCacheDir()
 	if err != nil {
-		logger.Error("Error getting cache directory", "error", err)
-		return
+		return nil, fmt.Errorf("error getting cache directory: %w", err)
 	}
 
-	repoDir := filepath.Join(cacheDir, ".repositories", config.Global.SCM, config.Global.Owner)
-	baseDir := config.Global.Path
-
-	// Setup SSH auth
-	sshKeyPath, err := getSSHKeyPath(config.Auth.KeyPath)
+	sshAuth, _, err := setupSSHAuth(config)
 	if err != nil {
-		logger.Error("Error getting SSH key path", "error", err)
-		return
+		return nil, err
 	}
 
-	sshKeyPath, err = homedir.Expand(sshKeyPath)
-	if err != nil {
-		logger.Error("Error expanding SSH key path", "error", err)
-		return
+	lastSynced := readLastSynced(cacheDir)
+
+	var errs []error
+	results := make(map[string]*RepoResult)
+	for _, target := range config.CloneTargets() {
+		targetResults, err := syncTargetRepositories(logger, config.forTarget(target), cacheDir, sshAuth, opts, lastSynced)
+		if err != nil {
+			logger.Error("Failed to sync repositories", "scm", target.SCM, "owner", target.Owner, "error", err)
+			errs = append(errs, fmt.Errorf("%s/%s: %w", target.SCM, target.Owner, err))
+		}
+		for _, result := range targetResults {
+			results[result.Key()] = result
+		}
 	}
 
-	sshAuth, err := ssh.NewPublicKeysFromFile("git", sshKeyPath, "")
+	if len(results) > 0 {
+		err = updateIndexTOML(logger, config, results)
+		if err != nil {
+			logger.Error("Failed to update index.toml", "error", err)
+		}
+	}
+
+	session.record(results)
+	emitResults(plugin.EventPostSync, results)
+	return results, errors.Join(append(errs, resultsError(results))...)
+}
+
+// syncTargetRepositories fetches the already-cloned repositories of a single
+// scm/owner. config must already be scoped to that target with forTarget.
+func syncTargetRepositories(logger *logger.RateLimitedLogger, config *Config, cacheDir string, sshAuth *ssh.PublicKeys, opts syncOptions, lastSynced map[string]time.Time) (map[string]*RepoResult, error) {
+	repoDir := filepath.Join(cacheDir, ".repositories", config.Global.SCM, config.Global.Owner)
+
+	scmType, err := normalizeSCM(config.Global.SCM)
 	if err != nil {
-		logger.Error("Error setting up SSH auth", "error", err)
-		return
+		return nil, err
 	}
+	if err := requireNetwork(scmType, "syncing"); err != nil {
+		return nil, err
+	}
+
+	// The callback is shared by every worker, so set it once up front
+	configureHostKeyCallback(sshAuth, scmType)
 
 	// Get list of repositories to sync
 	ctx := context.Background()
-	repos, err := lib.GetRepositories(ctx, lib.SCMType(config.Global.SCM), "", config.Global.Owner)
+	repos, listedAt, err := listForSync(ctx, logger, config, scmType, opts)
 	if err != nil {
-		logger.Error("Error fetching repositories", "error", err)
-		return
+		return nil, fmt.Errorf("error fetching repositories: %w", err)
 	}
 
 	// Filter repositories based on criteria
-	filteredRepos := filterRepositories(repos, config)
+	repos = skipArchivedRepositories(logger, repos, config)
+	filteredRepos := filterRepositories(logger, repos, config)
 
-	results := make(map[string]*RepoResult)
+	deps := repoDependencies(logger, config, filteredRepos)
+	order, err := orderByDependencies(lib.RepoNames(filteredRepos), deps)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, repo := range filteredRepos {
+	repoInfo := repoInfoByName(filteredRepos)
+	results := processRepositories(config.Global.SCM+"/"+config.Global.Owner, order, deps, config.Global.Concurrency, func(repo string, progress io.Writer, result *RepoResult) {
+		result.SCM = config.Global.SCM
+		result.Owner = config.Global.Owner
+		result.Info = repoInfo[repo]
+		result.LinkMode = config.Global.LinkMode
 		repoPath := filepath.Join(repoDir, repo)
-		result := &RepoResult{Name: repo}
-		results[repo] = result
 
 		// Check if the repository exists locally
 		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 			logger.Info("Repository not found locally, skipping", "repo", repo)
-			continue
+			return
 		}
 
 		// Open the existing repository
-		r, err := git.PlainOpen(repoPath)
+		r, err := gitClient.Open(repoPath)
 		if err != nil {
 			result.Error = err
 			logger.Error("Failed to open existing repository", "repo", repo, "error", err)
-			continue
+			return
 		}
 
-		// Fetch updates
-		err = r.Fetch(&git.FetchOptions{
-			Auth:     sshAuth,
-			Progress: os.Stdout,
-		})
-		if err != nil && err != git.NoErrAlreadyUpToDate {
-			result.Error = err
-			logger.Error("Fetch failed", "repo", repo, "error", err)
+		// Fetch updates, unless nothing was pushed since the last sync
+		result.LastSynced = lastSynced[result.Key()]
+		if !opts.needsFetch(result.Info, result.LastSynced, listedAt) {
+			result.UpToDate = true
+			logger.Debug("No pushes since the last sync, skipping fetch", "repo", repo, "pushed_at", result.Info.PushedAt)
 		} else {
-			result.Updated = true
-			logger.Info("Fetch successful", "repo", repo)
+			branch := resolveBranch(logger, config, result.Info)
+			started := time.Now()
+			updateRepo(logger, config, r, repoPath, sshAuth, branch, progress, result)
+			if result.Updated {
+				// Pushes that land during the fetch are caught next time
+				result.LastSynced = started
+			}
 		}
 
-		// Create local symlink
-		localSymlinkPath := filepath.Join(baseDir, repo)
-		err = createSymlink(repoPath, localSymlinkPath)
-		if err != nil {
-			logger.Error("Error creating local symlink", "repo", repo, "error", err)
-		} else {
-			result.LocalSymlink = localSymlinkPath
-		}
+		pinCommit(logger, config, repoPath, result)
+		updateSubmodules(logger, config, repoPath, sshAuth, result)
+		pullLFS(logger, config, repoPath, result)
+
+		linkLocally(logger, config, repoPath, result)
 
 		// Create global symlink
 		globalSymlinkPath := filepath.Join(cacheDir, config.Global.SCM, config.Global.Owner, repo)
-		err = createSymlink(repoPath, globalSymlinkPath)
+		err = createLink(config, repoPath, globalSymlinkPath)
 		if err != nil {
 			logger.Error("Error creating global symlink", "repo", repo, "error", err)
 		} else {
 			result.GlobalSymlink = globalSymlinkPath
 		}
-	}
 
-	err = updateIndexTOML(logger, config, results)
-	if err != nil {
-		logger.Error("Failed to update index.toml", "error", err)
+		runPostCloneHook(logger, config, repoPath, result)
+	})
+
+	return results, nil
+}
+
+// repoInfoByName indexes repository metadata by repository name
+func repoInfoByName(repos []lib.RepoInfo) map[string]lib.RepoInfo {
+	byName := make(map[string]lib.RepoInfo, len(repos))
+	for _, repo := range repos {
+		byName[repo.Name] = repo
 	}
+	return byName
+}
 
-	// Print summary table
-	printSummaryTable(config, results, repoDir)
+// namedGroup pairs a group with its name from the config
+type namedGroup struct {
+	Name string
+	Group
 }
 
-func getRepoType(config *Config, repo string) string {
-	for _, group := range config.Groups {
-		if matchesFilter(repo, group) && group.Type != "" {
-			fmt.Printf("DEBUG: Matched repo '%s' to type '%s'\n", repo, group.Type)
+// sortedGroups returns the configured groups by descending priority, breaking
+// ties by name, so the first matching group is the same on every run
+func sortedGroups(config *Config) []namedGroup {
+	groups := make([]namedGroup, 0, len(config.Groups))
+	for name, group := range config.Groups {
+		groups = append(groups, namedGroup{Name: name, Group: group})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Priority != groups[j].Priority {
+			return groups[i].Priority > groups[j].Priority
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+func getRepoType(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) string {
+	for _, group := range sortedGroups(config) {
+		if matchesGroup(logger, repo, group.Group) && group.Type != "" {
+			logger.Debug("Matched repo to type", "repo", repo.Name, "group", group.Name, "type", group.Type)
 			return group.Type
 		}
 	}
-	fmt.Printf("DEBUG: No specific type found for repo '%s', using default\n", repo)
+	logger.Debug("No specific type found for repo, using default", "repo", repo.Name)
 	return "default"
 }
 
-func filterRepositories(repos []string, config *Config) []string {
-	var filtered []string
+// resolveBranch returns the branch to check out for repo. A branch set on a
+// matching group takes precedence over global.default_branch; an empty result
+// means the remote's default branch.
+func resolveBranch(logger *logger.RateLimitedLogger, config *Config, repo lib.RepoInfo) string {
+	for _, group := range sortedGroups(config) {
+		if group.Branch != "" && matchesGroup(logger, repo, group.Group) {
+			return group.Branch
+		}
+	}
+	return config.Global.DefaultBranch
+}
+
+// skipArchivedRepositories drops archived repositories unless
+// global.include_archived is set
+func skipArchivedRepositories(logger *logger.RateLimitedLogger, repos []lib.RepoInfo, config *Config) []lib.RepoInfo {
+	if config.Global.IncludeArchived {
+		return repos
+	}
+
+	var active []lib.RepoInfo
+	for _, repo := range repos {
+		if !repo.Archived {
+			active = append(active, repo)
+		}
+	}
+
+	if skipped := len(repos) - len(active); skipped > 0 {
+		logger.Info("Skipped archived repositories", "owner", config.Global.Owner, "count", skipped)
+	}
+	return active
+}
+
+func filterRepositories(logger *logger.RateLimitedLogger, repos []lib.RepoInfo, config *Config) []lib.RepoInfo {
+	var filtered []lib.RepoInfo
 
-	fmt.Printf("DEBUG: Filtering %d repositories\n", len(repos))
-	fmt.Printf("DEBUG: Config: %+v\n", config)
+	logger.Debug("Filtering repositories", "count", len(repos), "groups", len(config.Groups))
 
+	groups := sortedGroups(config)
 	for _, repo := range repos {
-		fmt.Printf("DEBUG: Checking repo: %s\n", repo)
-		for groupName, group := range config.Groups {
-			fmt.Printf("DEBUG: Against group '%s': %+v\n", groupName, group)
-			if matchesFilter(repo, group) {
-				fmt.Printf("DEBUG: MATCH - Adding repo '%s' to filtered list\n", repo)
+		for _, group := range groups {
+			if matchesGroup(logger, repo, group.Group) {
+				logger.Debug("Repo matched group", "repo", repo.Name, "group", group.Name)
 				filtered = append(filtered, repo)
 				break
 			}
 		}
 	}
 
-	fmt.Printf("DEBUG: Filtered repositories: %v\n", filtered)
+	logger.Debug("Filtered repositories", "count", len(filtered), "repos", lib.RepoNames(filtered))
 	return filtered
 }
 
-func matchesFilter(repo string, group Group) bool {
-	fmt.Printf("DEBUG: Matching repo '%s' against group: %+v\n", repo, group)
-	switch group.Match {
+// matchesGroup reports whether repo matches the group's primary rule and
+// none of its exclude rules. Exclusions are only evaluated after the primary
+// match succeeds, so an exclude can knock a repo out but never pull one in.
+func matchesGroup(logger *logger.RateLimitedLogger, repo lib.RepoInfo, group Group) bool {
+	if !matchesRule(logger, repo, group.Match, group.Values) {
+		return false
+	}
+	for _, rule := range group.Exclude {
+		if matchesRule(logger, repo, rule.Match, rule.Values) {
+			logger.Debug("Repo excluded by rule", "repo", repo.Name, "match", rule.Match, "values", rule.Values)
+			return false
+		}
+	}
+	return true
+}
+
+// maxMatchPatterns bounds matchPatterns, which ad-hoc find queries fill as
+// well as config patterns
+const maxMatchPatterns = 256
+
+// matchPatterns holds the compiled regex match values by pattern, since a
+// rule is evaluated for every repository
+var (
+	matchPatternsMu sync.Mutex
+	matchPatterns   = make(map[string]*regexp.Regexp)
+)
+
+// compileMatchPattern compiles a regex match value the first time it is seen
+// and returns the same *regexp.Regexp after that. Once maxMatchPatterns are
+// cached the cache starts over, so a long session can't grow it without bound.
+func compileMatchPattern(pattern string) (*regexp.Regexp, error) {
+	matchPatternsMu.Lock()
+	defer matchPatternsMu.Unlock()
+	if re, ok := matchPatterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matchPatterns) >= maxMatchPatterns {
+		clear(matchPatterns)
+	}
+	matchPatterns[pattern] = re
+	return re, nil
+}
+
+func matchesRule(logger *logger.RateLimitedLogger, repoInfo lib.RepoInfo, match string, values []string) bool {
+	repo := repoInfo.Name
+
+	switch match {
 	case "endsWith":
-		for _, value := range group.Values {
-			fmt.Printf("DEBUG: Checking if '%s' ends with '%s'\n", repo, value)
+		for _, value := range values {
 			repoLower := strings.ToLower(repo)
 			valueLower := strings.ToLower(value)
 			if strings.HasSuffix(repoLower, valueLower) {
-				fmt.Printf("DEBUG: MATCH FOUND - repo '%s' ends with '%s'\n", repo, value)
 				return true
 			}
 			// Check if the repo name ends with the value followed by a hyphen and any characters
 			if strings.HasSuffix(repoLower, valueLower+"-") || strings.Contains(repoLower, valueLower+"-") {
-				fmt.Printf("DEBUG: MATCH FOUND - repo '%s' contains '%s-'\n", repo, value)
 				return true
 			}
-			fmt.Printf("DEBUG: NO MATCH - repo '%s' does not end with or contain '%s-'\n", repo, value)
 		}
 	case "startsWith":
-		for _, value := range group.Values {
+		for _, value := range values {
 			if strings.HasPrefix(strings.ToLower(repo), strings.ToLower(value)) {
-				fmt.Printf("DEBUG: MATCH FOUND - repo '%s' starts with '%s'\n", repo, value)
 				return true
 			}
 		}
 	case "includes":
-		for _, value := range group.Values {
+		for _, value := range values {
 			if strings.Contains(strings.ToLower(repo), strings.ToLower(value)) {
-				fmt.Printf("DEBUG: MATCH FOUND - repo '%s' includes '%s'\n", repo, value)
 				return true
 			}
 		}
 	case "isExactly":
-		for _, value := range group.Values {
+		for _, value := range values {
 			if strings.EqualFold(repo, value) {
-				fmt.Printf("DEBUG: MATCH FOUND - repo '%s' is exactly '%s'\n", repo, value)
 				return true
 			}
 		}
+	case "regex":
+		for _, value := range values {
+			// Patterns are validated in loadConfig, so a compile error here is unexpected
+			re, err := compileMatchPattern(value)
+			if err != nil {
+				logger.Debug("Skipping invalid pattern", "pattern", value, "error", err)
+				continue
+			}
+			if re.MatchString(repo) {
+				return true
+			}
+		}
+	case "hasTopic":
+		if repoInfo.Topics == nil {
+			logger.Warn("Topics unavailable for repository, hasTopic rule will not match", "repo", repo)
+			return false
+		}
+		for _, value := range values {
+			for _, topic := range repoInfo.Topics {
+				if strings.EqualFold(topic, value) {
+					return true
+				}
+			}
+		}
 	}
-	fmt.Printf("DEBUG: No match found for repo '%s'\n", repo)
 	return false
 }
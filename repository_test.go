@@ -0,0 +1,581 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/ssotops/gitspace/lib"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	logger := newTestLogger(t)
+	tests := []struct {
+		name  string
+		group Group
+		repo  lib.RepoInfo
+		want  bool
+	}{
+		{"exact", Group{Match: "isExactly", Values: []string{"api"}}, lib.RepoInfo{Name: "api"}, true},
+		{"exact ignores case", Group{Match: "isExactly", Values: []string{"API"}}, lib.RepoInfo{Name: "api"}, true},
+		{"exact needs the whole name", Group{Match: "isExactly", Values: []string{"api"}}, lib.RepoInfo{Name: "api-gateway"}, false},
+		{"prefix", Group{Match: "startsWith", Values: []string{"svc-"}}, lib.RepoInfo{Name: "svc-billing"}, true},
+		{"prefix mismatch", Group{Match: "startsWith", Values: []string{"svc-"}}, lib.RepoInfo{Name: "web-svc"}, false},
+		{"suffix", Group{Match: "endsWith", Values: []string{"-api"}}, lib.RepoInfo{Name: "billing-api"}, true},
+		{"suffix before a hyphen", Group{Match: "endsWith", Values: []string{"api"}}, lib.RepoInfo{Name: "billing-api-v2"}, true},
+		{"substring", Group{Match: "includes", Values: []string{"pay"}}, lib.RepoInfo{Name: "svc-Payments"}, true},
+		{"substring mismatch", Group{Match: "includes", Values: []string{"pay"}}, lib.RepoInfo{Name: "billing"}, false},
+		{"any value", Group{Match: "isExactly", Values: []string{"web", "api"}}, lib.RepoInfo{Name: "api"}, true},
+		{"unanchored regex", Group{Match: "regex", Values: []string{`svc-\d{4}-`}}, lib.RepoInfo{Name: "old-svc-2023-payments"}, true},
+		{"anchored regex", Group{Match: "regex", Values: []string{`^svc-\d{4}-.*$`}}, lib.RepoInfo{Name: "svc-2024-billing"}, true},
+		{"anchored regex mismatch", Group{Match: "regex", Values: []string{`^svc-\d{4}-.*$`}}, lib.RepoInfo{Name: "old-svc-2023-payments"}, false},
+		{"regex is case sensitive", Group{Match: "regex", Values: []string{`^svc-`}}, lib.RepoInfo{Name: "SVC-2024"}, false},
+		{"topic", Group{Match: "hasTopic", Values: []string{"backend"}}, lib.RepoInfo{Name: "api", Topics: []string{"Backend", "go"}}, true},
+		{"topic mismatch", Group{Match: "hasTopic", Values: []string{"frontend"}}, lib.RepoInfo{Name: "api", Topics: []string{"backend"}}, false},
+		{"topics unavailable", Group{Match: "hasTopic", Values: []string{"backend"}}, lib.RepoInfo{Name: "api"}, false},
+		{"unknown verb", Group{Match: "glob", Values: []string{"*"}}, lib.RepoInfo{Name: "api"}, false},
+		{
+			"negated",
+			Group{Match: "startsWith", Values: []string{"svc-"}, Exclude: []MatchRule{{Match: "endsWith", Values: []string{"-legacy"}}}},
+			lib.RepoInfo{Name: "svc-billing-legacy"},
+			false,
+		},
+		{
+			"negated rule not matching",
+			Group{Match: "startsWith", Values: []string{"svc-"}, Exclude: []MatchRule{{Match: "endsWith", Values: []string{"-legacy"}}}},
+			lib.RepoInfo{Name: "svc-billing"},
+			true,
+		},
+		{
+			"negated by topic",
+			Group{Match: "regex", Values: []string{".*"}, Exclude: []MatchRule{{Match: "hasTopic", Values: []string{"archived"}}}},
+			lib.RepoInfo{Name: "api", Topics: []string{"archived"}},
+			false,
+		},
+		{
+			"negation never pulls a repository in",
+			Group{Match: "isExactly", Values: []string{"web"}, Exclude: []MatchRule{{Match: "isExactly", Values: []string{"web"}}}},
+			lib.RepoInfo{Name: "api"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesGroup(logger, tt.repo, tt.group); got != tt.want {
+				t.Errorf("matchesGroup(%q) = %v, want %v", tt.repo.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileMatchPatternReusesRegexp(t *testing.T) {
+	first, err := compileMatchPattern(`^svc-\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := compileMatchPattern(`^svc-\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("the pattern was compiled twice")
+	}
+	if _, err := compileMatchPattern(`svc-(`); err == nil {
+		t.Error("an invalid pattern compiled")
+	}
+}
+
+func TestCompileMatchPatternCacheIsBounded(t *testing.T) {
+	// As many distinct find queries as a long session might run
+	for i := 0; i < 3*maxMatchPatterns; i++ {
+		if _, err := compileMatchPattern(fmt.Sprintf("^query-%d$", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	matchPatternsMu.Lock()
+	cached := len(matchPatterns)
+	matchPatternsMu.Unlock()
+	if cached > maxMatchPatterns {
+		t.Errorf("%d patterns cached, want at most %d", cached, maxMatchPatterns)
+	}
+	// Patterns dropped from the cache still compile
+	if re, err := compileMatchPattern("^query-0$"); err != nil || !re.MatchString("query-0") {
+		t.Errorf("got %v, %v for an evicted pattern", re, err)
+	}
+}
+
+func TestValidateConfigRejectsInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   Group
+		wantErr string // Empty for a valid group
+	}{
+		{"valid regex", Group{Match: "regex", Values: []string{`^svc-\d{4}-`}}, ""},
+		{"invalid regex", Group{Match: "regex", Values: []string{"svc-("}}, `groups.svc: invalid regex "svc-("`},
+		{
+			"invalid exclude regex",
+			Group{Match: "startsWith", Values: []string{"svc"}, Exclude: []MatchRule{{Match: "regex", Values: []string{"[a-"}}}},
+			`groups.svc.exclude[0]: invalid regex "[a-"`,
+		},
+		{"unknown verb", Group{Match: "glob", Values: []string{"svc-*"}}, `groups.svc: unknown match "glob"`},
+		{"no values", Group{Match: "regex"}, "groups.svc: values must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Groups: map[string]Group{"svc": tt.group}}
+			setValidGlobal(t, config)
+
+			err := validateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetRepoURLGitLab(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"", "git@gitlab.com:acme/api.git"},
+		{"https://gitlab.example.com", "git@gitlab.example.com:acme/api.git"},
+		{"https://gitlab.example.com:8443/", "git@gitlab.example.com:acme/api.git"},
+	}
+	for _, tt := range tests {
+		got, err := getRepoURL(lib.SCMTypeGitLab, tt.baseURL, "acme", "api")
+		if err != nil || got != tt.want {
+			t.Errorf("getRepoURL(%q) = %q, %v, want %q", tt.baseURL, got, err, tt.want)
+		}
+	}
+	if _, err := getRepoURL(lib.SCMTypeGitLab, "gitlab.example.com", "acme", "api"); err == nil {
+		t.Error("a base URL without a scheme was accepted")
+	}
+}
+
+func TestGetRepoURLLocal(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "team", "bare.git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"api", "file://" + filepath.ToSlash(filepath.Join(root, "team", "api"))},
+		{"bare", "file://" + filepath.ToSlash(filepath.Join(root, "team", "bare.git"))},
+	}
+	for _, tt := range tests {
+		got, err := getRepoURL(lib.SCMTypeLocal, root, "team", tt.repo)
+		if err != nil || got != tt.want {
+			t.Errorf("getRepoURL(%q) = %q, %v, want %q", tt.repo, got, err, tt.want)
+		}
+	}
+	if _, err := getRepoURL(lib.SCMTypeLocal, "relative", "team", "api"); err == nil {
+		t.Error("a relative root was accepted")
+	}
+}
+
+func TestSetupSSHAuthSkipsLocalTargets(t *testing.T) {
+	newTestLogger(t)
+	config := localCloneConfig(t, "")
+	if needsSSH(config) {
+		t.Error("local targets asked for an SSH key")
+	}
+	auth, keyPath, err := setupSSHAuth(config)
+	if err != nil || auth != nil || keyPath != "" {
+		t.Errorf("setupSSHAuth() = %v, %q, %v, want no auth", auth, keyPath, err)
+	}
+
+	config.Clone = []CloneTarget{{SCM: "github", Owner: "acme"}}
+	if !needsSSH(config) {
+		t.Error("a GitHub target didn't ask for an SSH key")
+	}
+}
+
+func TestEmptyRunsLeaveIndexAlone(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	// The owner has no repositories, so neither command has results
+	config := localCloneConfig(t, "")
+	if err := os.MkdirAll(filepath.Join(config.Global.BaseURL, "team"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(cacheDir, "index.toml")
+
+	for name, run := range map[string]func() (map[string]*RepoResult, error){
+		"clone": func() (map[string]*RepoResult, error) { return cloneRepositories(logger, config, nil) },
+		"sync":  func() (map[string]*RepoResult, error) { return syncRepositories(logger, config, syncOptions{}) },
+	} {
+		results, _ := run()
+		if len(results) != 0 {
+			t.Fatalf("%s got results %v, want none", name, results)
+		}
+		if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+			t.Errorf("%s without results wrote index.toml: %v", name, err)
+		}
+	}
+}
+
+func TestFilterRepositoriesExcludeRules(t *testing.T) {
+	logger := newTestLogger(t)
+	config := loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = "github"
+owner = "acme"
+
+[groups.services]
+match = "startsWith"
+values = ["svc-"]
+exclude = [{ match = "endsWith", values = ["-deprecated"] }, { match = "regex", values = ["^svc-tmp"] }]
+
+[groups.legacy]
+match = "includes"
+values = ["legacy"]
+`, t.TempDir()))
+
+	repos := []lib.RepoInfo{{Name: "svc-billing"}, {Name: "svc-billing-deprecated"}, {Name: "svc-tmp-spike"}, {Name: "svc-legacy-deprecated"}, {Name: "web"}}
+	got := lib.RepoNames(filterRepositories(logger, repos, config))
+	// An exclude only applies to its own group, so another group can still match
+	want := []string{"svc-billing", "svc-legacy-deprecated"}
+	if !slices.Equal(got, want) {
+		t.Errorf("filtered %v, want %v", got, want)
+	}
+}
+
+func TestCloneNormalizesSCM(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	dir := t.TempDir()
+	initTestRepo(t, filepath.Join(dir, "root", "team", "alpha"), "main")
+	// The scm is written the way a person might, not the way it is keyed
+	config := loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = " Local "
+base_url = %q
+owner = "team"
+
+[groups.all]
+match = "regex"
+values = [".*"]
+`, filepath.Join(dir, "work"), filepath.Join(dir, "root")))
+
+	results, err := cloneRepositories(logger, config, nil)
+	if err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if results["local/team/alpha"] == nil {
+		t.Errorf("got results %v, want local/team/alpha", results)
+	}
+	if _, err := os.Stat(clonedRepoPath(t, "alpha")); err != nil {
+		t.Errorf("not cloned under the canonical scm: %v", err)
+	}
+}
+
+func TestRepoExistsNormalizesSCM(t *testing.T) {
+	var requests []string
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+	})
+	for _, scm := range []string{"github", "GitHub.com", "https://github.com/"} {
+		if !repoExists(scm, "acme", "api") {
+			t.Errorf("repoExists(%q) = false", scm)
+		}
+	}
+	if repoExists("svn", "acme", "api") {
+		t.Error("repoExists accepted an unsupported scm")
+	}
+	if len(requests) != 3 || requests[0] != "https://github.com/acme/api" {
+		t.Errorf("requests %v, want three to https://github.com/acme/api", requests)
+	}
+}
+
+func TestMatchingWritesNothingToStdout(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{Groups: map[string]Group{
+		"services": {Match: "startsWith", Values: []string{"svc-"}, Type: "service", Exclude: []MatchRule{{Match: "regex", Values: []string{"-old$"}}}},
+	}}
+	repos := []lib.RepoInfo{{Name: "svc-api"}, {Name: "svc-api-old"}, {Name: "web"}}
+
+	output := captureStdout(t, func() {
+		filterRepositories(logger, repos, config)
+		for _, repo := range repos {
+			getRepoType(logger, config, repo)
+		}
+	})
+	if output != "" {
+		t.Errorf("matching printed %q, want debug output in the log only", output)
+	}
+}
+
+func TestResolveBranch(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{Groups: map[string]Group{
+		"services": {Match: "startsWith", Values: []string{"svc-"}, Branch: "develop"},
+		"billing":  {Match: "includes", Values: []string{"billing"}, Branch: "release", Priority: 10},
+		"docs":     {Match: "startsWith", Values: []string{"docs"}},
+	}}
+	config.Global.DefaultBranch = "main"
+
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"svc-api", "develop"},
+		{"svc-billing", "release"}, // The higher priority group wins
+		{"docs-site", "main"},      // A group without a branch falls through
+		{"web", "main"},
+	}
+	for _, tt := range tests {
+		if got := resolveBranch(logger, config, lib.RepoInfo{Name: tt.repo}); got != tt.want {
+			t.Errorf("resolveBranch(%q) = %q, want %q", tt.repo, got, tt.want)
+		}
+	}
+
+	config.Global.DefaultBranch = ""
+	if got := resolveBranch(logger, config, lib.RepoInfo{Name: "web"}); got != "" {
+		t.Errorf("resolveBranch without a default = %q, want the remote's default branch", got)
+	}
+}
+
+func TestSkipArchivedRepositories(t *testing.T) {
+	logger := newTestLogger(t)
+	repos := []lib.RepoInfo{{Name: "api"}, {Name: "old", Archived: true}, {Name: "web"}}
+
+	config := &Config{}
+	if got := lib.RepoNames(skipArchivedRepositories(logger, repos, config)); !slices.Equal(got, []string{"api", "web"}) {
+		t.Errorf("got %v, want archived repositories skipped by default", got)
+	}
+	config.Global.IncludeArchived = true
+	if got := lib.RepoNames(skipArchivedRepositories(logger, repos, config)); !slices.Equal(got, []string{"api", "old", "web"}) {
+		t.Errorf("got %v, want every repository with include_archived", got)
+	}
+}
+
+func TestGroupPrecedence(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{Groups: map[string]Group{
+		"b-services": {Match: "startsWith", Values: []string{"svc-"}, Type: "service"},
+		"a-tools":    {Match: "endsWith", Values: []string{"-cli"}, Type: "tool"},
+		"billing":    {Match: "includes", Values: []string{"billing"}, Type: "payments", Priority: 5},
+		"untyped":    {Match: "regex", Values: []string{".*"}, Priority: 10},
+	}}
+
+	var names []string
+	for _, group := range sortedGroups(config) {
+		names = append(names, group.Name)
+	}
+	if want := []string{"untyped", "billing", "a-tools", "b-services"}; !slices.Equal(names, want) {
+		t.Errorf("sorted groups %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"svc-billing", "payments"}, // Priority beats name
+		{"svc-cli", "tool"},         // Equal priority falls back to the name
+		{"svc-api", "service"},      // A group without a type doesn't decide it
+		{"web", "default"},
+	}
+	for _, tt := range tests {
+		if got := getRepoType(logger, config, lib.RepoInfo{Name: tt.repo}); got != tt.want {
+			t.Errorf("getRepoType(%q) = %q, want %q", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestValidateConfigRejectsBadCommits(t *testing.T) {
+	for commit, valid := range map[string]bool{
+		"0a1b":     true,
+		"0A1B2C3D": true,
+		"0123456789abcdef0123456789abcdef01234567": true,
+		"abc":    false,
+		"main":   false,
+		"v1.2.3": false,
+		"0123456789abcdef0123456789abcdef012345678": false,
+	} {
+		config := &Config{Groups: map[string]Group{"all": {Match: "regex", Values: []string{".*"}, Commit: commit}}}
+		setValidGlobal(t, config)
+		err := validateConfig(config)
+		if got := err == nil; got != valid {
+			t.Errorf("commit %q: got error %v, want valid %v", commit, err, valid)
+		}
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	dir := t.TempDir()
+	r, first := initTestRepo(t, filepath.Join(dir, "widget"), "develop")
+	if branch, commit := readHead(filepath.Join(dir, "widget")); branch != "develop" || commit != first.String() {
+		t.Errorf("got %q at %q, want develop at %s", branch, commit, first)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Hash: first}); err != nil {
+		t.Fatal(err)
+	}
+	if branch, commit := readHead(filepath.Join(dir, "widget")); branch != "" || commit != first.String() {
+		t.Errorf("detached HEAD got %q at %q", branch, commit)
+	}
+
+	// An empty repository is on a branch without a commit
+	if _, err := git.PlainInit(filepath.Join(dir, "empty"), false); err != nil {
+		t.Fatal(err)
+	}
+	if branch, commit := readHead(filepath.Join(dir, "empty")); branch != "master" || commit != "" {
+		t.Errorf("empty repository got %q at %q", branch, commit)
+	}
+
+	if branch, commit := readHead(filepath.Join(dir, "missing")); branch != "" || commit != "" {
+		t.Errorf("missing clone got %q at %q", branch, commit)
+	}
+}
+
+func TestIndexRecordsCloneHead(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	config := localCloneConfig(t, "", "alpha")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, err := readIndex(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("index has %d repositories, want 1", len(repos))
+	}
+	branch, commit := readHead(clonedRepoPath(t, "alpha"))
+	if got := repos[0].Metadata; got.Branch != branch || got.Commit != commit || commit == "" {
+		t.Errorf("index has %q at %q, want %q at %q", got.Branch, got.Commit, branch, commit)
+	}
+}
+
+func TestUpdateIndexTOMLMergesRuns(t *testing.T) {
+	logger := newTestLogger(t)
+	config := &Config{}
+	setValidGlobal(t, config)
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := func() map[string]indexedRepo {
+		t.Helper()
+		repos, err := readIndex(cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byKey := make(map[string]indexedRepo)
+		for _, repo := range repos {
+			byKey[repo.Owner+"/"+repo.Name] = repo
+		}
+		return byKey
+	}
+
+	run := func(owner, name string, result RepoResult) {
+		t.Helper()
+		result.Name, result.SCM, result.Owner = name, "github", owner
+		if err := updateIndexTOML(logger, config, map[string]*RepoResult{name: &result}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("acme", "widget", RepoResult{Cloned: true})
+	cloned := index()["acme/widget"].LastCloned
+	if cloned == "" {
+		t.Fatal("no lastCloned for a cloned repository")
+	}
+
+	// Another owner's run keeps acme's entry
+	run("labs", "gadget", RepoResult{Cloned: true})
+	if got := index(); len(got) != 2 || got["acme/widget"].LastCloned != cloned {
+		t.Errorf("after another owner's run the index has %v", got)
+	}
+
+	// A run that neither cloned nor synced widget keeps its timestamps
+	synced := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	run("acme", "widget", RepoResult{Updated: true, LastSynced: synced})
+	run("acme", "widget", RepoResult{UpToDate: true})
+	if got := index()["acme/widget"]; got.LastCloned != cloned || got.LastSynced != synced.Format(time.RFC3339) {
+		t.Errorf("widget has lastCloned %q, lastSynced %q, want %q and %s", got.LastCloned, got.LastSynced, cloned, synced.Format(time.RFC3339))
+	}
+
+	// Pruning removes exactly the pruned entry
+	if err := removeFromIndexTOML([]pruneCandidate{{SCM: "github", Owner: "acme", Name: "widget"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := index(); len(got) != 1 || got["labs/gadget"].Name != "gadget" {
+		t.Errorf("after pruning widget the index has %v", got)
+	}
+}
+
+func TestSyncListsGiteaReposFromBaseURL(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	t.Setenv("GITEA_TOKEN", "test-token")
+	config := loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = "gitea"
+base_url = "https://gitea.example.com"
+owner = "acme"
+
+[groups.all]
+match = "regex"
+values = [".*"]
+`, t.TempDir()))
+
+	var listed bool
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != "gitea.example.com" {
+			t.Errorf("request went to %s", r.URL.Host)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/version":
+			fmt.Fprint(w, `{"version": "1.21.0"}`)
+		case "/api/v1/users/acme/repos":
+			listed = true
+			fmt.Fprint(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncTargetRepositories(logger, config, cacheDir, nil, syncOptions{}, nil); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if !listed {
+		t.Error("sync didn't list the owner's repositories on the configured instance")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+// configBackup is a config saved under configs/backup by installConfig or
+// backupConfig
+type configBackup struct {
+	Path    string
+	SavedAt time.Time
+}
+
+// listConfigBackups returns the config backups, newest first
+func listConfigBackups() ([]configBackup, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	backupDir := filepath.Join(homeDir, configBackupDir)
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	var backups []configBackup
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+			continue
+		}
+		backup := configBackup{Path: filepath.Join(backupDir, entry.Name())}
+		backup.SavedAt = backupTime(entry.Name())
+		if backup.SavedAt.IsZero() {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			backup.SavedAt = info.ModTime()
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].SavedAt.After(backups[j].SavedAt)
+	})
+	return backups, nil
+}
+
+// backupTime parses the timestamp at the end of a backup's file name, such as
+// config_20240501_140322.toml, returning zero if there is none
+func backupTime(name string) time.Time {
+	const layout = "20060102_150405"
+	name = strings.TrimSuffix(name, ".toml")
+	if len(name) < len(layout) {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(layout, name[len(name)-len(layout):], time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// configDiff summarizes the line changes from one config to another
+type configDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// diffConfigFiles compares the config at from, which may be empty for no
+// config, with the one at to
+func diffConfigFiles(from, to string) (configDiff, error) {
+	var fromLines []string
+	if from != "" {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			return configDiff{}, fmt.Errorf("failed to read %s: %w", from, err)
+		}
+		fromLines = configLines(string(data))
+	}
+	data, err := os.ReadFile(to)
+	if err != nil {
+		return configDiff{}, fmt.Errorf("failed to read %s: %w", to, err)
+	}
+	return diffLines(fromLines, configLines(string(data))), nil
+}
+
+// configLines splits a config into lines, dropping blank lines and trailing
+// whitespace, which don't change what it means
+func configLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// diffLines returns the lines only in a and only in b, in order, using their
+// longest common subsequence
+func diffLines(a, b []string) configDiff {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff configDiff
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff.Removed = append(diff.Removed, a[i])
+			i++
+		default:
+			diff.Added = append(diff.Added, b[j])
+			j++
+		}
+	}
+	diff.Removed = append(diff.Removed, a[i:]...)
+	diff.Added = append(diff.Added, b[j:]...)
+	return diff
+}
+
+// restoreConfigBackup validates a backup and installs it as the active config
+func restoreConfigBackup(logger *logger.RateLimitedLogger, backupPath string) (*Config, error) {
+	config, err := loadConfig(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s is not a valid config: %w", filepath.Base(backupPath), err)
+	}
+	if err := installConfig(logger, backupPath); err != nil {
+		return nil, err
+	}
+	logger.Info("Restored config backup", "backup", backupPath)
+	return config, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBackupTime(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Time
+	}{
+		{"config_20240501_140322.toml", time.Date(2024, 5, 1, 14, 3, 22, 0, time.Local)},
+		{"gitspace_20231231_235959.toml", time.Date(2023, 12, 31, 23, 59, 59, 0, time.Local)},
+		{"config.toml", time.Time{}},
+		{"config_notatime_here.toml", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := backupTime(tt.name); !got.Equal(tt.want) {
+			t.Errorf("backupTime(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"[global]", `scm = "github"`, `owner = "acme"`, "[groups.all]"}
+	b := []string{"[global]", `scm = "gitlab"`, `owner = "acme"`, `depth = 1`, "[groups.all]"}
+	diff := diffLines(a, b)
+	if !slices.Equal(diff.Removed, []string{`scm = "github"`}) {
+		t.Errorf("removed %q", diff.Removed)
+	}
+	if !slices.Equal(diff.Added, []string{`scm = "gitlab"`, `depth = 1`}) {
+		t.Errorf("added %q", diff.Added)
+	}
+
+	if diff := diffLines(a, a); len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("identical configs differ: %+v", diff)
+	}
+	if diff := diffLines(nil, a); !slices.Equal(diff.Added, a) || len(diff.Removed) != 0 {
+		t.Errorf("diff from no config = %+v, want every line added", diff)
+	}
+}
+
+func TestDiffConfigFilesIgnoresBlankLines(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "from.toml")
+	to := filepath.Join(dir, "to.toml")
+	writeTestConfig(t, from, "[global]\nscm = \"github\"\n")
+	writeTestConfig(t, to, "[global]  \r\n\n\nscm = \"github\"\n\n")
+
+	diff, err := diffConfigFiles(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("whitespace changes showed as %+v", diff)
+	}
+}
+
+func TestListConfigBackupsNewestFirst(t *testing.T) {
+	newTestLogger(t)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupDir := filepath.Join(home, configBackupDir)
+	for _, name := range []string{"config_20240101_000000.toml", "config_20240301_000000.toml", "config_20240201_000000.toml", "notes.txt"} {
+		writeTestConfig(t, filepath.Join(backupDir, name), "")
+	}
+
+	backups, err := listConfigBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, backup := range backups {
+		names = append(names, filepath.Base(backup.Path))
+	}
+	want := []string{"config_20240301_000000.toml", "config_20240201_000000.toml", "config_20240101_000000.toml"}
+	if !slices.Equal(names, want) {
+		t.Errorf("listed %v, want %v", names, want)
+	}
+}
+
+func TestRestoreConfigBackup(t *testing.T) {
+	logger := newTestLogger(t)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupDir := filepath.Join(home, configBackupDir)
+
+	invalid := filepath.Join(backupDir, "config_20240101_000000.toml")
+	writeTestConfig(t, invalid, "[global]\nscm = \"svn\"\n")
+	if _, err := restoreConfigBackup(logger, invalid); err == nil {
+		t.Error("an invalid backup was restored")
+	}
+	if current, _ := getCurrentConfigPath(logger); current != "" {
+		t.Errorf("a failed restore left %s active", current)
+	}
+
+	valid := filepath.Join(backupDir, "config_20240201_000000.toml")
+	writeTestConfig(t, valid, validTestConfig(t))
+	if _, err := restoreConfigBackup(logger, valid); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	current, err := getCurrentConfigPath(logger)
+	if err != nil || current == "" {
+		t.Fatalf("no active config after restoring: %v", err)
+	}
+	diff, err := diffConfigFiles(current, valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("the active config differs from the backup: %+v", diff)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+const (
+	defaultMaxRetries = 3
+	retryBaseDelay    = time.Second
+	retryMaxDelay     = 30 * time.Second
+)
+
+// getMaxRetries returns global.max_retries, or the default when it isn't set
+func getMaxRetries(config *Config) int {
+	if config.Global.MaxRetries == nil {
+		return defaultMaxRetries
+	}
+	return *config.Global.MaxRetries
+}
+
+// withRetry runs fn, retrying retriable failures up to maxRetries times with
+// exponential backoff and jitter. It returns how many retries were made.
+func withRetry(logger *logger.RateLimitedLogger, maxRetries int, op, repo string, fn func() error) (int, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= maxRetries || !isRetriableError(err) {
+			return attempt, err
+		}
+
+		delay := retryDelay(attempt)
+		logger.Warn("Retrying after transient error", "op", op, "repo", repo, "attempt", attempt+1, "delay", delay.Round(time.Millisecond), "error", err)
+		time.Sleep(delay)
+	}
+}
+
+// retryDelay doubles the base delay for each attempt, capped at
+// retryMaxDelay, and adds up to 50% jitter so parallel workers don't retry
+// in lockstep
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// isRetriableError reports whether err looks transient. Authentication and
+// missing-repository errors are never retried, since another attempt would
+// fail the same way.
+func isRetriableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrRepositoryNotFound) ||
+		errors.Is(err, transport.ErrInvalidAuthMethod) {
+		return false
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, permanent := range []string{"unable to authenticate", "permission denied", "not found", "403", "401"} {
+		if strings.Contains(message, permanent) {
+			return false
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	for _, transient := range []string{"timeout", "timed out", "connection reset", "connection refused", "broken pipe", "eof", "temporary failure", "no route to host"} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestIsRetriableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{io.ErrUnexpectedEOF, true},
+		{fmt.Errorf("fetch: %w", syscall.ECONNRESET), true},
+		{context.DeadlineExceeded, true},
+		{errors.New("dial tcp: i/o timeout"), true},
+		{errors.New("ssh: handshake failed: EOF"), true},
+		{transport.ErrAuthenticationRequired, false},
+		{fmt.Errorf("clone: %w", transport.ErrRepositoryNotFound), false},
+		// A permanent failure wins over a transient-sounding message
+		{errors.New("ssh: unable to authenticate, connection reset"), false},
+		{errors.New("remote: 403 forbidden"), false},
+		{errors.New("reference has changed concurrently"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetriableError(tt.err); got != tt.want {
+			t.Errorf("isRetriableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, base := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if delay := retryDelay(attempt); delay < base || delay > base+base/2 {
+				t.Fatalf("retryDelay(%d) = %s, want between %s and %s", attempt, delay, base, base+base/2)
+			}
+		}
+	}
+	// Large attempts are capped instead of overflowing
+	for _, attempt := range []int{10, 63, 100} {
+		if delay := retryDelay(attempt); delay < retryMaxDelay || delay > retryMaxDelay+retryMaxDelay/2 {
+			t.Errorf("retryDelay(%d) = %s, want about %s", attempt, delay, retryMaxDelay)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	logger := newTestLogger(t)
+
+	calls := 0
+	retries, err := withRetry(logger, 3, "clone", "api", func() error {
+		calls++
+		return transport.ErrAuthorizationFailed
+	})
+	if calls != 1 || retries != 0 || !errors.Is(err, transport.ErrAuthorizationFailed) {
+		t.Errorf("a permanent error made %d calls, %d retries, %v, want a single call", calls, retries, err)
+	}
+
+	calls = 0
+	retries, err = withRetry(logger, 0, "clone", "api", func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	if calls != 1 || retries != 0 || err == nil {
+		t.Errorf("max_retries 0 made %d calls, %d retries, %v, want a single call", calls, retries, err)
+	}
+
+	calls = 0
+	retries, err = withRetry(logger, 3, "fetch", "api", func() error {
+		calls++
+		if calls == 1 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+	if calls != 2 || retries != 1 || err != nil {
+		t.Errorf("a transient error made %d calls, %d retries, %v, want success on the retry", calls, retries, err)
+	}
+}
+
+func TestGetMaxRetries(t *testing.T) {
+	config := &Config{}
+	if got := getMaxRetries(config); got != defaultMaxRetries {
+		t.Errorf("unset max_retries = %d, want %d", got, defaultMaxRetries)
+	}
+	zero := 0
+	config.Global.MaxRetries = &zero
+	if got := getMaxRetries(config); got != 0 {
+		t.Errorf("max_retries = 0 gave %d", got)
+	}
+}
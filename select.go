@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/huh"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
+)
+
+const cloneSelectionsFile = "clone_selections.json"
+
+// repoSelector narrows the filtered repositories of one scm/owner down to the
+// ones to clone. config is scoped to that target with forTarget.
+type repoSelector func(config *Config, repos []lib.RepoInfo) ([]lib.RepoInfo, error)
+
+// cloneSelections remembers the repositories unchecked in the clone
+// multi-select, keyed by config path and then by scm/owner. Unchecked rather
+// than checked names are kept, so repositories new to the filter start checked.
+type cloneSelections map[string]map[string][]string
+
+// newCloneSelector returns a repoSelector that asks which repositories to
+// clone, starting from the choice last made with the same config
+func newCloneSelector(logger *logger.RateLimitedLogger) repoSelector {
+	configPath, err := getLastUsedConfig(logger)
+	if err != nil {
+		logger.Warn("Selections won't be remembered per config", "error", err)
+	}
+
+	return func(config *Config, repos []lib.RepoInfo) ([]lib.RepoInfo, error) {
+		cacheDir, err := getCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("error getting cache directory: %w", err)
+		}
+		selections := readCloneSelections(cacheDir)
+		target := config.Global.SCM + "/" + config.Global.Owner
+
+		unchecked := make(map[string]bool)
+		for _, name := range selections[configPath][target] {
+			unchecked[name] = true
+		}
+		options := make([]huh.Option[string], len(repos))
+		for i, repo := range repos {
+			options[i] = huh.NewOption(repo.Name, repo.Name).Selected(!unchecked[repo.Name])
+		}
+
+		var chosen []string
+		err = huh.NewMultiSelect[string]().
+			Title(fmt.Sprintf("Repositories to clone from %s", target)).
+			Description("space toggles a repository, ctrl+a selects all or none, / filters").
+			Options(options...).
+			Value(&chosen).
+			Run()
+		if err != nil {
+			return nil, err
+		}
+
+		keep := make(map[string]bool, len(chosen))
+		for _, name := range chosen {
+			keep[name] = true
+		}
+		var selected []lib.RepoInfo
+		var deselected []string
+		for _, repo := range repos {
+			if keep[repo.Name] {
+				selected = append(selected, repo)
+			} else {
+				deselected = append(deselected, repo.Name)
+			}
+		}
+
+		sort.Strings(deselected)
+		if selections[configPath] == nil {
+			selections[configPath] = make(map[string][]string)
+		}
+		selections[configPath][target] = deselected
+		if err := writeCloneSelections(cacheDir, selections); err != nil {
+			logger.Warn("Failed to remember the selection", "error", err)
+		}
+		return selected, nil
+	}
+}
+
+// readCloneSelections loads the remembered selections, or none if the file is
+// missing or unreadable
+func readCloneSelections(cacheDir string) cloneSelections {
+	selections := make(cloneSelections)
+	data, err := os.ReadFile(filepath.Join(cacheDir, cloneSelectionsFile))
+	if err != nil {
+		return selections
+	}
+	if err := json.Unmarshal(data, &selections); err != nil {
+		return make(cloneSelections)
+	}
+	return selections
+}
+
+func writeCloneSelections(cacheDir string, selections cloneSelections) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(selections, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode clone selections: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(cacheDir, cloneSelectionsFile), data, 0644)
+}
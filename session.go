@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+const sessionFileName = "last_session.json"
+
+// sessionStats adds up the clone and sync runs of one gitspace session, for
+// the summary printed at exit and for last_session.json
+type sessionStats struct {
+	mu sync.Mutex
+
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Runs           int       `json:"runs"`
+	ReposProcessed int       `json:"repos_processed"`
+	Cloned         int       `json:"cloned"`
+	Updated        int       `json:"updated"`
+	Failed         int       `json:"failed"`
+	Retries        int       `json:"retries"`
+}
+
+var session = &sessionStats{StartedAt: time.Now()}
+
+// record adds the results of a clone or sync run
+func (s *sessionStats) record(results map[string]*RepoResult) {
+	summary := summarizeResults(results)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Runs++
+	s.ReposProcessed += summary.Total
+	s.Cloned += summary.Cloned
+	s.Updated += summary.Updated
+	s.Failed += summary.Failed
+	for _, result := range results {
+		s.Retries += result.Retries
+	}
+}
+
+// finishSession writes last_session.json when logging.session_file is set
+// and, for interactive sessions that cloned or synced anything, prints the
+// session summary
+func finishSession(logger *logger.RateLimitedLogger, config *Config, printSummary bool) {
+	session.mu.Lock()
+	session.FinishedAt = time.Now()
+	session.mu.Unlock()
+
+	if printSummary && session.Runs > 0 {
+		session.print()
+	}
+	if config == nil || !config.Logging.SessionFile {
+		return
+	}
+	if err := session.write(); err != nil {
+		logger.Warn("Failed to write session stats", "error", err)
+	}
+}
+
+func (s *sessionStats) print() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+
+	fmt.Println(headerStyle.Render("\nSession Summary:"))
+	fmt.Println(infoStyle.Render(fmt.Sprintf("Repositories processed: %d (%d runs)", s.ReposProcessed, s.Runs)))
+	fmt.Println(infoStyle.Render(fmt.Sprintf("Cloned: %d, updated: %d, failed: %d, retries: %d", s.Cloned, s.Updated, s.Failed, s.Retries)))
+	fmt.Println(infoStyle.Render(fmt.Sprintf("Duration: %s", s.FinishedAt.Sub(s.StartedAt).Round(time.Second))))
+	fmt.Println()
+}
+
+// write saves the stats to ~/.ssot/gitspace/last_session.json, replacing the
+// previous session's
+func (s *sessionStats) write() error {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode session stats: %w", err)
+	}
+
+	path := filepath.Join(cacheDir, sessionFileName)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestSession gives the test a fresh session, restoring the real one after
+func useTestSession(t *testing.T) *sessionStats {
+	t.Helper()
+	previous := session
+	session = &sessionStats{StartedAt: time.Now()}
+	t.Cleanup(func() { session = previous })
+	return session
+}
+
+func TestSessionRecord(t *testing.T) {
+	s := useTestSession(t)
+	s.record(map[string]*RepoResult{
+		"alpha": {Name: "alpha", Cloned: true, Retries: 2},
+		"beta":  {Name: "beta", Updated: true},
+	})
+	s.record(map[string]*RepoResult{
+		"gamma": {Name: "gamma", Error: errors.New("boom"), Retries: 1},
+	})
+
+	if s.Runs != 2 || s.ReposProcessed != 3 || s.Cloned != 1 || s.Updated != 1 || s.Failed != 1 || s.Retries != 3 {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestCloneRecordsSession(t *testing.T) {
+	logger := newTestLogger(t)
+	installFakeGitClient(t)
+	s := useTestSession(t)
+	config := localCloneConfig(t, "", "alpha", "beta")
+
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if s.Runs != 1 || s.ReposProcessed != 2 || s.Cloned != 2 {
+		t.Errorf("got %+v after cloning two repositories", s)
+	}
+}
+
+func TestFinishSessionWritesFile(t *testing.T) {
+	logger := newTestLogger(t)
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(cacheDir, sessionFileName)
+	s := useTestSession(t)
+	s.record(map[string]*RepoResult{"alpha": {Name: "alpha", Cloned: true}})
+
+	config := &Config{}
+	finishSession(logger, config, false)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("%s was written without session_file: %v", sessionFileName, err)
+	}
+
+	config.Logging.SessionFile = true
+	finishSession(logger, config, false)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written map[string]any
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written["runs"] != 1.0 || written["cloned"] != 1.0 || written["finished_at"] == nil {
+		t.Errorf("wrote %s", data)
+	}
+}
+
+func TestFinishSessionPrintsSummary(t *testing.T) {
+	logger := newTestLogger(t)
+	s := useTestSession(t)
+
+	if out := captureStdout(t, func() { finishSession(logger, nil, true) }); out != "" {
+		t.Errorf("a session without runs printed %q", out)
+	}
+	s.record(map[string]*RepoResult{"alpha": {Name: "alpha", Updated: true}})
+	if out := captureStdout(t, func() { finishSession(logger, nil, false) }); out != "" {
+		t.Errorf("a command printed the session summary %q", out)
+	}
+	out := captureStdout(t, func() { finishSession(logger, nil, true) })
+	if !strings.Contains(out, "Session Summary") || !strings.Contains(out, "updated: 1") {
+		t.Errorf("printed %q", out)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"gopkg.in/yaml.v3"
+)
+
+// repoStatus is the state of a cloned repository as of its last fetch
+type repoStatus struct {
+	SCM        string `json:"scm" yaml:"scm"`
+	Owner      string `json:"owner" yaml:"owner"`
+	Name       string `json:"name" yaml:"name"`
+	Branch     string `json:"branch,omitempty" yaml:"branch,omitempty"`           // Empty for a detached HEAD
+	Head       string `json:"head,omitempty" yaml:"head,omitempty"`               // Abbreviated HEAD commit
+	Upstream   bool   `json:"upstream" yaml:"upstream"`                           // origin has a copy of the branch
+	Ahead      int    `json:"ahead" yaml:"ahead"`                                 // Commits on the branch but not origin's copy
+	Behind     int    `json:"behind" yaml:"behind"`                               // Commits on origin's copy but not the branch
+	Dirty      bool   `json:"dirty" yaml:"dirty"`                                 // Uncommitted changes to tracked files
+	LastSynced string `json:"last_synced,omitempty" yaml:"last_synced,omitempty"` // RFC 3339, from index.toml
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// workspaceStatus reads the status of every clone under the config's clone
+// targets without fetching, using the remote-tracking refs from the last
+// clone or sync
+func workspaceStatus(config *Config) ([]repoStatus, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting cache directory: %w", err)
+	}
+	indexed, err := readIndex(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	lastSynced := make(map[string]time.Time, len(indexed))
+	for _, repo := range indexed {
+		lastSynced[repo.SCM+"/"+repo.Owner+"/"+repo.Name] = repo.lastSynced()
+	}
+
+	var statuses []repoStatus
+	for _, target := range config.CloneTargets() {
+		targetConfig := config.forTarget(target)
+		scm, owner := targetConfig.Global.SCM, targetConfig.Global.Owner
+		repoDir := filepath.Join(cacheDir, ".repositories", scm, owner)
+		entries, err := os.ReadDir(repoDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", repoDir, err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			status := repoStatus{SCM: scm, Owner: owner, Name: entry.Name()}
+			if err := readRepoStatus(filepath.Join(repoDir, entry.Name()), &status); err != nil {
+				status.Error = err.Error()
+			}
+			if synced := lastSynced[scm+"/"+owner+"/"+entry.Name()]; !synced.IsZero() {
+				status.LastSynced = synced.Format(time.RFC3339)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// readRepoStatus fills in the branch, ahead/behind and dirty state of the
+// repository at repoPath
+func readRepoStatus(repoPath string, status *repoStatus) error {
+	r, err := gitClient.Open(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	status.Head = head.Hash().String()[:7]
+
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if status.Dirty, err = hasLocalChanges(w); err != nil {
+		return err
+	}
+
+	if !head.Name().IsBranch() {
+		return nil
+	}
+	status.Branch = head.Name().Short()
+	remote, err := r.Reference(plumbing.NewRemoteReferenceName("origin", status.Branch), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read origin/%s: %w", status.Branch, err)
+	}
+	status.Upstream = true
+	status.Ahead, status.Behind, err = aheadBehind(r, head.Hash(), remote.Hash())
+	return err
+}
+
+// aheadBehind counts the commits reachable from local but not remote, and
+// from remote but not local
+func aheadBehind(r *git.Repository, local, remote plumbing.Hash) (int, int, error) {
+	if local == remote {
+		return 0, 0, nil
+	}
+	localCommits, err := ancestors(r, local)
+	if err != nil {
+		return 0, 0, err
+	}
+	remoteCommits, err := ancestors(r, remote)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ahead, behind := 0, 0
+	for hash := range localCommits {
+		if !remoteCommits[hash] {
+			ahead++
+		}
+	}
+	for hash := range remoteCommits {
+		if !localCommits[hash] {
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// ancestors returns the commits reachable from hash, including it. History
+// cut off by a shallow clone ends the walk.
+func ancestors(r *git.Repository, hash plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	iter, err := r.Log(&git.LogOptions{From: hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk history from %s: %w", hash.String()[:7], err)
+	}
+	defer iter.Close()
+
+	seen := make(map[plumbing.Hash]bool)
+	err = iter.ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) && !errors.Is(err, storer.ErrStop) {
+		return nil, fmt.Errorf("failed to walk history from %s: %w", hash.String()[:7], err)
+	}
+	return seen, nil
+}
+
+// writeStatus writes the statuses in the given format; text renders a table
+func writeStatus(w io.Writer, format string, statuses []repoStatus) error {
+	switch format {
+	case outputText:
+		printStatusTable(statuses)
+		return nil
+	case outputJSON:
+		if statuses == nil {
+			statuses = []repoStatus{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(statuses); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// printStatusTable prints the statuses as a table
+func printStatusTable(statuses []repoStatus) {
+	if len(statuses) == 0 {
+		fmt.Println("No cloned repositories.")
+		return
+	}
+	dirtyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("SCM", "Owner", "Repository", "Branch", "Ahead", "Behind", "Changes", "Last synced")
+	for _, status := range statuses {
+		branch := status.Branch
+		if branch == "" {
+			branch = "(detached " + status.Head + ")"
+		}
+		ahead, behind := "-", "-"
+		if status.Upstream {
+			ahead, behind = fmt.Sprint(status.Ahead), fmt.Sprint(status.Behind)
+		}
+		changes := "clean"
+		if status.Dirty {
+			changes = dirtyStyle.Render("dirty")
+		}
+		if status.Error != "" {
+			branch, changes = "error", status.Error
+		}
+		lastSynced := "never"
+		if synced, err := time.Parse(time.RFC3339, status.LastSynced); err == nil {
+			lastSynced = synced.Local().Format("2006-01-02 15:04")
+		}
+		t.Row(status.SCM, status.Owner, status.Name, branch, ahead, behind, changes, lastSynced)
+	}
+	fmt.Println(t)
+	fmt.Printf("Total: %d\n", len(statuses))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// statusByName indexes statuses by repository name
+func statusByName(statuses []repoStatus) map[string]repoStatus {
+	byName := make(map[string]repoStatus, len(statuses))
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+	return byName
+}
+
+func TestWorkspaceStatus(t *testing.T) {
+	logger := newTestLogger(t)
+	fake := installFakeGitClient(t)
+	config := localCloneConfig(t, "", "clean", "behind", "ahead", "dirty", "detached")
+	if _, err := cloneRepositories(logger, config, nil); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+
+	advanceOrigin(t, clonedRepoPath(t, "behind"), "pushed.txt", "pushed")
+	for _, name := range []string{"ahead", "detached"} {
+		r, err := git.PlainOpen(clonedRepoPath(t, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitTestFile(t, r, "local.txt", "local")
+		if name == "detached" {
+			head, _ := r.Head()
+			w, _ := r.Worktree()
+			if err := w.Checkout(&git.CheckoutOptions{Hash: head.Hash()}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(clonedRepoPath(t, "dirty"), "README.md"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(clonedRepoPath(t, "clean")), "broken"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := workspaceStatus(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := statusByName(statuses)
+	tests := []struct {
+		name          string
+		branch        string
+		ahead, behind int
+		dirty         bool
+	}{
+		{"clean", "main", 0, 0, false},
+		{"behind", "main", 0, 1, false},
+		{"ahead", "main", 1, 0, false},
+		{"dirty", "main", 0, 0, true},
+		{"detached", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("no status for %s", tt.name)
+			continue
+		}
+		if got.Error != "" || got.Branch != tt.branch || got.Ahead != tt.ahead || got.Behind != tt.behind || got.Dirty != tt.dirty {
+			t.Errorf("%s: got %+v", tt.name, got)
+		}
+		if got.Upstream != (tt.branch != "") {
+			t.Errorf("%s: upstream = %v", tt.name, got.Upstream)
+		}
+		if got.LastSynced == "" {
+			t.Errorf("%s: no last sync from the index", tt.name)
+		}
+	}
+	if byName["broken"].Error == "" {
+		t.Error("a directory that isn't a repository got no error")
+	}
+	if len(fake.fetched()) != 0 {
+		t.Errorf("status fetched %v", fake.fetched())
+	}
+}
+
+func TestWriteStatusJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeStatus(&out, outputJSON, nil); err != nil {
+		t.Fatal(err)
+	}
+	var empty []repoStatus
+	if err := json.Unmarshal(out.Bytes(), &empty); err != nil || empty == nil {
+		t.Errorf("no statuses wrote %q, want an empty array", out.String())
+	}
+
+	out.Reset()
+	statuses := []repoStatus{{SCM: "local", Owner: "team", Name: "alpha", Branch: "main", Upstream: true, Behind: 2}}
+	if err := writeStatus(&out, outputJSON, statuses); err != nil {
+		t.Fatal(err)
+	}
+	var got []repoStatus
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != statuses[0] {
+		t.Errorf("round trip gave %+v", got)
+	}
+}
+
+func TestStatusCommandRejectsUnknownOutput(t *testing.T) {
+	logger := newTestLogger(t)
+	if code := runCLI(logger, []string{"status", "--output", "xml"}); code != exitUsage {
+		t.Errorf("exited %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addTestSubmodule adds the repository at subPath to the one at repoPath as
+// the submodule name and commits it, using the git CLI since go-git can't
+func addTestSubmodule(t *testing.T, repoPath, subPath, name string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	for _, args := range [][]string{
+		{"-c", "protocol.file.allow=always", "submodule", "add", subPath, name},
+		{"-c", "user.name=gitspace", "-c", "user.email=gitspace@example.com", "commit", "-m", "Add " + name},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestCloneInitSubmodules(t *testing.T) {
+	tests := []struct {
+		name           string
+		extra          string
+		wantSubmodules bool
+	}{
+		{"off by default", "", false},
+		{"init_submodules", "init_submodules = true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := newTestLogger(t)
+			config := localCloneConfig(t, tt.extra, "app", "shared")
+			root := config.Global.BaseURL
+			addTestSubmodule(t, filepath.Join(root, "team", "app"), filepath.Join(root, "team", "shared"), "vendor/shared")
+
+			results, err := cloneRepositories(logger, config, nil)
+			if err != nil {
+				t.Fatalf("clone failed: %v", err)
+			}
+			result := results["local/team/app"]
+			if result == nil || result.Error != nil {
+				t.Fatalf("app result = %+v", result)
+			}
+			if result.Submodules != tt.wantSubmodules {
+				t.Errorf("Submodules = %v, want %v", result.Submodules, tt.wantSubmodules)
+			}
+			if results["local/team/shared"].Submodules {
+				t.Error("a repository without submodules reported them updated")
+			}
+
+			_, err = os.Stat(filepath.Join(clonedRepoPath(t, "app"), "vendor", "shared", "README.md"))
+			if checkedOut := err == nil; checkedOut != tt.wantSubmodules {
+				t.Errorf("submodule checked out = %v, want %v", checkedOut, tt.wantSubmodules)
+			}
+		})
+	}
+}
+
+func TestReportIncludesSubmodules(t *testing.T) {
+	report := buildResultsReport(map[string]*RepoResult{
+		"local/team/app": {Name: "app", SCM: "local", Owner: "team", Submodules: true},
+	})
+	if len(report.Repositories) != 1 || !report.Repositories[0].Submodules {
+		t.Errorf("report = %+v, want submodules_updated set", report)
+	}
+}
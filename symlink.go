@@ -4,71 +4,123 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
   "github.com/ssotops/gitspace-plugin-sdk/logger"
+  "github.com/ssotops/gitspace/lib"
 )
 
 func createLocalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
-	changes := make(map[string]string)
-	baseDir := config.Global.Path
-	repoDir := filepath.Join(getCacheDirOrDefault(logger), ".repositories", config.Global.SCM, config.Global.Owner)
+	report := newSymlinkReport()
 
-	err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+	for _, target := range config.CloneTargets() {
+		targetConfig := config.forTarget(target)
+		repoDir := filepath.Join(getCacheDirOrDefault(logger), ".repositories", targetConfig.Global.SCM, targetConfig.Global.Owner)
+		linkRepositories(logger, config.Global.LinkMode, "local", repoDir, localLinkFunc(logger, targetConfig), report)
+	}
+
+	printSymlinkReport(fmt.Sprintf("Created local links (%s)", config.Global.LinkMode), report)
+}
+
+func createGlobalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
+	report := newSymlinkReport()
+
+	for _, target := range config.CloneTargets() {
+		targetConfig := config.forTarget(target)
+		globalDir, err := getGlobalSymlinkDir(targetConfig)
 		if err != nil {
-			return err
-		}
-		if info.IsDir() && info.Name() != filepath.Base(repoDir) {
-			relPath, _ := filepath.Rel(repoDir, path)
-			symlink := filepath.Join(baseDir, relPath)
-			err := os.MkdirAll(filepath.Dir(symlink), 0755)
-			if err != nil {
-				logger.Error("Error creating directory for local symlink", "path", symlink, "error", err)
-				return nil
-			}
-			err = os.Symlink(path, symlink)
-			if err != nil {
-				logger.Error("Error creating local symlink", "path", path, "error", err)
-			} else {
-				changes[symlink] = path
-			}
-			return filepath.SkipDir // Skip subdirectories
+			logger.Error("Error getting global symlink directory", "error", err)
+			return
 		}
-		return nil
-	})
+		repoDir := filepath.Join(getCacheDirOrDefault(logger), ".repositories", targetConfig.Global.SCM, targetConfig.Global.Owner)
+		linkRepositories(logger, config.Global.LinkMode, "global", repoDir, linkUnder(globalDir), report)
+	}
 
-	if err != nil {
-		logger.Error("Error walking through repository directory", "error", err)
+	printSymlinkReport(fmt.Sprintf("Created global links (%s)", config.Global.LinkMode), report)
+}
+
+// symlinkAction is what ensureSymlink had to do to a link
+type symlinkAction int
+
+const (
+	symlinkCreated symlinkAction = iota
+	symlinkUpdated
+	symlinkUnchanged
+)
+
+// symlinkReport tracks links by outcome, each keyed by link path with the
+// link target as value
+type symlinkReport struct {
+	Created   map[string]string
+	Updated   map[string]string
+	Unchanged map[string]string
+	Failed    int
+}
+
+func newSymlinkReport() *symlinkReport {
+	return &symlinkReport{
+		Created:   make(map[string]string),
+		Updated:   make(map[string]string),
+		Unchanged: make(map[string]string),
 	}
+}
 
-	printSymlinkSummary("Created local symlinks", changes)
+func (r *symlinkReport) record(action symlinkAction, link, target string) {
+	switch action {
+	case symlinkCreated:
+		r.Created[link] = target
+	case symlinkUpdated:
+		r.Updated[link] = target
+	case symlinkUnchanged:
+		r.Unchanged[link] = target
+	}
 }
 
-func createGlobalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
-	changes := make(map[string]string)
-	globalDir, err := getGlobalSymlinkDir(config)
-	if err != nil {
-		logger.Error("Error getting global symlink directory", "error", err)
-		return
+// linkPathFunc returns where to link the repository with the given name
+type linkPathFunc func(repo string) (string, error)
+
+// linkUnder links each repository as linkDir/<name>
+func linkUnder(linkDir string) linkPathFunc {
+	return func(repo string) (string, error) {
+		return filepath.Join(linkDir, repo), nil
 	}
-	repoDir := filepath.Join(getCacheDirOrDefault(logger), ".repositories", config.Global.SCM, config.Global.Owner)
+}
 
-	err = filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+// localLinkFunc links each repository where global.path_template puts it,
+// using what index.toml knows about the repository to work out its type
+func localLinkFunc(logger *logger.RateLimitedLogger, config *Config) linkPathFunc {
+	known := indexedRepoInfo(getCacheDirOrDefault(logger), config.Global.SCM, config.Global.Owner)
+	return func(repo string) (string, error) {
+		info, ok := known[repo]
+		if !ok {
+			info = lib.RepoInfo{Name: repo}
+		}
+		return localLinkPath(logger, config, info)
+	}
+}
+
+// linkRepositories links each repository directory directly under repoDir
+// to the path linkPath gives using mode, recording the outcome of each link
+// in report
+func linkRepositories(logger *logger.RateLimitedLogger, mode, kind, repoDir string, linkPath linkPathFunc, report *symlinkReport) {
+	err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() && info.Name() != filepath.Base(repoDir) {
-			relPath, _ := filepath.Rel(repoDir, path)
-			symlink := filepath.Join(globalDir, relPath)
-			err := os.MkdirAll(filepath.Dir(symlink), 0755)
-			if err != nil {
-				logger.Error("Error creating directory for global symlink", "path", symlink, "error", err)
-				return nil
+			symlink, err := linkPath(info.Name())
+			action := symlinkUnchanged
+			if err == nil {
+				err = checkLinkCollision(symlink, path)
+			}
+			if err == nil {
+				action, err = ensureLink(mode, path, symlink)
 			}
-			err = os.Symlink(path, symlink)
 			if err != nil {
-				logger.Error("Error creating global symlink", "path", path, "error", err)
+				report.Failed++
+				logger.Error(fmt.Sprintf("Error creating %s link", kind), "path", symlink, "mode", mode, "error", err)
 			} else {
-				changes[symlink] = path
+				report.record(action, symlink, path)
 			}
 			return filepath.SkipDir // Skip subdirectories
 		}
@@ -76,10 +128,8 @@ func createGlobalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
 	})
 
 	if err != nil {
-		logger.Error("Error walking through repository directory", "error", err)
+		logger.Error("Error walking through repository directory", "path", repoDir, "error", err)
 	}
-
-	printSymlinkSummary("Created global symlinks", changes)
 }
 
 func deleteLocalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
@@ -106,35 +156,48 @@ func deleteLocalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
 		logger.Error("Error walking through local directory", "error", err)
 	}
 
+	for _, target := range config.CloneTargets() {
+		targetConfig := config.forTarget(target)
+		repoDir := filepath.Join(getCacheDirOrDefault(logger), ".repositories", targetConfig.Global.SCM, targetConfig.Global.Owner)
+		deleteLinkCopies(logger, config.Global.LinkMode, repoDir, localLinkFunc(logger, targetConfig), changes)
+	}
+
 	printSymlinkSummary("Deleted local symlinks", changes)
 }
 
 func deleteGlobalSymlinks(logger *logger.RateLimitedLogger, config *Config) {
 	changes := make(map[string]string)
-	globalDir, err := getGlobalSymlinkDir(config)
-	if err != nil {
-		logger.Error("Error getting global symlink directory", "error", err)
-		return
-	}
 
-	err = filepath.Walk(globalDir, func(path string, info os.FileInfo, err error) error {
+	for _, target := range config.CloneTargets() {
+		globalDir, err := getGlobalSymlinkDir(config.forTarget(target))
 		if err != nil {
-			return err
+			logger.Error("Error getting global symlink directory", "error", err)
+			return
 		}
-		if info.Mode()&os.ModeSymlink != 0 {
-			realPath, _ := os.Readlink(path)
-			err := os.Remove(path)
+
+		err = filepath.Walk(globalDir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
-				logger.Error("Error deleting global symlink", "path", path, "error", err)
-			} else {
-				changes[path] = realPath
+				return err
 			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				realPath, _ := os.Readlink(path)
+				err := os.Remove(path)
+				if err != nil {
+					logger.Error("Error deleting global symlink", "path", path, "error", err)
+				} else {
+					changes[path] = realPath
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			logger.Error("Error walking through global directory", "error", err)
 		}
-		return nil
-	})
 
-	if err != nil {
-		logger.Error("Error walking through global directory", "error", err)
+		targetConfig := config.forTarget(target)
+		repoDir := filepath.Join(getCacheDirOrDefault(logger), ".repositories", targetConfig.Global.SCM, targetConfig.Global.Owner)
+		deleteLinkCopies(logger, config.Global.LinkMode, repoDir, linkUnder(globalDir), changes)
 	}
 
 	printSymlinkSummary("Deleted global symlinks", changes)
@@ -148,8 +211,198 @@ func getGlobalSymlinkDir(config *Config) (string, error) {
 	return filepath.Join(cacheDir, config.Global.SCM, config.Global.Owner), nil
 }
 
+// Values for global.link_mode
+const (
+	linkModeSymlink  = "symlink"
+	linkModeCopy     = "copy"
+	linkModeHardlink = "hardlink"
+)
+
+// createLink links source at target using the configured link_mode
+func createLink(config *Config, source, target string) error {
+	_, err := ensureLink(config.Global.LinkMode, source, target)
+	return err
+}
+
+// linkMarkerFile is written at the top of each copied or hardlinked
+// repository, recording the clone it was made from, so that gitspace only
+// ever replaces or deletes directories it created itself
+const linkMarkerFile = ".gitspace-link"
+
+// writeLinkMarker marks link as a copy of target made by gitspace
+func writeLinkMarker(link, target string) error {
+	return os.WriteFile(filepath.Join(link, linkMarkerFile), []byte(target+"\n"), 0644)
+}
+
+// readLinkMarker returns the clone the copy at link was made from, and false
+// if link isn't a directory gitspace created
+func readLinkMarker(link string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(link, linkMarkerFile))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// ensureLink makes link a view of target: a symlink, or in copy and hardlink
+// modes a directory tree of copied or hardlinked files. Copies are replaced
+// on every run, since they can't be checked against the target cheaply, but
+// only if gitspace made them; anything else at link is an error.
+func ensureLink(mode, target, link string) (symlinkAction, error) {
+	if mode == "" || mode == linkModeSymlink {
+		return ensureSymlink(target, link)
+	}
+
+	action := symlinkCreated
+	if info, err := os.Lstat(link); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			err = os.Remove(link)
+		} else if _, ok := readLinkMarker(link); ok {
+			err = os.RemoveAll(link)
+		} else {
+			return 0, fmt.Errorf("%s exists and was not created by gitspace", link)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to remove previous %s: %w", link, err)
+		}
+		action = symlinkUpdated
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	// The marker goes in first so that a copy that fails halfway can still
+	// be replaced on the next run
+	if err := os.MkdirAll(link, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory for link: %w", err)
+	}
+	if err := writeLinkMarker(link, target); err != nil {
+		return 0, err
+	}
+
+	var err error
+	switch mode {
+	case linkModeCopy:
+		err = copyDir(target, link)
+	case linkModeHardlink:
+		err = hardlinkDir(target, link)
+	default:
+		err = fmt.Errorf("unknown link mode %q", mode)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return action, nil
+}
+
+// hardlinkDir recreates the directory tree of src at dst, hardlinking each
+// file. Directories themselves can't be hardlinked.
+func hardlinkDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, relPath)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(dstPath, info.Mode())
+		case info.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, dstPath)
+		default:
+			return os.Link(path, dstPath)
+		}
+	})
+}
+
+// deleteLinkCopies removes the copied or hardlinked repository directories at
+// linkPath for each repository under repoDir. Symlink mode has none, and
+// directories without the gitspace link marker are left alone.
+func deleteLinkCopies(logger *logger.RateLimitedLogger, mode, repoDir string, linkPath linkPathFunc, changes map[string]string) {
+	if mode == "" || mode == linkModeSymlink {
+		return
+	}
+
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Error reading repository directory", "path", repoDir, "error", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path, err := linkPath(entry.Name())
+		if err != nil {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if _, ok := readLinkMarker(path); !ok {
+			logger.Warn("Not deleting a directory gitspace did not create", "path", path)
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			logger.Error("Error deleting linked copy", "path", path, "error", err)
+		} else {
+			changes[path] = filepath.Join(repoDir, entry.Name())
+		}
+	}
+}
+
+// createSymlink points target at source, replacing a symlink that points
+// elsewhere
 func createSymlink(source, target string) error {
-	os.MkdirAll(filepath.Dir(target), 0755) // Ensure parent directory exists
-	os.Remove(target)                       // Remove existing symlink if it exists
-	return os.Symlink(source, target)
+	_, err := ensureSymlink(source, target)
+	return err
+}
+
+// ensureSymlink makes link a symlink to target. A link that already points at
+// target is left alone, one pointing elsewhere is retargeted, and anything at
+// link that isn't a symlink is an error rather than being replaced.
+func ensureSymlink(target, link string) (symlinkAction, error) {
+	info, err := os.Lstat(link)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			return 0, fmt.Errorf("failed to create directory for symlink: %w", err)
+		}
+		if err := os.Symlink(target, link); err != nil {
+			return 0, err
+		}
+		return symlinkCreated, nil
+	case err != nil:
+		return 0, err
+	case info.Mode()&os.ModeSymlink == 0:
+		return 0, fmt.Errorf("%s exists and is not a symlink", link)
+	}
+
+	current, err := os.Readlink(link)
+	if err != nil {
+		return 0, err
+	}
+	if current == target {
+		return symlinkUnchanged, nil
+	}
+
+	if err := os.Remove(link); err != nil {
+		return 0, err
+	}
+	if err := os.Symlink(target, link); err != nil {
+		return 0, err
+	}
+	return symlinkUpdated, nil
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "repos", "api")
+	other := filepath.Join(dir, "repos", "web")
+	link := filepath.Join(dir, "work", "nested", "api")
+
+	steps := []struct {
+		name   string
+		target string
+		want   symlinkAction
+	}{
+		{"missing link", target, symlinkCreated},
+		{"same target", target, symlinkUnchanged},
+		{"new target", other, symlinkUpdated},
+	}
+	for _, step := range steps {
+		action, err := ensureSymlink(step.target, link)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if action != step.want {
+			t.Errorf("%s: got action %d, want %d", step.name, action, step.want)
+		}
+		if current, err := os.Readlink(link); err != nil || current != step.target {
+			t.Errorf("%s: link points at %q, %v, want %q", step.name, current, err, step.target)
+		}
+	}
+
+	// Anything that isn't a symlink is left alone
+	notLink := filepath.Join(dir, "work", "web")
+	if err := os.MkdirAll(notLink, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ensureSymlink(other, notLink); err == nil {
+		t.Error("a directory was replaced by a symlink")
+	}
+	if info, err := os.Lstat(notLink); err != nil || !info.IsDir() {
+		t.Errorf("the directory is gone: %v", err)
+	}
+}
+
+func TestLinkRepositoriesReport(t *testing.T) {
+	logger := newTestLogger(t)
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "repos")
+	for _, repo := range []string{"api", "web"} {
+		if err := os.MkdirAll(filepath.Join(repoDir, repo, "src"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	linkDir := filepath.Join(dir, "links")
+
+	first := newSymlinkReport()
+	linkRepositories(logger, linkModeSymlink, "local", repoDir, linkUnder(linkDir), first)
+	if len(first.Created) != 2 || len(first.Unchanged) != 0 || first.Failed != 0 {
+		t.Errorf("first run %+v, want two links created", first)
+	}
+	// Subdirectories of a repository aren't linked
+	if _, err := os.Lstat(filepath.Join(linkDir, "src")); !os.IsNotExist(err) {
+		t.Errorf("linked a subdirectory: %v", err)
+	}
+
+	second := newSymlinkReport()
+	linkRepositories(logger, linkModeSymlink, "local", repoDir, linkUnder(linkDir), second)
+	if len(second.Created) != 0 || len(second.Unchanged) != 2 {
+		t.Errorf("second run %+v, want both links unchanged", second)
+	}
+}
+
+// writeTestTree creates a repository-like directory with a nested file
+func writeTestTree(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnsureLinkModes(t *testing.T) {
+	for _, mode := range []string{linkModeCopy, linkModeHardlink} {
+		t.Run(mode, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "repos", "api")
+			writeTestTree(t, target)
+			link := filepath.Join(dir, "work", "api")
+
+			if action, err := ensureLink(mode, target, link); err != nil || action != symlinkCreated {
+				t.Fatalf("first link: %d, %v", action, err)
+			}
+			// Copies can't be checked cheaply, so they are always redone
+			if action, err := ensureLink(mode, target, link); err != nil || action != symlinkUpdated {
+				t.Fatalf("second link: %d, %v", action, err)
+			}
+
+			info, err := os.Lstat(link)
+			if err != nil || !info.IsDir() {
+				t.Fatalf("link is not a directory: %v", err)
+			}
+			linked, err := os.Stat(filepath.Join(link, "src", "main.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			original, err := os.Stat(filepath.Join(target, "src", "main.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same := os.SameFile(linked, original); same != (mode == linkModeHardlink) {
+				t.Errorf("linked file shares the original's inode: %v", same)
+			}
+		})
+	}
+
+	if _, err := ensureLink("junction", t.TempDir(), filepath.Join(t.TempDir(), "api")); err == nil {
+		t.Error("an unknown link mode was accepted")
+	}
+}
+
+func TestEnsureLinkKeepsUnmarkedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "repos", "api")
+	writeTestTree(t, target)
+	// A directory the user made themselves, with no gitspace marker
+	link := filepath.Join(dir, "work", "api")
+	writeTestTree(t, link)
+	if err := os.WriteFile(filepath.Join(link, "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{linkModeCopy, linkModeHardlink} {
+		if _, err := ensureLink(mode, target, link); err == nil || !strings.Contains(err.Error(), "not created by gitspace") {
+			t.Errorf("%s: got error %v, want the directory refused", mode, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(link, "notes.txt")); err != nil {
+		t.Errorf("the directory was replaced: %v", err)
+	}
+}
+
+func TestDeleteLinkCopies(t *testing.T) {
+	logger := newTestLogger(t)
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "repos")
+	writeTestTree(t, filepath.Join(repoDir, "api"))
+	linkDir := filepath.Join(dir, "links")
+	if _, err := ensureLink(linkModeCopy, filepath.Join(repoDir, "api"), filepath.Join(linkDir, "api")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(map[string]string)
+	deleteLinkCopies(logger, linkModeSymlink, repoDir, linkUnder(linkDir), changes)
+	if len(changes) != 0 {
+		t.Errorf("symlink mode deleted %v", changes)
+	}
+
+	deleteLinkCopies(logger, linkModeCopy, repoDir, linkUnder(linkDir), changes)
+	if _, err := os.Stat(filepath.Join(linkDir, "api")); !os.IsNotExist(err) {
+		t.Errorf("the copy is still there: %v", err)
+	}
+	if changes[filepath.Join(linkDir, "api")] != filepath.Join(repoDir, "api") {
+		t.Errorf("changes %v, want the deleted copy", changes)
+	}
+	// The repository itself is untouched
+	if _, err := os.Stat(filepath.Join(repoDir, "api", "src", "main.go")); err != nil {
+		t.Errorf("the repository lost its files: %v", err)
+	}
+
+	// A directory gitspace didn't create is left alone
+	writeTestTree(t, filepath.Join(linkDir, "api"))
+	changes = make(map[string]string)
+	deleteLinkCopies(logger, linkModeCopy, repoDir, linkUnder(linkDir), changes)
+	if len(changes) != 0 {
+		t.Errorf("deleted %v, want the unmarked directory kept", changes)
+	}
+	if _, err := os.Stat(filepath.Join(linkDir, "api", "src", "main.go")); err != nil {
+		t.Errorf("the unmarked directory was deleted: %v", err)
+	}
+}
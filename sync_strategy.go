@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Values for global.sync_strategy
+const (
+	syncStrategyFetch  = "fetch"   // Only update origin/*, leave the working tree alone
+	syncStrategyFFOnly = "ff-only" // Fast-forward the checked-out branch after fetching
+	syncStrategyPull   = "pull"    // Same as ff-only; go-git can't create merge commits
+)
+
+var (
+	errNotFastForward = errors.New("cannot fast-forward: the local branch has diverged from origin")
+	errDirtyWorktree  = errors.New("the working tree has uncommitted changes")
+)
+
+// updateWorktree applies the sync strategy to a fetched repository and
+// reports whether the checked-out branch moved. force fast-forwards despite
+// uncommitted changes, as dirty_policy force asks.
+func updateWorktree(r *git.Repository, strategy string, force bool) (bool, error) {
+	if strategy == syncStrategyFetch {
+		return false, nil
+	}
+	return fastForward(r, force)
+}
+
+// fastForward moves the checked-out branch to origin's copy of it when that
+// is a fast-forward. A detached HEAD, such as a pinned commit, is left alone.
+// Uncommitted changes to tracked files stop the update unless force is set,
+// since it would overwrite those the two commits differ in; untracked files
+// are kept either way.
+func fastForward(r *git.Repository, force bool) (bool, error) {
+	head, err := r.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return false, nil
+	}
+
+	branch := head.Name().Short()
+	remoteRef, err := r.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return false, fmt.Errorf("branch %s has no origin/%s to fast-forward to: %w", branch, branch, err)
+	}
+	if remoteRef.Hash() == head.Hash() {
+		return false, nil
+	}
+
+	local, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return false, fmt.Errorf("failed to read local commit: %w", err)
+	}
+	remote, err := r.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return false, fmt.Errorf("failed to read origin/%s: %w", branch, err)
+	}
+	canFastForward, err := local.IsAncestor(remote)
+	if err != nil {
+		return false, fmt.Errorf("failed to compare with origin/%s: %w", branch, err)
+	}
+	if !canFastForward {
+		return false, fmt.Errorf("%s: %w", branch, errNotFastForward)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if !force {
+		dirty, err := hasLocalChanges(w)
+		if err != nil {
+			return false, err
+		}
+		if dirty {
+			return false, errDirtyWorktree
+		}
+	}
+
+	// go-git's hard and merge resets delete untracked and ignored files, so
+	// write the files the two commits differ in and only reset the index
+	if err := applyTreeChanges(w, local, remote); err != nil {
+		return false, fmt.Errorf("failed to fast-forward %s: %w", branch, err)
+	}
+	if err := w.Reset(&git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.MixedReset}); err != nil {
+		return false, fmt.Errorf("failed to fast-forward %s: %w", branch, err)
+	}
+	return true, nil
+}
+
+// applyTreeChanges updates the files in the worktree that differ between the
+// from and to commits, leaving every other file alone
+func applyTreeChanges(w *git.Worktree, from, to *object.Commit) error {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return err
+	}
+
+	root := w.Filesystem.Root()
+	for _, change := range changes {
+		if change.From.Name != "" && change.From.Name != change.To.Name {
+			if err := os.Remove(filepath.Join(root, change.From.Name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if change.To.Name == "" || change.To.TreeEntry.Mode == filemode.Submodule {
+			continue
+		}
+		file, err := toTree.TreeEntryFile(&change.To.TreeEntry)
+		if err != nil {
+			return err
+		}
+		if err := writeTreeFile(filepath.Join(root, change.To.Name), file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTreeFile writes a file from a commit to path
+func writeTreeFile(path string, file *object.File) error {
+	contents, err := file.Contents()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Replace rather than truncate, as the old file may be a symlink
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if file.Mode == filemode.Symlink {
+		return os.Symlink(contents, path)
+	}
+	mode, err := file.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(contents), mode.Perm())
+}
+
+// hasLocalChanges reports whether tracked files have uncommitted changes,
+// staged or not. Untracked files don't count.
+func hasLocalChanges(w *git.Worktree) (bool, error) {
+	status, err := w.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	for _, file := range status {
+		if file.Worktree == git.Untracked && file.Staging == git.Untracked {
+			continue
+		}
+		if file.Worktree != git.Unmodified || file.Staging != git.Unmodified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
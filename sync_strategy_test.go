@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// trackingTestRepo creates a repository on main whose origin/main is pushed
+// one commit ahead, and returns it with its path and that commit
+func trackingTestRepo(t *testing.T) (*git.Repository, string, plumbing.Hash) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "widget")
+	r, _ := initTestRepo(t, path, "main")
+	return r, path, advanceOrigin(t, path, "CHANGELOG.md", "v2\n")
+}
+
+func TestUpdateWorktreeFetchLeavesBranch(t *testing.T) {
+	r, path, _ := trackingTestRepo(t)
+	_, before := readHead(path)
+
+	moved, err := updateWorktree(r, syncStrategyFetch, false)
+	if err != nil || moved {
+		t.Fatalf("got %v, %v", moved, err)
+	}
+	if _, after := readHead(path); after != before {
+		t.Errorf("HEAD moved from %s to %s", before, after)
+	}
+}
+
+func TestFastForwardKeepsUntrackedFiles(t *testing.T) {
+	r, path, pushed := trackingTestRepo(t)
+	untracked := filepath.Join(path, "notes.txt")
+	if err := os.WriteFile(untracked, []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := updateWorktree(r, syncStrategyPull, false)
+	if err != nil || !moved {
+		t.Fatalf("got %v, %v, want a fast-forward", moved, err)
+	}
+	if branch, commit := readHead(path); branch != "main" || commit != pushed.String() {
+		t.Errorf("HEAD is %s at %s, want main at %s", branch, commit, pushed)
+	}
+	if data, err := os.ReadFile(untracked); err != nil || string(data) != "mine\n" {
+		t.Errorf("untracked file is %q, %v", data, err)
+	}
+	// The index follows, so the fast-forwarded files aren't reported changed
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err := hasLocalChanges(w); err != nil || dirty {
+		t.Errorf("worktree dirty %v, %v after a fast-forward", dirty, err)
+	}
+
+	// Already up to date
+	if moved, err := updateWorktree(r, syncStrategyFFOnly, false); err != nil || moved {
+		t.Errorf("second update got %v, %v", moved, err)
+	}
+}
+
+func TestFastForwardRefusals(t *testing.T) {
+	t.Run("diverged", func(t *testing.T) {
+		r, _, _ := trackingTestRepo(t)
+		commitTestFile(t, r, "local.txt", "local\n")
+		if _, err := fastForward(r, false); !errors.Is(err, errNotFastForward) {
+			t.Errorf("got error %v, want %v", err, errNotFastForward)
+		}
+	})
+	t.Run("uncommitted changes", func(t *testing.T) {
+		r, path, _ := trackingTestRepo(t)
+		if err := os.WriteFile(filepath.Join(path, "README.md"), []byte("edited\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fastForward(r, false); !errors.Is(err, errDirtyWorktree) {
+			t.Errorf("got error %v, want %v", err, errDirtyWorktree)
+		}
+		if data, _ := os.ReadFile(filepath.Join(path, "README.md")); string(data) != "edited\n" {
+			t.Errorf("the local edit became %q", data)
+		}
+	})
+	t.Run("no origin branch", func(t *testing.T) {
+		r, _ := initTestRepo(t, filepath.Join(t.TempDir(), "widget"), "main")
+		if _, err := fastForward(r, false); err == nil || !strings.Contains(err.Error(), "no origin/main") {
+			t.Errorf("got error %v", err)
+		}
+	})
+	t.Run("detached", func(t *testing.T) {
+		r, path, _ := trackingTestRepo(t)
+		_, commit := readHead(path)
+		w, err := r.Worktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(commit)}); err != nil {
+			t.Fatal(err)
+		}
+		if moved, err := fastForward(r, false); err != nil || moved {
+			t.Errorf("a detached HEAD got %v, %v", moved, err)
+		}
+	})
+}
+
+func TestValidateConfigSyncStrategy(t *testing.T) {
+	config := &Config{}
+	setValidGlobal(t, config)
+	if err := validateConfig(config); err != nil || config.Global.SyncStrategy != syncStrategyFetch {
+		t.Errorf("got %q, %v, want the fetch default", config.Global.SyncStrategy, err)
+	}
+
+	config.Global.SyncStrategy = "rebase"
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), "global.sync_strategy") {
+		t.Errorf("got error %v, want rebase rejected", err)
+	}
+}
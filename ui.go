@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
 	"github.com/ssotops/gitspace-plugin-sdk/logger"
 	"github.com/ssotops/gitspace/plugin"
 )
@@ -31,13 +39,7 @@ func printWelcomeMessage() {
 
 func handleMainMenu(logger *logger.RateLimitedLogger, config **Config, pluginManager *plugin.Manager) bool {
 	logger.Debug("Entering handleMainMenu")
-	options := []huh.Option[string]{
-		huh.NewOption("Repositories", "repositories"),
-		huh.NewOption("Symlinks", "symlinks"),
-		huh.NewOption("Plugins", "plugins"),
-		huh.NewOption("Gitspace", "gitspace"),
-		huh.NewOption("Quit", "quit"),
-	}
+	options := mainMenuOptions(pluginManager.PromotedPlugins())
 
 	var choice string
 	logger.Debug("Presenting main menu options to user")
@@ -65,11 +67,19 @@ func handleMainMenu(logger *logger.RateLimitedLogger, config **Config, pluginMan
 		return handleRepositoriesCommand(logger, *config)
 	case "gitspace":
 		handleGitspaceCommand(logger, config)
+		// The menu may have loaded or switched the config
+		configurePluginManager(pluginManager, *config)
 	case "symlinks":
 		handleSymlinksCommand(logger, *config)
 	case "quit":
 		return true
 	default:
+		if name, ok := strings.CutPrefix(choice, promotedPluginPrefix); ok {
+			if err := plugin.RunPromotedPlugin(logger, pluginManager, name); err != nil {
+				logger.Error("Error running plugin", "name", name, "error", err)
+			}
+			break
+		}
 		logger.Error("Invalid choice")
 	}
 
@@ -77,6 +87,24 @@ func handleMainMenu(logger *logger.RateLimitedLogger, config **Config, pluginMan
 	return false
 }
 
+// promotedPluginPrefix marks main menu choices that run a promoted plugin
+const promotedPluginPrefix = "plugin:"
+
+// mainMenuOptions returns the core main menu entries followed by an entry for
+// each loaded plugin that asked for one, with Quit last
+func mainMenuOptions(promoted []plugin.PromotedPlugin) []huh.Option[string] {
+	options := []huh.Option[string]{
+		huh.NewOption("Repositories", "repositories"),
+		huh.NewOption("Symlinks", "symlinks"),
+		huh.NewOption("Plugins", "plugins"),
+		huh.NewOption("Gitspace", "gitspace"),
+	}
+	for _, p := range promoted {
+		options = append(options, huh.NewOption(p.Title, promotedPluginPrefix+p.Name))
+	}
+	return append(options, huh.NewOption("Quit", "quit"))
+}
+
 func handleRepositoriesCommand(logger *logger.RateLimitedLogger, config *Config) bool {
 	if !ensureConfig(logger, &config) {
 		return false
@@ -87,7 +115,15 @@ func handleRepositoriesCommand(logger *logger.RateLimitedLogger, config *Config)
 			Title("Choose a repositories action").
 			Options(
 				huh.NewOption("Clone", "clone"),
+				huh.NewOption("Clone selected (choose from the filtered repositories)", "clone-select"),
 				huh.NewOption("Sync", "sync"),
+				huh.NewOption("Sync all (including repositories with no new pushes)", "sync-all"),
+				huh.NewOption("Watch (sync on an interval)", "watch"),
+				huh.NewOption("Refresh repository list", "refresh"),
+				huh.NewOption("List cached repositories", "index"),
+				huh.NewOption("Find repository", "find"),
+				huh.NewOption("Status (branch, ahead/behind, changes)", "status"),
+				huh.NewOption("Prune", "prune"),
 				huh.NewOption("Go back", "back"),
 				huh.NewOption("Quit", "quit"),
 			).
@@ -100,10 +136,43 @@ func handleRepositoriesCommand(logger *logger.RateLimitedLogger, config *Config)
 		}
 
 		switch subChoice {
-		case "clone":
-			cloneRepositories(logger, config)
-		case "sync":
-			syncRepositories(logger, config)
+		case "clone", "clone-select":
+			var selectRepos repoSelector
+			if subChoice == "clone-select" {
+				selectRepos = newCloneSelector(logger)
+			}
+			results, err := cloneRepositories(logger, config, selectRepos)
+			if err != nil {
+				logger.Error("Clone finished with errors", "error", err)
+			}
+			if len(results) > 0 {
+				printSummaryTable(results)
+			}
+		case "sync", "sync-all":
+			results, err := syncRepositories(logger, config, syncOptions{ForceAll: subChoice == "sync-all"})
+			if err != nil {
+				logger.Error("Sync finished with errors", "error", err)
+			}
+			printSummaryTable(results)
+		case "watch":
+			handleWatchCommand(logger, config)
+		case "refresh":
+			refreshRepositoryList(logger, config)
+		case "index":
+			handleIndexListCommand(logger)
+		case "find":
+			handleFindCommand(logger)
+		case "status":
+			statuses, err := workspaceStatus(config)
+			if err != nil {
+				logger.Error("Error reading repository status", "error", err)
+			} else {
+				printStatusTable(statuses)
+			}
+		case "prune":
+			if err := pruneRepositories(logger, config, false, confirmPrune); err != nil {
+				logger.Error("Prune failed", "error", err)
+			}
 		case "back":
 			return false // Go back to main menu
 		case "quit":
@@ -114,6 +183,115 @@ func handleRepositoriesCommand(logger *logger.RateLimitedLogger, config *Config)
 	}
 }
 
+// handleWatchCommand asks for an interval and syncs on it until Ctrl+C
+func handleWatchCommand(logger *logger.RateLimitedLogger, config *Config) {
+	value := getWatchInterval(config).String()
+	err := huh.NewInput().
+		Title("Sync interval (Ctrl+C stops watching)").
+		Value(&value).
+		Validate(func(s string) error {
+			_, err := parseWatchInterval(s)
+			return err
+		}).
+		Run()
+	if err != nil {
+		logger.Error("Error getting watch interval", "error", err)
+		return
+	}
+	interval, _ := parseWatchInterval(value)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watchRepositories(ctx, logger, config, interval)
+}
+
+func handleIndexListCommand(logger *logger.RateLimitedLogger) {
+	var filter indexFilter
+	var syncedWithin string
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("Owner (empty for all)").Value(&filter.Owner),
+			huh.NewInput().Title("Type (empty for all)").Value(&filter.Type),
+			huh.NewInput().Title("Label (empty for all)").Value(&filter.Label),
+			huh.NewInput().
+				Title("Synced within, e.g. 24h (empty for any time)").
+				Value(&syncedWithin).
+				Validate(func(s string) error {
+					if s == "" {
+						return nil
+					}
+					_, err := time.ParseDuration(s)
+					return err
+				}),
+		),
+	).Run()
+	if err != nil {
+		logger.Error("Error getting filters", "error", err)
+		return
+	}
+	if syncedWithin != "" {
+		filter.SyncedWithin, _ = time.ParseDuration(syncedWithin)
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		logger.Error("Error getting cache directory", "error", err)
+		return
+	}
+	repos, err := readIndex(cacheDir)
+	if err != nil {
+		logger.Error("Error reading index.toml", "error", err)
+		return
+	}
+	printIndexTable(filterIndex(repos, filter, time.Now()))
+}
+
+func handleFindCommand(logger *logger.RateLimitedLogger) {
+	var query string
+	match := "includes"
+	options := make([]huh.Option[string], len(knownMatchVerbs))
+	for i, verb := range knownMatchVerbs {
+		options[i] = huh.NewOption(verb, verb)
+	}
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Repository name to find").
+				Value(&query).
+				Validate(func(s string) error {
+					if s == "" {
+						return fmt.Errorf("query is required")
+					}
+					return nil
+				}),
+			huh.NewSelect[string]().
+				Title("Match").
+				Options(options...).
+				Value(&match),
+		),
+	).Run()
+	if err != nil {
+		logger.Error("Error getting query", "error", err)
+		return
+	}
+	if err := checkFindQuery(match, query); err != nil {
+		logger.Error("Invalid query", "error", err)
+		return
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		logger.Error("Error getting cache directory", "error", err)
+		return
+	}
+	found, err := findRepositories(logger, cacheDir, match, query)
+	if err != nil {
+		logger.Error("Error finding repositories", "error", err)
+		return
+	}
+	printFoundRepositories(found)
+}
+
 func printSymlinkSummary(title string, changes map[string]string) {
 	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00FFFF"))
 	symlinkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
@@ -130,27 +308,77 @@ func printSymlinkSummary(title string, changes map[string]string) {
 	fmt.Printf("\nTotal changes: %d\n", len(changes))
 }
 
-func printSummaryTable(config *Config, results map[string]*RepoResult, repoDir string) {
+// printSymlinkReport prints the created and retargeted links and a count of
+// each outcome, or just the counts under --quiet
+func printSymlinkReport(title string, report *symlinkReport) {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00FFFF"))
+	symlinkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
+	pathStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00"))
+
+	fmt.Println(titleStyle.Render(fmt.Sprintf("\n%s Summary:", title)))
+	if outputVerbosity != verbosityQuiet {
+		if len(report.Created) == 0 && len(report.Updated) == 0 {
+			fmt.Println("No changes were made.")
+		}
+		for symlink, target := range report.Created {
+			fmt.Printf("  %s -> %s\n", symlinkStyle.Render(symlink), pathStyle.Render(target))
+		}
+		for symlink, target := range report.Updated {
+			fmt.Printf("  %s -> %s (retargeted)\n", symlinkStyle.Render(symlink), pathStyle.Render(target))
+		}
+	}
+	fmt.Printf("\nCreated: %d, updated: %d, unchanged: %d", len(report.Created), len(report.Updated), len(report.Unchanged))
+	if report.Failed > 0 {
+		fmt.Printf(", failed: %d", report.Failed)
+	}
+	fmt.Println()
+}
+
+func printSummaryTable(results map[string]*RepoResult) {
 	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	ownerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("214"))
 	repoNameStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
 	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
 
 	fmt.Println(headerStyle.Render("\nRepository Processing Summary:"))
 	fmt.Println()
 
-	for _, result := range results {
+	// Group results by scm/owner, then by repository name. --quiet leaves out
+	// the repositories that didn't fail.
+	currentOwner := ""
+	for _, result := range sortedResults(results) {
+		if outputVerbosity == verbosityQuiet && result.Error == nil {
+			continue
+		}
+		if owner := result.SCM + "/" + result.Owner; owner != currentOwner {
+			currentOwner = owner
+			fmt.Println(ownerStyle.Render(owner))
+			fmt.Println()
+		}
+
 		fmt.Println(repoNameStyle.Render(result.Name))
 		fmt.Println()
 
 		status := "No changes"
 		statusEmoji := "✅"
-		if result.Error != nil {
+		switch resultStatus(result) {
+		case statusFailed:
 			status = "Failed"
 			statusEmoji = "❌"
-		} else if result.Cloned {
+		case statusCloned:
 			status = "Cloned"
-		} else if result.Updated {
+		case statusUnchanged:
+			if result.UpToDate {
+				status = "No changes (no pushes since the last sync)"
+			}
+		case statusUpdated:
 			status = "Updated"
+			if result.FastForwarded {
+				status = "Updated (fast-forwarded)"
+			}
+		case statusSkipped:
+			status = "Skipped (uncommitted changes)"
+			statusEmoji = "⚠️"
 		}
 
 		fmt.Println(infoStyle.Render(fmt.Sprintf("%s Status: %s", statusEmoji, status)))
@@ -160,6 +388,36 @@ func printSummaryTable(config *Config, results map[string]*RepoResult, repoDir s
 		if result.Error != nil {
 			fmt.Println(infoStyle.Render(fmt.Sprintf("❌ Error: %s", result.Error)))
 		}
+		if result.DirtyAction == dirtyStashed || result.DirtyAction == dirtyForced {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("⚠️ Uncommitted changes: %s", result.DirtyAction)))
+		}
+		if result.PinnedCommit != "" {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("📌 Pinned to commit: %s", result.PinnedCommit)))
+		}
+		if result.Submodules {
+			fmt.Println(infoStyle.Render("🧩 Submodules: updated"))
+		}
+		switch result.LFS {
+		case lfsOK:
+			fmt.Println(infoStyle.Render("📦 Git LFS: pulled"))
+		case lfsFailed:
+			fmt.Println(infoStyle.Render(fmt.Sprintf("❌ Git LFS: %s", result.LFSError)))
+		}
+		switch result.Hook {
+		case hookOK:
+			fmt.Println(infoStyle.Render("🪝 Post-clone hook: ok"))
+		case hookFailed:
+			fmt.Println(infoStyle.Render(fmt.Sprintf("❌ Post-clone hook: %s", result.HookError)))
+		}
+		if outputVerbosity == verbosityVerbose {
+			if result.Info.DefaultBranch != "" {
+				fmt.Println(infoStyle.Render(fmt.Sprintf("🌿 Default branch: %s", result.Info.DefaultBranch)))
+			}
+			if !result.LastSynced.IsZero() {
+				fmt.Println(infoStyle.Render(fmt.Sprintf("🕒 Last synced: %s", result.LastSynced.Format(time.RFC3339))))
+			}
+			fmt.Println(infoStyle.Render(fmt.Sprintf("🔁 Retries: %d", result.Retries)))
+		}
 
 		fmt.Println() // Add an empty line between repositories
 	}
@@ -167,36 +425,28 @@ func printSummaryTable(config *Config, results map[string]*RepoResult, repoDir s
 	fmt.Println(headerStyle.Render("Summary of changes:"))
 	fmt.Println()
 
-	totalRepos := len(results)
-	clonedRepos := 0
-	updatedRepos := 0
-	failedRepos := 0
-	localSymlinks := 0
-	globalSymlinks := 0
-
-	for _, result := range results {
-		if result.Error != nil {
-			failedRepos++
-		} else if result.Cloned {
-			clonedRepos++
-		} else if result.Updated {
-			updatedRepos++
-		}
-		if result.LocalSymlink != "" {
-			localSymlinks++
-		}
-		if result.GlobalSymlink != "" {
-			globalSymlinks++
-		}
-	}
+	summary := summarizeResults(results)
 
 	summaryStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
-	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Total repositories processed: %d", totalRepos)))
-	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Newly cloned: %d", clonedRepos)))
-	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Updated: %d", updatedRepos)))
-	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Failed operations: %d", failedRepos)))
-	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Local symlinks created: %d", localSymlinks)))
-	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Global symlinks created: %d", globalSymlinks)))
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Total repositories processed: %d", summary.Total)))
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Newly cloned: %d", summary.Cloned)))
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Updated: %d", summary.Updated)))
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Failed operations: %d", summary.Failed)))
+	if summary.Skipped > 0 {
+		fmt.Println(summaryStyle.Render(fmt.Sprintf("  Skipped (uncommitted changes): %d", summary.Skipped)))
+	}
+	if summary.UpToDate > 0 {
+		fmt.Println(summaryStyle.Render(fmt.Sprintf("  Not fetched (no pushes since the last sync): %d", summary.UpToDate)))
+	}
+	if summary.HooksFailed > 0 {
+		fmt.Println(summaryStyle.Render(fmt.Sprintf("  Failed post-clone hooks: %d", summary.HooksFailed)))
+	}
+	if summary.LFSPulled > 0 || summary.LFSFailed > 0 {
+		fmt.Println(summaryStyle.Render(fmt.Sprintf("  Git LFS pulls: %d ok, %d failed", summary.LFSPulled, summary.LFSFailed)))
+	}
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Link mode: %s", summary.LinkMode)))
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Local symlinks created: %d", summary.LocalSymlinks)))
+	fmt.Println(summaryStyle.Render(fmt.Sprintf("  Global symlinks created: %d", summary.GlobalSymlinks)))
 }
 
 func handleConfigPathsCommand(logger *logger.RateLimitedLogger) {
@@ -257,11 +507,18 @@ func handleGitspaceCommand(logger *logger.RateLimitedLogger, config **Config) {
 		err := huh.NewSelect[string]().
 			Title("Choose a Gitspace action").
 			Options(
+				huh.NewOption("Check for Updates", "check_update"),
 				huh.NewOption("Upgrade Gitspace", "upgrade"),
 				huh.NewOption("Print Config Paths", "config_paths"),
 				huh.NewOption("Print Version Info", "version_info"),
+				huh.NewOption("Doctor", "doctor"),
+				huh.NewOption("Create Config", "init_config"),
 				huh.NewOption("Load Config", "load_config"),
+				huh.NewOption("Restore Config Backup", "restore_config"),
+				huh.NewOption("Validate Config", "validate_config"),
+				huh.NewOption("Manage Groups", "manage_groups"),
 				huh.NewOption("Delete Current Config", "delete_config"),
+				huh.NewOption("Profiles", "profiles"),
 				huh.NewOption("Go back", "back"),
 			).
 			Value(&choice).
@@ -273,12 +530,33 @@ func handleGitspaceCommand(logger *logger.RateLimitedLogger, config **Config) {
 		}
 
 		switch choice {
+		case "check_update":
+			check, err := checkForUpdate("ssotops/gitspace")
+			if err != nil {
+				logger.Error("Failed to check for updates", "error", err)
+				continue
+			}
+			fmt.Println(check)
 		case "upgrade":
-			upgradeGitspace(logger)
+			var version string
+			err := huh.NewInput().
+				Title("Version to install (blank for the latest release)").
+				Placeholder("v1.2.3").
+				Value(&version).
+				Run()
+			if err != nil {
+				logger.Error("Error getting version", "error", err)
+				continue
+			}
+			if err := upgradeGitspace(logger, version, confirmUpgrade); err != nil {
+				logger.Error("Failed to upgrade Gitspace; keeping the current binary", "error", err)
+			}
 		case "config_paths":
 			handleConfigPathsCommand(logger)
 		case "version_info":
 			printVersionInfo(logger)
+		case "doctor":
+			handleDoctorCommand(logger, *config)
 		case "load_config":
 			newConfig, err := getConfigFromUser(logger)
 			if err != nil {
@@ -291,13 +569,18 @@ func handleGitspaceCommand(logger *logger.RateLimitedLogger, config **Config) {
 					logger.Info("No config file loaded")
 				}
 			}
+		case "restore_config":
+			handleRestoreConfigCommand(logger, config)
+		case "init_config":
+			handleInitConfigCommand(logger, config)
+		case "validate_config":
+			handleValidateConfigCommand(logger)
+		case "manage_groups":
+			handleManageGroupsCommand(logger, config)
 		case "delete_config":
-			if err := deleteCurrentConfig(logger); err != nil {
-				logger.Error("Failed to delete current config", "error", err)
-			} else {
-				logger.Info("Current config deleted successfully")
-				*config = nil
-			}
+			handleDeleteConfigCommand(logger, config)
+		case "profiles":
+			handleProfilesCommand(logger, config)
 		case "back":
 			return
 		default:
@@ -306,6 +589,393 @@ func handleGitspaceCommand(logger *logger.RateLimitedLogger, config **Config) {
 	}
 }
 
+// handleInitConfigCommand runs the init wizard and offers to make the new
+// config the active one
+func handleInitConfigCommand(logger *logger.RateLimitedLogger, config **Config) {
+	path := defaultInitPath
+	err := huh.NewInput().
+		Title("Where should the new config be written?").
+		Value(&path).
+		Run()
+	if err != nil {
+		logger.Error("Error getting config path", "error", err)
+		return
+	}
+
+	if err := runInitWizard(logger, path); err != nil {
+		if !errors.Is(err, errInitCancelled) {
+			logger.Error("Failed to create config", "error", err)
+		}
+		return
+	}
+
+	var activate bool
+	err = huh.NewConfirm().
+		Title("Use the new config now?").
+		Value(&activate).
+		Run()
+	if err != nil || !activate {
+		return
+	}
+
+	newConfig, err := loadConfig(path)
+	if err == nil {
+		err = installConfig(logger, path)
+	}
+	if err != nil {
+		logger.Error("Failed to activate the new config", "error", err)
+		return
+	}
+	*config = newConfig
+	logger.Info("Config loaded successfully", "path", newConfig.Global.Path)
+}
+
+// handleDeleteConfigCommand deletes the active config, offering to remove the
+// legacy symlink and old backups too, and reports what was removed
+func handleDeleteConfigCommand(logger *logger.RateLimitedLogger, config **Config) {
+	var opts configDeleteOptions
+
+	symlinkPath, err := legacyConfigSymlink()
+	if err != nil {
+		logger.Error("Failed to find legacy symlink", "error", err)
+		return
+	}
+	if _, err := os.Lstat(symlinkPath); err == nil {
+		err = huh.NewConfirm().
+			Title(fmt.Sprintf("Also remove the legacy symlink %s?", symlinkPath)).
+			Value(&opts.LegacySymlink).
+			Run()
+		if err != nil {
+			return
+		}
+	}
+
+	backups, err := listConfigBackups()
+	if err != nil {
+		logger.Warn("Failed to list config backups", "error", err)
+	}
+	if len(backups) > 0 {
+		err = huh.NewConfirm().
+			Title(fmt.Sprintf("Also purge old config backups? There are %d.", len(backups))).
+			Value(&opts.PurgeBackups).
+			Run()
+		if err != nil {
+			return
+		}
+	}
+	if opts.PurgeBackups {
+		days := "30"
+		err = huh.NewInput().
+			Title("Purge backups older than how many days? (0 purges all)").
+			Value(&days).
+			Validate(func(s string) error {
+				n, err := strconv.Atoi(strings.TrimSpace(s))
+				if err != nil || n < 0 {
+					return fmt.Errorf("enter a whole number of days")
+				}
+				return nil
+			}).
+			Run()
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(days))
+		opts.BackupMaxAge = time.Duration(n) * 24 * time.Hour
+	}
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title("Delete the current config?").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	removed, err := deleteCurrentConfig(logger, opts)
+	if len(removed) == 0 {
+		fmt.Println("Nothing was removed.")
+	} else {
+		fmt.Println("Removed:")
+		for _, path := range removed {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to delete current config", "error", err)
+		return
+	}
+	*config = nil
+}
+
+// maxDiffLines caps the added and removed lines shown before restoring a backup
+const maxDiffLines = 20
+
+// handleRestoreConfigCommand lets the user pick a config backup, shows how it
+// differs from the active config and installs it once confirmed
+func handleRestoreConfigCommand(logger *logger.RateLimitedLogger, config **Config) {
+	backups, err := listConfigBackups()
+	if err != nil {
+		logger.Error("Failed to list config backups", "error", err)
+		return
+	}
+	if len(backups) == 0 {
+		fmt.Println("No config backups found.")
+		return
+	}
+
+	options := make([]huh.Option[string], len(backups))
+	for i, backup := range backups {
+		label := fmt.Sprintf("%s  %s", backup.SavedAt.Format("2006-01-02 15:04:05"), filepath.Base(backup.Path))
+		options[i] = huh.NewOption(label, backup.Path)
+	}
+	var backupPath string
+	err = huh.NewSelect[string]().
+		Title("Restore which backup?").
+		Options(options...).
+		Value(&backupPath).
+		Run()
+	if err != nil {
+		logger.Error("Error getting backup", "error", err)
+		return
+	}
+
+	activePath, err := getCurrentConfigPath(logger)
+	if err != nil {
+		logger.Warn("Error checking for the active config", "error", err)
+	}
+	diff, err := diffConfigFiles(activePath, backupPath)
+	if err != nil {
+		logger.Error("Failed to compare configs", "error", err)
+		return
+	}
+	printConfigDiff(activePath, diff)
+	if activePath != "" && len(diff.Added) == 0 && len(diff.Removed) == 0 {
+		return
+	}
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title("Make this backup the active config?").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	newConfig, err := restoreConfigBackup(logger, backupPath)
+	if err != nil {
+		logger.Error("Failed to restore config backup", "error", err)
+		return
+	}
+	*config = newConfig
+	fmt.Printf("Restored %s as the active config.\n", filepath.Base(backupPath))
+}
+
+// printConfigDiff shows the lines a backup adds to and removes from the
+// active config
+func printConfigDiff(activePath string, diff configDiff) {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00FFFF"))
+	addedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+
+	fmt.Println()
+	if activePath == "" {
+		fmt.Println(titleStyle.Render("There is no active config; the backup would become it."))
+	} else if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+		fmt.Println(titleStyle.Render("The backup is the same as the active config."))
+		fmt.Println()
+		return
+	} else {
+		fmt.Println(titleStyle.Render(fmt.Sprintf("Changes from the active config: %d lines added, %d removed", len(diff.Added), len(diff.Removed))))
+	}
+	for i, line := range diff.Removed {
+		if i == maxDiffLines {
+			fmt.Printf("  ... %d more removed\n", len(diff.Removed)-maxDiffLines)
+			break
+		}
+		fmt.Println(removedStyle.Render("  - " + line))
+	}
+	for i, line := range diff.Added {
+		if i == maxDiffLines {
+			fmt.Printf("  ... %d more added\n", len(diff.Added)-maxDiffLines)
+			break
+		}
+		fmt.Println(addedStyle.Render("  + " + line))
+	}
+	fmt.Println()
+}
+
+// handleProfilesCommand switches between, saves, lists and deletes named
+// configs
+func handleProfilesCommand(logger *logger.RateLimitedLogger, config **Config) {
+	for {
+		active, err := getActiveProfile()
+		if err != nil {
+			logger.Warn("Error reading the active profile", "error", err)
+		}
+		title := "Choose a profile action"
+		if active != "" {
+			title = fmt.Sprintf("Choose a profile action (active: %s)", active)
+		}
+
+		var choice string
+		err = huh.NewSelect[string]().
+			Title(title).
+			Options(
+				huh.NewOption("Switch Profile", "switch"),
+				huh.NewOption("Save Config as Profile", "save"),
+				huh.NewOption("List Profiles", "list"),
+				huh.NewOption("Delete Profile", "delete"),
+				huh.NewOption("Go back", "back"),
+			).
+			Value(&choice).
+			Run()
+		if err != nil {
+			logger.Error("Error getting profile sub-choice", "error", err)
+			return
+		}
+
+		switch choice {
+		case "switch":
+			name, ok := chooseProfile(logger, "Switch to which profile?")
+			if !ok {
+				continue
+			}
+			newConfig, err := switchProfile(logger, name)
+			if err != nil {
+				logger.Error("Failed to switch profile", "error", err)
+				continue
+			}
+			*config = newConfig
+		case "save":
+			handleSaveProfileCommand(logger)
+		case "list":
+			names, err := listProfiles()
+			if err != nil {
+				logger.Error("Failed to list profiles", "error", err)
+				continue
+			}
+			if len(names) == 0 {
+				fmt.Println("No profiles saved yet.")
+				continue
+			}
+			fmt.Println()
+			for _, name := range names {
+				marker := " "
+				if name == active {
+					marker = "*"
+				}
+				fmt.Printf("%s %s\n", marker, name)
+			}
+			fmt.Println()
+		case "delete":
+			name, ok := chooseProfile(logger, "Delete which profile?")
+			if !ok {
+				continue
+			}
+			var confirm bool
+			err := huh.NewConfirm().
+				Title(fmt.Sprintf("Delete profile %s?", name)).
+				Value(&confirm).
+				Run()
+			if err != nil || !confirm {
+				continue
+			}
+			if err := deleteProfile(logger, name); err != nil {
+				logger.Error("Failed to delete profile", "error", err)
+			} else if name == active {
+				logger.Info("Deleted the active profile; the active config is current.toml again")
+			}
+		case "back":
+			return
+		}
+	}
+}
+
+// chooseProfile asks for one of the saved profiles, reporting false if there
+// are none or the prompt failed
+func chooseProfile(logger *logger.RateLimitedLogger, title string) (string, bool) {
+	names, err := listProfiles()
+	if err != nil {
+		logger.Error("Failed to list profiles", "error", err)
+		return "", false
+	}
+	if len(names) == 0 {
+		fmt.Println("No profiles saved yet. Use \"Save Config as Profile\" first.")
+		return "", false
+	}
+
+	var name string
+	err = huh.NewSelect[string]().
+		Title(title).
+		Options(huh.NewOptions(names...)...).
+		Value(&name).
+		Run()
+	if err != nil {
+		logger.Error("Error getting profile", "error", err)
+		return "", false
+	}
+	return name, true
+}
+
+// handleSaveProfileCommand saves a config file, the active one by default,
+// as a named profile
+func handleSaveProfileCommand(logger *logger.RateLimitedLogger) {
+	source, err := getCurrentConfigPath(logger)
+	if err != nil {
+		logger.Warn("Error checking for the active config", "error", err)
+	}
+
+	var name string
+	err = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Profile name, such as work or oss").
+				Value(&name).
+				Validate(func(s string) error {
+					_, err := profilePath(s)
+					return err
+				}),
+			huh.NewInput().
+				Title("Config file to save").
+				Value(&source),
+		),
+	).Run()
+	if err != nil {
+		logger.Error("Error getting profile", "error", err)
+		return
+	}
+
+	if err := saveProfile(logger, name, source); err != nil {
+		logger.Error("Failed to save profile", "error", err)
+		return
+	}
+	fmt.Printf("Saved profile %s. Switch to it from Profiles, or run gitspace --profile %s.\n", name, name)
+}
+
+// handleValidateConfigCommand checks a config file, the active one by
+// default, without loading it
+func handleValidateConfigCommand(logger *logger.RateLimitedLogger) {
+	path, err := getCurrentConfigPath(logger)
+	if err != nil {
+		logger.Warn("Error checking for the active config", "error", err)
+	}
+
+	err = huh.NewInput().
+		Title("Config file to validate").
+		Value(&path).
+		Run()
+	if err != nil {
+		logger.Error("Error getting config path", "error", err)
+		return
+	}
+
+	fmt.Println()
+	printConfigCheck(path, checkConfigFile(path))
+	fmt.Println()
+}
+
 func handleSymlinksCommand(logger *logger.RateLimitedLogger, config *Config) {
 	if !ensureConfig(logger, &config) {
 		return
@@ -425,3 +1095,44 @@ func handlePluginsCommand(logger *logger.RateLimitedLogger, config *Config, plug
 		}
 	}
 }
+
+// configurePluginManager applies the config's plugin settings to the manager.
+// Settings the config leaves unset go back to their defaults, so switching
+// configs doesn't carry them over. A nil config leaves the defaults in place.
+func configurePluginManager(pluginManager *plugin.Manager, config *Config) {
+	if config == nil {
+		pluginManager.SetLogLevels(logLevel, nil)
+		pluginManager.SetConfigView(nil)
+		return
+	}
+	pluginManager.SetConfigView(pluginConfigView(config))
+	pluginLevels := make(map[string]log.Level)
+	for name, value := range config.Plugins.LogLevels {
+		if level, err := log.ParseLevel(value); err == nil {
+			pluginLevels[name] = level
+		}
+	}
+	pluginManager.SetLogLevels(logLevel, pluginLevels)
+	pluginManager.SetNetworkTimeout(getNetworkTimeout(config))
+	maxRestarts := plugin.DefaultMaxRestarts
+	if config.Plugins.MaxRestarts != nil {
+		maxRestarts = *config.Plugins.MaxRestarts
+	}
+	pluginManager.SetMaxRestarts(maxRestarts)
+	requestTimeout := plugin.DefaultRequestTimeout
+	if timeout, err := time.ParseDuration(config.Plugins.RequestTimeout); err == nil {
+		requestTimeout = timeout
+	}
+	pluginManager.SetRequestTimeout(requestTimeout)
+	pluginManager.SetAllowIncompatible(config.Plugins.AllowIncompatible)
+	pluginManager.SetSkipChecksum(config.Plugins.SkipChecksum)
+	maxMessageSize := plugin.DefaultMaxMessageSize
+	if config.Plugins.MaxMessageSize > 0 {
+		maxMessageSize = config.Plugins.MaxMessageSize
+	}
+	pluginManager.SetMaxMessageSize(maxMessageSize)
+	pluginManager.SetResourceLimits(plugin.ResourceLimits{
+		MaxMemoryMB: config.Plugins.MaxMemoryMB,
+		MaxRuntime:  time.Duration(config.Plugins.MaxRuntimeSeconds) * time.Second,
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/ssotops/gitspace/lib"
+	"github.com/ssotops/gitspace/plugin"
+)
+
+// pluginTestConfig returns a config with extra appended to its [plugins] table
+func pluginTestConfig(t *testing.T, global, plugins string) *Config {
+	t.Helper()
+	return loadTestConfig(t, fmt.Sprintf(`
+[global]
+path = %q
+scm = "github"
+owner = "acme"
+%s
+
+[plugins]
+%s
+`, t.TempDir(), global, plugins))
+}
+
+func TestConfigurePluginManagerOnConfigSwitch(t *testing.T) {
+	logger := newTestLogger(t)
+	manager := newPluginManager(logger, pluginTestConfig(t, `network_timeout = "7s"`, ""))
+	if got := manager.NetworkTimeout(); got != 7*time.Second {
+		t.Fatalf("network timeout %s, want the config's 7s", got)
+	}
+
+	// Switching to a config that leaves it unset goes back to the default
+	configurePluginManager(manager, pluginTestConfig(t, "", ""))
+	if got := manager.NetworkTimeout(); got != lib.DefaultNetworkTimeout {
+		t.Errorf("network timeout %s after switching configs, want the default %s", got, lib.DefaultNetworkTimeout)
+	}
+}
+
+// menuValues returns the values of a menu's options, in order
+func menuValues(options []huh.Option[string]) []string {
+	values := make([]string, len(options))
+	for i, option := range options {
+		values[i] = option.Value
+	}
+	return values
+}
+
+func TestMainMenuOptions(t *testing.T) {
+	core := []string{"repositories", "symlinks", "plugins", "gitspace", "quit"}
+	if got := menuValues(mainMenuOptions(nil)); !slices.Equal(got, core) {
+		t.Errorf("without plugins the menu is %v, want %v", got, core)
+	}
+
+	options := mainMenuOptions([]plugin.PromotedPlugin{{Name: "deploy", Title: "Deploy"}, {Name: "lint", Title: "Lint"}})
+	want := []string{"repositories", "symlinks", "plugins", "gitspace", "plugin:deploy", "plugin:lint", "quit"}
+	if got := menuValues(options); !slices.Equal(got, want) {
+		t.Errorf("menu is %v, want %v", got, want)
+	}
+	if options[4].Key != "Deploy" {
+		t.Errorf("plugin entry is labeled %q, want its title", options[4].Key)
+	}
+}
+
+func TestMainMenuListsLoadedPromotedPlugin(t *testing.T) {
+	logger := newTestLogger(t)
+	pluginManager := plugin.NewManager(logger)
+	loadStubPlugin(t, pluginManager)
+
+	options := mainMenuOptions(pluginManager.PromotedPlugins())
+	i := slices.IndexFunc(options, func(option huh.Option[string]) bool { return option.Value == promotedPluginPrefix+"stub" })
+	if i < 0 || options[i].Key != "Stub Tools" {
+		t.Fatalf("main menu %v has no entry for the stub plugin", menuValues(options))
+	}
+	if options[len(options)-1].Value != "quit" {
+		t.Error("Quit isn't the last entry")
+	}
+
+	pluginManager.UnloadPlugin("stub")
+	if got := menuValues(mainMenuOptions(pluginManager.PromotedPlugins())); slices.Contains(got, promotedPluginPrefix+"stub") {
+		t.Errorf("an unloaded plugin is still in the menu: %v", got)
+	}
+}
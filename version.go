@@ -1,23 +1,40 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
   "runtime/debug"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/go-git/go-git/v5"
   "github.com/ssotops/gitspace-plugin-sdk/logger"
+	"github.com/ssotops/gitspace/lib"
 )
 
 type ReleaseInfo struct {
-	TagName string `json:"tag_name"`
-	ID      int    `json:"id"`
+	TagName    string `json:"tag_name"`
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
 }
 
 var Version string
@@ -63,7 +80,10 @@ func getGitCommitHash() (string, error) {
 	return ref.Hash().String(), nil
 }
 
-func upgradeGitspace(logger *logger.RateLimitedLogger) {
+// upgradeGitspace installs the release tagged version, or the latest release
+// when version is empty. It shows the release notes and asks confirm before
+// downloading anything.
+func upgradeGitspace(logger *logger.RateLimitedLogger, version string, confirm func(title string) (bool, error)) error {
 	logger.Info("Upgrading Gitspace...")
 
 	repo := "ssotops/gitspace"
@@ -72,59 +92,311 @@ func upgradeGitspace(logger *logger.RateLimitedLogger) {
 	osName := runtime.GOOS
 	arch := runtime.GOARCH
 
-	releaseInfo, err := fetchLatestReleaseInfo(repo)
+	releaseInfo, err := fetchReleaseInfo(repo, normalizeReleaseTag(version))
 	if err != nil {
-		logger.Error("Failed to fetch latest release information", "error", err)
-		return
+		return fmt.Errorf("failed to fetch release information: %w", err)
 	}
 
-	version := releaseInfo.TagName
-	logger.Info("Latest version", "version", version)
+	version = releaseInfo.TagName
+	logger.Info("Target version", "version", version)
 
-	assetName := fmt.Sprintf("%s_%s_%s", binary, osName, arch)
-	if osName == "windows" {
-		assetName += ".exe"
+	current, _ := getCurrentVersion()
+	if outputVerbosity != verbosityQuiet {
+		printReleaseNotes(releaseNotes(repo, current, releaseInfo))
 	}
-	downloadURL := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, version, assetName)
 
-	tempFile, err := downloadBinary(downloadURL)
+	title := fmt.Sprintf("Upgrade from %s to %s?", current, version)
+	if isOlderRelease(version, current) {
+		logger.Warn("Target version is older than the current one", "current", current, "target", version)
+		title = fmt.Sprintf("%s is older than the current %s. Downgrade?", version, current)
+	}
+	ok, err := confirm(title)
 	if err != nil {
-		logger.Error("Failed to download binary", "error", err)
-		return
+		return err
+	}
+	if !ok {
+		logger.Info("Upgrade cancelled")
+		return nil
 	}
-	defer os.Remove(tempFile)
 
-	if osName != "windows" {
-		err = os.Chmod(tempFile, 0755)
+	assetName := fmt.Sprintf("%s_%s_%s", binary, osName, arch)
+	if osName == "windows" {
+		assetName += ".exe"
+	}
+	downloadURL := releaseAssetURL(repo, version, assetName)
+
+	verify := isVerifiedRelease(version)
+	var wantSum string
+	if verify {
+		wantSum, err = fetchReleaseChecksum(repo, version, assetName)
 		if err != nil {
-			logger.Error("Failed to make binary executable", "error", err)
-			return
+			return fmt.Errorf("failed to fetch release checksum: %w", err)
 		}
+	} else {
+		logger.Warn("Release predates checksums and --version; installing it without verifying the download or test-running it",
+			"version", version, "first_verified", firstVerifiedRelease)
 	}
 
 	execPath, err := os.Executable()
 	if err != nil {
-		logger.Error("Failed to get current executable path", "error", err)
-		return
+		return fmt.Errorf("failed to get current executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+
+	// Download next to the executable, so the swap is a rename on one filesystem
+	tempFile, gotSum, err := downloadBinary(downloadURL, filepath.Dir(execPath))
+	if tempFile != "" {
+		defer os.Remove(tempFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download binary: %w", err)
+	}
+	if verify && gotSum != wantSum {
+		return fmt.Errorf("%s doesn't match the release checksum (expected %s, got %s)", assetName, wantSum, gotSum)
+	}
+
+	err = os.Chmod(tempFile, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to make binary executable: %w", err)
 	}
 
-	err = os.Rename(tempFile, execPath)
+	backup, err := replaceExecutable(tempFile, execPath, verify)
 	if err != nil {
-		logger.Error("Failed to replace current binary", "error", err)
-		return
+		return fmt.Errorf("failed to replace current binary: %w", err)
 	}
 
-	logger.Info("Gitspace has been successfully upgraded!", "version", version)
+	logger.Info("Gitspace has been successfully upgraded!", "version", version, "previous", backup)
+	return nil
+}
+
+// confirmUpgrade asks before installing a release
+func confirmUpgrade(title string) (bool, error) {
+	var ok bool
+	err := huh.NewConfirm().
+		Title(title).
+		Value(&ok).
+		Run()
+	return ok, err
+}
+
+// releaseNotes returns the releases whose notes to show when moving from
+// current to target, newest first: every release after current up to and
+// including target, or just target when current isn't an older release or
+// the list can't be fetched
+func releaseNotes(repo, current string, target *ReleaseInfo) []ReleaseInfo {
+	if !isOlderRelease(current, target.TagName) {
+		return []ReleaseInfo{*target}
+	}
+	releases, err := fetchReleases(repo)
+	if err != nil {
+		return []ReleaseInfo{*target}
+	}
+
+	var notes []ReleaseInfo
+	for _, release := range releases {
+		if release.Draft || release.Prerelease {
+			continue
+		}
+		afterCurrent, ok := compareVersions(release.TagName, current)
+		upToTarget, targetOK := compareVersions(release.TagName, target.TagName)
+		if ok && targetOK && afterCurrent > 0 && upToTarget <= 0 {
+			notes = append(notes, release)
+		}
+	}
+	if len(notes) == 0 {
+		return []ReleaseInfo{*target}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		cmp, _ := compareVersions(notes[i].TagName, notes[j].TagName)
+		return cmp > 0
+	})
+	return notes
+}
+
+func printReleaseNotes(releases []ReleaseInfo) {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	for _, release := range releases {
+		title := release.TagName
+		if release.Name != "" && release.Name != release.TagName {
+			title += " - " + release.Name
+		}
+		body := strings.TrimSpace(release.Body)
+		if body == "" {
+			body = "No release notes."
+		}
+		fmt.Println(headerStyle.Render(title))
+		fmt.Println(body)
+		fmt.Println()
+	}
+}
+
+// normalizeReleaseTag adds the v prefix release tags have, so 1.2.3 and
+// v1.2.3 name the same release
+func normalizeReleaseTag(version string) string {
+	version = strings.TrimSpace(version)
+	if version == "" || strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+// isOlderRelease reports whether target is an older version than current.
+// Builds without a release version, such as commit hashes, can't be compared.
+func isOlderRelease(target, current string) bool {
+	cmp, ok := compareVersions(target, current)
+	return ok && cmp < 0
+}
+
+// compareVersions compares two versions by semver precedence, returning -1,
+// 0 or 1. ok is false when either isn't a release version, such as the commit
+// hash a development build reports, and the comparison should be skipped.
+// Versions must have a dot, so an all-digit hash isn't read as a major version.
+func compareVersions(a, b string) (cmp int, ok bool) {
+	if !strings.Contains(a, ".") || !strings.Contains(b, ".") {
+		return 0, false
+	}
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return 0, false
+	}
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return 0, false
+	}
+	return va.Compare(vb), true
+}
+
+// firstVerifiedRelease is the first release published with checksums.txt and
+// built with --version. Older releases can still be installed, but neither
+// checked against a checksum nor test-run once swapped in.
+const firstVerifiedRelease = "v1.1.0"
+
+// isVerifiedRelease reports whether version is new enough to be checked
+// against its checksums file and test-run with --version. Versions that can't
+// be compared are assumed to be.
+func isVerifiedRelease(version string) bool {
+	return !isOlderRelease(version, firstVerifiedRelease)
+}
+
+// smokeTestTimeout bounds running the new binary with --version, so a binary
+// that hangs is rolled back instead of hanging the upgrade
+var smokeTestTimeout = 10 * time.Second
+
+// replaceExecutable swaps newPath in for execPath, keeping the old binary as
+// execPath.bak, which it returns. With smokeTest set, the new binary is run
+// with --version, and the old one put back if that fails; it is always put
+// back if the swap itself fails.
+func replaceExecutable(newPath, execPath string, smokeTest bool) (string, error) {
+	backup := execPath + ".bak"
+	if err := os.Rename(execPath, backup); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", execPath, err)
+	}
+
+	if err := moveFile(newPath, execPath); err != nil {
+		if restoreErr := os.Rename(backup, execPath); restoreErr != nil {
+			return "", fmt.Errorf("%w; restoring the backup also failed, the old binary is at %s: %v", err, backup, restoreErr)
+		}
+		return "", err
+	}
+
+	if !smokeTest {
+		return backup, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), smokeTestTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, execPath, "--version", "--quiet")
+	// Don't wait on children that hold on to the output after the kill
+	cmd.WaitDelay = time.Second
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s", smokeTestTimeout)
+		}
+		err = fmt.Errorf("new binary failed to run: %w: %s", err, strings.TrimSpace(string(output)))
+		if restoreErr := os.Rename(backup, execPath); restoreErr != nil {
+			return "", fmt.Errorf("%w; restoring the backup also failed, the old binary is at %s: %v", err, backup, restoreErr)
+		}
+		return "", err
+	}
+	return backup, nil
+}
+
+// moveFile renames src to dst, copying it instead when they are on
+// different filesystems
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
 }
 
 func fetchLatestReleaseInfo(repo string) (*ReleaseInfo, error) {
+	return fetchReleaseInfo(repo, "")
+}
+
+// fetchReleases fetches the repository's most recent releases
+func fetchReleases(repo string) ([]ReleaseInfo, error) {
+	if lib.Offline() {
+		return nil, fmt.Errorf("listing releases is %w", lib.ErrOffline)
+	}
+	resp, err := http.Get(fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=100", repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
+	var releases []ReleaseInfo
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// fetchReleaseInfo fetches the release tagged tag, or the latest release
+// when tag is empty
+func fetchReleaseInfo(repo, tag string) (*ReleaseInfo, error) {
+	if lib.Offline() {
+		return nil, fmt.Errorf("checking releases is %w", lib.ErrOffline)
+	}
 	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo)
+	if tag != "" {
+		url = fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repo, neturl.PathEscape(tag))
+	}
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	switch {
+	case resp.StatusCode == http.StatusNotFound && tag != "":
+		return nil, fmt.Errorf("there is no release %s", tag)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -139,25 +411,122 @@ func fetchLatestReleaseInfo(repo string) (*ReleaseInfo, error) {
 	return &releaseInfo, nil
 }
 
-func downloadBinary(url string) (string, error) {
-	resp, err := http.Get(url)
+// updateCheck compares the running version with the latest release
+type updateCheck struct {
+	Current   string
+	Latest    string
+	Available bool
+	Level     string // major, minor or patch, when Available
+	Known     bool   // false for builds that aren't a release version
+}
+
+func checkForUpdate(repo string) (*updateCheck, error) {
+	releaseInfo, err := fetchLatestReleaseInfo(repo)
+	if err != nil {
+		return nil, err
+	}
+	current, _ := getCurrentVersion()
+	return compareRelease(current, releaseInfo.TagName), nil
+}
+
+// compareRelease reports whether latest is newer than current, and by how
+// much. Versions that don't parse as semver can't be compared.
+func compareRelease(current, latest string) *updateCheck {
+	check := &updateCheck{Current: current, Latest: latest}
+	cmp, ok := compareVersions(latest, current)
+	check.Known = ok
+	if !ok || cmp <= 0 {
+		return check
+	}
+
+	c, _ := semver.NewVersion(current)
+	l, _ := semver.NewVersion(latest)
+	check.Available = true
+	switch {
+	case l.Major() != c.Major():
+		check.Level = "major"
+	case l.Minor() != c.Minor():
+		check.Level = "minor"
+	default:
+		check.Level = "patch"
+	}
+	return check
+}
+
+func (c *updateCheck) String() string {
+	switch {
+	case !c.Known:
+		return fmt.Sprintf("Can't tell whether an update is available: current build %s isn't a release (latest: %s)", c.Current, c.Latest)
+	case c.Available:
+		return fmt.Sprintf("Update available: %s -> %s (%s)", c.Current, c.Latest, c.Level)
+	default:
+		return fmt.Sprintf("Gitspace is up to date: %s (latest: %s)", c.Current, c.Latest)
+	}
+}
+
+// releaseChecksumsAsset lists the SHA-256 of every binary in a release, in
+// sha256sum format
+const releaseChecksumsAsset = "checksums.txt"
+
+func releaseAssetURL(repo, version, assetName string) string {
+	return fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, version, assetName)
+}
+
+// fetchReleaseChecksum returns the SHA-256 the release's checksums file
+// lists for assetName
+func fetchReleaseChecksum(repo, version, assetName string) (string, error) {
+	resp, err := http.Get(releaseAssetURL(repo, version, releaseChecksumsAsset))
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("release %s has no %s: %s", version, releaseChecksumsAsset, resp.Status)
+	}
 
-	tempFile, err := os.CreateTemp("", "gitspace-*")
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
 	}
+	return parseReleaseChecksum(data, assetName)
+}
+
+func parseReleaseChecksum(data []byte, assetName string) (string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		// sha256sum marks binary mode with a * before the name
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == assetName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s doesn't list %s", releaseChecksumsAsset, assetName)
+}
+
+// downloadBinary downloads url to a temporary file in dir, returning its path
+// and SHA-256. The path is set whenever the file was created, even on error.
+func downloadBinary(url, dir string) (string, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	tempFile, err := os.CreateTemp(dir, ".gitspace-upgrade-*")
+	if err != nil {
+		return "", "", err
+	}
 	defer tempFile.Close()
 
-	_, err = io.Copy(tempFile, resp.Body)
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tempFile, h), resp.Body)
 	if err != nil {
-		return "", err
+		return tempFile.Name(), "", err
 	}
 
-	return tempFile.Name(), nil
+	return tempFile.Name(), hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func printVersionInfo(logger *logger.RateLimitedLogger) {
@@ -167,6 +536,11 @@ func printVersionInfo(logger *logger.RateLimitedLogger) {
         logger.Info("Commit hash", "hash", commitHash)
     }
 
+    if lib.Offline() {
+        logger.Info("Offline mode: skipping the latest version check")
+        return
+    }
+
     releaseInfo, err := fetchLatestReleaseInfo("ssotops/gitspace")
     if err != nil {
         logger.Error("Failed to fetch latest release information", "error", err)
@@ -174,4 +548,14 @@ func printVersionInfo(logger *logger.RateLimitedLogger) {
     }
 
     logger.Info("Latest version", "version", releaseInfo.TagName)
+
+    check := compareRelease(version, releaseInfo.TagName)
+    switch {
+    case !check.Known:
+        logger.Info("Update check skipped: this build isn't a release version", "version", version)
+    case check.Available:
+        logger.Info("Update available", "version", check.Latest, "level", check.Level)
+    default:
+        logger.Info("Gitspace is up to date")
+    }
 }
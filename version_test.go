@@ -0,0 +1,624 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testReleaseServer serves GitHub releases of ssotops/gitspace and their
+// assets through http.DefaultTransport, recording the paths requested
+type testReleaseServer struct {
+	releases  []ReleaseInfo     // Newest first; the first is the latest
+	assets    map[string]string // By tag/name
+	mu        sync.Mutex
+	requested []string
+}
+
+// testAssetName is the upgrade binary for the running platform
+var testAssetName = fmt.Sprintf("gitspace_%s_%s", runtime.GOOS, runtime.GOARCH)
+
+// serveTestReleases stubs the network with a release server for releases
+func serveTestReleases(t *testing.T, releases ...ReleaseInfo) *testReleaseServer {
+	t.Helper()
+	s := &testReleaseServer{releases: releases, assets: make(map[string]string)}
+	stubTransport(t, s.serve)
+	return s
+}
+
+// addBinary adds the upgrade binary to the release tagged tag, listing sum
+// for it in the release's checksums file
+func (s *testReleaseServer) addBinary(tag, content, sum string) {
+	s.assets[tag+"/"+testAssetName] = content
+	s.assets[tag+"/"+releaseChecksumsAsset] = sum + "  other_asset\n" + sum + " *" + testAssetName + "\n"
+}
+
+func (s *testReleaseServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requested = append(s.requested, r.URL.Path)
+	s.mu.Unlock()
+
+	const api, download = "/repos/ssotops/gitspace/releases", "/ssotops/gitspace/releases/download/"
+	switch {
+	case r.URL.Host == "github.com" && strings.HasPrefix(r.URL.Path, download):
+		if asset, ok := s.assets[strings.TrimPrefix(r.URL.Path, download)]; ok {
+			io.WriteString(w, asset)
+			return
+		}
+	case r.URL.Path == api:
+		json.NewEncoder(w).Encode(s.releases)
+		return
+	case r.URL.Path == api+"/latest" && len(s.releases) > 0:
+		json.NewEncoder(w).Encode(s.releases[0])
+		return
+	case strings.HasPrefix(r.URL.Path, api+"/tags/"):
+		for _, release := range s.releases {
+			if release.TagName == strings.TrimPrefix(r.URL.Path, api+"/tags/") {
+				json.NewEncoder(w).Encode(release)
+				return
+			}
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// downloaded reports whether the release tagged tag's binary was requested
+func (s *testReleaseServer) downloaded(tag string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range s.requested {
+		if strings.HasSuffix(path, "/"+tag+"/"+testAssetName) {
+			return true
+		}
+	}
+	return false
+}
+
+// useVersion sets the version the running build reports
+func useVersion(t *testing.T, version string) {
+	previous := Version
+	Version = version
+	t.Cleanup(func() { Version = previous })
+}
+
+// sha256Hex returns the hex SHA-256 of content
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// alwaysConfirm accepts every upgrade prompt
+func alwaysConfirm(string) (bool, error) { return true, nil }
+
+func TestParseReleaseChecksum(t *testing.T) {
+	data := []byte("ABC123  gitspace_linux_amd64\ndef456 *gitspace_darwin_arm64\nbad line\n")
+	tests := []struct {
+		asset, want string
+	}{
+		{"gitspace_linux_amd64", "abc123"},
+		{"gitspace_darwin_arm64", "def456"},
+	}
+	for _, tt := range tests {
+		if got, err := parseReleaseChecksum(data, tt.asset); err != nil || got != tt.want {
+			t.Errorf("parseReleaseChecksum(%s) = %q, %v, want %q", tt.asset, got, err, tt.want)
+		}
+	}
+	if _, err := parseReleaseChecksum(data, "gitspace_windows_amd64.exe"); err == nil {
+		t.Error("found a checksum for an unlisted asset")
+	}
+}
+
+func TestUpgradeAbortsOnChecksumMismatch(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	useVerbosity(t, verbosityQuiet)
+	server := serveTestReleases(t, ReleaseInfo{TagName: "v1.1.0"})
+	server.addBinary("v1.1.0", "tampered binary", sha256Hex("release binary"))
+
+	execPath, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = upgradeGitspace(logger, "", alwaysConfirm)
+	if err == nil || !strings.Contains(err.Error(), "doesn't match the release checksum") {
+		t.Fatalf("got error %v, want the checksum mismatch", err)
+	}
+	if !server.downloaded("v1.1.0") {
+		t.Error("the binary wasn't downloaded")
+	}
+	after, err := os.Stat(execPath)
+	if err != nil || !os.SameFile(before, after) || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("the running binary was replaced: %v", err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(execPath), ".gitspace-upgrade-*"))
+	if _, err := os.Stat(execPath + ".bak"); err == nil || len(leftovers) > 0 {
+		t.Errorf("the aborted upgrade left files behind: %v", leftovers)
+	}
+}
+
+func TestUpgradeRequiresChecksumsFile(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	useVerbosity(t, verbosityQuiet)
+	server := serveTestReleases(t, ReleaseInfo{TagName: "v1.1.0"})
+	server.assets["v1.1.0/"+testAssetName] = "release binary"
+
+	err := upgradeGitspace(logger, "", alwaysConfirm)
+	if err == nil || !strings.Contains(err.Error(), "failed to fetch release checksum") {
+		t.Fatalf("got error %v, want the missing checksums file reported", err)
+	}
+	if server.downloaded("v1.1.0") {
+		t.Error("the binary was downloaded without a checksum to verify it")
+	}
+}
+
+func TestDownloadBinary(t *testing.T) {
+	serveTestReleases(t).assets["v1.1.0/"+testAssetName] = "release binary"
+	dir := t.TempDir()
+
+	path, sum, err := downloadBinary(releaseAssetURL("ssotops/gitspace", "v1.1.0", testAssetName), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "release binary" || filepath.Dir(path) != dir {
+		t.Errorf("downloaded %q to %s", data, path)
+	}
+	if sum != sha256Hex("release binary") {
+		t.Errorf("sum %s, want the binary's SHA-256", sum)
+	}
+
+	if path, _, err := downloadBinary(releaseAssetURL("ssotops/gitspace", "v9.9.9", testAssetName), dir); err == nil || path != "" {
+		t.Errorf("a missing asset returned %q, %v", path, err)
+	}
+}
+
+// writeScript writes an executable shell script to path
+func writeScript(t *testing.T, path, script string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	tests := []struct {
+		name       string
+		newScript  string // Empty for a missing new binary
+		unverified bool   // A release too old to be test-run
+		wantErr    string
+	}{
+		{"swapped", "exit 0", false, ""},
+		{"new binary fails to run", "echo broken >&2; exit 1", false, "new binary failed to run"},
+		{"new binary hangs", "sleep 30", false, "timed out"},
+		{"old release isn't run", "exit 1", true, ""},
+		{"new binary missing", "", false, "no such file"},
+	}
+	previous := smokeTestTimeout
+	smokeTestTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { smokeTestTimeout = previous })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			execPath := filepath.Join(dir, "gitspace")
+			newPath := filepath.Join(dir, ".gitspace-upgrade-1")
+			writeScript(t, execPath, "# old")
+			if tt.newScript != "" {
+				writeScript(t, newPath, tt.newScript)
+			}
+
+			backup, err := replaceExecutable(newPath, execPath, !tt.unverified)
+			current, _ := os.ReadFile(execPath)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(current), tt.newScript) {
+					t.Errorf("executable is %q, want the new binary", current)
+				}
+				if old, err := os.ReadFile(backup); err != nil || !strings.Contains(string(old), "# old") {
+					t.Errorf("backup %s holds %q, %v", backup, old, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+			// The old binary is back in place, not left as the backup
+			if !strings.Contains(string(current), "# old") {
+				t.Errorf("executable is %q after a failed upgrade, want the old binary", current)
+			}
+			if _, err := os.Stat(execPath + ".bak"); !os.IsNotExist(err) {
+				t.Error("the backup wasn't moved back")
+			}
+		})
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := os.WriteFile(src, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "binary" {
+		t.Errorf("moved %q, %v", data, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("the source is still there")
+	}
+}
+
+func TestReleaseAssetURL(t *testing.T) {
+	got := releaseAssetURL("ssotops/gitspace", "v1.2.3", "gitspace_linux_amd64")
+	if want := "https://github.com/ssotops/gitspace/releases/download/v1.2.3/gitspace_linux_amd64"; got != want {
+		t.Errorf("releaseAssetURL = %s, want %s", got, want)
+	}
+}
+
+func TestNormalizeReleaseTag(t *testing.T) {
+	tests := map[string]string{"": "", "1.2.3": "v1.2.3", "v1.2.3": "v1.2.3", " 1.2.3 ": "v1.2.3"}
+	for version, want := range tests {
+		if got := normalizeReleaseTag(version); got != want {
+			t.Errorf("normalizeReleaseTag(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestUpgradeToPinnedVersion(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	useVerbosity(t, verbosityQuiet)
+	server := serveTestReleases(t, ReleaseInfo{TagName: "v1.2.0"}, ReleaseInfo{TagName: "v1.1.0"})
+	// A mismatched checksum stops the upgrade before the test binary is replaced
+	for _, tag := range []string{"v1.2.0", "v1.1.0"} {
+		server.addBinary(tag, "binary "+tag, sha256Hex("other"))
+	}
+
+	var title string
+	err := upgradeGitspace(logger, "1.1.0", func(prompt string) (bool, error) {
+		title = prompt
+		return true, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "doesn't match the release checksum") {
+		t.Fatalf("got error %v, want the upgrade to reach the checksum check", err)
+	}
+	if !server.downloaded("v1.1.0") || server.downloaded("v1.2.0") {
+		t.Errorf("requested %v, want only the v1.1.0 binary", server.requested)
+	}
+	if title != "Upgrade from v1.0.0 to v1.1.0?" {
+		t.Errorf("asked %q", title)
+	}
+}
+
+func TestUpgradeToPinnedReleaseWithoutChecksums(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.2.0")
+	useVerbosity(t, verbosityQuiet)
+	// v1.0.5 predates checksums.txt; its binary is left out so the upgrade
+	// stops at the download instead of replacing the test binary
+	server := serveTestReleases(t, ReleaseInfo{TagName: "v1.2.0"}, ReleaseInfo{TagName: "v1.0.5"})
+
+	err := upgradeGitspace(logger, "v1.0.5", alwaysConfirm)
+	if err == nil || !strings.Contains(err.Error(), "failed to download binary") {
+		t.Fatalf("got error %v, want the upgrade to get past the checksum to the download", err)
+	}
+	if !server.downloaded("v1.0.5") {
+		t.Error("the binary wasn't requested")
+	}
+	for _, path := range server.requested {
+		if strings.HasSuffix(path, "/"+releaseChecksumsAsset) {
+			t.Errorf("requested %s for a release that predates it", path)
+		}
+	}
+	log, err := os.ReadFile(logger.GetLogFileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "predates checksums") {
+		t.Error("installing an unverified release wasn't warned about")
+	}
+}
+
+func TestIsVerifiedRelease(t *testing.T) {
+	tests := map[string]bool{
+		"v1.0.5":  false,
+		"v0.9.0":  false,
+		"v1.1.0":  true,
+		"v1.2.3":  true,
+		"abc1234": true, // Not comparable, so checked as usual
+	}
+	for version, want := range tests {
+		if got := isVerifiedRelease(version); got != want {
+			t.Errorf("isVerifiedRelease(%s) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestUpgradeRefusesUnknownVersion(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	server := serveTestReleases(t, ReleaseInfo{TagName: "v1.2.0"})
+
+	err := upgradeGitspace(logger, "v9.9.9", alwaysConfirm)
+	if err == nil || !strings.Contains(err.Error(), "there is no release v9.9.9") {
+		t.Errorf("got error %v, want the unknown tag reported", err)
+	}
+	if server.downloaded("v9.9.9") {
+		t.Error("downloaded a binary for an unknown release")
+	}
+}
+
+func TestDowngradeNeedsConfirmation(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v2.0.0")
+	useVerbosity(t, verbosityQuiet)
+	server := serveTestReleases(t, ReleaseInfo{TagName: "v2.0.0"}, ReleaseInfo{TagName: "v1.1.0"})
+	server.addBinary("v1.1.0", "binary", sha256Hex("binary"))
+
+	var title string
+	err := upgradeGitspace(logger, "v1.1.0", func(prompt string) (bool, error) {
+		title = prompt
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf("a declined downgrade failed: %v", err)
+	}
+	if !strings.Contains(title, "v1.1.0 is older than the current v2.0.0. Downgrade?") {
+		t.Errorf("asked %q, want the downgrade called out", title)
+	}
+	if server.downloaded("v1.1.0") {
+		t.Error("a declined downgrade downloaded the binary")
+	}
+}
+
+// tagNames returns the tags of releases, in order
+func tagNames(releases []ReleaseInfo) []string {
+	tags := make([]string, len(releases))
+	for i, release := range releases {
+		tags[i] = release.TagName
+	}
+	return tags
+}
+
+func TestReleaseNotes(t *testing.T) {
+	serveTestReleases(t,
+		ReleaseInfo{TagName: "v1.3.0-rc.1", Prerelease: true},
+		ReleaseInfo{TagName: "v1.2.0"},
+		ReleaseInfo{TagName: "v1.1.1"},
+		ReleaseInfo{TagName: "v1.1.0", Draft: true},
+		ReleaseInfo{TagName: "v1.0.0"},
+		ReleaseInfo{TagName: "v0.9.0"},
+	)
+	tests := []struct {
+		current, target string
+		want            []string
+	}{
+		{"v1.0.0", "v1.2.0", []string{"v1.2.0", "v1.1.1"}},
+		{"v1.0.0", "v1.1.1", []string{"v1.1.1"}},
+		// Downgrades and unversioned builds only show the target
+		{"v1.2.0", "v1.0.0", []string{"v1.0.0"}},
+		{"abc1234", "v1.2.0", []string{"v1.2.0"}},
+	}
+	for _, tt := range tests {
+		got := tagNames(releaseNotes("ssotops/gitspace", tt.current, &ReleaseInfo{TagName: tt.target}))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("notes from %s to %s: %v, want %v", tt.current, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseNotesWithoutReleaseList(t *testing.T) {
+	stubTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	got := releaseNotes("ssotops/gitspace", "v1.0.0", &ReleaseInfo{TagName: "v1.2.0", Body: "notes"})
+	if len(got) != 1 || got[0].Body != "notes" {
+		t.Errorf("got %+v, want just the target's notes", got)
+	}
+}
+
+func TestUpgradeShowsReleaseNotesBeforeDownloading(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	useVerbosity(t, verbosityNormal)
+	server := serveTestReleases(t,
+		ReleaseInfo{TagName: "v1.2.0", Name: "Spring", Body: "Fixes the sync bug"},
+		ReleaseInfo{TagName: "v1.1.0", Body: "  "},
+		ReleaseInfo{TagName: "v1.0.0", Body: "Already installed"},
+	)
+	server.addBinary("v1.2.0", "binary", sha256Hex("binary"))
+
+	asked := false
+	output := captureStdout(t, func() {
+		err := upgradeGitspace(logger, "", func(string) (bool, error) {
+			asked = true
+			if server.downloaded("v1.2.0") {
+				t.Error("the binary was downloaded before confirming")
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Errorf("a declined upgrade failed: %v", err)
+		}
+	})
+
+	if !asked {
+		t.Fatal("the upgrade didn't ask for confirmation")
+	}
+	newest := strings.Index(output, "v1.2.0 - Spring")
+	older := strings.Index(output, "v1.1.0")
+	if newest < 0 || older < newest || !strings.Contains(output, "Fixes the sync bug") {
+		t.Errorf("release notes aren't listed newest first:\n%s", output)
+	}
+	if !strings.Contains(output, "No release notes.") {
+		t.Errorf("a release without notes isn't called out:\n%s", output)
+	}
+	if strings.Contains(output, "Already installed") {
+		t.Errorf("the installed release's notes were shown:\n%s", output)
+	}
+	if server.downloaded("v1.2.0") {
+		t.Error("a declined upgrade downloaded the binary")
+	}
+}
+
+func TestQuietUpgradeSkipsReleaseNotes(t *testing.T) {
+	logger := newTestLogger(t)
+	useVersion(t, "v1.0.0")
+	useVerbosity(t, verbosityQuiet)
+	serveTestReleases(t, ReleaseInfo{TagName: "v1.2.0", Body: "Fixes the sync bug"})
+
+	output := captureStdout(t, func() {
+		upgradeGitspace(logger, "", func(string) (bool, error) { return false, nil })
+	})
+	if strings.Contains(output, "Fixes the sync bug") {
+		t.Errorf("--quiet printed release notes:\n%s", output)
+	}
+}
+
+func TestCompareRelease(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		available       bool
+		level           string
+		known           bool
+	}{
+		{"v1.2.3", "v2.0.0", true, "major", true},
+		{"v1.2.3", "v1.3.0", true, "minor", true},
+		{"v1.2.3", "v1.2.4", true, "patch", true},
+		{"1.2.3", "v1.2.4", true, "patch", true},
+		{"v1.2.3", "v1.2.3", false, "", true},
+		{"v1.3.0", "v1.2.3", false, "", true},
+		{"v1.2.3-rc.1", "v1.2.3", true, "patch", true},
+		{"abc1234", "v1.2.3", false, "", false},
+	}
+	for _, tt := range tests {
+		got := compareRelease(tt.current, tt.latest)
+		if got.Available != tt.available || got.Level != tt.level || got.Known != tt.known {
+			t.Errorf("compareRelease(%s, %s) = %+v", tt.current, tt.latest, got)
+		}
+	}
+}
+
+func TestUpdateCheckString(t *testing.T) {
+	tests := []struct {
+		current, want string
+	}{
+		{"v1.0.0", "Update available: v1.0.0 -> v1.1.0 (minor)"},
+		{"v1.1.0", "Gitspace is up to date: v1.1.0 (latest: v1.1.0)"},
+		{"abc1234", "Can't tell whether an update is available: current build abc1234 isn't a release (latest: v1.1.0)"},
+	}
+	for _, tt := range tests {
+		if got := compareRelease(tt.current, "v1.1.0").String(); got != tt.want {
+			t.Errorf("from %s: %q, want %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestCheckUpdateCommand(t *testing.T) {
+	tests := []struct {
+		current  string
+		wantCode int
+		wantOut  string
+	}{
+		{"v1.0.0", exitUpdateAvailable, "Update available: v1.0.0 -> v1.1.0 (minor)"},
+		{"v1.1.0", exitOK, "up to date"},
+		{"abc1234", exitOK, "isn't a release"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.current, func(t *testing.T) {
+			logger := newTestLogger(t)
+			useVersion(t, tt.current)
+			server := serveTestReleases(t, ReleaseInfo{TagName: "v1.1.0"})
+			server.addBinary("v1.1.0", "binary", sha256Hex("binary"))
+
+			var code int
+			output := captureStdout(t, func() { code = runCLI(logger, []string{"--check-update"}) })
+			if code != tt.wantCode || !strings.Contains(output, tt.wantOut) {
+				t.Errorf("exited %d with %q, want %d and %q", code, output, tt.wantCode, tt.wantOut)
+			}
+			if server.downloaded("v1.1.0") {
+				t.Error("checking for an update downloaded the release")
+			}
+		})
+	}
+}
+
+func TestCheckUpdateCommandFails(t *testing.T) {
+	logger := newTestLogger(t)
+	serveTestReleases(t)
+	if code := runCLI(logger, []string{"--check-update"}); code != exitError {
+		t.Errorf("exited %d when the latest release couldn't be fetched, want %d", code, exitError)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		wantCmp int
+		wantOK  bool
+	}{
+		{"v1.2.3", "1.2.3", 0, true},
+		{"v1.10.0", "v1.9.0", 1, true},
+		{"v1.2.3", "v1.2.4", -1, true},
+		{"v1.2.3-rc.1", "v1.2.3", -1, true},
+		{"abc1234", "v1.2.3", 0, false},
+		{"v1.2.3", "unknown", 0, false},
+		// An all-digit commit hash isn't read as a major version
+		{"1234567", "v1.2.3", 0, false},
+	}
+	for _, tt := range tests {
+		cmp, ok := compareVersions(tt.a, tt.b)
+		if cmp != tt.wantCmp || ok != tt.wantOK {
+			t.Errorf("compareVersions(%s, %s) = %d, %v, want %d, %v", tt.a, tt.b, cmp, ok, tt.wantCmp, tt.wantOK)
+		}
+	}
+	if !isOlderRelease("v1.0.0", "v1.1.0") || isOlderRelease("v1.1.0", "v1.0.0") || isOlderRelease("v1.0.0", "abc1234") {
+		t.Error("isOlderRelease disagrees with compareVersions")
+	}
+}
+
+func TestPrintVersionInfo(t *testing.T) {
+	tests := []struct {
+		current, want string
+	}{
+		{"v1.0.0", "Update available"},
+		{"v1.1.0", "Gitspace is up to date"},
+		{"abc1234", "Update check skipped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.current, func(t *testing.T) {
+			logger := newTestLogger(t)
+			useVersion(t, tt.current)
+			serveTestReleases(t, ReleaseInfo{TagName: "v1.1.0"})
+
+			printVersionInfo(logger)
+			log, err := os.ReadFile(logger.GetLogFileName())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(log), "Latest version version=v1.1.0") || !strings.Contains(string(log), tt.want) {
+				t.Errorf("logged:\n%s\nwant %q", log, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ssotops/gitspace-plugin-sdk/logger"
+)
+
+const defaultWatchInterval = 10 * time.Minute
+
+// getWatchInterval returns global.watch_interval, or the default when it
+// isn't set
+func getWatchInterval(config *Config) time.Duration {
+	if config.Global.WatchInterval == "" {
+		return defaultWatchInterval
+	}
+	interval, err := time.ParseDuration(config.Global.WatchInterval)
+	if err != nil || interval <= 0 {
+		return defaultWatchInterval
+	}
+	return interval
+}
+
+// watchRepositories syncs right away and then every interval until ctx is
+// cancelled. Only one sync runs at a time: a tick that arrives while the
+// previous sync is still going is skipped rather than queued. When ctx is
+// cancelled mid-sync, the sync is allowed to finish so no fetch is left half
+// done.
+func watchRepositories(ctx context.Context, logger *logger.RateLimitedLogger, config *Config, interval time.Duration) {
+	var running atomic.Bool
+	done := make(chan struct{}, 1)
+
+	startSync := func() {
+		if !running.CompareAndSwap(false, true) {
+			logger.Warn("Previous sync is still running, skipping this one", "interval", interval)
+			return
+		}
+		go func() {
+			defer func() {
+				running.Store(false)
+				done <- struct{}{}
+			}()
+
+			start := time.Now()
+			results, err := syncRepositories(logger, config, syncOptions{})
+			summary := summarizeResults(results)
+			if err != nil {
+				logger.Error("Sync finished with errors", "error", err, "failed", summary.Failed)
+			}
+			logger.Info("Sync finished", "repositories", summary.Total, "updated", summary.Updated,
+				"failed", summary.Failed, "took", time.Since(start).Round(time.Second))
+		}()
+	}
+
+	logger.Info("Watching repositories", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	nextRun := time.Now().Add(interval)
+
+	startSync()
+	for {
+		select {
+		case <-ctx.Done():
+			if running.Load() {
+				logger.Info("Stopping watch after the current sync finishes")
+				<-done
+			}
+			logger.Info("Stopped watching repositories")
+			return
+		case <-done:
+			logger.Info("Next sync", "at", nextRun.Format(time.TimeOnly))
+		case now := <-ticker.C:
+			nextRun = now.Add(interval)
+			startSync()
+		}
+	}
+}
+
+// parseWatchInterval parses an interval from the command line or the menu
+func parseWatchInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("interval must be positive (got %s)", value)
+	}
+	return interval, nil
+}